@@ -1,3 +1,7 @@
 CREATE USER kuzkus WITH PASSWORD 'postgres_url';
 
-GRANT SELECT, INSERT ON ALL TABLES IN SCHEMA public TO kuzkus;
+-- tables are created by migrations of the service, which may connect as kuzkus
+GRANT USAGE, CREATE ON SCHEMA public TO kuzkus;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO kuzkus;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO kuzkus;
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kuzkuss/url_service/models"
+	link "github.com/kuzkuss/url_service/proto/link"
+)
+
+const topHosts = 10
+
+type cli struct {
+	client  link.LinksClient
+	in      io.Reader
+	out     io.Writer
+	output  string
+	timeout time.Duration
+	apiKey  string
+	token   string
+	// short domain of the links, the default one of the server if empty
+	domain  string
+	now     func() time.Time
+}
+
+// LinkStats summarizes links of the client.
+type LinkStats struct {
+	Total        int         `json:"total"`
+	CreatedDay   int         `json:"created_last_day"`
+	CreatedWeek  int         `json:"created_last_week"`
+	CreatedMonth int         `json:"created_last_month"`
+	Hosts        []HostStats `json:"top_hosts"`
+}
+
+type HostStats struct {
+	Host  string `json:"host"`
+	Links int    `json:"links"`
+}
+
+// LinkEvent is a change of the link received by watch.
+type LinkEvent struct {
+	Cursor       string    `json:"cursor"`
+	Type         string    `json:"type"`
+	ShortLink    string    `json:"short_link"`
+	OriginalLink string    `json:"original_link,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// ImportResult is the outcome of creating a link for an imported URL.
+type ImportResult struct {
+	OriginalLink string `json:"original_link"`
+	ShortLink    string `json:"short_link,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func (c *cli) runCommand(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "create":
+		return c.create(ctx, args)
+	case "get":
+		return c.get(ctx, args)
+	case "list":
+		return c.list(ctx, args)
+	case "update":
+		return c.update(ctx, args)
+	case "delete":
+		return c.delete(ctx, args)
+	case "import":
+		return c.importLinks(ctx, args)
+	case "export":
+		return c.export(ctx, args)
+	case "stats":
+		return c.stats(ctx, args)
+	case "watch":
+		return c.watch(ctx, args)
+	}
+
+	return usageError{msg: "unknown command " + name}
+}
+
+// callContext limits the call by the timeout and adds credentials to its metadata.
+func (c *cli) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.withCredentials(ctx), c.timeout)
+}
+
+func (c *cli) withCredentials(ctx context.Context) context.Context {
+	if c.token != "" {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer " + c.token)
+	} else if c.apiKey != "" {
+		return metadata.AppendToOutgoingContext(ctx, "x-api-key", c.apiKey)
+	}
+	return ctx
+}
+
+func (c *cli) create(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	idempotencyKey := flags.String("idempotency-key", "", "key making retries of the request safe")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return usageError{msg: "usage: create [-idempotency-key key] <original_link>"}
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	if *idempotencyKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "idempotency-key", *idempotencyKey)
+	}
+
+	shortLink, err := c.client.CreateShortLink(ctx, &link.OriginalLink{OriginalLink: flags.Arg(0), Domain: c.domain})
+	if err != nil {
+		return err
+	}
+
+	return c.printLinks([]models.Link{{OriginalLink: flags.Arg(0), ShortLink: shortLink.ShortLink, Domain: shortLink.Domain, ShortURL: shortLink.ShortUrl}})
+}
+
+func (c *cli) get(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usageError{msg: "usage: get <short_link>"}
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	originalLink, err := c.client.GetOriginalLink(ctx, &link.ShortLink{ShortLink: args[0], Domain: c.domain})
+	if err != nil {
+		return err
+	}
+
+	return c.printLinks([]models.Link{{OriginalLink: originalLink.OriginalLink, ShortLink: args[0]}})
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return usageError{msg: "usage: list"}
+	}
+
+	links, err := c.listLinks(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.printLinks(links)
+}
+
+func (c *cli) update(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return usageError{msg: "usage: update <short_link> <original_link>"}
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	_, err := c.client.UpdateLink(ctx, &link.Link{ShortLink: args[0], OriginalLink: args[1], Domain: c.domain})
+	if err != nil {
+		return err
+	}
+
+	return c.printLinks([]models.Link{{OriginalLink: args[1], ShortLink: args[0]}})
+}
+
+// delete deletes every given link, even if deletion of some of them fails,
+// and returns the last error.
+func (c *cli) delete(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError{msg: "usage: delete <short_link>..."}
+	}
+
+	var lastErr error
+	deleted := make([]models.Link, 0, len(args))
+	for _, shortLink := range args {
+		callCtx, cancel := c.callContext(ctx)
+		_, err := c.client.DeleteLink(callCtx, &link.ShortLink{ShortLink: shortLink, Domain: c.domain})
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		deleted = append(deleted, models.Link{ShortLink: shortLink})
+	}
+
+	if err := c.printLinks(deleted); err != nil {
+		return err
+	}
+	return lastErr
+}
+
+// importLinks creates links for every URL of the input, even if creation of
+// some of them fails, and returns the last error.
+func (c *cli) importLinks(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageError{msg: "usage: import [file]"}
+	}
+
+	data, err := c.readInput(args)
+	if err != nil {
+		return err
+	}
+	originalLinks, err := parseImport(data)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	results := make([]ImportResult, 0, len(originalLinks))
+	for _, originalLink := range originalLinks {
+		result := ImportResult{OriginalLink: originalLink}
+
+		callCtx, cancel := c.callContext(ctx)
+		shortLink, err := c.client.CreateShortLink(callCtx, &link.OriginalLink{OriginalLink: originalLink, Domain: c.domain})
+		cancel()
+		if err != nil {
+			lastErr = err
+			result.Error = statusMessage(err)
+		} else {
+			result.ShortLink = shortLink.ShortLink
+		}
+
+		results = append(results, result)
+	}
+
+	if err := c.printImport(results); err != nil {
+		return err
+	}
+	return lastErr
+}
+
+// export writes links as JSON, which import reads back.
+func (c *cli) export(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usageError{msg: "usage: export [file]"}
+	}
+
+	links, err := c.listLinks(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "export encoding error")
+	}
+	data = append(data, '\n')
+
+	if len(args) == 0 || args[0] == "-" {
+		_, err = c.out.Write(data)
+		return errors.Wrap(err, "export writing error")
+	}
+	return errors.Wrap(os.WriteFile(args[0], data, 0600), "export writing error")
+}
+
+func (c *cli) stats(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return usageError{msg: "usage: stats"}
+	}
+
+	links, err := c.listLinks(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.printStats(computeStats(links, c.now()))
+}
+
+func (c *cli) listLinks(ctx context.Context) ([]models.Link, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	list, err := c.client.ListLinks(ctx, &link.Nothing{})
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]models.Link, 0, len(list.Links))
+	for _, pbLink := range list.Links {
+		modelLink := models.Link{
+			ShortLink:    pbLink.ShortLink,
+			OriginalLink: pbLink.OriginalLink,
+			Domain:       pbLink.Domain,
+			ShortURL:     pbLink.ShortUrl,
+		}
+		if pbLink.CreatedAt != nil {
+			createdAt := pbLink.CreatedAt.AsTime()
+			modelLink.CreatedAt = &createdAt
+		}
+		if pbLink.UpdatedAt != nil {
+			updatedAt := pbLink.UpdatedAt.AsTime()
+			modelLink.UpdatedAt = &updatedAt
+		}
+		links = append(links, modelLink)
+	}
+
+	return links, nil
+}
+
+func (c *cli) readInput(args []string) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		data, err := io.ReadAll(c.in)
+		return data, errors.Wrap(err, "import reading error")
+	}
+
+	data, err := os.ReadFile(args[0])
+	return data, errors.Wrap(err, "import reading error")
+}
+
+// parseImport returns original links of JSON written by export or of lines of text,
+// skipping empty lines and lines starting with #.
+func parseImport(data []byte) ([]string, error) {
+	var originalLinks []string
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var links []models.Link
+		if err := json.Unmarshal(trimmed, &links); err != nil {
+			return nil, errors.Wrap(err, "import decoding error")
+		}
+		for _, l := range links {
+			originalLinks = append(originalLinks, l.OriginalLink)
+		}
+		return originalLinks, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		originalLinks = append(originalLinks, line)
+	}
+
+	return originalLinks, errors.Wrap(scanner.Err(), "import reading error")
+}
+
+func computeStats(links []models.Link, now time.Time) LinkStats {
+	stats := LinkStats{
+		Total: len(links),
+		Hosts: make([]HostStats, 0),
+	}
+
+	hosts := make(map[string]int)
+	for _, l := range links {
+		if l.CreatedAt != nil {
+			age := now.Sub(*l.CreatedAt)
+			if age <= 24*time.Hour {
+				stats.CreatedDay++
+			}
+			if age <= 7*24*time.Hour {
+				stats.CreatedWeek++
+			}
+			if age <= 30*24*time.Hour {
+				stats.CreatedMonth++
+			}
+		}
+
+		host := l.OriginalLink
+		if u, err := url.Parse(l.OriginalLink); err == nil && u.Host != "" {
+			host = u.Hostname()
+		}
+		hosts[host]++
+	}
+
+	for host, count := range hosts {
+		stats.Hosts = append(stats.Hosts, HostStats{Host: host, Links: count})
+	}
+	sort.Slice(stats.Hosts, func(i, j int) bool {
+		if stats.Hosts[i].Links != stats.Hosts[j].Links {
+			return stats.Hosts[i].Links > stats.Hosts[j].Links
+		}
+		return stats.Hosts[i].Host < stats.Hosts[j].Host
+	})
+	if len(stats.Hosts) > topHosts {
+		stats.Hosts = stats.Hosts[:topHosts]
+	}
+
+	return stats
+}
+
+// watch prints changes of links as they happen until the server ends the stream.
+// The stream is not limited by the timeout.
+func (c *cli) watch(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	cursor := flags.String("cursor", "", "cursor of the last received event to resume after it")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return usageError{msg: "usage: watch [-cursor cursor]"}
+	}
+
+	stream, err := c.client.WatchLinks(c.withCredentials(ctx), &link.WatchLinksRequest{Cursor: *cursor})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		err = c.printEvent(LinkEvent{
+			Cursor:       event.Cursor,
+			Type:         strings.ToLower(event.Type.String()),
+			ShortLink:    event.GetLink().GetShortLink(),
+			OriginalLink: event.GetLink().GetOriginalLink(),
+			Time:         event.Time.AsTime(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
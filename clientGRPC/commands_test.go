@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	link "github.com/kuzkuss/url_service/proto/link"
+)
+
+type TestCaseCommand struct {
+	Command string
+	Args []string
+	Input string
+	Output string
+	ExpectedOutput string
+	ExpectedCode int
+}
+
+var testNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+// linksClientStub stores links in memory and records metadata of the last call.
+type linksClientStub struct {
+	links    map[string]string
+	created  []*link.Link
+	metadata metadata.MD
+}
+
+func (s *linksClientStub) CreateShortLink(ctx context.Context, in *link.OriginalLink, _ ...grpc.CallOption) (*link.ShortLink, error) {
+	s.metadata, _ = metadata.FromOutgoingContext(ctx)
+	if !strings.HasPrefix(in.OriginalLink, "https://") {
+		return nil, status.Error(codes.InvalidArgument, "bad request")
+	}
+	return &link.ShortLink{ShortLink: "short_" + strings.TrimPrefix(in.OriginalLink, "https://")}, nil
+}
+
+func (s *linksClientStub) GetOriginalLink(ctx context.Context, in *link.ShortLink, _ ...grpc.CallOption) (*link.OriginalLink, error) {
+	s.metadata, _ = metadata.FromOutgoingContext(ctx)
+	originalLink, ok := s.links[in.ShortLink]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "item is not found")
+	}
+	return &link.OriginalLink{OriginalLink: originalLink}, nil
+}
+
+func (s *linksClientStub) ListLinks(ctx context.Context, _ *link.Nothing, _ ...grpc.CallOption) (*link.LinkList, error) {
+	s.metadata, _ = metadata.FromOutgoingContext(ctx)
+	return &link.LinkList{Links: s.created}, nil
+}
+
+func (s *linksClientStub) UpdateLink(ctx context.Context, in *link.Link, _ ...grpc.CallOption) (*link.Nothing, error) {
+	s.metadata, _ = metadata.FromOutgoingContext(ctx)
+	if _, ok := s.links[in.ShortLink]; !ok {
+		return nil, status.Error(codes.NotFound, "item is not found")
+	}
+	return &link.Nothing{}, nil
+}
+
+func (s *linksClientStub) DeleteLink(ctx context.Context, in *link.ShortLink, _ ...grpc.CallOption) (*link.Nothing, error) {
+	s.metadata, _ = metadata.FromOutgoingContext(ctx)
+	if _, ok := s.links[in.ShortLink]; !ok {
+		return nil, status.Error(codes.NotFound, "item is not found")
+	}
+	return &link.Nothing{}, nil
+}
+
+// WatchLinks streams creation of the stored links after the cursor, their indexes are the cursors.
+func (s *linksClientStub) WatchLinks(ctx context.Context, in *link.WatchLinksRequest, _ ...grpc.CallOption) (link.Links_WatchLinksClient, error) {
+	s.metadata, _ = metadata.FromOutgoingContext(ctx)
+	stream := &watchStreamStub{}
+	for idx, created := range s.created {
+		if in.Cursor == "" || strconv.Itoa(idx) > in.Cursor {
+			stream.events = append(stream.events, &link.LinkEvent{
+				Cursor: strconv.Itoa(idx),
+				Type: link.LinkEvent_CREATED,
+				Link: created,
+				Time: timestamppb.New(testNow),
+			})
+		}
+	}
+	if in.Cursor == "expired" {
+		stream.err = status.Error(codes.OutOfRange, "cursor is expired")
+	}
+	return stream, nil
+}
+
+type watchStreamStub struct {
+	grpc.ClientStream
+	events []*link.LinkEvent
+	err    error
+}
+
+func (s *watchStreamStub) Recv() (*link.LinkEvent, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.events) == 0 {
+		return nil, io.EOF
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func newStub() *linksClientStub {
+	return &linksClientStub{
+		links: map[string]string{"short_link": "https://go.dev"},
+		created: []*link.Link{
+			{ShortLink: "short_link", OriginalLink: "https://go.dev/doc", CreatedAt: timestamppb.New(testNow.Add(-time.Hour))},
+			{ShortLink: "short_old", OriginalLink: "https://go.dev/blog", CreatedAt: timestamppb.New(testNow.Add(-10 * 24 * time.Hour))},
+			{ShortLink: "short_other", OriginalLink: "https://example.com"},
+		},
+	}
+}
+
+func TestCommands(t *testing.T) {
+	cases := map[string]TestCaseCommand {
+		"create": {
+			Command: "create",
+			Args: []string{"https://go.dev"},
+			Output: outputJSON,
+			ExpectedOutput: "[\n  {\n    \"original_link\": \"https://go.dev\",\n    \"short_link\": \"short_go.dev\"\n  }\n]\n",
+		},
+		"create_invalid": {
+			Command: "create",
+			Args: []string{"go.dev"},
+			ExpectedCode: int(codes.InvalidArgument),
+		},
+		"get_not_found": {
+			Command: "get",
+			Args: []string{"short_missing"},
+			ExpectedCode: int(codes.NotFound),
+		},
+		"list": {
+			Command: "list",
+			Output: outputTable,
+			ExpectedOutput: "SHORT_LINK   ORIGINAL_LINK        CREATED_AT            UPDATED_AT\n" +
+				"short_link   https://go.dev/doc   2026-10-19T11:00:00Z  -\n" +
+				"short_old    https://go.dev/blog  2026-10-09T12:00:00Z  -\n" +
+				"short_other  https://example.com  -                     -\n",
+		},
+		"delete_partially": {
+			Command: "delete",
+			Args: []string{"short_missing", "short_link"},
+			Output: outputTable,
+			ExpectedOutput: "SHORT_LINK  ORIGINAL_LINK  CREATED_AT  UPDATED_AT\nshort_link                 -           -\n",
+			ExpectedCode: int(codes.NotFound),
+		},
+		"import_lines": {
+			Command: "import",
+			Input: "# links\nhttps://go.dev\n\ngo.dev\n",
+			Output: outputJSON,
+			ExpectedOutput: "[\n  {\n    \"original_link\": \"https://go.dev\",\n    \"short_link\": \"short_go.dev\"\n  },\n" +
+				"  {\n    \"original_link\": \"go.dev\",\n    \"error\": \"InvalidArgument: bad request\"\n  }\n]\n",
+			ExpectedCode: int(codes.InvalidArgument),
+		},
+		"import_export": {
+			Command: "import",
+			Input: `[{"original_link": "https://go.dev", "short_link": "short_link"}]`,
+			Output: outputTable,
+			ExpectedOutput: "ORIGINAL_LINK   SHORT_LINK    ERROR\nhttps://go.dev  short_go.dev  \n",
+		},
+		"stats": {
+			Command: "stats",
+			Output: outputJSON,
+			ExpectedOutput: "{\n  \"total\": 3,\n  \"created_last_day\": 1,\n  \"created_last_week\": 1,\n  \"created_last_month\": 2,\n" +
+				"  \"top_hosts\": [\n    {\n      \"host\": \"go.dev\",\n      \"links\": 2\n    },\n" +
+				"    {\n      \"host\": \"example.com\",\n      \"links\": 1\n    }\n  ]\n}\n",
+		},
+		"watch": {
+			Command: "watch",
+			Args: []string{"-cursor", "0"},
+			Output: outputTable,
+			ExpectedOutput: "2026-10-19T12:00:00Z\tcreated\tshort_old\thttps://go.dev/blog\t1\n" +
+				"2026-10-19T12:00:00Z\tcreated\tshort_other\thttps://example.com\t2\n",
+		},
+		"watch_json": {
+			Command: "watch",
+			Output: outputJSON,
+			ExpectedOutput: `{"cursor":"0","type":"created","short_link":"short_link","original_link":"https://go.dev/doc","time":"2026-10-19T12:00:00Z"}` + "\n" +
+				`{"cursor":"1","type":"created","short_link":"short_old","original_link":"https://go.dev/blog","time":"2026-10-19T12:00:00Z"}` + "\n" +
+				`{"cursor":"2","type":"created","short_link":"short_other","original_link":"https://example.com","time":"2026-10-19T12:00:00Z"}` + "\n",
+		},
+		"watch_expired": {
+			Command: "watch",
+			Args: []string{"-cursor", "expired"},
+			ExpectedCode: int(codes.OutOfRange),
+		},
+		"unknown": {
+			Command: "rename",
+			ExpectedCode: exitUsage,
+		},
+		"bad_arguments": {
+			Command: "update",
+			Args: []string{"short_link"},
+			ExpectedCode: exitUsage,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := &cli{
+				client: newStub(),
+				in: strings.NewReader(test.Input),
+				out: &out,
+				output: test.Output,
+				timeout: time.Second,
+				now: func() time.Time { return testNow },
+			}
+
+			err := c.runCommand(context.Background(), test.Command, test.Args)
+			require.Equal(t, test.ExpectedCode, exitCode(err))
+			if test.ExpectedOutput != "" {
+				assert.Equal(t, test.ExpectedOutput, out.String())
+			}
+		})
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	stub := newStub()
+	c := &cli{client: stub, out: &bytes.Buffer{}, timeout: time.Second, apiKey: "key"}
+
+	require.NoError(t, c.runCommand(context.Background(), "create", []string{"-idempotency-key", "request", "https://go.dev"}))
+	assert.Equal(t, []string{"key"}, stub.metadata.Get("x-api-key"))
+	assert.Equal(t, []string{"request"}, stub.metadata.Get("idempotency-key"))
+
+	c.token = "token"
+	require.NoError(t, c.runCommand(context.Background(), "get", []string{"short_link"}))
+	assert.Equal(t, []string{"Bearer token"}, stub.metadata.Get("authorization"))
+	assert.Empty(t, stub.metadata.Get("x-api-key"))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitUsage, exitCode(usageError{msg: "usage"}))
+	assert.Equal(t, int(codes.PermissionDenied), exitCode(status.Error(codes.PermissionDenied, "forbidden")))
+	assert.Equal(t, int(codes.Unavailable), exitCode(errors.Wrap(status.Error(codes.Unavailable, "unavailable"), "call error")))
+	assert.Equal(t, exitLocal, exitCode(errors.New("file error")))
+}
@@ -2,46 +2,192 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"time"
 
-	link "github.com/kuzkuss/url_service/proto/link"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	link "github.com/kuzkuss/url_service/proto/link"
+)
+
+// Exit codes of errors not returned by the server. Errors of gRPC calls
+// exit with the code of their gRPC status (1-16).
+const (
+	exitOK    = 0
+	exitUsage = 64
+	exitLocal = 70
 )
 
+const usage = `Usage: clientGRPC [flags] <command> [arguments]
+
+Commands:
+  create [-idempotency-key key] <original_link>   create short link
+  get <short_link>                                print original link
+  list                                            list links of the client
+  update <short_link> <original_link>             change original link
+  delete <short_link>...                          delete links
+  import [file]                                   create links for URLs read from file or stdin,
+                                                  one per line or JSON written by export
+  export [file]                                   write links as JSON to file or stdout
+  stats                                           print statistics of links of the client
+  watch [-cursor cursor]                          print changes of links as they happen,
+                                                  after the event with cursor if it is set
+
+Exit code is 0 on success, the gRPC status code (1-16) if the server returns an error,
+64 on usage error and 70 on other errors.
+
+Flags:
+`
+
+type options struct {
+	addr       string
+	apiKey     string
+	token      string
+	domain     string
+	output     string
+	timeout    time.Duration
+	tls        bool
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+}
+
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
 func main() {
-	grpcConnLink, err := grpc.Dial(
-		"0.0.0.0:8081",
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-	if err != nil {
-		log.Fatal(err)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, in io.Reader, out io.Writer, errOut io.Writer) int {
+	opts := options{}
+	flags := flag.NewFlagSet("clientGRPC", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	flags.Usage = func() {
+		fmt.Fprint(errOut, usage)
+		flags.PrintDefaults()
 	}
-	defer grpcConnLink.Close()
-	linkManager := link.NewLinksClient(grpcConnLink)
+	flags.StringVar(&opts.addr, "addr", "0.0.0.0:8081", "address of the gRPC server")
+	flags.StringVar(&opts.apiKey, "key", os.Getenv("URL_SERVICE_API_KEY"), "api key, URL_SERVICE_API_KEY by default")
+	flags.StringVar(&opts.token, "token", os.Getenv("URL_SERVICE_TOKEN"), "bearer token used instead of api key, URL_SERVICE_TOKEN by default")
+	flags.StringVar(&opts.domain, "domain", "", "short domain of the links, the default one of the server if empty")
+	flags.StringVar(&opts.output, "output", outputTable, "output format: table or json")
+	flags.DurationVar(&opts.timeout, "timeout", 10*time.Second, "timeout of every call")
+	flags.BoolVar(&opts.tls, "tls", false, "connect over TLS")
+	flags.StringVar(&opts.caFile, "tls-ca", "", "CA certificates verifying the server, system pool by default")
+	flags.StringVar(&opts.certFile, "tls-cert", "", "client certificate for mutual TLS")
+	flags.StringVar(&opts.keyFile, "tls-key", "", "key of the client certificate")
+	flags.StringVar(&opts.serverName, "tls-server-name", "", "server name verified in its certificate, host of addr by default")
 
-	pbOriginalLink := &link.OriginalLink {
-		OriginalLink: "https://www.golang.org",
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	if opts.output != outputTable && opts.output != outputJSON {
+		fmt.Fprintf(errOut, "unknown output format %q\n", opts.output)
+		return exitUsage
 	}
 
-	shortLink, err := linkManager.CreateShortLink(context.Background(), pbOriginalLink)
+	conn, err := dial(opts)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(errOut, err)
+		return exitLocal
 	}
+	defer conn.Close()
 
-	pbShortLink := &link.ShortLink {
-		ShortLink: shortLink.ShortLink,
+	c := &cli{
+		client:  link.NewLinksClient(conn),
+		in:      in,
+		out:     out,
+		output:  opts.output,
+		timeout: opts.timeout,
+		apiKey:  opts.apiKey,
+		token:   opts.token,
+		domain:  opts.domain,
+		now:     time.Now,
 	}
 
-	originalLink, err := linkManager.GetOriginalLink(context.Background(), pbShortLink)
+	err = c.runCommand(context.Background(), flags.Arg(0), flags.Args()[1:])
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(errOut, "error:", statusMessage(err))
+	}
+	return exitCode(err)
+}
+
+// exitCode returns the gRPC status code of err, or exitUsage and exitLocal
+// for errors raised before the call.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	if st, ok := status.FromError(errors.Cause(err)); ok {
+		return int(st.Code())
 	}
 
-	if originalLink.OriginalLink != pbOriginalLink.OriginalLink {
-		log.Fatal("incorrect work")
+	return exitLocal
+}
+
+func dial(opts options) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if opts.tls {
+		tlsConf := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: opts.serverName,
+		}
+
+		if opts.caFile != "" {
+			pem, err := os.ReadFile(opts.caFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "CA file error")
+			}
+			tlsConf.RootCAs = x509.NewCertPool()
+			if !tlsConf.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, errors.Errorf("CA file %s has no certificates", opts.caFile)
+			}
+		}
+
+		if opts.certFile != "" || opts.keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "client certificate error")
+			}
+			tlsConf.Certificates = []tls.Certificate{cert}
+		}
+
+		creds = credentials.NewTLS(tlsConf)
+	}
+
+	conn, err := grpc.Dial(opts.addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, errors.Wrap(err, "connection error")
 	}
 
-	fmt.Println("SUCCESS")
+	return conn, nil
 }
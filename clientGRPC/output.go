@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/status"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func (c *cli) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return errors.Wrap(encoder.Encode(v), "output error")
+}
+
+func (c *cli) printLinks(links []models.Link) error {
+	if c.output == outputJSON {
+		return c.printJSON(links)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHORT_LINK\tORIGINAL_LINK\tCREATED_AT\tUPDATED_AT")
+	for _, l := range links {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", l.ShortLink, l.OriginalLink, formatTime(l.CreatedAt), formatTime(l.UpdatedAt))
+	}
+	return errors.Wrap(w.Flush(), "output error")
+}
+
+func (c *cli) printImport(results []ImportResult) error {
+	if c.output == outputJSON {
+		return c.printJSON(results)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORIGINAL_LINK\tSHORT_LINK\tERROR")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.OriginalLink, result.ShortLink, result.Error)
+	}
+	return errors.Wrap(w.Flush(), "output error")
+}
+
+func (c *cli) printStats(stats LinkStats) error {
+	if c.output == outputJSON {
+		return c.printJSON(stats)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "total\t%d\n", stats.Total)
+	fmt.Fprintf(w, "created last day\t%d\n", stats.CreatedDay)
+	fmt.Fprintf(w, "created last week\t%d\n", stats.CreatedWeek)
+	fmt.Fprintf(w, "created last month\t%d\n", stats.CreatedMonth)
+	for _, host := range stats.Hosts {
+		fmt.Fprintf(w, "host %s\t%d\n", host.Host, host.Links)
+	}
+	return errors.Wrap(w.Flush(), "output error")
+}
+
+// printEvent prints event as soon as it is received: a line of JSON or of tab separated fields.
+func (c *cli) printEvent(event LinkEvent) error {
+	if c.output == outputJSON {
+		return errors.Wrap(json.NewEncoder(c.out).Encode(event), "output error")
+	}
+
+	_, err := fmt.Fprintf(c.out, "%s\t%s\t%s\t%s\t%s\n", event.Time.UTC().Format(time.RFC3339), event.Type,
+		event.ShortLink, event.OriginalLink, event.Cursor)
+	return errors.Wrap(err, "output error")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// statusMessage formats error of a gRPC call as "<code>: <message>".
+func statusMessage(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return fmt.Sprintf("%s: %s", st.Code(), st.Message())
+	}
+	return err.Error()
+}
@@ -1,24 +1,96 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"flag"
+	"log/slog"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/labstack/echo/v4"
-	echoMiddleware "github.com/labstack/echo/v4/middleware"
-	echoLog "github.com/labstack/gommon/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"github.com/kuzkuss/url_service/cmd/server"
 	"github.com/kuzkuss/url_service/config"
+	auditDeliveryHttp "github.com/kuzkuss/url_service/internal/audit/delivery/http"
+	auditRepository "github.com/kuzkuss/url_service/internal/audit/repository"
+	auditInMem "github.com/kuzkuss/url_service/internal/audit/repository/in_memory"
+	auditMetrics "github.com/kuzkuss/url_service/internal/audit/repository/metrics"
+	auditPg "github.com/kuzkuss/url_service/internal/audit/repository/postgres"
+	auditTracing "github.com/kuzkuss/url_service/internal/audit/repository/tracing"
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	authDeliveryHttp "github.com/kuzkuss/url_service/internal/auth/delivery/http"
+	authDeliveryGrpc "github.com/kuzkuss/url_service/internal/auth/delivery/grpc"
+	authRepository "github.com/kuzkuss/url_service/internal/auth/repository"
+	authInMem "github.com/kuzkuss/url_service/internal/auth/repository/in_memory"
+	authMetrics "github.com/kuzkuss/url_service/internal/auth/repository/metrics"
+	authPg "github.com/kuzkuss/url_service/internal/auth/repository/postgres"
+	authTracing "github.com/kuzkuss/url_service/internal/auth/repository/tracing"
+	"github.com/kuzkuss/url_service/internal/auth/token"
+	authUsecase "github.com/kuzkuss/url_service/internal/auth/usecase"
+	"github.com/kuzkuss/url_service/internal/gateway"
+	healthDeliveryHttp "github.com/kuzkuss/url_service/internal/health/delivery/http"
+	healthDeliveryGrpc "github.com/kuzkuss/url_service/internal/health/delivery/grpc"
+	healthRepository "github.com/kuzkuss/url_service/internal/health/repository"
+	healthInMem "github.com/kuzkuss/url_service/internal/health/repository/in_memory"
+	healthPg "github.com/kuzkuss/url_service/internal/health/repository/postgres"
+	healthUsecase "github.com/kuzkuss/url_service/internal/health/usecase"
+	idempotencyRepository "github.com/kuzkuss/url_service/internal/idempotency/repository"
+	idempotencyInMem "github.com/kuzkuss/url_service/internal/idempotency/repository/in_memory"
+	idempotencyMetrics "github.com/kuzkuss/url_service/internal/idempotency/repository/metrics"
+	idempotencyPg "github.com/kuzkuss/url_service/internal/idempotency/repository/postgres"
+	idempotencyTracing "github.com/kuzkuss/url_service/internal/idempotency/repository/tracing"
+	idempotencyUsecase "github.com/kuzkuss/url_service/internal/idempotency/usecase"
 	linkDeliveryHttp "github.com/kuzkuss/url_service/internal/link/delivery/http"
 	linkDeliveryGrpc "github.com/kuzkuss/url_service/internal/link/delivery/grpc"
+	linkEvents "github.com/kuzkuss/url_service/internal/link/events"
+	linkOutbox "github.com/kuzkuss/url_service/internal/link/outbox"
 	linkRepository "github.com/kuzkuss/url_service/internal/link/repository"
 	linkInMem "github.com/kuzkuss/url_service/internal/link/repository/in_memory"
+	linkMetrics "github.com/kuzkuss/url_service/internal/link/repository/metrics"
 	linkPg "github.com/kuzkuss/url_service/internal/link/repository/postgres"
+	linkTracing "github.com/kuzkuss/url_service/internal/link/repository/tracing"
 	linkUsecase "github.com/kuzkuss/url_service/internal/link/usecase"
+	"github.com/kuzkuss/url_service/internal/migrations"
+	"github.com/kuzkuss/url_service/internal/observability"
+	observabilityDeliveryGrpc "github.com/kuzkuss/url_service/internal/observability/delivery/grpc"
+	observabilityDeliveryHttp "github.com/kuzkuss/url_service/internal/observability/delivery/http"
+	quotaRepository "github.com/kuzkuss/url_service/internal/quota/repository"
+	quotaInMem "github.com/kuzkuss/url_service/internal/quota/repository/in_memory"
+	quotaMetrics "github.com/kuzkuss/url_service/internal/quota/repository/metrics"
+	quotaPg "github.com/kuzkuss/url_service/internal/quota/repository/postgres"
+	quotaTracing "github.com/kuzkuss/url_service/internal/quota/repository/tracing"
+	quotaUsecase "github.com/kuzkuss/url_service/internal/quota/usecase"
+	"github.com/kuzkuss/url_service/internal/ratelimit"
+	rateLimitDeliveryHttp "github.com/kuzkuss/url_service/internal/ratelimit/delivery/http"
+	rateLimitDeliveryGrpc "github.com/kuzkuss/url_service/internal/ratelimit/delivery/grpc"
+	webhookDeliveryHttp "github.com/kuzkuss/url_service/internal/webhook/delivery/http"
+	webhookRepository "github.com/kuzkuss/url_service/internal/webhook/repository"
+	webhookInMem "github.com/kuzkuss/url_service/internal/webhook/repository/in_memory"
+	webhookMetrics "github.com/kuzkuss/url_service/internal/webhook/repository/metrics"
+	webhookPg "github.com/kuzkuss/url_service/internal/webhook/repository/postgres"
+	webhookTracing "github.com/kuzkuss/url_service/internal/webhook/repository/tracing"
+	webhookUsecase "github.com/kuzkuss/url_service/internal/webhook/usecase"
+	workspaceDeliveryGrpc "github.com/kuzkuss/url_service/internal/workspace/delivery/grpc"
+	workspaceDeliveryHttp "github.com/kuzkuss/url_service/internal/workspace/delivery/http"
+	workspaceRepository "github.com/kuzkuss/url_service/internal/workspace/repository"
+	workspaceInMem "github.com/kuzkuss/url_service/internal/workspace/repository/in_memory"
+	workspaceMetrics "github.com/kuzkuss/url_service/internal/workspace/repository/metrics"
+	workspacePg "github.com/kuzkuss/url_service/internal/workspace/repository/postgres"
+	workspaceTracing "github.com/kuzkuss/url_service/internal/workspace/repository/tracing"
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
 	link "github.com/kuzkuss/url_service/proto/link"
 )
 
@@ -29,64 +101,347 @@ import (
 func main() {
 	conf, err := config.LoadConfig()
 	if err != nil {
-		log.Fatal(err)
+		fatal(slog.Default(), "config loading error", err)
 	}
 
+	logger, err := observability.NewLogger(os.Stdout, conf.Log)
+	if err != nil {
+		fatal(slog.Default(), "logger creation error", err)
+	}
+	slog.SetDefault(logger)
+
+	// the only subcommand is "migrate", without subcommand the server is started
+	migrateCommand := flag.Arg(0) == "migrate"
+	if flag.NArg() > 0 && !migrateCommand {
+		fatal(logger, "unknown command", errors.New("usage: main [-conf file] [" + migrations.CommandUsage + "]"))
+	}
+	if migrateCommand && conf.Database != config.DatabasePostgres {
+		fatal(logger, "migrate command error", errors.New("migrations are applied to postgres database only"))
+	}
+
+	app := server.NewLifecycle(conf.ShutdownTimeout, logger)
+
+	reloader := config.NewReloader(conf, os.LookupEnv, logger)
+	reloader.OnReload(func(conf *config.Config) {
+		if err := observability.SetLogLevel(logger, conf.Log.Level); err != nil {
+			logger.Error("log level change error", "error", err.Error())
+		}
+		app.SetDrainTimeout(conf.ShutdownTimeout)
+	})
+	app.AddWorker("config reloader", reloader.Run)
+
+	shutdownTracing, err := observability.SetupTracing(context.Background(), conf.Tracing)
+	if err != nil {
+		fatal(logger, "tracing setup error", err)
+	}
+	app.AddCloser("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
 	var linkDB linkRepository.RepositoryI
+	var authDB authRepository.RepositoryI
+	var quotaDB quotaRepository.RepositoryI
+	var idempotencyDB idempotencyRepository.RepositoryI
+	var healthDB healthRepository.RepositoryI
+	var webhookDB webhookRepository.RepositoryI
+	var auditDB auditRepository.RepositoryI
+	var workspaceDB workspaceRepository.RepositoryI
 
 	switch conf.Database {
-	case "postgres":
-		db, err := gorm.Open(postgres.New(postgres.Config{DSN: conf.PostgresConnectionString}), &gorm.Config{})
+	case config.DatabasePostgres:
+		logger.Info("connecting to postgres", "dsn", conf.PostgresConnectionString)
+		db, err := gorm.Open(postgres.New(postgres.Config{DSN: conf.PostgresConnectionString}), &gorm.Config{
+			Logger: observability.NewGormLogger(logger),
+		})
 		if err != nil {
-			log.Fatal(err)
+			fatal(logger, "postgres connection error", err)
+		}
+
+		if err := db.Use(observability.GormTracing{}); err != nil {
+			fatal(logger, "gorm tracing error", err)
 		}
 
 		sqlDB, _ := db.DB()
-		sqlDB.SetMaxIdleConns(10)
-		sqlDB.SetMaxOpenConns(100)
+
+		migrationList, err := migrations.Embedded()
+		if err != nil {
+			fatal(logger, "migrations loading error", err)
+		}
+		migrator := migrations.New(sqlDB, migrationList, logger)
+		if migrateCommand {
+			err := migrations.Command(context.Background(), migrator, flag.Args()[1:], os.Stdout)
+			sqlDB.Close()
+			if err != nil {
+				fatal(logger, "migrate command error", err)
+			}
+			return
+		}
+		if conf.MigrateOnStart {
+			if _, err := migrator.Up(context.Background()); err != nil {
+				fatal(logger, "migration error", err)
+			}
+		}
+
+		setPoolLimits := func(pool config.PostgresPoolConfig) {
+			sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+			sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+			sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+			sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+		}
+		setPoolLimits(conf.PostgresPool)
+		reloader.OnReload(func(conf *config.Config) {
+			setPoolLimits(conf.PostgresPool)
+		})
+		app.AddCloser("postgres", sqlDB.Close)
+		registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, "postgres"))
 
 		linkDB = linkPg.New(db)
-	case "in_memory":
-		linkDB = linkInMem.New()
+		authDB = authPg.New(db)
+		quotaDB = quotaPg.New(db)
+		idempotencyDB = idempotencyPg.New(db)
+		healthDB = healthPg.New(db)
+		webhookDB = webhookPg.New(db)
+		auditDB = auditPg.New(db)
+		workspaceDB = workspacePg.New(db)
+	case config.DatabaseInMemory:
+		// audit records of links and workspaces are written by their repositories
+		auditDB = auditInMem.New()
+		linkDB = linkInMem.New(auditDB)
+		authDB = authInMem.New()
+		quotaDB = quotaInMem.New()
+		idempotencyDB = idempotencyInMem.New()
+		healthDB = healthInMem.New()
+		webhookDB = webhookInMem.New()
+		workspaceDB = workspaceInMem.New(auditDB)
 	}
 
-	linkUC := linkUsecase.New(linkDB)
+	repositoryMetrics := observability.NewRepositoryMetrics(registry, conf.Database)
+	linkDB = linkMetrics.New(linkDB, repositoryMetrics)
+	authDB = authMetrics.New(authDB, repositoryMetrics)
+	quotaDB = quotaMetrics.New(quotaDB, repositoryMetrics)
+	idempotencyDB = idempotencyMetrics.New(idempotencyDB, repositoryMetrics)
+	webhookDB = webhookMetrics.New(webhookDB, repositoryMetrics)
+	auditDB = auditMetrics.New(auditDB, repositoryMetrics)
+	workspaceDB = workspaceMetrics.New(workspaceDB, repositoryMetrics)
+
+	repositoryTracing := observability.NewRepositoryTracing(conf.Database)
+	linkDB = linkTracing.New(linkDB, repositoryTracing)
+	authDB = authTracing.New(authDB, repositoryTracing)
+	quotaDB = quotaTracing.New(quotaDB, repositoryTracing)
+	idempotencyDB = idempotencyTracing.New(idempotencyDB, repositoryTracing)
+	webhookDB = webhookTracing.New(webhookDB, repositoryTracing)
+	auditDB = auditTracing.New(auditDB, repositoryTracing)
+	workspaceDB = workspaceTracing.New(workspaceDB, repositoryTracing)
+
+	auditUC := auditUsecase.New(auditDB, logger)
+	workspaceUC := workspaceUsecase.New(workspaceDB, auditUC, logger)
+	quotaUC := quotaUsecase.New(quotaDB, conf.Quota.DailyLinks, conf.Quota.MonthlyLinks, logger)
+	reloader.OnReload(func(conf *config.Config) {
+		quotaUC.SetLimits(conf.Quota.DailyLinks, conf.Quota.MonthlyLinks)
+	})
+	linkEventBus := linkEvents.NewBus(conf.LinkEvents.HistorySize, conf.LinkEvents.BufferSize)
+	// watch streams are ended before the gRPC server waits for pending calls
+	app.OnShutdown(linkEventBus.Close)
+	webhookUC := webhookUsecase.New(webhookDB, auditUC, conf.Webhooks, logger)
+	app.AddWorker("webhooks", webhookUC.Run)
+	outboxRelay := linkOutbox.NewRelay(linkDB, conf.Outbox, logger)
+	if conf.Outbox.Bus {
+		outboxRelay.AddSink("bus", linkOutbox.NewFeedSink(linkDB))
+		app.AddWorker("link event feed", linkOutbox.NewFeed(linkDB, linkEventBus, conf.Outbox, logger).Run)
+	}
+	if conf.Outbox.Webhooks {
+		outboxRelay.AddSink("webhooks", linkOutbox.SinkFunc(webhookUC.LinkChanged))
+	}
+	if conf.Outbox.Log {
+		outboxRelay.AddSink("log", linkOutbox.NewLogSink(logger))
+	}
+	app.AddWorker("link outbox relay", outboxRelay.Run)
+	linkUC := linkUsecase.New(linkDB, quotaUC, auditUC, linkEventBus, webhookUC, conf.Domains,
+		conf.Workspaces, linkUsecase.NewMetrics(registry), logger)
+	var idempotencyUC idempotencyUsecase.UseCaseI
+	if conf.Idempotency.Window > 0 {
+		idempotencyUC = idempotencyUsecase.New(idempotencyDB, conf.Idempotency, logger)
+		app.AddWorker("idempotency keys cleanup", idempotencyUC.Run)
+	}
+	var tokenVerifier token.VerifierI
+	if conf.JWT.Enabled() {
+		tokenVerifier, err = token.New(conf.JWT)
+		if err != nil {
+			fatal(logger, "token verifier creation error", err)
+		}
+	}
+
+	var adminKey string
+	if conf.AdminEnabled {
+		adminKey = conf.AdminAPIKey
+	}
+	authUC := authUsecase.New(authDB, workspaceUC, auditUC, adminKey, tokenVerifier)
+	healthUC := healthUsecase.New(healthDB)
 
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.IPExtractor, err = server.IPExtractor(conf.TrustedProxiesHTTP)
+	if err != nil {
+		fatal(logger, "ip extractor error", err)
+	}
+
+	e.Use(observabilityDeliveryHttp.RequestID())
+	e.Use(observabilityDeliveryHttp.Source())
+	e.Use(observabilityDeliveryHttp.Tracing())
+	e.Use(observabilityDeliveryHttp.NewMetrics(registry).Middleware())
 
-	e.Logger.SetHeader(`time=${time_rfc3339} level=${level} prefix=${prefix} ` +
-		`file=${short_file} line=${line} message:`)
-	e.Logger.SetLevel(echoLog.INFO)
+	e.Use(observabilityDeliveryHttp.AccessLog(logger))
 
-	e.Use(echoMiddleware.LoggerWithConfig(echoMiddleware.LoggerConfig{
-		Format: `time=${time_custom} remote_ip=${remote_ip} ` +
-			`host=${host} method=${method} uri=${uri} user_agent=${user_agent} ` +
-			`status=${status} error="${error}" ` +
-			`bytes_in=${bytes_in} bytes_out=${bytes_out}` + "\n",
-		CustomTimeFormat: "2006-01-02 15:04:05",
-	}))
+	e.Use(observabilityDeliveryHttp.Recover(logger))
 
-	e.Use(echoMiddleware.Recover())
+	limiter := ratelimit.New(conf.RateLimit.RequestsPerMinute, conf.RateLimit.Burst)
+	ipLimiter := ratelimit.New(conf.RateLimit.IPRequestsPerMinute, conf.RateLimit.IPBurst)
+	reloader.OnReload(func(conf *config.Config) {
+		limiter.SetLimit(conf.RateLimit.RequestsPerMinute, conf.RateLimit.Burst)
+		ipLimiter.SetLimit(conf.RateLimit.IPRequestsPerMinute, conf.RateLimit.IPBurst)
+	})
 
-	linkDeliveryHttp.New(e, linkUC)
+	// credentials are looked up only for requests within the limit of their IP address,
+	// then clients are limited by their principal, so they are identified before it
+	e.Use(rateLimitDeliveryHttp.NewIP(ipLimiter))
+	authHandler := authDeliveryHttp.New(e, authUC, logger)
+	e.Use(authHandler.Identify)
+	e.Use(rateLimitDeliveryHttp.New(limiter))
+
+	// listenerTLS returns TLS configuration of the listener reloading its certificate,
+	// nil if TLS is disabled
+	listenerTLS := func(name string, conf config.TLSConfig, nextProtos ...string) *tls.Config {
+		if !conf.Enabled() {
+			return nil
+		}
+		tlsReloader, err := server.NewTLSReloader(conf, logger.With("listener", name))
+		if err != nil {
+			fatal(logger, name + " tls error", err)
+		}
+		app.AddWorker(name + " tls reloader", tlsReloader.Run)
+		return tlsReloader.TLSConfig(nextProtos...)
+	}
+
+	commit, buildTime, goVersion := pkg.BuildInfo()
+	buildInfo := models.BuildInfo{
+		GitCommit: commit,
+		BuildTime: buildTime,
+		GoVersion: goVersion,
+		Database:  conf.Database,
+	}
+
+	if conf.PortAdmin != "" {
+		adminE := echo.New()
+		adminE.HideBanner = true
+		adminE.HidePort = true
+		adminE.Use(observabilityDeliveryHttp.Recover(logger))
+		healthDeliveryHttp.New(adminE, healthUC, buildInfo, logger)
+		observabilityDeliveryHttp.RegisterMetricsEndpoint(adminE, registry)
+
+		adminServer := server.NewServer(adminE, conf.HostAdmin + ":" + conf.PortAdmin, logger)
+		adminServer.TLSConfig = listenerTLS("admin", conf.TLSAdmin, "h2", "http/1.1")
+		app.AddServer("admin", adminServer.Start, adminServer.Shutdown)
+	} else {
+		healthDeliveryHttp.New(e, healthUC, buildInfo, logger)
+		observabilityDeliveryHttp.RegisterMetricsEndpoint(e, registry)
+	}
+
+	linkDeliveryHttp.New(e, linkUC, idempotencyUC, authHandler.Authorize, logger)
+	webhookDeliveryHttp.New(e, webhookUC, authHandler.Authorize, logger)
+	auditDeliveryHttp.New(e, auditUC, authHandler.Authorize, logger)
+	workspaceDeliveryHttp.New(e, workspaceUC, authHandler.Authorize, logger)
 
 	lis, err := net.Listen("tcp", conf.HostGRPC + ":" + conf.PortGRPC)
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "grpc listener error", err)
+	}
+	authInterceptor := authDeliveryGrpc.New(authUC, map[string]string{
+		"/link.Links/CreateShortLink": models.ScopeLinksWrite,
+		"/link.Links/ListLinks":       models.ScopeLinksRead,
+		"/link.Links/UpdateLink":      models.ScopeLinksWrite,
+		"/link.Links/DeleteLink":      models.ScopeLinksWrite,
+		"/link.Links/WatchLinks":      models.ScopeLinksRead,
+		"/link.Workspaces/ListMembers":  models.ScopeLinksRead,
+		"/link.Workspaces/SaveMember":   models.ScopeLinksWrite,
+		"/link.Workspaces/DeleteMember": models.ScopeLinksWrite,
+	}, "/link.Links/GetOriginalLink", "/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch",
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo")
+	rateLimitInterceptor := rateLimitDeliveryGrpc.New(limiter)
+	ipRateLimitInterceptor := rateLimitDeliveryGrpc.NewIP(ipLimiter)
+	unaryInterceptors, streamInterceptors := observabilityDeliveryGrpc.Chain(conf.GRPCInterceptors,
+		observabilityDeliveryGrpc.NewMetrics(registry), logger)
+	// the gateway does not use the source interceptors, its calls keep the source of the HTTP request
+	unaryInterceptors = append(unaryInterceptors, observabilityDeliveryGrpc.UnarySource, ipRateLimitInterceptor.Unary,
+		authInterceptor.IdentifyUnary, rateLimitInterceptor.Unary, authInterceptor.Unary)
+	streamInterceptors = append(streamInterceptors, observabilityDeliveryGrpc.StreamSource, ipRateLimitInterceptor.Stream,
+		authInterceptor.IdentifyStream, rateLimitInterceptor.Stream, authInterceptor.Stream)
+	grpcOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	if tlsConf := listenerTLS("grpc", conf.TLSGRPC, "h2"); tlsConf != nil {
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(tlsConf)))
 	}
-	grpcServer := grpc.NewServer()
-	link .RegisterLinksServer(grpcServer, linkDeliveryGrpc.New(linkUC))
+	grpcServer := grpc.NewServer(grpcOptions...)
+	linkService := linkDeliveryGrpc.New(linkUC, idempotencyUC, logger)
+	link .RegisterLinksServer(grpcServer, linkService)
+	link.RegisterWorkspacesServer(grpcServer, workspaceDeliveryGrpc.New(workspaceUC, logger))
 
-	go func() {
-		log.Println("starting server at " + conf.HostGRPC + ":" + conf.PortGRPC)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal(err)
-		}
-	}()
+	// REST gateway under /v1 calls the gRPC handlers in process through the auth
+	// interceptor, so it validates requests and reports errors as the gRPC API does.
+	// Access log, metrics and rate limit are applied by the HTTP middleware.
+	gatewayConn := gateway.NewConn(rateLimitDeliveryGrpc.New(nil).Unary, authInterceptor.Unary)
+	link.RegisterLinksServer(gatewayConn, linkService)
+	gatewayMux := gateway.NewMux(
+		[]string{authDeliveryGrpc.MetadataAPIKey, authDeliveryGrpc.MetadataAuthorization, linkDeliveryGrpc.MetadataIdempotencyKey},
+		[]string{linkDeliveryGrpc.MetadataIdempotentReplayed, rateLimitDeliveryGrpc.MetadataRetryAfter},
+	)
+	if err := link.RegisterLinksHandlerClient(context.Background(), gatewayMux, link.NewLinksClient(gatewayConn)); err != nil {
+		fatal(logger, "gateway registration error", err)
+	}
+	e.Any("/v1/*", echo.WrapHandler(gatewayMux))
+
+	healthChecker := healthDeliveryGrpc.New(healthUC, conf.HealthCheckInterval, logger, "link.Links")
+	healthChecker.Register(grpcServer)
+	app.AddWorker("health checker", healthChecker.Run)
+	reloader.OnReload(func(conf *config.Config) {
+		healthChecker.SetInterval(conf.HealthCheckInterval)
+	})
+	app.OnShutdown(healthChecker.Server.Shutdown)
 
-	s := server.NewServer(e, conf)
-	if err := s.Start(conf); err != nil {
-		e.Logger.Fatal(err)
+	if conf.GRPCReflection {
+		reflection.Register(grpcServer)
 	}
+
+	app.AddServer("grpc", func() error {
+		logger.Info("starting grpc server", "addr", lis.Addr().String(), "tls", conf.TLSGRPC.Enabled())
+		return server.ServeGRPC(grpcServer, lis)
+	}, server.GracefulStopGRPC(grpcServer))
+
+	s := server.NewServer(e, conf.HostHTTP + ":" + conf.PortHTTP, logger)
+	s.TLSConfig = listenerTLS("http", conf.TLSHTTP, "h2", "http/1.1")
+	app.AddServer("http", s.Start, s.Shutdown)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Run(ctx); err != nil {
+		fatal(logger, "server error", err)
+	}
+	logger.Info("server stopped")
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err.Error())
+	os.Exit(1)
 }
 
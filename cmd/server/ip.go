@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// IPExtractor returns extractor of client IP address of HTTP requests. X-Forwarded-For
+// is trusted only in requests coming from trustedProxies (CIDR ranges) and only up to
+// the nearest address which is not a trusted proxy; without trusted proxies the address
+// of the connection is used, so clients can not spoof their address by headers.
+func IPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "trusted proxy %q error", proxy)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,68 @@
+package server_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/cmd/server"
+)
+
+type TestCaseIP struct {
+	TrustedProxies []string
+	RemoteAddr string
+	ForwardedFor string
+	ExpectedRes string
+}
+
+func TestIPExtractor(t *testing.T) {
+	cases := map[string]TestCaseIP {
+		"direct": {
+			RemoteAddr: "192.0.2.1:1234",
+			ForwardedFor: "198.51.100.1",
+			ExpectedRes: "192.0.2.1",
+		},
+		"private_network_is_not_trusted": {
+			RemoteAddr: "10.0.0.1:1234",
+			ForwardedFor: "198.51.100.1",
+			ExpectedRes: "10.0.0.1",
+		},
+		"untrusted_proxy": {
+			TrustedProxies: []string{"10.0.0.0/8"},
+			RemoteAddr: "192.0.2.1:1234",
+			ForwardedFor: "198.51.100.1",
+			ExpectedRes: "192.0.2.1",
+		},
+		"trusted_proxy": {
+			TrustedProxies: []string{"10.0.0.0/8"},
+			RemoteAddr: "10.0.0.1:1234",
+			ForwardedFor: "198.51.100.1",
+			ExpectedRes: "198.51.100.1",
+		},
+		"spoofed_by_client": {
+			TrustedProxies: []string{"10.0.0.0/8"},
+			RemoteAddr: "10.0.0.1:1234",
+			ForwardedFor: "203.0.113.1, 198.51.100.1",
+			ExpectedRes: "198.51.100.1",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			extractor, err := server.IPExtractor(test.TrustedProxies)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.RemoteAddr = test.RemoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, test.ForwardedFor)
+			req.Header.Set(echo.HeaderXRealIP, "203.0.113.2")
+			assert.Equal(t, test.ExpectedRes, extractor(req))
+		})
+	}
+
+	_, err := server.IPExtractor([]string{"10.0.0.1"})
+	require.Error(t, err)
+}
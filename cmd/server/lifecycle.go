@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+type component struct {
+	name     string
+	serve    func() error
+	shutdown func(ctx context.Context) error
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// Lifecycle runs servers and background workers until the context is done,
+// then stops them in order: shutdown hooks, servers (draining in-flight requests),
+// workers (letting them flush) and finally closers such as repositories.
+// The whole shutdown is limited by the drain timeout.
+type Lifecycle struct {
+	drainTimeout atomic.Int64
+	hooks        []func()
+	servers      []component
+	workers      []worker
+	closers      []closer
+	logger       *slog.Logger
+}
+
+func NewLifecycle(drainTimeout time.Duration, logger *slog.Logger) *Lifecycle {
+	lifecycle := &Lifecycle{
+		logger: logger,
+	}
+	lifecycle.SetDrainTimeout(drainTimeout)
+
+	return lifecycle
+}
+
+// SetDrainTimeout changes the limit of shutdown which has not begun yet.
+func (l *Lifecycle) SetDrainTimeout(drainTimeout time.Duration) {
+	l.drainTimeout.Store(int64(drainTimeout))
+}
+
+// OnShutdown registers hook called as soon as shutdown begins,
+// e.g. to report the service is not serving anymore.
+func (l *Lifecycle) OnShutdown(hook func()) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// AddServer registers server. serve blocks until the server is stopped by
+// shutdown, which must wait for in-flight requests until ctx is done.
+func (l *Lifecycle) AddServer(name string, serve func() error, shutdown func(ctx context.Context) error) {
+	l.servers = append(l.servers, component{name: name, serve: serve, shutdown: shutdown})
+}
+
+// AddWorker registers background worker. run must return soon after its
+// context is done; the context is cancelled once servers are stopped.
+func (l *Lifecycle) AddWorker(name string, run func(ctx context.Context)) {
+	l.workers = append(l.workers, worker{name: name, run: run})
+}
+
+// AddCloser registers resource closed after servers and workers are stopped.
+// Closers are called in the order they were added.
+func (l *Lifecycle) AddCloser(name string, close func() error) {
+	l.closers = append(l.closers, closer{name: name, close: close})
+}
+
+// Run starts everything and blocks until ctx is done or a server fails,
+// then shuts down. It returns the error of the failed server or of the shutdown.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	var workersWG sync.WaitGroup
+	for _, w := range l.workers {
+		workersWG.Add(1)
+		go func(w worker) {
+			defer workersWG.Done()
+			w.run(workerCtx)
+		}(w)
+	}
+
+	serveErrs := make(chan error, len(l.servers))
+	for _, s := range l.servers {
+		go func(s component) {
+			err := s.serve()
+			if err != nil {
+				err = errors.Wrapf(err, "%s server error", s.name)
+			}
+			serveErrs <- err
+		}(s)
+	}
+
+	var runErr error
+	stopped := 0
+	select {
+	case <-ctx.Done():
+		l.logger.Info("shutting down")
+	case runErr = <-serveErrs:
+		stopped++
+		l.logger.Warn("server stopped, shutting down", "error", runErr)
+	}
+
+	for _, hook := range l.hooks {
+		hook()
+	}
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(l.drainTimeout.Load()))
+	defer cancelDrain()
+
+	shutdownErr := l.shutdownServers(drainCtx)
+
+	for ; stopped < len(l.servers); stopped++ {
+		if err := <-serveErrs; err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+
+	cancelWorkers()
+	if err := wait(drainCtx, &workersWG); err != nil && shutdownErr == nil {
+		shutdownErr = errors.Wrap(err, "workers are not stopped")
+	}
+
+	for _, c := range l.closers {
+		if err := c.close(); err != nil && shutdownErr == nil {
+			shutdownErr = errors.Wrapf(err, "close %s error", c.name)
+		}
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	return shutdownErr
+}
+
+func (l *Lifecycle) shutdownServers(ctx context.Context) error {
+	errs := make(chan error, len(l.servers))
+	for _, s := range l.servers {
+		go func(s component) {
+			err := s.shutdown(ctx)
+			if err != nil {
+				err = errors.Wrapf(err, "%s server shutdown error", s.name)
+			}
+			errs <- err
+		}(s)
+	}
+
+	var shutdownErr error
+	for range l.servers {
+		if err := <-errs; err != nil && shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+
+	return shutdownErr
+}
+
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ServeGRPC serves gRPC requests until the server is stopped. Unlike grpc.Server.Serve
+// it reports no error if the server was stopped before it started serving.
+func ServeGRPC(s *grpc.Server, lis net.Listener) error {
+	if err := s.Serve(lis); err != grpc.ErrServerStopped {
+		return err
+	}
+	return nil
+}
+
+// GracefulStopGRPC returns shutdown function for gRPC server: it waits for
+// pending calls to finish and closes remaining connections when ctx is done.
+func GracefulStopGRPC(s *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			s.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/kuzkuss/url_service/cmd/server"
+	"github.com/kuzkuss/url_service/internal/observability"
+)
+
+type eventLog struct {
+	mx     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mx.Lock()
+	l.events = append(l.events, event)
+	l.mx.Unlock()
+}
+
+func (l *eventLog) get() []string {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func blockingServer(events *eventLog, name string) (func() error, func(ctx context.Context) error) {
+	stop := make(chan struct{})
+	serve := func() error {
+		<-stop
+		events.add(name + " stopped")
+		return nil
+	}
+	shutdown := func(ctx context.Context) error {
+		events.add(name + " shutdown")
+		close(stop)
+		return nil
+	}
+	return serve, shutdown
+}
+
+func TestLifecycleShutdownOrder(t *testing.T) {
+	events := &eventLog{}
+	app := server.NewLifecycle(time.Second, observability.NopLogger())
+
+	serve, shutdown := blockingServer(events, "http")
+	app.AddServer("http", serve, shutdown)
+
+	workerStarted := make(chan struct{})
+	app.AddWorker("worker", func(ctx context.Context) {
+		close(workerStarted)
+		<-ctx.Done()
+		events.add("worker flushed")
+	})
+	app.OnShutdown(func() {
+		events.add("hook")
+	})
+	app.AddCloser("first", func() error {
+		events.add("first closed")
+		return nil
+	})
+	app.AddCloser("second", func() error {
+		events.add("second closed")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+
+	<-workerStarted
+	cancel()
+
+	require.NoError(t, <-done)
+
+	got := events.get()
+	require.Len(t, got, 6)
+	assert.Equal(t, "hook", got[0])
+	assert.ElementsMatch(t, []string{"http shutdown", "http stopped"}, got[1:3])
+	assert.Equal(t, []string{"worker flushed", "first closed", "second closed"}, got[3:])
+}
+
+func TestLifecycleServerFailure(t *testing.T) {
+	events := &eventLog{}
+	app := server.NewLifecycle(time.Second, observability.NopLogger())
+
+	serveErr := errors.New("listen error")
+	app.AddServer("grpc", func() error {
+		return serveErr
+	}, func(ctx context.Context) error {
+		return nil
+	})
+
+	serve, shutdown := blockingServer(events, "http")
+	app.AddServer("http", serve, shutdown)
+
+	app.AddCloser("repository", func() error {
+		events.add("repository closed")
+		return nil
+	})
+
+	err := app.Run(context.Background())
+	require.Equal(t, serveErr, errors.Cause(err))
+	assert.Equal(t, []string{"http shutdown", "http stopped", "repository closed"}, events.get())
+}
+
+func TestLifecycleDrainTimeout(t *testing.T) {
+	app := server.NewLifecycle(10 * time.Millisecond, observability.NopLogger())
+
+	app.AddWorker("stuck", func(ctx context.Context) {
+		select {}
+	})
+
+	closed := false
+	app.AddCloser("repository", func() error {
+		closed = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := app.Run(ctx)
+	require.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	assert.True(t, closed)
+}
+
+func TestGracefulStopGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	app := server.NewLifecycle(time.Second, observability.NopLogger())
+	app.AddServer("grpc", func() error {
+		return server.ServeGRPC(grpcServer, lis)
+	}, server.GracefulStopGRPC(grpcServer))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+
+	cancel()
+	require.NoError(t, <-done)
+}
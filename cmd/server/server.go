@@ -2,30 +2,44 @@ package server
 
 import (
 	"github.com/labstack/echo/v4"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
-	"github.com/kuzkuss/url_service/config"
 )
 
 type Server struct {
 	http.Server
+	logger *slog.Logger
 }
 
-func NewServer(e *echo.Echo, conf *config.Config) *Server {
+func NewServer(e *echo.Echo, addr string, logger *slog.Logger) *Server {
 	return &Server{
-		http.Server{
-			Addr:              conf.HostHTTP + ":" + conf.PortHTTP,
+		Server: http.Server{
+			Addr:              addr,
 			Handler:           e,
 			ReadTimeout:       30 * time.Second,
 			ReadHeaderTimeout: 30 * time.Second,
 			WriteTimeout:      30 * time.Second,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		},
+		logger: logger,
 	}
 }
 
-func (s *Server) Start(conf *config.Config) error {
-	log.Println("starting server at " + conf.HostHTTP + ":" + conf.PortHTTP)
-	return s.ListenAndServe()
+// Start serves requests until the server is shut down. Requests are served
+// over TLS if TLSConfig is set.
+func (s *Server) Start() error {
+	s.logger.Info("starting http server", "addr", s.Addr, "tls", s.TLSConfig != nil)
+
+	var err error
+	if s.TLSConfig != nil {
+		err = s.ListenAndServeTLS("", "")
+	} else {
+		err = s.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
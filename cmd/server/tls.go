@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/config"
+)
+
+const certReloadDebounce = 100 * time.Millisecond
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSReloader provides TLS configuration of a listener with certificate and client
+// CAs read from files of conf. Files are read again when they change, so renewed
+// certificates are used by new connections without restart. If the new files are
+// invalid, the error is logged and the previous certificate is kept.
+type TLSReloader struct {
+	conf      config.TLSConfig
+	mx        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	logger    *slog.Logger
+}
+
+func NewTLSReloader(conf config.TLSConfig, logger *slog.Logger) (*TLSReloader, error) {
+	reloader := &TLSReloader{
+		conf:   conf,
+		logger: logger,
+	}
+
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// TLSConfig returns configuration getting the current certificate for every handshake.
+// nextProtos are the application protocols negotiated with ALPN.
+func (r *TLSReloader) TLSConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tlsVersions[r.conf.MinVersion],
+		NextProtos:     nextProtos,
+		GetCertificate: r.getCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mx.RLock()
+			defer r.mx.RUnlock()
+
+			conf := &tls.Config{
+				MinVersion:   tlsVersions[r.conf.MinVersion],
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCAs != nil {
+				conf.ClientCAs = r.clientCAs
+				conf.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.conf.RequireClientCert {
+					conf.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+
+			return conf, nil
+		},
+	}
+}
+
+func (r *TLSReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+
+	return r.cert, nil
+}
+
+// Reload reads the certificate and client CAs from files.
+func (r *TLSReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.conf.CertFile, r.conf.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "tls certificate error")
+	}
+
+	var clientCAs *x509.CertPool
+	if r.conf.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.conf.ClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "tls client CA error")
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.Errorf("tls client CA file %s has no certificates", r.conf.ClientCAFile)
+		}
+	}
+
+	r.mx.Lock()
+	r.cert, r.clientCAs = &cert, clientCAs
+	r.mx.Unlock()
+
+	return nil
+}
+
+// Run reloads the files when they change until ctx is done. Directories of
+// the files are watched, so replacing the files (as certificate managers and
+// Kubernetes secrets do) is noticed too.
+func (r *TLSReloader) Run(ctx context.Context) {
+	files := []string{r.conf.CertFile, r.conf.KeyFile}
+	if r.conf.ClientCAFile != "" {
+		files = append(files, r.conf.ClientCAFile)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		r.logger.Warn("tls files are not watched", "error", err.Error())
+		return
+	}
+	defer watcher.Close()
+
+	dirs := make(map[string]struct{})
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		dirs[dir] = struct{}{}
+		if err := watcher.Add(dir); err != nil {
+			r.logger.Warn("tls files are not watched", "error", err.Error())
+			return
+		}
+	}
+
+	debounce := time.NewTimer(certReloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-watcher.Events:
+			if !affectsFiles(event, files) {
+				continue
+			}
+			// certificate and key are usually written one after another
+			debounce.Reset(certReloadDebounce)
+		case <-debounce.C:
+			if err := r.Reload(); err != nil {
+				r.logger.Error("tls certificate reload failed, current certificate is kept", "error", err.Error())
+				continue
+			}
+			r.logger.Info("tls certificate reloaded", "cert_file", r.conf.CertFile)
+		case err := <-watcher.Errors:
+			r.logger.Warn("tls files watch error", "error", err.Error())
+		}
+	}
+}
+
+// affectsFiles reports whether event may change content of files: one of them
+// or, for Kubernetes secrets, the "..data" symlink to their directory is changed.
+func affectsFiles(event fsnotify.Event, files []string) bool {
+	if strings.HasPrefix(filepath.Base(event.Name), "..") {
+		return true
+	}
+	for _, file := range files {
+		if filepath.Clean(event.Name) == filepath.Clean(file) {
+			return true
+		}
+	}
+	return false
+}
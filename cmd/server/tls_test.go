@@ -0,0 +1,206 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/cmd/server"
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/internal/observability"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM encoded certificate for commonName signed by the CA and its key.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTLSFiles(t *testing.T, dir string, files map[string][]byte) {
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0600))
+	}
+}
+
+// serveTLS accepts connections on a TLS listener, completing handshakes, until the test ends.
+func serveTLS(t *testing.T, tlsConf *tls.Config) string {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", tlsConf)
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	return lis.Addr().String()
+}
+
+func handshake(addr string, ca *testCA, clientCert *tls.Certificate) (*tls.ConnectionState, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConf := &tls.Config{RootCAs: roots, ServerName: "localhost", NextProtos: []string{"h2"}}
+	if clientCert != nil {
+		clientConf.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	conn, err := tls.Dial("tcp", addr, clientConf)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// client certificate is verified by the server after the client handshake completes
+	if _, err := conn.Read(make([]byte, 1)); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	state := conn.ConnectionState()
+	return &state, nil
+}
+
+func TestTLSReloaderClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	clientCertPEM, clientKeyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	writeTLSFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey, "ca.crt": ca.pem})
+
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	reloader, err := server.NewTLSReloader(config.TLSConfig{
+		CertFile:          filepath.Join(dir, "server.crt"),
+		KeyFile:           filepath.Join(dir, "server.key"),
+		MinVersion:        "1.2",
+		ClientCAFile:      filepath.Join(dir, "ca.crt"),
+		RequireClientCert: true,
+	}, observability.NopLogger())
+	require.NoError(t, err)
+	addr := serveTLS(t, reloader.TLSConfig("h2"))
+
+	state, err := handshake(addr, ca, &clientCert)
+	require.NoError(t, err)
+	assert.Equal(t, "h2", state.NegotiatedProtocol)
+	assert.Equal(t, "server", state.PeerCertificates[0].Subject.CommonName)
+
+	_, err = handshake(addr, ca, nil)
+	require.Error(t, err)
+
+	otherCertPEM, otherKeyPEM := newTestCA(t).issue(t, "other", x509.ExtKeyUsageClientAuth)
+	otherCert, err := tls.X509KeyPair(otherCertPEM, otherKeyPEM)
+	require.NoError(t, err)
+	_, err = handshake(addr, ca, &otherCert)
+	require.Error(t, err)
+}
+
+func TestTLSReloaderRun(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeTLSFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey})
+
+	reloader, err := server.NewTLSReloader(config.TLSConfig{
+		CertFile:   filepath.Join(dir, "server.crt"),
+		KeyFile:    filepath.Join(dir, "server.key"),
+		MinVersion: "1.3",
+	}, observability.NopLogger())
+	require.NoError(t, err)
+	addr := serveTLS(t, reloader.TLSConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Run(ctx)
+
+	peerName := func() string {
+		state, err := handshake(addr, ca, nil)
+		if err != nil {
+			return err.Error()
+		}
+		return state.PeerCertificates[0].Subject.CommonName
+	}
+	require.Equal(t, "server", peerName())
+
+	// invalid files are not loaded, the previous certificate is kept
+	writeTLSFiles(t, dir, map[string][]byte{"server.crt": []byte("invalid")})
+	time.Sleep(300 * time.Millisecond)
+	require.Equal(t, "server", peerName())
+
+	renewedCert, renewedKey := ca.issue(t, "renewed", x509.ExtKeyUsageServerAuth)
+	writeTLSFiles(t, dir, map[string][]byte{"server.crt": renewedCert, "server.key": renewedKey})
+	assert.Eventually(t, func() bool {
+		return peerName() == "renewed"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestNewTLSReloaderError(t *testing.T) {
+	_, err := server.NewTLSReloader(config.TLSConfig{
+		CertFile: filepath.Join(t.TempDir(), "missing.crt"),
+		KeyFile:  filepath.Join(t.TempDir(), "missing.key"),
+	}, observability.NopLogger())
+	require.Error(t, err)
+}
@@ -1,41 +1,256 @@
 package config
 
 import (
+	"bytes"
 	"flag"
 	"os"
+	"time"
 
 	toml "github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
 )
 
+// Config of the service. Keys missing in the file get values of the default tags;
+// every key can be overridden by environment variable (see applyEnv). Keys tagged
+// reloadable are applied to the running service on reload (see Reloader).
+// Administrative access is enabled by admin_enabled with admin_api_key, which
+// is a secret and is set by environment variable only.
 type Config struct {
-	Database string `toml:"database"`
-	HostHTTP string `toml:"http_host"`
-	PortHTTP string `toml:"http_port"`
-	HostGRPC string `toml:"grpc_host"`
-	PortGRPC string `toml:"grpc_port"`
+	File string `toml:"-"`
+	Database string `toml:"database" default:"postgres"`
+	HostHTTP string `toml:"http_host" default:"0.0.0.0"`
+	PortHTTP string `toml:"http_port" default:"8080"`
+	TLSHTTP TLSConfig `toml:"http_tls"`
+	TrustedProxiesHTTP []string `toml:"http_trusted_proxies"`
+	HostAdmin string `toml:"admin_host" default:"0.0.0.0"`
+	PortAdmin string `toml:"admin_port"`
+	TLSAdmin TLSConfig `toml:"admin_tls"`
+	HostGRPC string `toml:"grpc_host" default:"0.0.0.0"`
+	PortGRPC string `toml:"grpc_port" default:"8081"`
+	TLSGRPC TLSConfig `toml:"grpc_tls"`
+	GRPCReflection bool `toml:"grpc_reflection"`
+	HealthCheckInterval time.Duration `toml:"health_check_interval" default:"5s" reloadable:"true"`
+	ShutdownTimeout time.Duration `toml:"shutdown_timeout" default:"15s" reloadable:"true"`
+	GRPCInterceptors InterceptorsConfig `toml:"grpc_interceptors"`
 	PostgresConnectionString string `toml:"postgres_connection_string"`
+	PostgresPool PostgresPoolConfig `toml:"postgres_pool"`
+	MigrateOnStart bool `toml:"migrate_on_start" default:"true"`
+	AdminEnabled bool `toml:"admin_enabled"`
+	AdminAPIKey string `toml:"admin_api_key"`
+	JWT JWTConfig `toml:"jwt"`
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+	Quota QuotaConfig `toml:"quota"`
+	Idempotency IdempotencyConfig `toml:"idempotency"`
+	LinkEvents LinkEventsConfig `toml:"link_events"`
+	Webhooks WebhooksConfig `toml:"webhooks"`
+	Outbox OutboxConfig `toml:"outbox"`
+	Domains DomainsConfig `toml:"domains"`
+	Workspaces WorkspacesConfig `toml:"workspaces"`
+	Tracing TracingConfig `toml:"tracing"`
+	Log LogConfig `toml:"log"`
 }
 
+// TLSConfig enables TLS on a listener if cert_file and key_file are set; the files
+// are read again when they change. Client certificates signed by client_ca_file are
+// verified and authenticate the client by their common name (gRPC listener only);
+// require_client_cert rejects connections without them.
+type TLSConfig struct {
+	CertFile string `toml:"cert_file"`
+	KeyFile string `toml:"key_file"`
+	MinVersion string `toml:"min_version" default:"1.2"`
+	ClientCAFile string `toml:"client_ca_file"`
+	RequireClientCert bool `toml:"require_client_cert"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// LogConfig sets minimal level of written records ("debug", "info", "warn" or "error")
+// and their format ("json" or "text").
+type LogConfig struct {
+	Level string `toml:"level" default:"info" reloadable:"true"`
+	Format string `toml:"format" default:"json"`
+}
+
+// PostgresPoolConfig limits connections to Postgres. Zero lifetime and idle time
+// keep connections open forever.
+type PostgresPoolConfig struct {
+	MaxIdleConns int `toml:"max_idle_conns" default:"10" reloadable:"true"`
+	MaxOpenConns int `toml:"max_open_conns" default:"100" reloadable:"true"`
+	ConnMaxLifetime time.Duration `toml:"conn_max_lifetime" reloadable:"true"`
+	ConnMaxIdleTime time.Duration `toml:"conn_max_idle_time" reloadable:"true"`
+}
+
+// TracingConfig sets exporter of spans: "stdout", "otlp" or empty to disable tracing.
+// Traces started by the client are always sampled, others with probability sample_ratio.
+type TracingConfig struct {
+	Exporter string `toml:"exporter"`
+	ServiceName string `toml:"service_name" default:"url_service"`
+	OTLPEndpoint string `toml:"otlp_endpoint" default:"localhost:4317"`
+	OTLPInsecure bool `toml:"otlp_insecure"`
+	SampleRatio float64 `toml:"sample_ratio" default:"1"`
+}
+
+// IdempotencyConfig sets how long responses of requests with idempotency key
+// are stored. Zero window disables idempotency keys. Expired keys are removed
+// every cleanup_interval.
+type IdempotencyConfig struct {
+	Window time.Duration `toml:"window" default:"24h"`
+	CleanupInterval time.Duration `toml:"cleanup_interval" default:"1h"`
+}
+
+// LinkEventsConfig sets how many recent link events are kept to resume watching
+// and how many events may wait for a watcher before it is disconnected.
+type LinkEventsConfig struct {
+	HistorySize int `toml:"history_size" default:"1000"`
+	BufferSize int `toml:"buffer_size" default:"100"`
+}
+
+// WebhooksConfig sets delivery of webhook notifications by workers sending them
+// concurrently from the stored delivery queues, notifications of a subscription are
+// sent one at a time in order. Workers look for due notifications every poll_interval.
+// A failed notification is sent again after initial_backoff doubled after every
+// attempt up to max_backoff; after max_attempts it is kept as a dead letter.
+// queue_size clicks wait for the check of click thresholds. delivery_log_size
+// attempts are kept in the delivery log of a subscription. Notifications are not
+// sent to loopback, private, link-local (including cloud metadata) and unspecified
+// addresses; allow_loopback permits loopback ones, e.g. for local receivers in
+// development, and allowed_private_networks permits private ones in the listed CIDR
+// ranges, e.g. for receivers in the internal network.
+type WebhooksConfig struct {
+	Workers int `toml:"workers" default:"4"`
+	PollInterval time.Duration `toml:"poll_interval" default:"1s"`
+	QueueSize int `toml:"queue_size" default:"1000"`
+	Timeout time.Duration `toml:"timeout" default:"10s"`
+	MaxAttempts int `toml:"max_attempts" default:"8"`
+	InitialBackoff time.Duration `toml:"initial_backoff" default:"1s"`
+	MaxBackoff time.Duration `toml:"max_backoff" default:"5m"`
+	DeliveryLogSize int `toml:"delivery_log_size" default:"100"`
+	AllowLoopback bool `toml:"allow_loopback"`
+	AllowedPrivateNetworks []string `toml:"allowed_private_networks"`
+}
+
+// OutboxConfig sets relay of link events from the outbox: every poll_interval
+// events are read in batches of batch_size and passed to the enabled sinks (bus
+// for watching links, webhooks and log). Events failed in a sink are relayed again.
+// The bus sink appends events to the feed, which every instance of the service reads
+// every poll_interval to publish events to its watchers; events are kept in the feed
+// for feed_retention.
+type OutboxConfig struct {
+	PollInterval time.Duration `toml:"poll_interval" default:"500ms"`
+	BatchSize int `toml:"batch_size" default:"100"`
+	FeedRetention time.Duration `toml:"feed_retention" default:"10m"`
+	Bus bool `toml:"bus" default:"true"`
+	Webhooks bool `toml:"webhooks" default:"true"`
+	Log bool `toml:"log"`
+}
+
+// DomainsConfig lists short domains links are created on. Short links on different
+// domains are independent, a link is resolved on the domain of the Host header or
+// on default one if the host is not allowed. Without allowed domains all links
+// belong to a single unnamed domain. Short URLs returned to clients start with
+// the public base URL of the domain from base_urls, https://<domain> by default;
+// links without domain use base_url and get no short URL if it is empty.
+type DomainsConfig struct {
+	Allowed []string `toml:"allowed"`
+	Default string `toml:"default"`
+	BaseURL string `toml:"base_url"`
+	BaseURLs map[string]string `toml:"base_urls"`
+}
+
+// WorkspacesConfig sets scope of deduplication of original links: with "workspace"
+// shortening a link returns the existing short link of the same workspace only, with
+// "global" the existing short link of any workspace. Short links themselves stay unique
+// on their domain, since they are resolved without credentials.
+type WorkspacesConfig struct {
+	Scope string `toml:"scope" default:"workspace"`
+}
+
+// InterceptorsConfig enables interceptors of every gRPC call.
+type InterceptorsConfig struct {
+	RequestID bool `toml:"request_id" default:"true"`
+	AccessLog bool `toml:"access_log" default:"true"`
+	Metrics bool `toml:"metrics" default:"true"`
+	Recovery bool `toml:"recovery" default:"true"`
+	Tracing bool `toml:"tracing" default:"true"`
+}
+
+// RateLimitConfig describes token bucket applied to every client, identified by
+// the authenticated principal or IP address. Zero requests_per_minute disables rate limiting.
+// Before clients are identified, requests of every IP address are limited by
+// ip_requests_per_minute and ip_burst, so made up credentials are not looked up
+// without limit; zero ip_requests_per_minute disables this limit.
+type RateLimitConfig struct {
+	RequestsPerMinute int `toml:"requests_per_minute" default:"600" reloadable:"true"`
+	Burst int `toml:"burst" default:"50" reloadable:"true"`
+	IPRequestsPerMinute int `toml:"ip_requests_per_minute" default:"3000" reloadable:"true"`
+	IPBurst int `toml:"ip_burst" default:"200" reloadable:"true"`
+}
+
+// QuotaConfig limits number of links created by an owner per calendar day
+// and month (UTC). Zero disables the corresponding quota.
+type QuotaConfig struct {
+	DailyLinks int64 `toml:"daily_links" default:"1000" reloadable:"true"`
+	MonthlyLinks int64 `toml:"monthly_links" default:"20000" reloadable:"true"`
+}
+
+// JWTConfig describes validation of bearer tokens. Tokens are accepted
+// only if at least one key source (jwks_file, public_key_files or hmac_secret) is set.
+// Tokens without workspace_claim are bound to the default workspace, tokens
+// without expiration are rejected.
+type JWTConfig struct {
+	JWKSFile string `toml:"jwks_file"`
+	PublicKeyFiles []string `toml:"public_key_files"`
+	HMACSecret string `toml:"hmac_secret"`
+	Issuer string `toml:"issuer"`
+	Audience string `toml:"audience"`
+	ClockSkew time.Duration `toml:"clock_skew" default:"30s"`
+	OwnerClaim string `toml:"owner_claim" default:"sub"`
+	WorkspaceClaim string `toml:"workspace_claim" default:"workspace"`
+}
+
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSFile != "" || len(c.PublicKeyFiles) > 0 || c.HMACSecret != ""
+}
+
+// LoadConfig reads file given by -conf flag (config.toml by default),
+// applies environment overrides and validates the result.
 func LoadConfig() (*Config, error) {
 	var configFile string
-	flag.StringVar(&configFile, "conf", "config.toml", "toml file with configs")
+	flag.StringVar(&configFile, "conf", "config.toml", "toml file with configs, empty to use defaults")
 	flag.Parse()
-	
-	f, err := os.Open(configFile)
-	if err != nil {
-		return nil, err
-	}
-	decoder := toml.NewDecoder(f).Strict(true)
-	if decoder == nil {
-		return nil, errors.New("couldn't create decoder")
+
+	return Load(configFile, os.LookupEnv)
+}
+
+// Load reads configFile, if it is not empty, overrides its values by environment
+// variables found by lookupEnv and validates the result.
+func Load(configFile string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	var data []byte
+	if configFile != "" {
+		var err error
+		data, err = os.ReadFile(configFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "config file error")
+		}
 	}
 
+	decoder := toml.NewDecoder(bytes.NewReader(data)).Strict(true)
+
 	conf := &Config{}
 	if err := decoder.Decode(conf); err != nil {
+		return nil, errors.Wrapf(err, "config file %s error", configFile)
+	}
+
+	if err := applyEnv(conf, lookupEnv); err != nil {
 		return nil, err
 	}
 
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	conf.File = configFile
 	return conf, nil
 }
-
@@ -0,0 +1,270 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+)
+
+type TestCaseLoadError struct {
+	File string
+	Env map[string]string
+	ExpectedError string
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	conf, err := config.Load("", lookupEnv(map[string]string{
+		"URL_SERVICE_DATABASE": "in_memory",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "in_memory", conf.Database)
+	assert.Equal(t, "8080", conf.PortHTTP)
+	assert.Equal(t, "8081", conf.PortGRPC)
+	assert.Equal(t, "", conf.PortAdmin)
+	assert.False(t, conf.AdminEnabled)
+	assert.Equal(t, 15*time.Second, conf.ShutdownTimeout)
+	assert.True(t, conf.GRPCInterceptors.Recovery)
+	assert.Equal(t, 600, conf.RateLimit.RequestsPerMinute)
+	assert.Equal(t, 3000, conf.RateLimit.IPRequestsPerMinute)
+	assert.Equal(t, 200, conf.RateLimit.IPBurst)
+	assert.Equal(t, 24*time.Hour, conf.Idempotency.Window)
+	assert.Equal(t, time.Hour, conf.Idempotency.CleanupInterval)
+	assert.Equal(t, 1000, conf.LinkEvents.HistorySize)
+	assert.Equal(t, 8, conf.Webhooks.MaxAttempts)
+	assert.Equal(t, 5*time.Minute, conf.Webhooks.MaxBackoff)
+	assert.False(t, conf.Webhooks.AllowLoopback)
+	assert.Empty(t, conf.Webhooks.AllowedPrivateNetworks)
+	assert.Equal(t, 500*time.Millisecond, conf.Outbox.PollInterval)
+	assert.Equal(t, 10*time.Minute, conf.Outbox.FeedRetention)
+	assert.True(t, conf.Outbox.Bus)
+	assert.False(t, conf.Outbox.Log)
+	assert.Equal(t, "info", conf.Log.Level)
+	assert.Equal(t, 1.0, conf.Tracing.SampleRatio)
+	assert.Equal(t, "sub", conf.JWT.OwnerClaim)
+	assert.Equal(t, "workspace", conf.JWT.WorkspaceClaim)
+	assert.Equal(t, "workspace", conf.Workspaces.Scope)
+}
+
+func TestLoadFileAndEnv(t *testing.T) {
+	file := writeFile(t, "config.toml", `
+database = "postgres"
+http_port = "9090"
+postgres_connection_string = "host=localhost"
+
+[rate_limit]
+requests_per_minute = 100
+
+[jwt]
+public_key_files = ["a.pem"]
+
+[domains]
+allowed = ["a.io", "b.io"]
+default = "a.io"
+
+[domains.base_urls]
+"a.io" = "https://a.io/s"
+`)
+	secret := writeFile(t, "dsn", "host=url_pg password=secret\n")
+
+	conf, err := config.Load(file, lookupEnv(map[string]string{
+		"URL_SERVICE_GRPC_PORT": "9091",
+		"URL_SERVICE_POSTGRES_CONNECTION_STRING_FILE": secret,
+		"URL_SERVICE_GRPC_INTERCEPTORS_TRACING": "false",
+		"URL_SERVICE_JWT_PUBLIC_KEY_FILES": "b.pem, c.pem",
+		"URL_SERVICE_JWT_CLOCK_SKEW": "1m",
+		"URL_SERVICE_TRACING_SAMPLE_RATIO": "0.5",
+		"URL_SERVICE_DOMAINS_BASE_URL": "http://localhost:9090",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "9090", conf.PortHTTP)
+	assert.Equal(t, "9091", conf.PortGRPC)
+	assert.Equal(t, "host=url_pg password=secret", conf.PostgresConnectionString)
+	assert.Equal(t, 100, conf.RateLimit.RequestsPerMinute)
+	assert.Equal(t, 50, conf.RateLimit.Burst)
+	assert.False(t, conf.GRPCInterceptors.Tracing)
+	assert.True(t, conf.GRPCInterceptors.Metrics)
+	assert.Equal(t, []string{"b.pem", "c.pem"}, conf.JWT.PublicKeyFiles)
+	assert.Equal(t, time.Minute, conf.JWT.ClockSkew)
+	assert.Equal(t, 0.5, conf.Tracing.SampleRatio)
+	assert.Equal(t, []string{"a.io", "b.io"}, conf.Domains.Allowed)
+	assert.Equal(t, "a.io", conf.Domains.Default)
+	assert.Equal(t, "http://localhost:9090", conf.Domains.BaseURL)
+	assert.Equal(t, map[string]string{"a.io": "https://a.io/s"}, conf.Domains.BaseURLs)
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := map[string]TestCaseLoadError {
+		"unknown_database": {
+			File: `database = "postgress"`,
+			ExpectedError: `database "postgress" is unknown`,
+		},
+		"missing_dsn": {
+			File: `database = "postgres"`,
+			ExpectedError: "postgres_connection_string is required",
+		},
+		"bad_port": {
+			Env: map[string]string{"URL_SERVICE_HTTP_PORT": "80800"},
+			ExpectedError: `http_port "80800" is not a port number`,
+		},
+		"bad_env_value": {
+			Env: map[string]string{"URL_SERVICE_SHUTDOWN_TIMEOUT": "15"},
+			ExpectedError: "URL_SERVICE_SHUTDOWN_TIMEOUT",
+		},
+		"both_env_and_file": {
+			Env: map[string]string{
+				"URL_SERVICE_ADMIN_API_KEY": "key",
+				"URL_SERVICE_ADMIN_API_KEY_FILE": "key_file",
+			},
+			ExpectedError: "both URL_SERVICE_ADMIN_API_KEY and URL_SERVICE_ADMIN_API_KEY_FILE are set",
+		},
+		"missing_secret_file": {
+			Env: map[string]string{"URL_SERVICE_ADMIN_API_KEY_FILE": "/nonexistent"},
+			ExpectedError: "URL_SERVICE_ADMIN_API_KEY_FILE",
+		},
+		"admin_without_key": {
+			Env: map[string]string{"URL_SERVICE_ADMIN_ENABLED": "true"},
+			ExpectedError: "admin_api_key is required when admin_enabled is set",
+		},
+		"bad_trusted_proxy": {
+			Env: map[string]string{"URL_SERVICE_HTTP_TRUSTED_PROXIES": "10.0.0.1"},
+			ExpectedError: `http_trusted_proxies item "10.0.0.1" is not a CIDR range`,
+		},
+		"bad_webhooks_private_network": {
+			File: "[webhooks]\nallowed_private_networks = [\"10.0.0.1\"]",
+			ExpectedError: `webhooks.allowed_private_networks item "10.0.0.1" is not a CIDR range`,
+		},
+		"unknown_key": {
+			File: "databse = \"in_memory\"",
+			ExpectedError: "databse",
+		},
+		"tls_without_key": {
+			Env: map[string]string{"URL_SERVICE_HTTP_TLS_CERT_FILE": "server.crt"},
+			ExpectedError: "http_tls requires both cert_file and key_file",
+		},
+		"client_ca_on_http": {
+			Env: map[string]string{
+				"URL_SERVICE_HTTP_TLS_CERT_FILE": "server.crt",
+				"URL_SERVICE_HTTP_TLS_KEY_FILE": "server.key",
+				"URL_SERVICE_HTTP_TLS_CLIENT_CA_FILE": "ca.crt",
+			},
+			ExpectedError: "http_tls does not support client certificates",
+		},
+		"require_client_cert_without_ca": {
+			Env: map[string]string{
+				"URL_SERVICE_GRPC_TLS_CERT_FILE": "server.crt",
+				"URL_SERVICE_GRPC_TLS_KEY_FILE": "server.key",
+				"URL_SERVICE_GRPC_TLS_REQUIRE_CLIENT_CERT": "true",
+			},
+			ExpectedError: "grpc_tls.require_client_cert requires client_ca_file",
+		},
+		"bad_tls_version": {
+			Env: map[string]string{"URL_SERVICE_GRPC_TLS_MIN_VERSION": "1.0"},
+			ExpectedError: `grpc_tls.min_version "1.0" is unknown`,
+		},
+		"zero_link_events_buffer": {
+			Env: map[string]string{"URL_SERVICE_LINK_EVENTS_BUFFER_SIZE": "0"},
+			ExpectedError: "link_events.buffer_size must be positive",
+		},
+		"webhooks_backoff": {
+			Env: map[string]string{"URL_SERVICE_WEBHOOKS_MAX_BACKOFF": "500ms"},
+			ExpectedError: "webhooks.max_backoff must not be less than initial_backoff",
+		},
+		"zero_idempotency_cleanup_interval": {
+			Env: map[string]string{"URL_SERVICE_IDEMPOTENCY_CLEANUP_INTERVAL": "0s"},
+			ExpectedError: "idempotency.cleanup_interval must be positive",
+		},
+		"zero_outbox_batch": {
+			Env: map[string]string{"URL_SERVICE_OUTBOX_BATCH_SIZE": "0"},
+			ExpectedError: "outbox.batch_size must be positive",
+		},
+		"short_outbox_feed_retention": {
+			Env: map[string]string{"URL_SERVICE_OUTBOX_FEED_RETENTION": "100ms"},
+			ExpectedError: "outbox.feed_retention must be greater than poll_interval",
+		},
+		"default_domain_not_allowed": {
+			Env: map[string]string{
+				"URL_SERVICE_DOMAINS_ALLOWED": "a.io, b.io",
+				"URL_SERVICE_DOMAINS_DEFAULT": "c.io",
+			},
+			ExpectedError: `domains.default "c.io" must be one of domains.allowed`,
+		},
+		"default_domain_without_allowed": {
+			Env: map[string]string{"URL_SERVICE_DOMAINS_DEFAULT": "c.io"},
+			ExpectedError: "domains.default requires domains.allowed",
+		},
+		"base_url_of_unknown_domain": {
+			Env: map[string]string{
+				"URL_SERVICE_DOMAINS_ALLOWED": "a.io",
+				"URL_SERVICE_DOMAINS_DEFAULT": "a.io",
+				"URL_SERVICE_DOMAINS_BASE_URLS": "a.io=https://a.io, b.io=https://b.io",
+			},
+			ExpectedError: `domains.base_urls has domain "b.io" missing in domains.allowed`,
+		},
+		"unknown_workspace_scope": {
+			Env: map[string]string{"URL_SERVICE_WORKSPACES_SCOPE": "team"},
+			ExpectedError: `workspaces.scope "team" is unknown`,
+		},
+		"bad_base_url": {
+			Env: map[string]string{"URL_SERVICE_DOMAINS_BASE_URL": "sho.rt"},
+			ExpectedError: `domains.base_url "sho.rt" is not an absolute http(s) URL`,
+		},
+		"bad_base_urls_item": {
+			Env: map[string]string{"URL_SERVICE_DOMAINS_BASE_URLS": "a.io"},
+			ExpectedError: "URL_SERVICE_DOMAINS_BASE_URLS",
+		},
+		"bad_log_level": {
+			Env: map[string]string{"URL_SERVICE_LOG_LEVEL": "verbose"},
+			ExpectedError: `log.level "verbose" is unknown`,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			file := writeFile(t, "config.toml", test.File)
+			env := map[string]string{"URL_SERVICE_DATABASE": "in_memory"}
+			if test.File != "" {
+				env = map[string]string{}
+			}
+			for key, value := range test.Env {
+				env[key] = value
+			}
+
+			_, err := config.Load(file, lookupEnv(env))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.ExpectedError)
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	conf, err := config.Load("", lookupEnv(map[string]string{"URL_SERVICE_DATABASE": "in_memory"}))
+	require.NoError(t, err)
+
+	conf.Database = "mongo"
+	conf.PortGRPC = "port"
+	err = conf.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `database "mongo" is unknown`)
+	assert.Contains(t, err.Error(), `grpc_port "port" is not a port number`)
+}
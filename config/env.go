@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EnvPrefix = "URL_SERVICE_"
+	envFileSuffix = "_FILE"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides fields of conf by environment variables named after their
+// toml keys: URL_SERVICE_HTTP_PORT sets http_port, URL_SERVICE_JWT_HMAC_SECRET sets
+// hmac_secret of the [jwt] table. Variable with _FILE suffix names a file holding
+// the value, which keeps secrets such as the Postgres password out of the environment.
+// Lists are comma separated, so are key=value items of maps.
+func applyEnv(conf *Config, lookupEnv func(string) (string, bool)) error {
+	return applyEnvToStruct(reflect.ValueOf(conf).Elem(), EnvPrefix, lookupEnv)
+}
+
+func applyEnvToStruct(v reflect.Value, prefix string, lookupEnv func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			if err := applyEnvToStruct(v.Field(i), name + "_", lookupEnv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok, err := lookupValue(name, lookupEnv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setValue(v.Field(i), value); err != nil {
+			return errors.Wrapf(err, "environment variable %s error", name)
+		}
+	}
+
+	return nil
+}
+
+// lookupValue returns value of the variable or content of the file named by
+// the variable with _FILE suffix. Setting both is ambiguous and rejected.
+func lookupValue(name string, lookupEnv func(string) (string, bool)) (string, bool, error) {
+	value, ok := lookupEnv(name)
+	file, fileOk := lookupEnv(name + envFileSuffix)
+	switch {
+	case ok && fileOk:
+		return "", false, errors.Errorf("both %s and %s are set", name, name + envFileSuffix)
+	case fileOk:
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, errors.Wrapf(err, "environment variable %s error", name + envFileSuffix)
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+
+	return value, ok, nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return errors.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return errors.Errorf("unsupported type %s", v.Type())
+		}
+		items := make(map[string]string)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, itemValue, ok := strings.Cut(item, "=")
+			if !ok {
+				return errors.Errorf("%q is not key=value", item)
+			}
+			items[strings.TrimSpace(key)] = strings.TrimSpace(itemValue)
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return errors.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+const reloadDebounce = 100 * time.Millisecond
+
+// Reloader loads the config file again when it changes or the process gets SIGHUP.
+// Valid config is applied as a whole: values of keys tagged reloadable are passed
+// to the appliers, changes of other keys are logged as requiring restart and ignored.
+// Invalid config is logged and the current one is kept.
+type Reloader struct {
+	mx        sync.Mutex
+	current   *Config
+	checksum  [sha256.Size]byte
+	lookupEnv func(string) (string, bool)
+	appliers  []func(conf *Config)
+	logger    *slog.Logger
+}
+
+func NewReloader(conf *Config, lookupEnv func(string) (string, bool), logger *slog.Logger) *Reloader {
+	reloader := &Reloader{
+		current:   conf,
+		lookupEnv: lookupEnv,
+		logger:    logger,
+	}
+	reloader.checksum, _ = fileChecksum(conf.File)
+
+	return reloader
+}
+
+// OnReload registers apply called with the new config after every reload
+// changing reloadable keys. It must only read reloadable keys.
+func (r *Reloader) OnReload(apply func(conf *Config)) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.appliers = append(r.appliers, apply)
+}
+
+// Current returns the config applied last.
+func (r *Reloader) Current() *Config {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return r.current
+}
+
+// Reload loads the config and applies its reloadable changes.
+func (r *Reloader) Reload() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	// the file is not loaded again until it changes, even if it is invalid
+	r.checksum, _ = fileChecksum(r.current.File)
+
+	conf, err := Load(r.current.File, r.lookupEnv)
+	if err != nil {
+		r.logger.Error("config reload failed, current config is kept", "error", err.Error())
+		return errors.Wrap(err, "config reload error")
+	}
+
+	var applied, restart []string
+	for _, change := range diff(reflect.ValueOf(r.current).Elem(), reflect.ValueOf(conf).Elem(), "") {
+		if change.reloadable {
+			applied = append(applied, change.key)
+			continue
+		}
+		restart = append(restart, change.key)
+		change.revert()
+	}
+
+	if len(restart) > 0 {
+		r.logger.Warn("config changes require restart", "keys", strings.Join(restart, ","))
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	for _, apply := range r.appliers {
+		apply(conf)
+	}
+	r.current = conf
+	r.logger.Info("config reloaded", "keys", strings.Join(applied, ","))
+
+	return nil
+}
+
+// Run reloads the config on SIGHUP and on changes of the config file until ctx is done.
+// The directory of the file is watched, so replacing the file (as editors and
+// Kubernetes config maps do) is noticed too.
+func (r *Reloader) Run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	file := r.Current().File
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	if file != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			err = watcher.Add(filepath.Dir(file))
+		}
+		if err != nil {
+			r.logger.Warn("config file is not watched, reload it with SIGHUP", "error", err.Error())
+		} else {
+			defer watcher.Close()
+			events, watchErrors = watcher.Events, watcher.Errors
+		}
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.logger.Info("SIGHUP received, reloading config")
+			_ = r.Reload()
+		case event := <-events:
+			if !affectsFile(event, file) {
+				continue
+			}
+			// editors write the file in several steps
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			if r.fileChanged() {
+				_ = r.Reload()
+			}
+		case err := <-watchErrors:
+			r.logger.Warn("config file watch error", "error", err.Error())
+		}
+	}
+}
+
+// affectsFile reports whether event may change content of file: the file itself
+// or, for Kubernetes config maps, the "..data" symlink to its directory is changed.
+func affectsFile(event fsnotify.Event, file string) bool {
+	return filepath.Clean(event.Name) == filepath.Clean(file) ||
+		strings.HasPrefix(filepath.Base(event.Name), "..")
+}
+
+func (r *Reloader) fileChanged() bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	checksum, err := fileChecksum(r.current.File)
+	return err == nil && !bytes.Equal(checksum[:], r.checksum[:])
+}
+
+func fileChecksum(file string) ([sha256.Size]byte, error) {
+	if file == "" {
+		return [sha256.Size]byte{}, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+type change struct {
+	key        string
+	reloadable bool
+	revert     func()
+}
+
+// diff returns keys of fields with different values in old and new,
+// named like "rate_limit.burst". Reverting a change sets the value of old in new.
+func diff(old reflect.Value, new reflect.Value, prefix string) []change {
+	var changes []change
+
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		key = prefix + key
+
+		oldField, newField := old.Field(i), new.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			changes = append(changes, diff(oldField, newField, key + ".")...)
+			continue
+		}
+
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+		changes = append(changes, change{
+			key:        key,
+			reloadable: field.Tag.Get("reloadable") == "true",
+			revert:     func() { newField.Set(oldField) },
+		})
+	}
+
+	return changes
+}
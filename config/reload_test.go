@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/internal/observability"
+)
+
+const reloadConfig = `
+database = "in_memory"
+grpc_port = "8081"
+
+[rate_limit]
+requests_per_minute = 600
+
+[log]
+level = "info"
+`
+
+func TestReloaderReload(t *testing.T) {
+	file := writeFile(t, "config.toml", reloadConfig)
+	conf, err := config.Load(file, lookupEnv(nil))
+	require.NoError(t, err)
+
+	reloader := config.NewReloader(conf, lookupEnv(nil), observability.NopLogger())
+	var applied []*config.Config
+	reloader.OnReload(func(conf *config.Config) {
+		applied = append(applied, conf)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		require.NoError(t, reloader.Reload())
+		assert.Empty(t, applied)
+	})
+
+	t.Run("reloadable_and_restart", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte(`
+database = "in_memory"
+grpc_port = "9091"
+
+[rate_limit]
+requests_per_minute = 60
+
+[log]
+level = "debug"
+`), 0600))
+
+		require.NoError(t, reloader.Reload())
+		require.Len(t, applied, 1)
+		assert.Equal(t, 60, applied[0].RateLimit.RequestsPerMinute)
+		assert.Equal(t, "debug", applied[0].Log.Level)
+		assert.Equal(t, "8081", applied[0].PortGRPC)
+		assert.Equal(t, applied[0], reloader.Current())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte(`database = "mongo"`), 0600))
+
+		require.Error(t, reloader.Reload())
+		assert.Len(t, applied, 1)
+		assert.Equal(t, 60, reloader.Current().RateLimit.RequestsPerMinute)
+	})
+
+	t.Run("restart_only", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte(`
+database = "in_memory"
+grpc_port = "9092"
+
+[rate_limit]
+requests_per_minute = 60
+
+[log]
+level = "debug"
+`), 0600))
+
+		require.NoError(t, reloader.Reload())
+		assert.Len(t, applied, 1)
+		assert.Equal(t, "8081", reloader.Current().PortGRPC)
+	})
+}
+
+func TestReloaderWatch(t *testing.T) {
+	file := writeFile(t, "config.toml", reloadConfig)
+	conf, err := config.Load(file, lookupEnv(nil))
+	require.NoError(t, err)
+
+	reloader := config.NewReloader(conf, lookupEnv(nil), observability.NopLogger())
+	var mx sync.Mutex
+	var burst int
+	reloader.OnReload(func(conf *config.Config) {
+		mx.Lock()
+		defer mx.Unlock()
+		burst = conf.RateLimit.Burst
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reloader.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// the watcher is started asynchronously, so the file is rewritten until it is noticed
+	assert.Eventually(t, func() bool {
+		_ = os.WriteFile(file, []byte(strings.Replace(reloadConfig, "requests_per_minute = 600",
+			"requests_per_minute = 600\nburst = 5", 1)), 0600)
+
+		mx.Lock()
+		defer mx.Unlock()
+		return burst == 5
+	}, 5*time.Second, 200*time.Millisecond)
+}
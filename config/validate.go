@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DatabasePostgres = "postgres"
+	DatabaseInMemory = "in_memory"
+
+	WorkspaceScopeWorkspace = "workspace"
+	WorkspaceScopeGlobal    = "global"
+)
+
+var (
+	databases        = []string{DatabasePostgres, DatabaseInMemory}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+	logFormats       = []string{"json", "text"}
+	tracingExporters = []string{"", "stdout", "otlp"}
+	tlsVersions      = []string{"1.2", "1.3"}
+	workspaceScopes  = []string{WorkspaceScopeWorkspace, WorkspaceScopeGlobal}
+)
+
+// Validate checks values which would otherwise fail late or silently, such as
+// unknown database or port out of range, and reports all problems at once.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(contains(databases, c.Database), "database %q is unknown, expected one of %s", c.Database, strings.Join(databases, ", "))
+	check(c.Database != DatabasePostgres || c.PostgresConnectionString != "",
+		"postgres_connection_string is required for postgres database")
+
+	check(!c.AdminEnabled || c.AdminAPIKey != "", "admin_api_key is required when admin_enabled is set")
+
+	check(validPort(c.PortHTTP), "http_port %q is not a port number", c.PortHTTP)
+	check(validPort(c.PortGRPC), "grpc_port %q is not a port number", c.PortGRPC)
+	check(c.PortAdmin == "" || validPort(c.PortAdmin), "admin_port %q is not a port number", c.PortAdmin)
+	check(c.PortAdmin == "" || c.HostAdmin + ":" + c.PortAdmin != c.HostHTTP + ":" + c.PortHTTP,
+		"admin_port must differ from http_port")
+	check(c.PortHTTP != c.PortGRPC || c.HostHTTP != c.HostGRPC, "grpc_port must differ from http_port")
+	for _, proxy := range c.TrustedProxiesHTTP {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil, "http_trusted_proxies item %q is not a CIDR range", proxy)
+	}
+
+	c.validateTLS(check, "http_tls", c.TLSHTTP, false)
+	c.validateTLS(check, "admin_tls", c.TLSAdmin, false)
+	c.validateTLS(check, "grpc_tls", c.TLSGRPC, true)
+
+	check(c.HealthCheckInterval >= 0, "health_check_interval must not be negative")
+	check(c.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+
+	check(c.PostgresPool.MaxIdleConns >= 0, "postgres_pool.max_idle_conns must not be negative")
+	check(c.PostgresPool.MaxOpenConns >= 0, "postgres_pool.max_open_conns must not be negative")
+	check(c.PostgresPool.ConnMaxLifetime >= 0, "postgres_pool.conn_max_lifetime must not be negative")
+	check(c.PostgresPool.ConnMaxIdleTime >= 0, "postgres_pool.conn_max_idle_time must not be negative")
+
+	check(c.RateLimit.RequestsPerMinute >= 0, "rate_limit.requests_per_minute must not be negative")
+	check(c.RateLimit.RequestsPerMinute == 0 || c.RateLimit.Burst > 0, "rate_limit.burst must be positive")
+	check(c.RateLimit.IPRequestsPerMinute >= 0, "rate_limit.ip_requests_per_minute must not be negative")
+	check(c.RateLimit.IPRequestsPerMinute == 0 || c.RateLimit.IPBurst > 0, "rate_limit.ip_burst must be positive")
+	check(c.Quota.DailyLinks >= 0, "quota.daily_links must not be negative")
+	check(c.Quota.MonthlyLinks >= 0, "quota.monthly_links must not be negative")
+	check(c.Idempotency.Window >= 0, "idempotency.window must not be negative")
+	check(c.Idempotency.CleanupInterval > 0, "idempotency.cleanup_interval must be positive")
+	check(c.LinkEvents.HistorySize > 0, "link_events.history_size must be positive")
+	check(c.LinkEvents.BufferSize > 0, "link_events.buffer_size must be positive")
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive")
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Webhooks.QueueSize > 0, "webhooks.queue_size must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.InitialBackoff > 0, "webhooks.initial_backoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.max_backoff must not be less than initial_backoff")
+	check(c.Webhooks.DeliveryLogSize > 0, "webhooks.delivery_log_size must be positive")
+	for _, network := range c.Webhooks.AllowedPrivateNetworks {
+		_, _, err := net.ParseCIDR(network)
+		check(err == nil, "webhooks.allowed_private_networks item %q is not a CIDR range", network)
+	}
+	check(c.Outbox.PollInterval > 0, "outbox.poll_interval must be positive")
+	check(c.Outbox.BatchSize > 0, "outbox.batch_size must be positive")
+	check(c.Outbox.FeedRetention > c.Outbox.PollInterval, "outbox.feed_retention must be greater than poll_interval")
+	check(len(c.Domains.Allowed) == 0 || contains(c.Domains.Allowed, c.Domains.Default),
+		"domains.default %q must be one of domains.allowed", c.Domains.Default)
+	check(len(c.Domains.Allowed) > 0 || c.Domains.Default == "", "domains.default requires domains.allowed")
+	check(!contains(c.Domains.Allowed, ""), "domains.allowed must not contain empty domain")
+	check(c.Domains.BaseURL == "" || validBaseURL(c.Domains.BaseURL),
+		"domains.base_url %q is not an absolute http(s) URL without query", c.Domains.BaseURL)
+	baseURLDomains := make([]string, 0, len(c.Domains.BaseURLs))
+	for domain := range c.Domains.BaseURLs {
+		baseURLDomains = append(baseURLDomains, domain)
+	}
+	sort.Strings(baseURLDomains)
+	for _, domain := range baseURLDomains {
+		baseURL := c.Domains.BaseURLs[domain]
+		check(contains(c.Domains.Allowed, domain), "domains.base_urls has domain %q missing in domains.allowed", domain)
+		check(validBaseURL(baseURL), "domains.base_urls.%s %q is not an absolute http(s) URL without query", domain, baseURL)
+	}
+	check(contains(workspaceScopes, c.Workspaces.Scope), "workspaces.scope %q is unknown, expected one of %s",
+		c.Workspaces.Scope, strings.Join(workspaceScopes, ", "))
+	check(c.JWT.ClockSkew >= 0, "jwt.clock_skew must not be negative")
+
+	check(contains(logLevels, strings.ToLower(c.Log.Level)), "log.level %q is unknown, expected one of %s",
+		c.Log.Level, strings.Join(logLevels, ", "))
+	check(contains(logFormats, c.Log.Format), "log.format %q is unknown, expected one of %s",
+		c.Log.Format, strings.Join(logFormats, ", "))
+
+	check(contains(tracingExporters, c.Tracing.Exporter), "tracing.exporter %q is unknown, expected stdout, otlp or empty",
+		c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c *Config) validateTLS(check func(ok bool, format string, args ...interface{}), key string, conf TLSConfig,
+	clientCertsAllowed bool) {
+	check(!conf.Enabled() || conf.CertFile != "" && conf.KeyFile != "", "%s requires both cert_file and key_file", key)
+	check(contains(tlsVersions, conf.MinVersion), "%s.min_version %q is unknown, expected one of %s",
+		key, conf.MinVersion, strings.Join(tlsVersions, ", "))
+	check(clientCertsAllowed || conf.ClientCAFile == "" && !conf.RequireClientCert,
+		"%s does not support client certificates", key)
+	check(conf.ClientCAFile == "" || conf.Enabled(), "%s.client_ca_file requires cert_file and key_file", key)
+	check(!conf.RequireClientCert || conf.ClientCAFile != "", "%s.require_client_cert requires client_ca_file", key)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// validBaseURL reports whether short links can be appended to the path of baseURL.
+func validBaseURL(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.RawQuery == "" && u.Fragment == ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
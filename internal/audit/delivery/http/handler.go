@@ -0,0 +1,83 @@
+package delivery
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type Delivery struct {
+	AuditUC auditUsecase.UseCaseI
+	Logger *slog.Logger
+}
+
+// GetRecords godoc
+// @Summary      GetRecords
+// @Description  get records of the audit log of mutating operations matching the filters, the latest first; the next page is requested with before_id set to id of the last record
+// @Tags     audit
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param    actor query string false "owner making the operation, admin or anonymous"
+// @Param    action query string false "operation" Enums(link.create, link.update, link.delete, api_key.create, workspace.create, member.update, member.delete, webhook.create, webhook.delete, webhook.redeliver)
+// @Param    resource query string false "link as <domain>/<short link> (short link for links without domain), owner of api key, workspace id, member as <workspace id>/<owner id> or webhook subscription id"
+// @Param    transport query string false "transport of the request" Enums(http, grpc)
+// @Param    from query string false "start of the period, RFC 3339"
+// @Param    to query string false "end of the period (exclusive), RFC 3339"
+// @Param    before_id query int false "return records with lower id"
+// @Param    limit query int false "maximum number of records, 100 by default, up to 1000"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.AuditRecord} "success get audit records"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /audit [get]
+func (del *Delivery) GetRecords(c echo.Context) error {
+	filter := models.AuditFilter{
+		Actor:     c.QueryParam("actor"),
+		Action:    c.QueryParam("action"),
+		Resource:  c.QueryParam("resource"),
+		Transport: c.QueryParam("transport"),
+	}
+
+	err := echo.QueryParamsBinder(c).
+		Time("from", &filter.From, time.RFC3339).
+		Time("to", &filter.To, time.RFC3339).
+		Int64("before_id", &filter.BeforeID).
+		Int("limit", &filter.Limit).
+		BindError()
+	if err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid audit filter", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	records, err := del.AuditUC.GetRecords(c.Request().Context(), filter)
+	if errors.Is(errors.Cause(err), models.ErrBadRequest) {
+		del.Logger.InfoContext(c.Request().Context(), "invalid audit filter", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else if err != nil {
+		del.Logger.ErrorContext(c.Request().Context(), "audit records loading failed", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: records})
+}
+
+// New registers audit routes wrapped with middleware returned by authorize
+// for the required scope; only administrators read the audit log.
+func New(e *echo.Echo, auditUC auditUsecase.UseCaseI, authorize func(scope string) echo.MiddlewareFunc,
+	logger *slog.Logger) {
+	handler := &Delivery{
+		AuditUC: auditUC,
+		Logger: logger,
+	}
+
+	e.GET("/audit", handler.GetRecords, authorize(models.ScopeAdmin))
+}
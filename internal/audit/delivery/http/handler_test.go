@@ -0,0 +1,116 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	auditDelivery "github.com/kuzkuss/url_service/internal/audit/delivery/http"
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type TestCaseRequest struct {
+	Target string
+	ExpectedResponse string
+	StatusCode int
+}
+
+// adminOnly authorizes requests made with the admin header.
+func adminOnly(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if scope != models.ScopeAdmin || c.Request().Header.Get("X-Admin") == "" {
+				return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+			}
+			return next(c)
+		}
+	}
+}
+
+func TestHttpDeliveryGetRecords(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	records := []models.AuditRecord {
+		{ID: 2, Time: from, Actor: "owner", Action: models.AuditLinkUpdate, Resource: "short_link",
+			Before: json.RawMessage(`{"original_link":"before"}`), After: json.RawMessage(`{"original_link":"after"}`),
+			Transport: pkg.TransportHTTP, ClientIP: "192.0.2.1"},
+	}
+
+	mockAuditUsecase := auditMocks.NewUseCaseI(t)
+
+	mockAuditUsecase.On("GetRecords", mock.Anything, models.AuditFilter{
+		Actor: "owner",
+		Action: models.AuditLinkUpdate,
+		Resource: "short_link",
+		Transport: pkg.TransportHTTP,
+		From: from,
+		To: from.Add(time.Hour),
+		BeforeID: 3,
+		Limit: 10,
+	}).Return(records, nil)
+	mockAuditUsecase.On("GetRecords", mock.Anything, models.AuditFilter{Limit: 5000}).
+		Return(nil, errors.Wrap(models.ErrBadRequest, "limit must be from 1 to 1000"))
+	mockAuditUsecase.On("GetRecords", mock.Anything, models.AuditFilter{Actor: "error"}).
+		Return(nil, errors.New("error"))
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: records})
+	require.NoError(t, err)
+
+	e := echo.New()
+	auditDelivery.New(e, mockAuditUsecase, adminOnly, observability.NopLogger())
+
+	cases := map[string]TestCaseRequest {
+		"success": {
+			Target: "/audit?actor=owner&action=link.update&resource=short_link&transport=http" +
+				"&from=2024-05-01T00:00:00Z&to=2024-05-01T01:00:00Z&before_id=3&limit=10",
+			ExpectedResponse: string(jsonResponse) + "\n",
+			StatusCode: http.StatusOK,
+		},
+		"invalid_time": {
+			Target: "/audit?from=yesterday",
+			StatusCode: http.StatusBadRequest,
+		},
+		"invalid_before_id": {
+			Target: "/audit?before_id=last",
+			StatusCode: http.StatusBadRequest,
+		},
+		"invalid_limit": {
+			Target: "/audit?limit=5000",
+			StatusCode: http.StatusBadRequest,
+		},
+		"usecase_error": {
+			Target: "/audit?actor=error",
+			StatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, test.Target, nil)
+			req.Header.Set("X-Admin", "true")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("forbidden", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/audit", nil))
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,41 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kuzkuss/url_service/internal/audit/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type auditRepository struct {
+	mx      sync.RWMutex
+	records []models.AuditRecord
+}
+
+func New() repository.RepositoryI {
+	return &auditRepository{}
+}
+
+func (dbAudit *auditRepository) CreateRecord(ctx context.Context, record *models.AuditRecord) error {
+	dbAudit.mx.Lock()
+	defer dbAudit.mx.Unlock()
+
+	record.ID = int64(len(dbAudit.records)) + 1
+	dbAudit.records = append(dbAudit.records, *record)
+	return nil
+}
+
+func (dbAudit *auditRepository) SelectRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error) {
+	dbAudit.mx.RLock()
+	defer dbAudit.mx.RUnlock()
+
+	records := make([]models.AuditRecord, 0)
+	for idx := len(dbAudit.records) - 1; idx >= 0 && len(records) < filter.Limit; idx-- {
+		if filter.Matches(&dbAudit.records[idx]) {
+			records = append(records, dbAudit.records[idx])
+		}
+	}
+
+	return records, nil
+}
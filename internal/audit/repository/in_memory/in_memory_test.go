@@ -0,0 +1,84 @@
+package in_memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auditRep "github.com/kuzkuss/url_service/internal/audit/repository/in_memory"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestRepositoryRecords(t *testing.T) {
+	repository := auditRep.New()
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	records := []*models.AuditRecord {
+		{Time: start, Actor: "owner", Action: models.AuditLinkCreate, Resource: "first", Transport: "http"},
+		{Time: start.Add(time.Hour), Actor: "owner", Action: models.AuditLinkUpdate, Resource: "first", Transport: "grpc"},
+		{Time: start.Add(2 * time.Hour), Actor: "other", Action: models.AuditLinkCreate, Resource: "second", Transport: "http"},
+		{Time: start.Add(3 * time.Hour), Actor: "owner", Action: models.AuditLinkDelete, Resource: "first", Transport: "http"},
+	}
+	for idx, record := range records {
+		require.NoError(t, repository.CreateRecord(context.Background(), record))
+		assert.Equal(t, int64(idx + 1), record.ID)
+	}
+
+	ids := func(filter models.AuditFilter) []int64 {
+		selected, err := repository.SelectRecords(context.Background(), filter)
+		require.NoError(t, err)
+
+		ids := make([]int64, 0, len(selected))
+		for _, record := range selected {
+			ids = append(ids, record.ID)
+		}
+		return ids
+	}
+
+	cases := map[string]struct {
+		Filter models.AuditFilter
+		ExpectedIDs []int64
+	}{
+		"all": {
+			Filter: models.AuditFilter{Limit: 10},
+			ExpectedIDs: []int64{4, 3, 2, 1},
+		},
+		"limit": {
+			Filter: models.AuditFilter{Limit: 2},
+			ExpectedIDs: []int64{4, 3},
+		},
+		"before_id": {
+			Filter: models.AuditFilter{BeforeID: 3, Limit: 10},
+			ExpectedIDs: []int64{2, 1},
+		},
+		"actor": {
+			Filter: models.AuditFilter{Actor: "owner", Limit: 10},
+			ExpectedIDs: []int64{4, 2, 1},
+		},
+		"action_resource": {
+			Filter: models.AuditFilter{Action: models.AuditLinkCreate, Resource: "first", Limit: 10},
+			ExpectedIDs: []int64{1},
+		},
+		"transport": {
+			Filter: models.AuditFilter{Transport: "grpc", Limit: 10},
+			ExpectedIDs: []int64{2},
+		},
+		"period": {
+			Filter: models.AuditFilter{From: start.Add(time.Hour), To: start.Add(3 * time.Hour), Limit: 10},
+			ExpectedIDs: []int64{3, 2},
+		},
+		"nothing": {
+			Filter: models.AuditFilter{Actor: "unknown", Limit: 10},
+			ExpectedIDs: []int64{},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.ExpectedIDs, ids(test.Filter))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/audit/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "audit"
+
+type auditRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps audit repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &auditRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbAudit *auditRepository) CreateRecord(ctx context.Context, record *models.AuditRecord) error {
+	start := time.Now()
+	err := dbAudit.repository.CreateRecord(ctx, record)
+	dbAudit.metrics.Observe(repositoryName, "CreateRecord", start, err)
+	return err
+}
+
+func (dbAudit *auditRepository) SelectRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error) {
+	start := time.Now()
+	records, err := dbAudit.repository.SelectRecords(ctx, filter)
+	dbAudit.metrics.Observe(repositoryName, "SelectRecords", start, err)
+	return records, err
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/audit/repository"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+func (dbAudit *auditRepository) CreateRecord(ctx context.Context, record *models.AuditRecord) error {
+	tx := dbAudit.db.WithContext(ctx).Create(record)
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table audit_log)")
+	}
+
+	return nil
+}
+
+func (dbAudit *auditRepository) SelectRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error) {
+	records := make([]models.AuditRecord, 0)
+
+	query := dbAudit.db.WithContext(ctx)
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Resource != "" {
+		query = query.Where("resource = ?", filter.Resource)
+	}
+	if filter.Transport != "" {
+		query = query.Where("transport = ?", filter.Transport)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	tx := query.Order("id DESC").Limit(filter.Limit).Find(&records)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table audit_log)")
+	}
+
+	return records, nil
+}
@@ -0,0 +1,124 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kuzkuss/url_service/models"
+	auditRep "github.com/kuzkuss/url_service/internal/audit/repository/postgres"
+)
+
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	return gdb, mock
+}
+
+func TestRepositoryCreateRecord(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	record := &models.AuditRecord {
+		Time: time.Now(),
+		Actor: "owner",
+		Action: models.AuditLinkUpdate,
+		Resource: "short_link",
+		Before: json.RawMessage(`{"original_link":"before"}`),
+		After: json.RawMessage(`{"original_link":"after"}`),
+		Transport: "http",
+		ClientIP: "192.0.2.1",
+		RequestID: "request_id",
+	}
+
+	query := regexp.QuoteMeta(`INSERT INTO "audit_log" ("created_at","actor","action","resource","before_state",` +
+		`"after_state","transport","client_ip","request_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING "id"`)
+	insertErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(record.Time, record.Actor, record.Action, record.Resource, record.Before,
+		record.After, record.Transport, record.ClientIP, record.RequestID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WillReturnError(insertErr)
+	mock.ExpectRollback()
+
+	repository := auditRep.New(gdb)
+
+	require.NoError(t, repository.CreateRecord(context.Background(), record))
+	assert.Equal(t, int64(7), record.ID)
+
+	err := repository.CreateRecord(context.Background(), record)
+	require.Equal(t, insertErr, errors.Cause(err))
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositorySelectRecords(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	query := regexp.QuoteMeta(`SELECT * FROM "audit_log" WHERE actor = $1 AND action = $2 AND resource = $3 ` +
+		`AND transport = $4 AND created_at >= $5 AND created_at < $6 AND id < $7 ORDER BY id DESC LIMIT 5`)
+	allQuery := regexp.QuoteMeta(`SELECT * FROM "audit_log" ORDER BY id DESC LIMIT 100`)
+
+	mock.ExpectQuery(query).WithArgs("owner", models.AuditLinkCreate, "short_link", "grpc", from, to, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "actor", "action", "resource", "transport"}).
+			AddRow(9, from, "owner", models.AuditLinkCreate, "short_link", "grpc"))
+	mock.ExpectQuery(allQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	repository := auditRep.New(gdb)
+
+	records, err := repository.SelectRecords(context.Background(), models.AuditFilter{
+		Actor: "owner",
+		Action: models.AuditLinkCreate,
+		Resource: "short_link",
+		Transport: "grpc",
+		From: from,
+		To: to,
+		BeforeID: 10,
+		Limit: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []models.AuditRecord{{ID: 9, Time: from, Actor: "owner", Action: models.AuditLinkCreate,
+		Resource: "short_link", Transport: "grpc"}}, records)
+
+	records, err = repository.SelectRecords(context.Background(), models.AuditFilter{Limit: 100})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
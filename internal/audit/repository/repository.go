@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+// RepositoryI stores the audit log. Records are only appended, they are never
+// changed or removed.
+type RepositoryI interface {
+	CreateRecord(ctx context.Context, record *models.AuditRecord) (error)
+	// SelectRecords returns at most filter.Limit records matching the filter, the latest first.
+	SelectRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error)
+}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/audit/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "audit"
+
+type auditRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps audit repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &auditRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbAudit *auditRepository) CreateRecord(ctx context.Context, record *models.AuditRecord) error {
+	ctx, span := dbAudit.tracing.Start(ctx, repositoryName, "CreateRecord")
+	err := dbAudit.repository.CreateRecord(ctx, record)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbAudit *auditRepository) SelectRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error) {
+	ctx, span := dbAudit.tracing.Start(ctx, repositoryName, "SelectRecords")
+	records, err := dbAudit.repository.SelectRecords(ctx, filter)
+	observability.EndSpan(span, err)
+	return records, err
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+
+	auditRep "github.com/kuzkuss/url_service/internal/audit/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+type UseCaseI interface {
+	// Record appends the operation with the resource to the audit log. Actor, transport
+	// and client address are taken from ctx. Before and after are states of the resource
+	// encoded to JSON, nil for created and deleted resources. Failures are only logged,
+	// so the operation, which is already done, is not reported as failed.
+	Record(ctx context.Context, action string, resource string, before interface{}, after interface{})
+	// NewRecord returns the record of the operation with the resource without states,
+	// for repositories writing it in the transaction of the change (see models.AuditRecord.SetStates),
+	// so the change fails if it can not be recorded. Actor, transport and client address are taken from ctx.
+	NewRecord(ctx context.Context, action string, resource string) *models.AuditRecord
+	GetRecords(ctx context.Context, filter models.AuditFilter) ([]models.AuditRecord, error)
+}
+
+type useCase struct {
+	auditRepository auditRep.RepositoryI
+	logger          *slog.Logger
+}
+
+func New(auditRepository auditRep.RepositoryI, logger *slog.Logger) UseCaseI {
+	return &useCase{
+		auditRepository: auditRepository,
+		logger:          logger,
+	}
+}
+
+func (uc *useCase) Record(ctx context.Context, action string, resource string, before interface{}, after interface{}) {
+	ctx, span := observability.StartSpan(ctx, "audit.usecase.Record")
+	var err error
+	defer func() { observability.EndSpan(span, err) }()
+
+	record := uc.NewRecord(ctx, action, resource)
+	if err = record.SetStates(before, after); err != nil {
+		uc.logger.ErrorContext(ctx, "audit record is not written", "action", action, "resource", resource, "error", err)
+		return
+	}
+
+	// the record is written even if the request is cancelled after the operation
+	err = uc.auditRepository.CreateRecord(context.WithoutCancel(ctx), record)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "audit record is not written", "action", action, "resource", resource, "error", err)
+	}
+}
+
+func (uc *useCase) NewRecord(ctx context.Context, action string, resource string) *models.AuditRecord {
+	source := pkg.SourceFromContext(ctx)
+	return &models.AuditRecord{
+		Time:      time.Now(),
+		Actor:     actor(ctx),
+		Action:    action,
+		Resource:  resource,
+		Transport: source.Transport,
+		ClientIP:  source.ClientIP,
+		RequestID: pkg.RequestIDFromContext(ctx),
+	}
+}
+
+// GetRecords returns records matching the filter, the latest first. Limit is
+// DefaultLimit if it is not set.
+func (uc *useCase) GetRecords(ctx context.Context, filter models.AuditFilter) (_ []models.AuditRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "audit.usecase.GetRecords")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if filter.Limit < 0 || filter.Limit > MaxLimit {
+		return nil, errors.Wrapf(models.ErrBadRequest, "limit must be from 1 to %d", MaxLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultLimit
+	}
+
+	records, err := uc.auditRepository.SelectRecords(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "audit repository error")
+	}
+
+	return records, nil
+}
+
+// actor identifies the principal making the request by its owner.
+func actor(ctx context.Context) string {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	switch {
+	case !ok:
+		return models.AuditActorAnonymous
+	case principal.OwnerID != "":
+		return principal.OwnerID
+	case principal.HasScope(models.ScopeAdmin):
+		return models.AuditActorAdmin
+	default:
+		return models.AuditActorAnonymous
+	}
+}
@@ -0,0 +1,181 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	auditInMem "github.com/kuzkuss/url_service/internal/audit/repository/in_memory"
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/repository/mocks"
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+func TestUsecaseRecord(t *testing.T) {
+	ctx := pkg.WithRequestID(context.Background(), "request_id")
+	ctx = pkg.WithSource(ctx, pkg.Source{Transport: pkg.TransportGRPC, ClientIP: "192.0.2.1"})
+
+	cases := map[string]struct {
+		Principal *models.Principal
+		ExpectedActor string
+	}{
+		"owner": {
+			Principal: &models.Principal{OwnerID: "owner", Scopes: []string{models.ScopeLinksWrite}},
+			ExpectedActor: "owner",
+		},
+		"admin": {
+			Principal: &models.Principal{Scopes: []string{models.ScopeAdmin}},
+			ExpectedActor: models.AuditActorAdmin,
+		},
+		"anonymous": {
+			ExpectedActor: models.AuditActorAnonymous,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			repository := auditInMem.New()
+			usecase := auditUsecase.New(repository, observability.NopLogger())
+
+			recordCtx := ctx
+			if test.Principal != nil {
+				recordCtx = pkg.WithPrincipal(ctx, test.Principal)
+			}
+			usecase.Record(recordCtx, models.AuditLinkUpdate, "short_link",
+				models.Link{OriginalLink: "before"}, models.Link{OriginalLink: "after"})
+
+			records, err := usecase.GetRecords(context.Background(), models.AuditFilter{})
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+
+			record := records[0]
+			assert.Equal(t, test.ExpectedActor, record.Actor)
+			assert.Equal(t, models.AuditLinkUpdate, record.Action)
+			assert.Equal(t, "short_link", record.Resource)
+			assert.JSONEq(t, `{"original_link":"before"}`, string(record.Before))
+			assert.JSONEq(t, `{"original_link":"after"}`, string(record.After))
+			assert.Equal(t, pkg.TransportGRPC, record.Transport)
+			assert.Equal(t, "192.0.2.1", record.ClientIP)
+			assert.Equal(t, "request_id", record.RequestID)
+			assert.False(t, record.Time.IsZero())
+		})
+	}
+
+	t.Run("created", func(t *testing.T) {
+		repository := auditInMem.New()
+		usecase := auditUsecase.New(repository, observability.NopLogger())
+
+		usecase.Record(ctx, models.AuditLinkCreate, "short_link", nil, models.Link{OriginalLink: "after"})
+
+		records, err := usecase.GetRecords(context.Background(), models.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Nil(t, records[0].Before)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		repository := auditInMem.New()
+		usecase := auditUsecase.New(repository, observability.NopLogger())
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		usecase.Record(cancelledCtx, models.AuditLinkDelete, "short_link", models.Link{}, nil)
+
+		records, err := usecase.GetRecords(context.Background(), models.AuditFilter{})
+		require.NoError(t, err)
+		assert.Len(t, records, 1)
+	})
+
+	t.Run("repository_error", func(t *testing.T) {
+		mockAuditRepo := auditMocks.NewRepositoryI(t)
+		mockAuditRepo.On("CreateRecord", mock.Anything, mock.AnythingOfType("*models.AuditRecord")).
+			Return(errors.New("error")).Once()
+
+		// the failure is only logged
+		auditUsecase.New(mockAuditRepo, observability.NopLogger()).
+			Record(ctx, models.AuditLinkCreate, "short_link", nil, json.RawMessage(`{}`))
+	})
+}
+
+func TestUsecaseNewRecord(t *testing.T) {
+	ctx := pkg.WithRequestID(context.Background(), "request_id")
+	ctx = pkg.WithSource(ctx, pkg.Source{Transport: pkg.TransportHTTP, ClientIP: "192.0.2.1"})
+	ctx = pkg.WithPrincipal(ctx, &models.Principal{OwnerID: "owner", Scopes: []string{models.ScopeLinksWrite}})
+
+	mockAuditRepo := auditMocks.NewRepositoryI(t)
+	record := auditUsecase.New(mockAuditRepo, observability.NopLogger()).
+		NewRecord(ctx, models.AuditLinkDelete, "short_link")
+
+	// the record is written by the repository changing the resource
+	assert.Equal(t, "owner", record.Actor)
+	assert.Equal(t, models.AuditLinkDelete, record.Action)
+	assert.Equal(t, "short_link", record.Resource)
+	assert.Equal(t, pkg.TransportHTTP, record.Transport)
+	assert.Equal(t, "192.0.2.1", record.ClientIP)
+	assert.Equal(t, "request_id", record.RequestID)
+	assert.False(t, record.Time.IsZero())
+	assert.Nil(t, record.Before)
+	assert.Nil(t, record.After)
+
+	require.NoError(t, record.SetStates(models.Link{OriginalLink: "before"}, nil))
+	assert.JSONEq(t, `{"original_link":"before"}`, string(record.Before))
+	assert.Nil(t, record.After)
+}
+
+func TestUsecaseGetRecords(t *testing.T) {
+	selectErr := errors.New("error")
+	records := []models.AuditRecord{{ID: 1, Actor: "owner"}}
+
+	mockAuditRepo := auditMocks.NewRepositoryI(t)
+
+	mockAuditRepo.On("SelectRecords", mock.Anything, models.AuditFilter{Actor: "owner", Limit: auditUsecase.DefaultLimit}).
+		Return(records, nil)
+	mockAuditRepo.On("SelectRecords", mock.Anything, models.AuditFilter{Actor: "owner", Limit: auditUsecase.MaxLimit}).
+		Return(records, nil)
+	mockAuditRepo.On("SelectRecords", mock.Anything, models.AuditFilter{Actor: "error", Limit: 10}).
+		Return(nil, selectErr)
+
+	usecase := auditUsecase.New(mockAuditRepo, observability.NopLogger())
+
+	cases := map[string]struct {
+		Filter models.AuditFilter
+		ExpectedRes []models.AuditRecord
+		Error error
+	}{
+		"default_limit": {
+			Filter: models.AuditFilter{Actor: "owner"},
+			ExpectedRes: records,
+		},
+		"max_limit": {
+			Filter: models.AuditFilter{Actor: "owner", Limit: auditUsecase.MaxLimit},
+			ExpectedRes: records,
+		},
+		"too_large_limit": {
+			Filter: models.AuditFilter{Actor: "owner", Limit: auditUsecase.MaxLimit + 1},
+			Error: models.ErrBadRequest,
+		},
+		"negative_limit": {
+			Filter: models.AuditFilter{Actor: "owner", Limit: -1},
+			Error: models.ErrBadRequest,
+		},
+		"repository_error": {
+			Filter: models.AuditFilter{Actor: "error", Limit: 10},
+			Error: selectErr,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			actualRes, err := usecase.GetRecords(context.Background(), test.Filter)
+			require.Equal(t, test.Error, errors.Cause(err))
+			assert.Equal(t, test.ExpectedRes, actualRes)
+		})
+	}
+}
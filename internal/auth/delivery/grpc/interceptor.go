@@ -0,0 +1,176 @@
+package delivery
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	authUsecase "github.com/kuzkuss/url_service/internal/auth/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+const (
+	MetadataAPIKey        = "x-api-key"
+	MetadataAuthorization = "authorization"
+	bearerPrefix          = "Bearer "
+)
+
+type AuthInterceptor struct {
+	AuthUC        authUsecase.UseCaseI
+	methodScopes  map[string]string
+	publicMethods map[string]struct{}
+}
+
+// New creates interceptor authenticating every call by bearer token, api key or
+// client certificate verified during TLS handshake, in that order, except the ones
+// to publicMethods (full method names, e.g. "/link.Links/GetOriginalLink").
+// Calls to methods listed in methodScopes additionally require the corresponding scope.
+func New(authUC authUsecase.UseCaseI, methodScopes map[string]string, publicMethods ...string) *AuthInterceptor {
+	interceptor := &AuthInterceptor{
+		AuthUC:        authUC,
+		methodScopes:  methodScopes,
+		publicMethods: make(map[string]struct{}, len(publicMethods)),
+	}
+
+	for _, method := range publicMethods {
+		interceptor.publicMethods[method] = struct{}{}
+	}
+
+	return interceptor
+}
+
+// IdentifyUnary authenticates call carrying credentials and stores the resulting principal
+// in the context, so that following interceptors such as rate limiting can tell clients apart.
+// Calls without valid credentials are passed on as anonymous; Unary rejects them where
+// authentication is required.
+func (ai *AuthInterceptor) IdentifyUnary(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(ai.identify(ctx), req)
+}
+
+// IdentifyStream identifies the client once, when the stream is opened.
+func (ai *AuthInterceptor) IdentifyStream(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ai.identify(ss.Context())
+	if ctx == ss.Context() {
+		return handler(srv, ss)
+	}
+	return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+}
+
+func (ai *AuthInterceptor) Unary(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := ai.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Stream authorizes the call once, when the stream is opened.
+func (ai *AuthInterceptor) Stream(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := ai.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize returns context with principal of the call to method, the same context for public methods.
+func (ai *AuthInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	if _, ok := ai.publicMethods[method]; ok {
+		return ctx, nil
+	}
+
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if ok {
+		return ai.checkScope(ctx, method, principal)
+	}
+
+	principal, err := ai.authenticate(ctx)
+	if err != nil {
+		if errors.Is(errors.Cause(err), models.ErrUnauthorized) {
+			return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+		}
+		return nil, status.Error(codes.Internal, models.ErrInternalServerError.Error())
+	}
+
+	return ai.checkScope(pkg.WithPrincipal(ctx, principal), method, principal)
+}
+
+func (ai *AuthInterceptor) checkScope(ctx context.Context, method string,
+	principal *models.Principal) (context.Context, error) {
+	if scope, ok := ai.methodScopes[method]; ok && !principal.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, models.ErrForbidden.Error())
+	}
+
+	return ctx, nil
+}
+
+// identify returns context with principal of the call carrying valid credentials,
+// the same context otherwise.
+func (ai *AuthInterceptor) identify(ctx context.Context) context.Context {
+	if !hasCredentials(ctx) {
+		return ctx
+	}
+
+	principal, err := ai.authenticate(ctx)
+	if err != nil {
+		return ctx
+	}
+	return pkg.WithPrincipal(ctx, principal)
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ps *principalStream) Context() context.Context {
+	return ps.ctx
+}
+
+func hasCredentials(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get(MetadataAuthorization)) > 0 || len(md.Get(MetadataAPIKey)) > 0 {
+		return true
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(tlsInfo.State.VerifiedChains) > 0
+}
+
+func (ai *AuthInterceptor) authenticate(ctx context.Context) (*models.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(MetadataAuthorization); len(values) > 0 && strings.HasPrefix(values[0], bearerPrefix) {
+		return ai.AuthUC.AuthenticateToken(ctx, strings.TrimPrefix(values[0], bearerPrefix))
+	}
+
+	if values := md.Get(MetadataAPIKey); len(values) > 0 {
+		return ai.AuthUC.Authenticate(ctx, values[0])
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			return ai.AuthUC.AuthenticateCertificate(ctx, tlsInfo.State.VerifiedChains[0][0])
+		}
+	}
+
+	return ai.AuthUC.Authenticate(ctx, "")
+}
@@ -0,0 +1,218 @@
+package delivery_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	authDelivery "github.com/kuzkuss/url_service/internal/auth/delivery/grpc"
+	authMocks "github.com/kuzkuss/url_service/internal/auth/usecase/mocks"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type TestCaseUnary struct {
+	Method string
+	APIKey string
+	Token string
+	Cert *x509.Certificate
+	ExpectedRes interface{}
+	Code codes.Code
+}
+
+func TestGrpcAuthInterceptorUnary(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("Authenticate", mock.Anything, "owner_key").Return(&models.Principal{
+		OwnerID: "owner",
+		Scopes: []string{models.ScopeLinksWrite},
+	}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "bad_key").Return(nil, models.ErrUnauthorized)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "error_key").Return(nil, errors.New("error"))
+	mockAuthUsecase.On("AuthenticateToken", mock.Anything, "reader_token").Return(&models.Principal{
+		OwnerID: "reader",
+		Scopes: []string{models.ScopeLinksRead},
+	}, nil)
+
+	clientCert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+	mockAuthUsecase.On("AuthenticateCertificate", mock.Anything, clientCert).Return(&models.Principal{
+		OwnerID: "client",
+		Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}, nil)
+
+	interceptor := authDelivery.New(mockAuthUsecase, map[string]string{
+		"/link.Links/CreateShortLink": models.ScopeLinksWrite,
+		"/link.Links/ListLinks": models.ScopeLinksRead,
+	}, "/link.Links/GetOriginalLink")
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if principal, ok := pkg.PrincipalFromContext(ctx); ok {
+			return principal.OwnerID, nil
+		}
+		return "anonymous", nil
+	}
+
+	cases := map[string]TestCaseUnary {
+		"success": {
+			Method: "/link.Links/CreateShortLink",
+			APIKey: "owner_key",
+			ExpectedRes: "owner",
+			Code: codes.OK,
+		},
+		"token": {
+			Method: "/link.Links/ListLinks",
+			Token: "reader_token",
+			ExpectedRes: "reader",
+			Code: codes.OK,
+		},
+		"certificate": {
+			Method: "/link.Links/CreateShortLink",
+			Cert: clientCert,
+			ExpectedRes: "client",
+			Code: codes.OK,
+		},
+		"api_key_over_certificate": {
+			Method: "/link.Links/CreateShortLink",
+			APIKey: "owner_key",
+			Cert: clientCert,
+			ExpectedRes: "owner",
+			Code: codes.OK,
+		},
+		"permission_denied": {
+			Method: "/link.Links/CreateShortLink",
+			Token: "reader_token",
+			Code: codes.PermissionDenied,
+		},
+		"public": {
+			Method: "/link.Links/GetOriginalLink",
+			ExpectedRes: "anonymous",
+			Code: codes.OK,
+		},
+		"unauthenticated": {
+			Method: "/link.Links/CreateShortLink",
+			APIKey: "bad_key",
+			Code: codes.Unauthenticated,
+		},
+		"internal": {
+			Method: "/link.Links/CreateShortLink",
+			APIKey: "error_key",
+			Code: codes.Internal,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.APIKey != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authDelivery.MetadataAPIKey, test.APIKey))
+			}
+			if test.Token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authDelivery.MetadataAuthorization, "Bearer " + test.Token))
+			}
+			if test.Cert != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+					State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{test.Cert}}},
+				}})
+			}
+
+			actualRes, err := interceptor.Unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: test.Method}, handler)
+			require.Equal(t, test.Code, status.Code(err))
+			assert.Equal(t, test.ExpectedRes, actualRes)
+		})
+	}
+}
+
+func TestGrpcAuthInterceptorIdentifyUnary(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("Authenticate", mock.Anything, "owner_key").Return(&models.Principal{OwnerID: "owner"}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "bad_key").Return(nil, models.ErrUnauthorized)
+
+	interceptor := authDelivery.New(mockAuthUsecase, nil)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if principal, ok := pkg.PrincipalFromContext(ctx); ok {
+			return principal.OwnerID, nil
+		}
+		return "anonymous", nil
+	}
+
+	cases := map[string]TestCaseUnary {
+		"identified": {
+			APIKey: "owner_key",
+			ExpectedRes: "owner",
+		},
+		"bad_key": {
+			APIKey: "bad_key",
+			ExpectedRes: "anonymous",
+		},
+		"no_credentials": {
+			ExpectedRes: "anonymous",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.APIKey != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authDelivery.MetadataAPIKey, test.APIKey))
+			}
+
+			actualRes, err := interceptor.IdentifyUnary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/link.Links/ListLinks"}, handler)
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedRes, actualRes)
+		})
+	}
+}
+
+type serverStreamStub struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStreamStub) Context() context.Context {
+	return s.ctx
+}
+
+func TestGrpcAuthInterceptorStream(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("Authenticate", mock.Anything, "reader_key").Return(&models.Principal{
+		OwnerID: "reader",
+		Scopes: []string{models.ScopeLinksRead},
+	}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "").Return(nil, models.ErrUnauthorized)
+
+	interceptor := authDelivery.New(mockAuthUsecase, map[string]string{
+		"/link.Links/WatchLinks": models.ScopeLinksRead,
+	})
+
+	var ownerID string
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		principal, _ := pkg.PrincipalFromContext(stream.Context())
+		ownerID = principal.OwnerID
+		return nil
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/link.Links/WatchLinks"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(authDelivery.MetadataAPIKey, "reader_key"))
+	err := interceptor.Stream(nil, &serverStreamStub{ctx: ctx}, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "reader", ownerID)
+
+	err = interceptor.Stream(nil, &serverStreamStub{ctx: context.Background()}, info, handler)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,147 @@
+package delivery
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	authUsecase "github.com/kuzkuss/url_service/internal/auth/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+const (
+	HeaderAPIKey = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+type Delivery struct {
+	AuthUC authUsecase.UseCaseI
+	Logger *slog.Logger
+}
+
+// CreateAPIKey godoc
+// @Summary      CreateAPIKey
+// @Description  create api key for owner in the workspace, the default one if it is not set, available to administrator only
+// @Tags     auth
+// @Accept	 application/json
+// @Produce  application/json
+// @Param    X-API-Key header string false "administrator api key"
+// @Param    Authorization header string false "bearer token with admin scope"
+// @Param    owner_id body models.APIKey true "api key owner and workspace"
+// @Success 201 {object} pkg.Response{body=models.APIKey} "api key created"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /keys [post]
+func (del *Delivery) CreateAPIKey(c echo.Context) error {
+	var key models.APIKey
+	err := c.Bind(&key)
+	if err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid api key data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	if err := pkg.Validate(&key); err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid api key data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	err = del.AuthUC.CreateAPIKey(c.Request().Context(), &key)
+	if errors.Is(errors.Cause(err), models.ErrBadRequest) {
+		del.Logger.InfoContext(c.Request().Context(), "invalid api key data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	} else if err != nil {
+		del.Logger.ErrorContext(c.Request().Context(), "api key creation failed", "owner_id", key.OwnerID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+	}
+
+	return c.JSON(http.StatusCreated, pkg.Response{Body: key})
+}
+
+// Identify is a middleware that authenticates request carrying bearer token or api key
+// and stores the resulting principal in the request context, so that following
+// middleware such as rate limiting can tell clients apart. Requests without valid
+// credentials are passed on as anonymous; Auth rejects them where authentication is required.
+func (del *Delivery) Identify(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !hasCredentials(c) {
+			return next(c)
+		}
+
+		principal, err := del.authenticate(c)
+		if err != nil {
+			return next(c)
+		}
+
+		c.SetRequest(c.Request().WithContext(pkg.WithPrincipal(c.Request().Context(), principal)))
+		return next(c)
+	}
+}
+
+// Auth is a middleware that authenticates request by bearer token or api key
+// and stores the resulting principal in the request context. Requests already
+// identified by Identify are not authenticated again.
+func (del *Delivery) Auth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := pkg.PrincipalFromContext(c.Request().Context()); ok {
+			return next(c)
+		}
+
+		principal, err := del.authenticate(c)
+		if err != nil {
+			causeErr := errors.Cause(err)
+			switch {
+			case errors.Is(causeErr, models.ErrUnauthorized):
+				del.Logger.InfoContext(c.Request().Context(), "authentication failed", "error", err)
+				return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+			default:
+				del.Logger.ErrorContext(c.Request().Context(), "authentication error", "error", err)
+				return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+			}
+		}
+
+		c.SetRequest(c.Request().WithContext(pkg.WithPrincipal(c.Request().Context(), principal)))
+		return next(c)
+	}
+}
+
+// Authorize returns middleware that authenticates request and rejects
+// principals not granted scope.
+func (del *Delivery) Authorize(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return del.Auth(func(c echo.Context) error {
+			principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+			if !ok || !principal.HasScope(scope) {
+				return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+			}
+			return next(c)
+		})
+	}
+}
+
+func (del *Delivery) authenticate(c echo.Context) (*models.Principal, error) {
+	if authorization := c.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(authorization, bearerPrefix) {
+		return del.AuthUC.AuthenticateToken(c.Request().Context(), strings.TrimPrefix(authorization, bearerPrefix))
+	}
+	return del.AuthUC.Authenticate(c.Request().Context(), c.Request().Header.Get(HeaderAPIKey))
+}
+
+func hasCredentials(c echo.Context) bool {
+	return c.Request().Header.Get(echo.HeaderAuthorization) != "" || c.Request().Header.Get(HeaderAPIKey) != ""
+}
+
+func New(e *echo.Echo, authUC authUsecase.UseCaseI, logger *slog.Logger) *Delivery {
+	handler := &Delivery{
+		AuthUC: authUC,
+		Logger: logger,
+	}
+
+	e.POST("/keys", handler.CreateAPIKey, handler.Authorize(models.ScopeAdmin))
+
+	return handler
+}
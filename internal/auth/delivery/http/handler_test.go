@@ -0,0 +1,248 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	authDelivery "github.com/kuzkuss/url_service/internal/auth/delivery/http"
+	authMocks "github.com/kuzkuss/url_service/internal/auth/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type TestCaseRequest struct {
+	APIKey string
+	ArgData string
+	ExpectedResponse string
+	StatusCode int
+}
+
+func TestHttpDeliveryCreateAPIKey(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("Authenticate", mock.Anything, "admin_key").Return(&models.Principal{Scopes: []string{models.ScopeAdmin}}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "owner_key").Return(&models.Principal{
+		OwnerID: "owner",
+		Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "").Return(nil, models.ErrUnauthorized)
+	mockAuthUsecase.On("CreateAPIKey", mock.Anything, &models.APIKey{OwnerID: "owner"}).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.APIKey).Key = "new_key"
+		args.Get(1).(*models.APIKey).WorkspaceID = models.DefaultWorkspace
+	}).Return(nil)
+	mockAuthUsecase.On("CreateAPIKey", mock.Anything, &models.APIKey{OwnerID: "owner", WorkspaceID: "unknown"}).
+		Return(errors.Wrap(models.ErrBadRequest, `workspace "unknown" is not found`))
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: models.APIKey{Key: "new_key", OwnerID: "owner",
+		WorkspaceID: models.DefaultWorkspace}})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	authDelivery.New(e, mockAuthUsecase, observability.NopLogger())
+
+	cases := map[string]TestCaseRequest {
+		"success": {
+			APIKey: "admin_key",
+			ArgData: `{"owner_id":"owner"}`,
+			ExpectedResponse: string(jsonResponse) + "\n",
+			StatusCode: http.StatusCreated,
+		},
+		"invalid_request": {
+			APIKey: "admin_key",
+			ArgData: `{}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"unknown_workspace": {
+			APIKey: "admin_key",
+			ArgData: `{"owner_id":"owner","workspace_id":"unknown"}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"not_admin": {
+			APIKey: "owner_key",
+			ArgData: `{"owner_id":"owner"}`,
+			StatusCode: http.StatusForbidden,
+		},
+		"unauthorized": {
+			APIKey: "",
+			ArgData: `{"owner_id":"owner"}`,
+			StatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/keys", strings.NewReader(test.ArgData))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if test.APIKey != "" {
+				req.Header.Set(authDelivery.HeaderAPIKey, test.APIKey)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHttpDeliveryAuth(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("Authenticate", mock.Anything, "owner_key").Return(&models.Principal{OwnerID: "owner"}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "bad_key").Return(nil, models.ErrUnauthorized)
+	mockAuthUsecase.On("AuthenticateToken", mock.Anything, "owner_token").Return(&models.Principal{OwnerID: "owner"}, nil)
+
+	e := echo.New()
+	delivery := authDelivery.Delivery {
+		AuthUC: mockAuthUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	handler := delivery.Auth(func(c echo.Context) error {
+		principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+		require.True(t, ok)
+		return c.String(http.StatusOK, principal.OwnerID)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(authDelivery.HeaderAPIKey, "owner_key")
+		rec := httptest.NewRecorder()
+
+		err := handler(e.NewContext(req, rec))
+		require.NoError(t, err)
+		assert.Equal(t, "owner", rec.Body.String())
+	})
+
+	t.Run("token", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer owner_token")
+		rec := httptest.NewRecorder()
+
+		err := handler(e.NewContext(req, rec))
+		require.NoError(t, err)
+		assert.Equal(t, "owner", rec.Body.String())
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(authDelivery.HeaderAPIKey, "bad_key")
+		rec := httptest.NewRecorder()
+
+		err := handler(e.NewContext(req, rec))
+		require.Equal(t, &echo.HTTPError{
+			Code: http.StatusUnauthorized,
+			Message: models.ErrUnauthorized.Error(),
+		}, err)
+	})
+}
+
+func TestHttpDeliveryIdentify(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("Authenticate", mock.Anything, "owner_key").Return(&models.Principal{OwnerID: "owner"}, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "bad_key").Return(nil, models.ErrUnauthorized)
+
+	e := echo.New()
+	delivery := authDelivery.Delivery {
+		AuthUC: mockAuthUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	handler := delivery.Identify(func(c echo.Context) error {
+		if principal, ok := pkg.PrincipalFromContext(c.Request().Context()); ok {
+			return c.String(http.StatusOK, principal.OwnerID)
+		}
+		return c.String(http.StatusOK, "anonymous")
+	})
+
+	cases := map[string]TestCaseRequest {
+		"identified": {
+			APIKey: "owner_key",
+			ExpectedResponse: "owner",
+		},
+		"bad_key": {
+			APIKey: "bad_key",
+			ExpectedResponse: "anonymous",
+		},
+		"no_credentials": {
+			ExpectedResponse: "anonymous",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			if test.APIKey != "" {
+				req.Header.Set(authDelivery.HeaderAPIKey, test.APIKey)
+			}
+			rec := httptest.NewRecorder()
+
+			err := handler(e.NewContext(req, rec))
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+		})
+	}
+}
+
+func TestHttpDeliveryAuthorize(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+
+	mockAuthUsecase.On("AuthenticateToken", mock.Anything, "reader_token").Return(&models.Principal{
+		OwnerID: "reader",
+		Scopes: []string{models.ScopeLinksRead},
+	}, nil)
+	mockAuthUsecase.On("AuthenticateToken", mock.Anything, "admin_token").Return(&models.Principal{
+		OwnerID: "admin",
+		Scopes: []string{models.ScopeAdmin},
+	}, nil)
+
+	e := echo.New()
+	delivery := authDelivery.Delivery {
+		AuthUC: mockAuthUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	handler := delivery.Authorize(models.ScopeLinksWrite)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	cases := map[string]TestCaseRequest {
+		"forbidden": {
+			APIKey: "reader_token",
+			StatusCode: http.StatusForbidden,
+		},
+		"admin": {
+			APIKey: "admin_token",
+			StatusCode: http.StatusOK,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer " + test.APIKey)
+			rec := httptest.NewRecorder()
+
+			err := handler(e.NewContext(req, rec))
+			if err != nil {
+				require.Equal(t, test.StatusCode, err.(*echo.HTTPError).Code)
+				return
+			}
+			require.Equal(t, test.StatusCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kuzkuss/url_service/internal/auth/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type authRepository struct {
+	mx    sync.RWMutex
+	store map[string]models.APIKey
+}
+
+func New() repository.RepositoryI {
+	return &authRepository{
+		store: make(map[string]models.APIKey),
+	}
+}
+
+func (dbAuth *authRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	dbAuth.mx.Lock()
+	defer dbAuth.mx.Unlock()
+
+	if _, ok := dbAuth.store[key.KeyHash]; ok {
+		return models.ErrConflict
+	}
+
+	dbAuth.store[key.KeyHash] = models.APIKey{
+		KeyHash:     key.KeyHash,
+		OwnerID:     key.OwnerID,
+		WorkspaceID: key.WorkspaceID,
+	}
+	return nil
+}
+
+func (dbAuth *authRepository) SelectAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	dbAuth.mx.RLock()
+	key, ok := dbAuth.store[keyHash]
+	dbAuth.mx.RUnlock()
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,37 @@
+package in_memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	authRep "github.com/kuzkuss/url_service/internal/auth/repository/in_memory"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestRepositoryAPIKey(t *testing.T) {
+	key := models.APIKey {
+		Key: "key",
+		KeyHash: "key_hash",
+		OwnerID: "owner",
+		WorkspaceID: "team",
+	}
+
+	repository := authRep.New()
+
+	err := repository.CreateAPIKey(context.Background(), &key)
+	require.NoError(t, err)
+
+	err = repository.CreateAPIKey(context.Background(), &key)
+	require.Equal(t, models.ErrConflict, errors.Cause(err))
+
+	actualRes, err := repository.SelectAPIKeyByHash(context.Background(), key.KeyHash)
+	require.NoError(t, err)
+	assert.Equal(t, &models.APIKey{KeyHash: key.KeyHash, OwnerID: key.OwnerID, WorkspaceID: key.WorkspaceID}, actualRes)
+
+	_, err = repository.SelectAPIKeyByHash(context.Background(), "unknown_hash")
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+}
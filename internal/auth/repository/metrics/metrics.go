@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/auth/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "api_keys"
+
+type authRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps auth repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &authRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbAuth *authRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	start := time.Now()
+	err := dbAuth.repository.CreateAPIKey(ctx, key)
+	dbAuth.metrics.Observe(repositoryName, "CreateAPIKey", start, err)
+	return err
+}
+
+func (dbAuth *authRepository) SelectAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	start := time.Now()
+	key, err := dbAuth.repository.SelectAPIKeyByHash(ctx, keyHash)
+	dbAuth.metrics.Observe(repositoryName, "SelectAPIKeyByHash", start, err)
+	return key, err
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/auth/repository"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type authRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &authRepository{
+		db: db,
+	}
+}
+
+func (dbAuth *authRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	tx := dbAuth.db.WithContext(ctx).Create(key)
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table api_keys)")
+	}
+
+	return nil
+}
+
+func (dbAuth *authRepository) SelectAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	key := models.APIKey{}
+
+	tx := dbAuth.db.WithContext(ctx).Where("key_hash = ?", keyHash).Take(&key)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	} else if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table api_keys)")
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,107 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kuzkuss/url_service/models"
+	authRep "github.com/kuzkuss/url_service/internal/auth/repository/postgres"
+)
+
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	return gdb, mock
+}
+
+func TestRepositoryCreateAPIKey(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	key := models.APIKey {
+		Key: "key",
+		KeyHash: "key_hash",
+		OwnerID: "owner",
+		WorkspaceID: "team",
+	}
+
+	createErr := errors.New("error")
+
+	query := regexp.QuoteMeta(`INSERT INTO "api_keys" ("key_hash","owner_id","workspace_id") VALUES ($1,$2,$3)`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(key.KeyHash, key.OwnerID, key.WorkspaceID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(key.KeyHash, key.OwnerID, key.WorkspaceID).WillReturnError(createErr)
+	mock.ExpectRollback()
+
+	repository := authRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.CreateAPIKey(context.Background(), &key)
+		require.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := repository.CreateAPIKey(context.Background(), &key)
+		require.Equal(t, createErr, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositorySelectAPIKeyByHash(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	query := regexp.QuoteMeta(`SELECT * FROM "api_keys" WHERE key_hash = $1 LIMIT 1`)
+
+	mock.ExpectQuery(query).WithArgs("key_hash").
+		WillReturnRows(sqlmock.NewRows([]string{"key_hash", "owner_id", "workspace_id"}).AddRow("key_hash", "owner", "team"))
+
+	mock.ExpectQuery(query).WithArgs("unknown_hash").
+		WillReturnRows(sqlmock.NewRows([]string{"key_hash", "owner_id"}))
+
+	repository := authRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		actualRes, err := repository.SelectAPIKeyByHash(context.Background(), "key_hash")
+		require.NoError(t, err)
+		assert.Equal(t, &models.APIKey{KeyHash: "key_hash", OwnerID: "owner", WorkspaceID: "team"}, actualRes)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := repository.SelectAPIKeyByHash(context.Background(), "unknown_hash")
+		require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+type RepositoryI interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (error)
+	SelectAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+}
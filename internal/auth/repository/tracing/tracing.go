@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/auth/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "api_keys"
+
+type authRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps auth repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &authRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbAuth *authRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	ctx, span := dbAuth.tracing.Start(ctx, repositoryName, "CreateAPIKey")
+	err := dbAuth.repository.CreateAPIKey(ctx, key)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbAuth *authRepository) SelectAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	ctx, span := dbAuth.tracing.Start(ctx, repositoryName, "SelectAPIKeyByHash")
+	key, err := dbAuth.repository.SelectAPIKeyByHash(ctx, keyHash)
+	observability.EndSpan(span, err)
+	return key, err
+}
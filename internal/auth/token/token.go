@@ -0,0 +1,241 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const (
+	defaultOwnerClaim     = "sub"
+	defaultWorkspaceClaim = "workspace"
+)
+
+type VerifierI interface {
+	Verify(rawToken string) (*models.Principal, error)
+}
+
+type verificationKey struct {
+	kid string
+	key crypto.PublicKey
+}
+
+type verifier struct {
+	keys           []verificationKey
+	issuer         string
+	audience       string
+	clockSkew      time.Duration
+	ownerClaim     string
+	workspaceClaim string
+}
+
+// New creates verifier of bearer tokens signed by keys from conf.
+func New(conf config.JWTConfig) (VerifierI, error) {
+	v := &verifier{
+		issuer:         conf.Issuer,
+		audience:       conf.Audience,
+		clockSkew:      conf.ClockSkew,
+		ownerClaim:     conf.OwnerClaim,
+		workspaceClaim: conf.WorkspaceClaim,
+	}
+	if v.ownerClaim == "" {
+		v.ownerClaim = defaultOwnerClaim
+	}
+	if v.workspaceClaim == "" {
+		v.workspaceClaim = defaultWorkspaceClaim
+	}
+
+	if conf.JWKSFile != "" {
+		keys, err := loadJWKS(conf.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, keys...)
+	}
+
+	for _, path := range conf.PublicKeyFiles {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, verificationKey{key: key})
+	}
+
+	if conf.HMACSecret != "" {
+		v.keys = append(v.keys, verificationKey{key: []byte(conf.HMACSecret)})
+	}
+
+	if len(v.keys) == 0 {
+		return nil, errors.New("no keys to verify tokens")
+	}
+
+	return v, nil
+}
+
+func (v *verifier) Verify(rawToken string) (*models.Principal, error) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+
+	var lastErr error = errors.New("no matching key")
+	for _, candidate := range v.keys {
+		claims := jwt.MapClaims{}
+		_, err := parser.ParseWithClaims(rawToken, claims, func(t *jwt.Token) (interface{}, error) {
+			if kid, _ := t.Header["kid"].(string); kid != "" && candidate.kid != "" && kid != candidate.kid {
+				return nil, errors.New("key id mismatch")
+			}
+			if !keyMatchesMethod(candidate.key, t.Method) {
+				return nil, errors.Errorf("key does not match signing method %s", t.Method.Alg())
+			}
+			return candidate.key, nil
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if err := v.validateClaims(claims); err != nil {
+			return nil, errors.Wrap(models.ErrUnauthorized, err.Error())
+		}
+
+		return v.principal(claims)
+	}
+
+	return nil, errors.Wrap(models.ErrUnauthorized, lastErr.Error())
+}
+
+func (v *verifier) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+
+	// tokens without expiration would be valid forever
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return errors.New("token has no expiration")
+	}
+	if now.After(exp.Add(v.clockSkew)) {
+		return errors.New("token is expired")
+	}
+
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(v.clockSkew).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+
+	if iat, ok := numericClaim(claims, "iat"); ok && now.Add(v.clockSkew).Before(iat) {
+		return errors.New("token is issued in the future")
+	}
+
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return errors.Errorf("unexpected issuer %q", iss)
+		}
+	}
+
+	if v.audience != "" && !contains(stringsClaim(claims, "aud"), v.audience) {
+		return errors.New("unexpected audience")
+	}
+
+	return nil
+}
+
+func (v *verifier) principal(claims jwt.MapClaims) (*models.Principal, error) {
+	owner, _ := claims[v.ownerClaim].(string)
+	if owner == "" {
+		return nil, errors.Wrapf(models.ErrUnauthorized, "claim %q is missing", v.ownerClaim)
+	}
+
+	workspace, _ := claims[v.workspaceClaim].(string)
+	if workspace == "" {
+		workspace = models.DefaultWorkspace
+	}
+
+	scopes := stringsClaim(claims, "scope")
+	scopes = append(scopes, stringsClaim(claims, "scp")...)
+
+	return &models.Principal{
+		OwnerID:     owner,
+		WorkspaceID: workspace,
+		Scopes:      scopes,
+	}, nil
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read public key file")
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.Errorf("unsupported public key in %s", path)
+}
+
+func keyMatchesMethod(key crypto.PublicKey, method jwt.SigningMethod) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, isRSA := method.(*jwt.SigningMethodRSA)
+		_, isPSS := method.(*jwt.SigningMethodRSAPSS)
+		return isRSA || isPSS
+	case *ecdsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	case []byte:
+		_, ok := method.(*jwt.SigningMethodHMAC)
+		return ok
+	default:
+		return false
+	}
+}
+
+func numericClaim(claims jwt.MapClaims, name string) (time.Time, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+// stringsClaim returns claim that may be either space separated string or array of strings.
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		res := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package token_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/internal/auth/token"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseVerify struct {
+	Token string
+	ExpectedRes *models.Principal
+	Error error
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	signed, err := tok.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestVerifierJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier, err := token.New(config.JWTConfig{
+		JWKSFile: writeJWKS(t, "key-1", &rsaKey.PublicKey),
+		Issuer: "https://issuer",
+		Audience: "url_service",
+		ClockSkew: time.Minute,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "owner",
+			"iss": "https://issuer",
+			"aud": []string{"other", "url_service"},
+			"exp": now.Add(time.Hour).Unix(),
+			"scope": "links:read links:write",
+		}
+	}
+
+	expiredInSkew := valid()
+	expiredInSkew["exp"] = now.Add(-30 * time.Second).Unix()
+
+	expired := valid()
+	expired["exp"] = now.Add(-2 * time.Minute).Unix()
+
+	wrongIssuer := valid()
+	wrongIssuer["iss"] = "https://other"
+
+	wrongAudience := valid()
+	wrongAudience["aud"] = "other"
+
+	noSubject := valid()
+	delete(noSubject, "sub")
+
+	noExpiration := valid()
+	delete(noExpiration, "exp")
+
+	principal := &models.Principal{
+		OwnerID: "owner",
+		WorkspaceID: models.DefaultWorkspace,
+		Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}
+
+	cases := map[string]TestCaseVerify {
+		"success": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, valid()),
+			ExpectedRes: principal,
+		},
+		"expired_within_skew": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, expiredInSkew),
+			ExpectedRes: principal,
+		},
+		"expired": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, expired),
+			Error: models.ErrUnauthorized,
+		},
+		"no_expiration": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, noExpiration),
+			Error: models.ErrUnauthorized,
+		},
+		"wrong_issuer": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, wrongIssuer),
+			Error: models.ErrUnauthorized,
+		},
+		"wrong_audience": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, wrongAudience),
+			Error: models.ErrUnauthorized,
+		},
+		"no_subject": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, noSubject),
+			Error: models.ErrUnauthorized,
+		},
+		"unknown_kid": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-2", rsaKey, valid()),
+			Error: models.ErrUnauthorized,
+		},
+		"wrong_signature": {
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", otherKey, valid()),
+			Error: models.ErrUnauthorized,
+		},
+		"wrong_method": {
+			Token: sign(t, jwt.SigningMethodHS256, "key-1", []byte("secret"), valid()),
+			Error: models.ErrUnauthorized,
+		},
+		"malformed": {
+			Token: "not.a.token",
+			Error: models.ErrUnauthorized,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			actualRes, err := verifier.Verify(test.Token)
+			require.Equal(t, test.Error, errors.Cause(err))
+			assert.Equal(t, test.ExpectedRes, actualRes)
+		})
+	}
+}
+
+func TestVerifierStaticKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ec.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	verifier, err := token.New(config.JWTConfig{
+		PublicKeyFiles: []string{path},
+		HMACSecret: "secret",
+		OwnerClaim: "client_id",
+		WorkspaceClaim: "tenant",
+	})
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"client_id": "service",
+		"tenant": "team",
+		"scp": []string{models.ScopeAdmin},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	expected := &models.Principal{OwnerID: "service", WorkspaceID: "team", Scopes: []string{models.ScopeAdmin}}
+
+	t.Run("ecdsa", func(t *testing.T) {
+		actualRes, err := verifier.Verify(sign(t, jwt.SigningMethodES256, "", ecKey, claims))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actualRes)
+	})
+
+	t.Run("hmac", func(t *testing.T) {
+		actualRes, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, "", []byte("secret"), claims))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actualRes)
+	})
+
+	t.Run("hmac_wrong_secret", func(t *testing.T) {
+		_, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, "", []byte("other"), claims))
+		require.Equal(t, models.ErrUnauthorized, errors.Cause(err))
+	})
+}
+
+func TestVerifierNoKeys(t *testing.T) {
+	_, err := token.New(config.JWTConfig{})
+	require.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	authRep "github.com/kuzkuss/url_service/internal/auth/repository"
+	"github.com/kuzkuss/url_service/internal/auth/token"
+	"github.com/kuzkuss/url_service/internal/observability"
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const keyLength = 32
+
+type UseCaseI interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (error)
+	Authenticate(ctx context.Context, key string) (*models.Principal, error)
+	AuthenticateToken(ctx context.Context, rawToken string) (*models.Principal, error)
+	AuthenticateCertificate(ctx context.Context, cert *x509.Certificate) (*models.Principal, error)
+}
+
+type useCase struct {
+	authRepository authRep.RepositoryI
+	workspaceUC    workspaceUsecase.UseCaseI
+	auditUC        auditUsecase.UseCaseI
+	adminKey       string
+	tokenVerifier  token.VerifierI
+}
+
+// New creates auth usecase. Requests carrying adminKey are authenticated
+// as an administrator; an empty adminKey disables administrative access.
+// Bearer tokens are rejected if tokenVerifier is nil. Workspaces of created keys
+// and of tokens and roles of owners in them are looked up by workspaceUC unless it is nil.
+// Created keys are recorded in the audit log by auditUC unless it is nil.
+func New(authRepository authRep.RepositoryI, workspaceUC workspaceUsecase.UseCaseI, auditUC auditUsecase.UseCaseI,
+	adminKey string, tokenVerifier token.VerifierI) UseCaseI {
+	return &useCase{
+		authRepository: authRepository,
+		workspaceUC:    workspaceUC,
+		auditUC:        auditUC,
+		adminKey:       adminKey,
+		tokenVerifier:  tokenVerifier,
+	}
+}
+
+func (uc *useCase) CreateAPIKey(ctx context.Context, key *models.APIKey) (err error) {
+	ctx, span := observability.StartSpan(ctx, "auth.usecase.CreateAPIKey")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if key.WorkspaceID == "" {
+		key.WorkspaceID = models.DefaultWorkspace
+	}
+	err = uc.checkWorkspace(ctx, key.WorkspaceID)
+	if errors.Is(errors.Cause(err), models.ErrNotFound) {
+		return errors.Wrapf(models.ErrBadRequest, "workspace %q is not found", key.WorkspaceID)
+	} else if err != nil {
+		return err
+	}
+
+	raw := make([]byte, keyLength)
+	if _, err := rand.Read(raw); err != nil {
+		return errors.Wrap(err, "generation api key error")
+	}
+
+	key.Key = base64.RawURLEncoding.EncodeToString(raw)
+	key.KeyHash = hashKey(key.Key)
+
+	err = uc.authRepository.CreateAPIKey(ctx, key)
+	if err != nil {
+		key.Key = ""
+		return errors.Wrap(err, "auth repository error")
+	}
+
+	if uc.auditUC != nil {
+		// neither the key nor its hash is recorded
+		uc.auditUC.Record(ctx, models.AuditAPIKeyCreate, key.OwnerID, nil,
+			models.APIKey{OwnerID: key.OwnerID, WorkspaceID: key.WorkspaceID})
+	}
+	return nil
+}
+
+func (uc *useCase) Authenticate(ctx context.Context, key string) (_ *models.Principal, err error) {
+	ctx, span := observability.StartSpan(ctx, "auth.usecase.Authenticate")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if key == "" {
+		return nil, models.ErrUnauthorized
+	}
+
+	if uc.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(uc.adminKey)) == 1 {
+		return &models.Principal{WorkspaceID: models.DefaultWorkspace, Scopes: []string{models.ScopeAdmin}}, nil
+	}
+
+	apiKey, err := uc.authRepository.SelectAPIKeyByHash(ctx, hashKey(key))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.ErrUnauthorized
+	} else if err != nil {
+		return nil, errors.Wrap(err, "auth repository error")
+	}
+
+	// keys created before workspaces are kept by the in-memory repository without workspace
+	workspaceID := apiKey.WorkspaceID
+	if workspaceID == "" {
+		workspaceID = models.DefaultWorkspace
+	}
+
+	principal := &models.Principal{
+		OwnerID:     apiKey.OwnerID,
+		WorkspaceID: workspaceID,
+		Scopes:      []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}
+	if err := uc.setRole(ctx, principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// AuthenticateToken rejects tokens bound to unknown workspaces.
+func (uc *useCase) AuthenticateToken(ctx context.Context, rawToken string) (_ *models.Principal, err error) {
+	ctx, span := observability.StartSpan(ctx, "auth.usecase.AuthenticateToken")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if rawToken == "" || uc.tokenVerifier == nil {
+		return nil, models.ErrUnauthorized
+	}
+
+	principal, err := uc.tokenVerifier.Verify(rawToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "token verification error")
+	}
+
+	err = uc.checkWorkspace(ctx, principal.WorkspaceID)
+	if errors.Is(errors.Cause(err), models.ErrNotFound) {
+		return nil, errors.Wrapf(models.ErrUnauthorized, "workspace %q is not found", principal.WorkspaceID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := uc.setRole(ctx, principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// AuthenticateCertificate authenticates client by certificate already verified
+// during TLS handshake. The owner is the common name of the certificate subject,
+// the workspace is the default one.
+func (uc *useCase) AuthenticateCertificate(ctx context.Context, cert *x509.Certificate) (_ *models.Principal, err error) {
+	ctx, span := observability.StartSpan(ctx, "auth.usecase.AuthenticateCertificate")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if cert == nil || cert.Subject.CommonName == "" {
+		return nil, models.ErrUnauthorized
+	}
+
+	principal := &models.Principal{
+		OwnerID:     cert.Subject.CommonName,
+		WorkspaceID: models.DefaultWorkspace,
+		Scopes:      []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}
+	if err := uc.setRole(ctx, principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// checkWorkspace fails with models.ErrNotFound if the workspace does not exist.
+func (uc *useCase) checkWorkspace(ctx context.Context, workspaceID string) error {
+	if uc.workspaceUC == nil {
+		return nil
+	}
+
+	_, err := uc.workspaceUC.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return errors.Wrap(err, "workspace usecase error")
+	}
+	return nil
+}
+
+// setRole sets the role of the owner in the workspace of principal.
+func (uc *useCase) setRole(ctx context.Context, principal *models.Principal) error {
+	if uc.workspaceUC == nil {
+		return nil
+	}
+
+	role, err := uc.workspaceUC.GetRole(ctx, principal.WorkspaceID, principal.OwnerID)
+	if err != nil {
+		return errors.Wrap(err, "workspace usecase error")
+	}
+	principal.Role = role
+	return nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
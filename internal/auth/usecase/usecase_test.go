@@ -0,0 +1,235 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/usecase/mocks"
+	authMocks "github.com/kuzkuss/url_service/internal/auth/repository/mocks"
+	tokenMocks "github.com/kuzkuss/url_service/internal/auth/token/mocks"
+	authUsecase "github.com/kuzkuss/url_service/internal/auth/usecase"
+	workspaceMocks "github.com/kuzkuss/url_service/internal/workspace/usecase/mocks"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseAuthenticate struct {
+	ArgData string
+	ExpectedRes *models.Principal
+	Error error
+}
+
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUsecaseCreateAPIKey(t *testing.T) {
+	createErr := errors.New("error")
+
+	mockAuthRepo := authMocks.NewRepositoryI(t)
+
+	mockAuthRepo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key *models.APIKey) bool {
+		return key.OwnerID == "owner" && key.WorkspaceID == models.DefaultWorkspace
+	})).Return(nil)
+	mockAuthRepo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key *models.APIKey) bool {
+		return key.OwnerID == "team_owner" && key.WorkspaceID == "team"
+	})).Return(nil)
+	mockAuthRepo.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key *models.APIKey) bool {
+		return key.OwnerID == "owner_error"
+	})).Return(createErr)
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("GetWorkspace", mock.Anything, models.DefaultWorkspace).
+		Return(&models.Workspace{ID: models.DefaultWorkspace}, nil)
+	mockWorkspaceUsecase.On("GetWorkspace", mock.Anything, "team").Return(&models.Workspace{ID: "team"}, nil)
+	mockWorkspaceUsecase.On("GetWorkspace", mock.Anything, "unknown").
+		Return(nil, errors.Wrap(models.ErrNotFound, "workspace repository error"))
+
+	// only the owner and the workspace of the key are recorded
+	mockAudit := auditMocks.NewUseCaseI(t)
+	mockAudit.On("Record", mock.Anything, models.AuditAPIKeyCreate, "owner", nil,
+		models.APIKey{OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}).Return().Once()
+	mockAudit.On("Record", mock.Anything, models.AuditAPIKeyCreate, "team_owner", nil,
+		models.APIKey{OwnerID: "team_owner", WorkspaceID: "team"}).Return().Once()
+
+	usecase := authUsecase.New(mockAuthRepo, mockWorkspaceUsecase, mockAudit, "", nil)
+
+	t.Run("success", func(t *testing.T) {
+		key := models.APIKey{OwnerID: "owner"}
+		err := usecase.CreateAPIKey(context.Background(), &key)
+		require.NoError(t, err)
+		assert.NotEmpty(t, key.Key)
+		assert.Equal(t, hash(key.Key), key.KeyHash)
+		assert.Equal(t, models.DefaultWorkspace, key.WorkspaceID)
+	})
+
+	t.Run("workspace", func(t *testing.T) {
+		key := models.APIKey{OwnerID: "team_owner", WorkspaceID: "team"}
+		err := usecase.CreateAPIKey(context.Background(), &key)
+		require.NoError(t, err)
+		assert.NotEmpty(t, key.Key)
+	})
+
+	t.Run("unknown_workspace", func(t *testing.T) {
+		key := models.APIKey{OwnerID: "owner", WorkspaceID: "unknown"}
+		err := usecase.CreateAPIKey(context.Background(), &key)
+		require.Equal(t, models.ErrBadRequest, errors.Cause(err))
+		assert.Empty(t, key.Key)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		key := models.APIKey{OwnerID: "owner_error"}
+		err := usecase.CreateAPIKey(context.Background(), &key)
+		require.Equal(t, createErr, errors.Cause(err))
+		assert.Empty(t, key.Key)
+	})
+}
+
+func TestUsecaseAuthenticate(t *testing.T) {
+	getErr := errors.New("error")
+
+	mockAuthRepo := authMocks.NewRepositoryI(t)
+
+	mockAuthRepo.On("SelectAPIKeyByHash", mock.Anything, hash("key_success")).
+		Return(&models.APIKey{KeyHash: hash("key_success"), OwnerID: "owner"}, nil)
+	mockAuthRepo.On("SelectAPIKeyByHash", mock.Anything, hash("key_team")).
+		Return(&models.APIKey{KeyHash: hash("key_team"), OwnerID: "owner", WorkspaceID: "team"}, nil)
+	mockAuthRepo.On("SelectAPIKeyByHash", mock.Anything, hash("key_unknown")).Return(nil, models.ErrNotFound)
+	mockAuthRepo.On("SelectAPIKeyByHash", mock.Anything, hash("key_error")).Return(nil, getErr)
+
+	usecase := authUsecase.New(mockAuthRepo, nil, nil, "admin_key", nil)
+
+	cases := map[string]TestCaseAuthenticate {
+		"success": {
+			ArgData: "key_success",
+			ExpectedRes: &models.Principal{
+				OwnerID: "owner",
+				WorkspaceID: models.DefaultWorkspace,
+				Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+			},
+			Error: nil,
+		},
+		"workspace": {
+			ArgData: "key_team",
+			ExpectedRes: &models.Principal{
+				OwnerID: "owner",
+				WorkspaceID: "team",
+				Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+			},
+			Error: nil,
+		},
+		"admin": {
+			ArgData: "admin_key",
+			ExpectedRes: &models.Principal{WorkspaceID: models.DefaultWorkspace, Scopes: []string{models.ScopeAdmin}},
+			Error: nil,
+		},
+		"empty": {
+			ArgData: "",
+			Error: models.ErrUnauthorized,
+		},
+		"unknown": {
+			ArgData: "key_unknown",
+			Error: models.ErrUnauthorized,
+		},
+		"error": {
+			ArgData: "key_error",
+			Error: getErr,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			actualRes, err := usecase.Authenticate(context.Background(), test.ArgData)
+			require.Equal(t, test.Error, errors.Cause(err))
+			assert.Equal(t, test.ExpectedRes, actualRes)
+		})
+	}
+}
+
+func TestUsecaseAuthenticateToken(t *testing.T) {
+	principal := &models.Principal{
+		OwnerID: "owner",
+		WorkspaceID: "team",
+		Scopes: []string{models.ScopeLinksRead},
+	}
+
+	mockAuthRepo := authMocks.NewRepositoryI(t)
+	mockVerifier := tokenMocks.NewVerifierI(t)
+
+	mockVerifier.On("Verify", "token_success").Return(principal, nil)
+	mockVerifier.On("Verify", "token_unknown_workspace").
+		Return(&models.Principal{OwnerID: "owner", WorkspaceID: "unknown"}, nil)
+	mockVerifier.On("Verify", "token_invalid").Return(nil, models.ErrUnauthorized)
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("GetWorkspace", mock.Anything, "team").Return(&models.Workspace{ID: "team"}, nil)
+	mockWorkspaceUsecase.On("GetWorkspace", mock.Anything, "unknown").
+		Return(nil, errors.Wrap(models.ErrNotFound, "workspace repository error"))
+	mockWorkspaceUsecase.On("GetRole", mock.Anything, "team", "owner").Return(models.RoleEditor, nil)
+
+	usecase := authUsecase.New(mockAuthRepo, mockWorkspaceUsecase, nil, "", mockVerifier)
+
+	cases := map[string]TestCaseAuthenticate {
+		"success": {
+			ArgData: "token_success",
+			ExpectedRes: principal,
+			Error: nil,
+		},
+		"unknown_workspace": {
+			ArgData: "token_unknown_workspace",
+			Error: models.ErrUnauthorized,
+		},
+		"invalid": {
+			ArgData: "token_invalid",
+			Error: models.ErrUnauthorized,
+		},
+		"empty": {
+			ArgData: "",
+			Error: models.ErrUnauthorized,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			actualRes, err := usecase.AuthenticateToken(context.Background(), test.ArgData)
+			require.Equal(t, test.Error, errors.Cause(err))
+			assert.Equal(t, test.ExpectedRes, actualRes)
+		})
+	}
+
+	t.Run("role", func(t *testing.T) {
+		actualRes, err := usecase.AuthenticateToken(context.Background(), "token_success")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleEditor, actualRes.Role)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := authUsecase.New(mockAuthRepo, nil, nil, "", nil).AuthenticateToken(context.Background(), "token_success")
+		require.Equal(t, models.ErrUnauthorized, errors.Cause(err))
+	})
+}
+
+func TestUsecaseAuthenticateCertificate(t *testing.T) {
+	usecase := authUsecase.New(authMocks.NewRepositoryI(t), nil, nil, "", nil)
+
+	actualRes, err := usecase.AuthenticateCertificate(context.Background(),
+		&x509.Certificate{Subject: pkix.Name{CommonName: "client"}})
+	require.NoError(t, err)
+	assert.Equal(t, &models.Principal{
+		OwnerID: "client",
+		WorkspaceID: models.DefaultWorkspace,
+		Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}, actualRes)
+
+	_, err = usecase.AuthenticateCertificate(context.Background(), &x509.Certificate{})
+	require.Equal(t, models.ErrUnauthorized, errors.Cause(err))
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Conn is a client connection calling handlers of the services registered on it
+// in the same process, through the same interceptors as the gRPC server.
+// Outgoing metadata of the call is passed to the handler as incoming metadata,
+// headers and trailers set by the handler are returned to grpc.Header and
+// grpc.Trailer call options. Streaming methods are not supported.
+type Conn struct {
+	services    map[string]*service
+	interceptor grpc.UnaryServerInterceptor
+}
+
+type service struct {
+	impl    interface{}
+	methods map[string]grpc.MethodDesc
+}
+
+// NewConn creates connection calling handlers through interceptors, the first is the outermost.
+func NewConn(interceptors ...grpc.UnaryServerInterceptor) *Conn {
+	return &Conn{
+		services:    make(map[string]*service),
+		interceptor: chainUnary(interceptors),
+	}
+}
+
+// RegisterService implements grpc.ServiceRegistrar, so services are registered
+// with the generated Register<Service>Server functions.
+func (c *Conn) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	srv := &service{
+		impl:    impl,
+		methods: make(map[string]grpc.MethodDesc, len(desc.Methods)),
+	}
+	for _, method := range desc.Methods {
+		srv.methods[method.MethodName] = method
+	}
+	c.services[desc.ServiceName] = srv
+}
+
+func (c *Conn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	serviceName, methodName, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	srv, ok := c.services[serviceName]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown service %s", serviceName)
+	}
+	desc, ok := srv.methods[methodName]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s for service %s", methodName, serviceName)
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	stream := &transportStream{method: method}
+	ctx = metadata.NewIncomingContext(ctx, md.Copy())
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	resp, err := desc.Handler(srv.impl, ctx, func(req interface{}) error {
+		proto.Merge(req.(proto.Message), args.(proto.Message))
+		return nil
+	}, c.interceptor)
+
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case grpc.HeaderCallOption:
+			*opt.HeaderAddr = stream.header
+		case grpc.TrailerCallOption:
+			*opt.TrailerAddr = stream.trailer
+		}
+	}
+
+	if err != nil {
+		// as the gRPC server does, errors without status are unknown
+		return status.Convert(err).Err()
+	}
+
+	proto.Merge(reply.(proto.Message), resp.(proto.Message))
+	return nil
+}
+
+func (c *Conn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "streaming method %s is not supported in process", method)
+}
+
+// chainUnary returns interceptor calling interceptors in order, the first is the outermost.
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// transportStream collects headers and trailers set by the handler.
+type transportStream struct {
+	method  string
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (ts *transportStream) Method() string {
+	return ts.method
+}
+
+func (ts *transportStream) SetHeader(md metadata.MD) error {
+	ts.header = metadata.Join(ts.header, md)
+	return nil
+}
+
+func (ts *transportStream) SendHeader(md metadata.MD) error {
+	return ts.SetHeader(md)
+}
+
+func (ts *transportStream) SetTrailer(md metadata.MD) error {
+	ts.trailer = metadata.Join(ts.trailer, md)
+	return nil
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const authorizationHeader = "authorization"
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// NewMux creates REST gateway mux translating HTTP requests to calls of the
+// methods by their HTTP rules. incomingHeaders are the HTTP headers passed to
+// the methods as metadata, outgoingHeaders are the metadata keys of response
+// headers returned as HTTP headers; other headers are dropped.
+// Errors are written as {"message": ...} with HTTP status of the gRPC code,
+// as echo does for HTTP errors.
+func NewMux(incomingHeaders []string, outgoingHeaders []string) *runtime.ServeMux {
+	outgoing := headerMatcher(outgoingHeaders, textproto.CanonicalMIMEHeaderKey)
+
+	return runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headerMatcher(incomingHeaders, strings.ToLower)),
+		runtime.WithOutgoingHeaderMatcher(outgoing),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.HTTPBodyMarshaler{
+			Marshaler: &runtime.JSONPb{
+				UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+			},
+		}),
+		runtime.WithErrorHandler(func(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler,
+			w http.ResponseWriter, _ *http.Request, err error) {
+			if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+				for key, values := range md.HeaderMD {
+					if header, ok := outgoing(key); ok {
+						for _, value := range values {
+							w.Header().Add(header, value)
+						}
+					}
+				}
+			}
+
+			st := status.Convert(err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+			_ = json.NewEncoder(w).Encode(errorResponse{Message: st.Message()})
+		}),
+	)
+}
+
+// headerMatcher matches headers case-insensitively and converts their names with name.
+func headerMatcher(headers []string, name func(string) string) runtime.HeaderMatcherFunc {
+	allowed := make(map[string]struct{}, len(headers))
+	for _, header := range headers {
+		allowed[strings.ToLower(header)] = struct{}{}
+	}
+	// the runtime passes Authorization header as authorization metadata itself,
+	// matching it too would duplicate the value
+	delete(allowed, authorizationHeader)
+
+	return func(key string) (string, bool) {
+		if _, ok := allowed[strings.ToLower(key)]; !ok {
+			return "", false
+		}
+		return name(key), true
+	}
+}
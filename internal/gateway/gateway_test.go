@@ -0,0 +1,225 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	authDelivery "github.com/kuzkuss/url_service/internal/auth/delivery/grpc"
+	authMocks "github.com/kuzkuss/url_service/internal/auth/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/gateway"
+	linkDelivery "github.com/kuzkuss/url_service/internal/link/delivery/grpc"
+	linkMocks "github.com/kuzkuss/url_service/internal/link/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	rateLimitDelivery "github.com/kuzkuss/url_service/internal/ratelimit/delivery/grpc"
+	"github.com/kuzkuss/url_service/models"
+	link "github.com/kuzkuss/url_service/proto/link"
+)
+
+type TestCaseGateway struct {
+	Method string
+	URL string
+	APIKey string
+	Token string
+	Body string
+	ExpectedResponse string
+	RetryAfter string
+	StatusCode int
+}
+
+func TestGateway(t *testing.T) {
+	mockAuthUsecase := authMocks.NewUseCaseI(t)
+	principal := &models.Principal{
+		OwnerID: "owner",
+		Scopes: []string{models.ScopeLinksRead, models.ScopeLinksWrite},
+	}
+	mockAuthUsecase.On("Authenticate", mock.Anything, "owner_key").Return(principal, nil)
+	mockAuthUsecase.On("Authenticate", mock.Anything, "").Return(nil, models.ErrUnauthorized)
+	mockAuthUsecase.On("AuthenticateToken", mock.Anything, "owner_token").Return(principal, nil)
+	mockAuthUsecase.On("AuthenticateToken", mock.Anything, "bad_token").Return(nil, models.ErrUnauthorized)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &models.Link{OriginalLink: "original_link_success"}).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(*models.Link).ShortLink = "short_link_success"
+		}).Return(nil)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &models.Link{OriginalLink: "original_link_quota"}).
+		Return(&models.RateLimitError{Reason: "day link quota exceeded", RetryAfter: 90 * time.Second})
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "example.com", "short_link_success").Return("original_link_success", nil)
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "example.com", "short_link_not_found").Return("", models.ErrNotFound)
+	mockLinkUsecase.On("GetLinks", mock.Anything, principal).Return([]models.Link{
+		{OriginalLink: "original_link_success", ShortLink: "short_link_success"},
+	}, nil)
+	mockLinkUsecase.On("UpdateLink", mock.Anything, principal, &models.Link{
+		OriginalLink: "original_link_new", ShortLink: "short_link_success",
+	}).Return(nil)
+	mockLinkUsecase.On("DeleteLink", mock.Anything, principal, "", "short_link_success").Return(nil)
+
+	conn := gateway.NewConn(
+		rateLimitDelivery.New(nil).Unary,
+		authDelivery.New(mockAuthUsecase, map[string]string{
+			"/link.Links/CreateShortLink": models.ScopeLinksWrite,
+			"/link.Links/ListLinks": models.ScopeLinksRead,
+			"/link.Links/UpdateLink": models.ScopeLinksWrite,
+			"/link.Links/DeleteLink": models.ScopeLinksWrite,
+		}, "/link.Links/GetOriginalLink").Unary,
+	)
+	link.RegisterLinksServer(conn, linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger()))
+
+	mux := gateway.NewMux([]string{"X-API-Key", "Authorization"}, []string{"Retry-After"})
+	require.NoError(t, link.RegisterLinksHandlerClient(context.Background(), mux, link.NewLinksClient(conn)))
+
+	cases := map[string]TestCaseGateway {
+		"create": {
+			Method: http.MethodPost,
+			URL: "/v1/links",
+			APIKey: "owner_key",
+			Body: `{"originalLink": "original_link_success"}`,
+			ExpectedResponse: `{"shortLink": "short_link_success"}`,
+			StatusCode: http.StatusOK,
+		},
+		"create_invalid": {
+			Method: http.MethodPost,
+			URL: "/v1/links",
+			APIKey: "owner_key",
+			Body: `{}`,
+			ExpectedResponse: `{"message": "bad request"}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"create_unauthorized": {
+			Method: http.MethodPost,
+			URL: "/v1/links",
+			Body: `{"originalLink": "original_link_success"}`,
+			ExpectedResponse: `{"message": "unauthorized"}`,
+			StatusCode: http.StatusUnauthorized,
+		},
+		"create_quota_exceeded": {
+			Method: http.MethodPost,
+			URL: "/v1/links",
+			APIKey: "owner_key",
+			Body: `{"originalLink": "original_link_quota"}`,
+			ExpectedResponse: `{"message": "too many requests: day link quota exceeded, retry after 1m30s"}`,
+			RetryAfter: "90",
+			StatusCode: http.StatusTooManyRequests,
+		},
+		"get": {
+			Method: http.MethodGet,
+			URL: "/v1/links/short_link_success",
+			ExpectedResponse: `{"originalLink": "original_link_success"}`,
+			StatusCode: http.StatusOK,
+		},
+		"get_not_found": {
+			Method: http.MethodGet,
+			URL: "/v1/links/short_link_not_found",
+			ExpectedResponse: `{"message": "item is not found"}`,
+			StatusCode: http.StatusNotFound,
+		},
+		"list": {
+			Method: http.MethodGet,
+			URL: "/v1/links",
+			APIKey: "owner_key",
+			ExpectedResponse: `{"links": [{"shortLink": "short_link_success", "originalLink": "original_link_success"}]}`,
+			StatusCode: http.StatusOK,
+		},
+		"list_bearer_token": {
+			Method: http.MethodGet,
+			URL: "/v1/links",
+			Token: "owner_token",
+			ExpectedResponse: `{"links": [{"shortLink": "short_link_success", "originalLink": "original_link_success"}]}`,
+			StatusCode: http.StatusOK,
+		},
+		"list_bad_token": {
+			Method: http.MethodGet,
+			URL: "/v1/links",
+			Token: "bad_token",
+			ExpectedResponse: `{"message": "unauthorized"}`,
+			StatusCode: http.StatusUnauthorized,
+		},
+		"update": {
+			Method: http.MethodPut,
+			URL: "/v1/links/short_link_success",
+			APIKey: "owner_key",
+			Body: `{"originalLink": "original_link_new", "unknown": true}`,
+			ExpectedResponse: `{}`,
+			StatusCode: http.StatusOK,
+		},
+		"delete": {
+			Method: http.MethodDelete,
+			URL: "/v1/links/short_link_success",
+			APIKey: "owner_key",
+			ExpectedResponse: `{}`,
+			StatusCode: http.StatusOK,
+		},
+		"unknown_route": {
+			Method: http.MethodGet,
+			URL: "/v1/unknown",
+			ExpectedResponse: `{"message": "Not Found"}`,
+			StatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(test.Method, test.URL, strings.NewReader(test.Body))
+			req.Header.Set("Content-Type", "application/json")
+			if test.APIKey != "" {
+				req.Header.Set("X-API-Key", test.APIKey)
+			}
+			if test.Token != "" {
+				req.Header.Set("Authorization", "Bearer " + test.Token)
+			}
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			assert.JSONEq(t, test.ExpectedResponse, rec.Body.String())
+			assert.Equal(t, test.RetryAfter, rec.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestConn(t *testing.T) {
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name + " " + info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "", "short_link").Return("original_link", nil)
+
+	conn := gateway.NewConn(interceptor("first"), interceptor("second"))
+	link.RegisterLinksServer(conn, linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger()))
+	client := link.NewLinksClient(conn)
+
+	var header metadata.MD
+	resp, err := client.GetOriginalLink(context.Background(), &link.ShortLink{ShortLink: "short_link"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "original_link", resp.OriginalLink)
+	assert.Equal(t, []string{"first /link.Links/GetOriginalLink", "second /link.Links/GetOriginalLink"}, calls)
+
+	// principal is set by the auth interceptor only
+	_, err = client.ListLinks(context.Background(), &link.Nothing{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	err = conn.Invoke(context.Background(), "/link.Links/Unknown", &link.Nothing{}, &link.Nothing{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	_, err = conn.NewStream(context.Background(), &grpc.StreamDesc{}, "/link.Links/Watch")
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
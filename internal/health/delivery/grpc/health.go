@@ -0,0 +1,98 @@
+package delivery
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	healthUsecase "github.com/kuzkuss/url_service/internal/health/usecase"
+)
+
+const defaultInterval = 5 * time.Second
+
+// HealthChecker serves grpc.health.v1.Health with status of the whole server
+// ("") and of the listed services, refreshed by checking HealthUC periodically.
+type HealthChecker struct {
+	HealthUC healthUsecase.UseCaseI
+	Server   *health.Server
+	interval atomic.Int64
+	services []string
+	logger   *slog.Logger
+}
+
+func New(healthUC healthUsecase.UseCaseI, interval time.Duration, logger *slog.Logger, services ...string) *HealthChecker {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	checker := &HealthChecker{
+		HealthUC: healthUC,
+		Server:   health.NewServer(),
+		services: append([]string{""}, services...),
+		logger:   logger,
+	}
+	checker.SetInterval(interval)
+
+	for _, service := range checker.services {
+		checker.Server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+
+	return checker
+}
+
+// SetInterval changes period of the checks starting from the next one.
+func (hc *HealthChecker) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	hc.interval.Store(int64(interval))
+}
+
+func (hc *HealthChecker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, hc.Server)
+}
+
+// Run refreshes serving status until ctx is done, then reports NOT_SERVING
+// to every watcher.
+func (hc *HealthChecker) Run(ctx context.Context) {
+	interval := time.Duration(hc.interval.Load())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		hc.Update(ctx)
+
+		select {
+		case <-ctx.Done():
+			hc.Server.Shutdown()
+			return
+		case <-ticker.C:
+		}
+
+		if current := time.Duration(hc.interval.Load()); current != interval {
+			interval = current
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// Update checks the service once and sets serving status accordingly.
+func (hc *HealthChecker) Update(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, time.Duration(hc.interval.Load()))
+	defer cancel()
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := hc.HealthUC.Check(checkCtx); err != nil {
+		hc.logger.WarnContext(ctx, "health check failed", "error", err)
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	for _, service := range hc.services {
+		hc.Server.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,62 @@
+package delivery_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	healthDelivery "github.com/kuzkuss/url_service/internal/health/delivery/grpc"
+	healthMocks "github.com/kuzkuss/url_service/internal/health/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+)
+
+func servingStatus(t *testing.T, checker *healthDelivery.HealthChecker, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := checker.Server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func TestGrpcHealthCheckerUpdate(t *testing.T) {
+	mockHealthUsecase := healthMocks.NewUseCaseI(t)
+	mockHealthUsecase.On("Check", mock.Anything).Return(nil).Once()
+	mockHealthUsecase.On("Check", mock.Anything).Return(errors.New("error")).Once()
+
+	checker := healthDelivery.New(mockHealthUsecase, time.Second, observability.NopLogger(), "link.Links")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker, ""))
+
+	checker.Update(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, checker, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, checker, "link.Links"))
+
+	checker.Update(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker, "link.Links"))
+}
+
+func TestGrpcHealthCheckerRun(t *testing.T) {
+	mockHealthUsecase := healthMocks.NewUseCaseI(t)
+	mockHealthUsecase.On("Check", mock.Anything).Return(nil)
+
+	checker := healthDelivery.New(mockHealthUsecase, time.Millisecond, observability.NopLogger(), "link.Links")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return servingStatus(t, checker, "link.Links") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, checker, "link.Links"))
+}
@@ -0,0 +1,70 @@
+package delivery
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	healthUsecase "github.com/kuzkuss/url_service/internal/health/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type Delivery struct {
+	HealthUC  healthUsecase.UseCaseI
+	BuildInfo models.BuildInfo
+	Logger    *slog.Logger
+}
+
+// Healthz godoc
+// @Summary      Healthz
+// @Description  check that the process is alive
+// @Tags     health
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=models.HealthStatus} "process is alive"
+// @Router   /healthz [get]
+func (del *Delivery) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, pkg.Response{Body: models.HealthStatus{Status: models.StatusOK}})
+}
+
+// Readyz godoc
+// @Summary      Readyz
+// @Description  check that the storage is reachable and its schema is applied
+// @Tags     health
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=models.HealthStatus} "ready to serve requests"
+// @Failure 503 {object} echo.HTTPError "not ready"
+// @Router   /readyz [get]
+func (del *Delivery) Readyz(c echo.Context) error {
+	err := del.HealthUC.Ready(c.Request().Context())
+	if err != nil {
+		del.Logger.WarnContext(c.Request().Context(), "service is not ready", "error", err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, models.ErrServiceUnavailable.Error())
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: models.HealthStatus{Status: models.StatusOK}})
+}
+
+// Version godoc
+// @Summary      Version
+// @Description  get build information and configured storage
+// @Tags     health
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=models.BuildInfo} "build information"
+// @Router   /version [get]
+func (del *Delivery) Version(c echo.Context) error {
+	return c.JSON(http.StatusOK, pkg.Response{Body: del.BuildInfo})
+}
+
+func New(e *echo.Echo, healthUC healthUsecase.UseCaseI, buildInfo models.BuildInfo, logger *slog.Logger) {
+	handler := &Delivery{
+		HealthUC:  healthUC,
+		BuildInfo: buildInfo,
+		Logger:    logger,
+	}
+
+	e.GET("/healthz", handler.Healthz)
+	e.GET("/readyz", handler.Readyz)
+	e.GET("/version", handler.Version)
+}
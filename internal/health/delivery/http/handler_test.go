@@ -0,0 +1,82 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	healthDelivery "github.com/kuzkuss/url_service/internal/health/delivery/http"
+	healthMocks "github.com/kuzkuss/url_service/internal/health/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type TestCaseRequest struct {
+	Path string
+	ExpectedResponse interface{}
+	StatusCode int
+}
+
+func TestHttpDeliveryHealth(t *testing.T) {
+	buildInfo := models.BuildInfo {
+		GitCommit: "commit",
+		BuildTime: "2023-01-15T00:00:00Z",
+		GoVersion: "go1.19",
+		Database: "postgres",
+	}
+
+	mockHealthUsecase := healthMocks.NewUseCaseI(t)
+	mockHealthUsecase.On("Ready", mock.Anything).Return(nil).Once()
+	mockHealthUsecase.On("Ready", mock.Anything).Return(errors.New("error")).Once()
+
+	e := echo.New()
+	healthDelivery.New(e, mockHealthUsecase, buildInfo, observability.NopLogger())
+
+	okStatus := pkg.Response{Body: models.HealthStatus{Status: models.StatusOK}}
+
+	cases := []TestCaseRequest {
+		{
+			Path: "/healthz",
+			ExpectedResponse: okStatus,
+			StatusCode: http.StatusOK,
+		},
+		{
+			Path: "/readyz",
+			ExpectedResponse: okStatus,
+			StatusCode: http.StatusOK,
+		},
+		{
+			Path: "/readyz",
+			ExpectedResponse: map[string]string{"message": models.ErrServiceUnavailable.Error()},
+			StatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			Path: "/version",
+			ExpectedResponse: pkg.Response{Body: buildInfo},
+			StatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.Path, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, test.Path, nil)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			expected, err := json.Marshal(test.ExpectedResponse)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.StatusCode, rec.Code)
+			assert.JSONEq(t, string(expected), rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package in_memory
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/health/repository"
+)
+
+type healthRepository struct{}
+
+func New() repository.RepositoryI {
+	return &healthRepository{}
+}
+
+// Ping always succeeds: in memory storage lives in the process.
+func (dbHealth *healthRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (dbHealth *healthRepository) CheckSchema(ctx context.Context) error {
+	return nil
+}
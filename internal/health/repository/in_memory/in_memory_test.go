@@ -0,0 +1,17 @@
+package in_memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	healthRep "github.com/kuzkuss/url_service/internal/health/repository/in_memory"
+)
+
+func TestRepositoryHealth(t *testing.T) {
+	repository := healthRep.New()
+
+	require.NoError(t, repository.Ping(context.Background()))
+	require.NoError(t, repository.CheckSchema(context.Background()))
+}
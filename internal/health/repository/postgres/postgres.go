@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kuzkuss/url_service/internal/health/repository"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+var requiredTables = []string{"links", "api_keys", "quota_usage", "idempotency_keys"}
+
+const selectTablesQuery = `SELECT table_name FROM information_schema.tables ` +
+	`WHERE table_schema = current_schema() AND table_name IN ?`
+
+type healthRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &healthRepository{
+		db: db,
+	}
+}
+
+func (dbHealth *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := dbHealth.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "database error")
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return errors.Wrap(err, "database ping error")
+	}
+
+	return nil
+}
+
+func (dbHealth *healthRepository) CheckSchema(ctx context.Context) error {
+	var tables []string
+
+	tx := dbHealth.db.WithContext(ctx).Raw(selectTablesQuery, requiredTables).Scan(&tables)
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (information_schema.tables)")
+	}
+
+	present := make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		present[table] = struct{}{}
+	}
+
+	var missing []string
+	for _, table := range requiredTables {
+		if _, ok := present[table]; !ok {
+			missing = append(missing, table)
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.Errorf("schema is not applied, missing tables: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
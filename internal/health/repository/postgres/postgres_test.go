@@ -0,0 +1,100 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	healthRep "github.com/kuzkuss/url_service/internal/health/repository/postgres"
+)
+
+func TestRepositoryPing(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := healthRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.Ping(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		db.Close()
+		err := repository.Ping(context.Background())
+		require.Error(t, err)
+	})
+}
+
+func TestRepositoryCheckSchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := regexp.QuoteMeta(`SELECT table_name FROM information_schema.tables ` +
+		`WHERE table_schema = current_schema() AND table_name IN ($1,$2,$3,$4)`)
+	args := []driver.Value{"links", "api_keys", "quota_usage", "idempotency_keys"}
+
+	mock.ExpectQuery(query).WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"table_name"}).
+			AddRow("api_keys").AddRow("idempotency_keys").AddRow("links").AddRow("quota_usage"))
+
+	mock.ExpectQuery(query).WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("links"))
+
+	selectErr := errors.New("error")
+	mock.ExpectQuery(query).WithArgs(args...).WillReturnError(selectErr)
+
+	repository := healthRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.CheckSchema(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("missing_tables", func(t *testing.T) {
+		err := repository.CheckSchema(context.Background())
+		require.EqualError(t, err, "schema is not applied, missing tables: api_keys, quota_usage, idempotency_keys")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := repository.CheckSchema(context.Background())
+		require.Equal(t, selectErr, errors.Cause(err))
+	})
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+)
+
+type RepositoryI interface {
+	// Ping checks that the storage is reachable.
+	Ping(ctx context.Context) error
+	// CheckSchema checks that all the tables used by the service exist.
+	CheckSchema(ctx context.Context) error
+}
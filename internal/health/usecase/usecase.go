@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	healthRep "github.com/kuzkuss/url_service/internal/health/repository"
+)
+
+type UseCaseI interface {
+	// Check returns error if the storage is unreachable.
+	Check(ctx context.Context) (error)
+	// Ready returns error if the service can't serve requests:
+	// the storage is unreachable or its schema is not applied.
+	Ready(ctx context.Context) (error)
+}
+
+type useCase struct {
+	healthRepository healthRep.RepositoryI
+}
+
+func New(healthRepository healthRep.RepositoryI) UseCaseI {
+	return &useCase{
+		healthRepository: healthRepository,
+	}
+}
+
+func (uc *useCase) Check(ctx context.Context) (error) {
+	err := uc.healthRepository.Ping(ctx)
+	if err != nil {
+		return errors.Wrap(err, "health repository error")
+	}
+
+	return nil
+}
+
+func (uc *useCase) Ready(ctx context.Context) (error) {
+	if err := uc.Check(ctx); err != nil {
+		return err
+	}
+
+	err := uc.healthRepository.CheckSchema(ctx)
+	if err != nil {
+		return errors.Wrap(err, "health repository error")
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	healthMocks "github.com/kuzkuss/url_service/internal/health/repository/mocks"
+	healthUsecase "github.com/kuzkuss/url_service/internal/health/usecase"
+)
+
+func TestUsecaseCheck(t *testing.T) {
+	pingErr := errors.New("error")
+
+	t.Run("success", func(t *testing.T) {
+		mockHealthRepo := healthMocks.NewRepositoryI(t)
+		mockHealthRepo.On("Ping", context.Background()).Return(nil)
+
+		err := healthUsecase.New(mockHealthRepo).Check(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockHealthRepo := healthMocks.NewRepositoryI(t)
+		mockHealthRepo.On("Ping", context.Background()).Return(pingErr)
+
+		err := healthUsecase.New(mockHealthRepo).Check(context.Background())
+		require.Equal(t, pingErr, errors.Cause(err))
+	})
+}
+
+func TestUsecaseReady(t *testing.T) {
+	schemaErr := errors.New("schema error")
+	pingErr := errors.New("ping error")
+
+	t.Run("success", func(t *testing.T) {
+		mockHealthRepo := healthMocks.NewRepositoryI(t)
+		mockHealthRepo.On("Ping", context.Background()).Return(nil)
+		mockHealthRepo.On("CheckSchema", context.Background()).Return(nil)
+
+		err := healthUsecase.New(mockHealthRepo).Ready(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		mockHealthRepo := healthMocks.NewRepositoryI(t)
+		mockHealthRepo.On("Ping", context.Background()).Return(pingErr)
+
+		err := healthUsecase.New(mockHealthRepo).Ready(context.Background())
+		require.Equal(t, pingErr, errors.Cause(err))
+	})
+
+	t.Run("schema_missing", func(t *testing.T) {
+		mockHealthRepo := healthMocks.NewRepositoryI(t)
+		mockHealthRepo.On("Ping", context.Background()).Return(nil)
+		mockHealthRepo.On("CheckSchema", context.Background()).Return(schemaErr)
+
+		err := healthUsecase.New(mockHealthRepo).Ready(context.Background())
+		require.Equal(t, schemaErr, errors.Cause(err))
+	})
+}
@@ -0,0 +1,97 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/idempotency/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type recordKey struct {
+	workspaceID string
+	ownerID     string
+	key         string
+}
+
+type idempotencyRepository struct {
+	mx    sync.Mutex
+	store map[recordKey]models.IdempotencyRecord
+}
+
+func New() repository.RepositoryI {
+	return &idempotencyRepository{
+		store: make(map[recordKey]models.IdempotencyRecord),
+	}
+}
+
+func (dbIdempotency *idempotencyRepository) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	dbIdempotency.mx.Lock()
+	defer dbIdempotency.mx.Unlock()
+
+	k := recordKey{workspaceID: record.WorkspaceID, ownerID: record.OwnerID, key: record.Key}
+	if _, ok := dbIdempotency.store[k]; ok {
+		return models.ErrConflict
+	}
+
+	dbIdempotency.store[k] = *record
+
+	return nil
+}
+
+func (dbIdempotency *idempotencyRepository) SelectRecord(ctx context.Context, workspaceID string, ownerID string, key string) (*models.IdempotencyRecord, error) {
+	dbIdempotency.mx.Lock()
+	defer dbIdempotency.mx.Unlock()
+
+	record, ok := dbIdempotency.store[recordKey{workspaceID: workspaceID, ownerID: ownerID, key: key}]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return &record, nil
+}
+
+func (dbIdempotency *idempotencyRepository) CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	dbIdempotency.mx.Lock()
+	defer dbIdempotency.mx.Unlock()
+
+	k := recordKey{workspaceID: record.WorkspaceID, ownerID: record.OwnerID, key: record.Key}
+	stored, ok := dbIdempotency.store[k]
+	if !ok || !stored.CreatedAt.Equal(record.CreatedAt) {
+		return models.ErrNotFound
+	}
+
+	stored.Response = record.Response
+	stored.Completed = true
+	dbIdempotency.store[k] = stored
+
+	return nil
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	dbIdempotency.mx.Lock()
+	defer dbIdempotency.mx.Unlock()
+
+	var deleted int64
+	for k, stored := range dbIdempotency.store {
+		if !now.Before(stored.ExpiresAt) {
+			delete(dbIdempotency.store, k)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteRecord(ctx context.Context, workspaceID string, ownerID string, key string, createdAt time.Time) error {
+	dbIdempotency.mx.Lock()
+	defer dbIdempotency.mx.Unlock()
+
+	k := recordKey{workspaceID: workspaceID, ownerID: ownerID, key: key}
+	if stored, ok := dbIdempotency.store[k]; ok && stored.CreatedAt.Equal(createdAt) {
+		delete(dbIdempotency.store, k)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package in_memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	idempotencyRep "github.com/kuzkuss/url_service/internal/idempotency/repository/in_memory"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestRepositoryRecordLifecycle(t *testing.T) {
+	createdAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	record := models.IdempotencyRecord {
+		WorkspaceID: "team",
+		OwnerID: "owner",
+		Key: "key",
+		Fingerprint: "fingerprint",
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+	}
+
+	repository := idempotencyRep.New()
+
+	_, err := repository.SelectRecord(context.Background(), "team", "owner", "key")
+	require.Equal(t, models.ErrNotFound, err)
+
+	err = repository.CreateRecord(context.Background(), &record)
+	require.NoError(t, err)
+
+	err = repository.CreateRecord(context.Background(), &record)
+	require.Equal(t, models.ErrConflict, err)
+
+	err = repository.CreateRecord(context.Background(), &models.IdempotencyRecord{WorkspaceID: "team", OwnerID: "other_owner", Key: "key"})
+	require.NoError(t, err)
+
+	// the same owner id in another workspace is another owner
+	err = repository.CreateRecord(context.Background(), &models.IdempotencyRecord{WorkspaceID: "other", OwnerID: "owner", Key: "key"})
+	require.NoError(t, err)
+
+	stale := record
+	stale.CreatedAt = createdAt.Add(-time.Hour)
+	err = repository.CompleteRecord(context.Background(), &stale)
+	require.Equal(t, models.ErrNotFound, err)
+
+	record.Response = []byte("response")
+	err = repository.CompleteRecord(context.Background(), &record)
+	require.NoError(t, err)
+
+	stored, err := repository.SelectRecord(context.Background(), "team", "owner", "key")
+	require.NoError(t, err)
+	assert.True(t, stored.Completed)
+	assert.Equal(t, []byte("response"), stored.Response)
+
+	err = repository.DeleteRecord(context.Background(), "team", "owner", "key", stale.CreatedAt)
+	require.NoError(t, err)
+	_, err = repository.SelectRecord(context.Background(), "team", "owner", "key")
+	require.NoError(t, err)
+
+	err = repository.DeleteRecord(context.Background(), "team", "owner", "key", createdAt)
+	require.NoError(t, err)
+	_, err = repository.SelectRecord(context.Background(), "team", "owner", "key")
+	require.Equal(t, models.ErrNotFound, err)
+}
+
+func TestRepositoryDeleteExpired(t *testing.T) {
+	now := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	repository := idempotencyRep.New()
+
+	for key, expiresAt := range map[string]time.Time{"expired": now.Add(-time.Minute), "expires_now": now, "valid": now.Add(time.Minute)} {
+		err := repository.CreateRecord(context.Background(), &models.IdempotencyRecord{WorkspaceID: "team", OwnerID: "owner",
+			Key: key, ExpiresAt: expiresAt})
+		require.NoError(t, err)
+	}
+
+	deleted, err := repository.DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	for _, key := range []string{"expired", "expires_now"} {
+		_, err = repository.SelectRecord(context.Background(), "team", "owner", key)
+		require.Equal(t, models.ErrNotFound, err)
+	}
+	_, err = repository.SelectRecord(context.Background(), "team", "owner", "valid")
+	require.NoError(t, err)
+}
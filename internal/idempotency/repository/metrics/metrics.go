@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/idempotency/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "idempotency_keys"
+
+type idempotencyRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps idempotency repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &idempotencyRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbIdempotency *idempotencyRepository) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	start := time.Now()
+	err := dbIdempotency.repository.CreateRecord(ctx, record)
+	dbIdempotency.metrics.Observe(repositoryName, "CreateRecord", start, err)
+	return err
+}
+
+func (dbIdempotency *idempotencyRepository) SelectRecord(ctx context.Context, workspaceID string, ownerID string, key string) (*models.IdempotencyRecord, error) {
+	start := time.Now()
+	record, err := dbIdempotency.repository.SelectRecord(ctx, workspaceID, ownerID, key)
+	dbIdempotency.metrics.Observe(repositoryName, "SelectRecord", start, err)
+	return record, err
+}
+
+func (dbIdempotency *idempotencyRepository) CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	start := time.Now()
+	err := dbIdempotency.repository.CompleteRecord(ctx, record)
+	dbIdempotency.metrics.Observe(repositoryName, "CompleteRecord", start, err)
+	return err
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteRecord(ctx context.Context, workspaceID string, ownerID string, key string, createdAt time.Time) error {
+	start := time.Now()
+	err := dbIdempotency.repository.DeleteRecord(ctx, workspaceID, ownerID, key, createdAt)
+	dbIdempotency.metrics.Observe(repositoryName, "DeleteRecord", start, err)
+	return err
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	start := time.Now()
+	deleted, err := dbIdempotency.repository.DeleteExpired(ctx, now)
+	dbIdempotency.metrics.Observe(repositoryName, "DeleteExpired", start, err)
+	return deleted, err
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kuzkuss/url_service/internal/idempotency/repository"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+const uniqueViolation = "23505"
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+func (dbIdempotency *idempotencyRepository) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	tx := dbIdempotency.db.WithContext(ctx).Create(record)
+
+	var pgErr *pgconn.PgError
+	if errors.As(tx.Error, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.ErrConflict
+	} else if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table idempotency_keys)")
+	}
+
+	return nil
+}
+
+func (dbIdempotency *idempotencyRepository) SelectRecord(ctx context.Context, workspaceID string, ownerID string, key string) (*models.IdempotencyRecord, error) {
+	record := models.IdempotencyRecord{}
+
+	tx := dbIdempotency.db.WithContext(ctx).Where("workspace_id = ? AND owner_id = ? AND idempotency_key = ?", workspaceID, ownerID, key).Take(&record)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	} else if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table idempotency_keys)")
+	}
+
+	return &record, nil
+}
+
+func (dbIdempotency *idempotencyRepository) CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	tx := dbIdempotency.db.WithContext(ctx).Model(&models.IdempotencyRecord{}).
+		Where("workspace_id = ? AND owner_id = ? AND idempotency_key = ? AND created_at = ?",
+			record.WorkspaceID, record.OwnerID, record.Key, record.CreatedAt).
+		Updates(map[string]interface{}{"response": record.Response, "completed": true})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table idempotency_keys)")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tx := dbIdempotency.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyRecord{})
+	if tx.Error != nil {
+		return 0, errors.Wrap(tx.Error, "database error (table idempotency_keys)")
+	}
+
+	return tx.RowsAffected, nil
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteRecord(ctx context.Context, workspaceID string, ownerID string, key string, createdAt time.Time) error {
+	tx := dbIdempotency.db.WithContext(ctx).
+		Where("workspace_id = ? AND owner_id = ? AND idempotency_key = ? AND created_at = ?", workspaceID, ownerID, key, createdAt).
+		Delete(&models.IdempotencyRecord{})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table idempotency_keys)")
+	}
+
+	return nil
+}
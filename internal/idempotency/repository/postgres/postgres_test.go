@@ -0,0 +1,234 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pkg/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kuzkuss/url_service/models"
+	idempotencyRep "github.com/kuzkuss/url_service/internal/idempotency/repository/postgres"
+)
+
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	return gdb, mock
+}
+
+var createdAt = time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func newRecord() models.IdempotencyRecord {
+	return models.IdempotencyRecord {
+		WorkspaceID: "team",
+		OwnerID: "owner",
+		Key: "key",
+		Fingerprint: "fingerprint",
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+	}
+}
+
+func TestRepositoryCreateRecord(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	record := newRecord()
+	createErr := errors.New("error")
+
+	query := regexp.QuoteMeta(`INSERT INTO "idempotency_keys" ` +
+		`("workspace_id","owner_id","idempotency_key","fingerprint","response","completed","created_at","expires_at") ` +
+		`VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`)
+	args := []driver.Value{record.WorkspaceID, record.OwnerID, record.Key, record.Fingerprint, []byte(nil), false,
+		record.CreatedAt, record.ExpiresAt}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(args...).WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(args...).WillReturnError(createErr)
+	mock.ExpectRollback()
+
+	repository := idempotencyRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.CreateRecord(context.Background(), &record)
+		require.NoError(t, err)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		err := repository.CreateRecord(context.Background(), &record)
+		require.Equal(t, models.ErrConflict, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := repository.CreateRecord(context.Background(), &record)
+		require.Equal(t, createErr, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositorySelectRecord(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	record := newRecord()
+	record.Response = []byte("response")
+	record.Completed = true
+
+	query := regexp.QuoteMeta(`SELECT * FROM "idempotency_keys" WHERE workspace_id = $1 AND owner_id = $2 AND idempotency_key = $3 LIMIT 1`)
+	columns := []string{"workspace_id", "owner_id", "idempotency_key", "fingerprint", "response", "completed", "created_at", "expires_at"}
+
+	mock.ExpectQuery(query).WithArgs("team", "owner", "key").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(record.WorkspaceID, record.OwnerID, record.Key, record.Fingerprint,
+			record.Response, record.Completed, record.CreatedAt, record.ExpiresAt))
+
+	mock.ExpectQuery(query).WithArgs("team", "owner", "unknown_key").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	repository := idempotencyRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		actualRes, err := repository.SelectRecord(context.Background(), "team", "owner", "key")
+		require.NoError(t, err)
+		assert.Equal(t, &record, actualRes)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := repository.SelectRecord(context.Background(), "team", "owner", "unknown_key")
+		require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryCompleteRecord(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	record := newRecord()
+	record.Response = []byte("response")
+
+	query := regexp.QuoteMeta(`UPDATE "idempotency_keys" SET "completed"=$1,"response"=$2 ` +
+		`WHERE workspace_id = $3 AND owner_id = $4 AND idempotency_key = $5 AND created_at = $6`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(true, record.Response, record.WorkspaceID, record.OwnerID, record.Key, record.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(true, record.Response, record.WorkspaceID, record.OwnerID, record.Key, record.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repository := idempotencyRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.CompleteRecord(context.Background(), &record)
+		require.NoError(t, err)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		err := repository.CompleteRecord(context.Background(), &record)
+		require.Equal(t, models.ErrNotFound, err)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryDeleteRecord(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	deleteErr := errors.New("error")
+
+	query := regexp.QuoteMeta(`DELETE FROM "idempotency_keys" WHERE workspace_id = $1 AND owner_id = $2 AND idempotency_key = $3 AND created_at = $4`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs("team", "owner", "key", createdAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs("team", "owner", "key", createdAt).WillReturnError(deleteErr)
+	mock.ExpectRollback()
+
+	repository := idempotencyRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.DeleteRecord(context.Background(), "team", "owner", "key", createdAt)
+		require.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := repository.DeleteRecord(context.Background(), "team", "owner", "key", createdAt)
+		require.Equal(t, deleteErr, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryDeleteExpired(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	deleteErr := errors.New("error")
+
+	query := regexp.QuoteMeta(`DELETE FROM "idempotency_keys" WHERE expires_at <= $1`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(createdAt).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(createdAt).WillReturnError(deleteErr)
+	mock.ExpectRollback()
+
+	repository := idempotencyRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		deleted, err := repository.DeleteExpired(context.Background(), createdAt)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := repository.DeleteExpired(context.Background(), createdAt)
+		require.Equal(t, deleteErr, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+type RepositoryI interface {
+	// CreateRecord reserves idempotency key of the owner in the workspace.
+	// Returns ErrConflict if the key is already reserved.
+	CreateRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	SelectRecord(ctx context.Context, workspaceID string, ownerID string, key string) (*models.IdempotencyRecord, error)
+	// CompleteRecord saves response of the reservation created at createdAt.
+	CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	// DeleteRecord removes the reservation created at createdAt, so newer
+	// reservation of the same key is never removed.
+	DeleteRecord(ctx context.Context, workspaceID string, ownerID string, key string, createdAt time.Time) error
+	// DeleteExpired removes records expired at now and returns their number.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
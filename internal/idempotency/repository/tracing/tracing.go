@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/idempotency/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "idempotency_keys"
+
+type idempotencyRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps idempotency repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &idempotencyRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbIdempotency *idempotencyRepository) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := dbIdempotency.tracing.Start(ctx, repositoryName, "CreateRecord")
+	err := dbIdempotency.repository.CreateRecord(ctx, record)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbIdempotency *idempotencyRepository) SelectRecord(ctx context.Context, workspaceID string, ownerID string, key string) (*models.IdempotencyRecord, error) {
+	ctx, span := dbIdempotency.tracing.Start(ctx, repositoryName, "SelectRecord")
+	record, err := dbIdempotency.repository.SelectRecord(ctx, workspaceID, ownerID, key)
+	observability.EndSpan(span, err)
+	return record, err
+}
+
+func (dbIdempotency *idempotencyRepository) CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := dbIdempotency.tracing.Start(ctx, repositoryName, "CompleteRecord")
+	err := dbIdempotency.repository.CompleteRecord(ctx, record)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteRecord(ctx context.Context, workspaceID string, ownerID string, key string, createdAt time.Time) error {
+	ctx, span := dbIdempotency.tracing.Start(ctx, repositoryName, "DeleteRecord")
+	err := dbIdempotency.repository.DeleteRecord(ctx, workspaceID, ownerID, key, createdAt)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbIdempotency *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := dbIdempotency.tracing.Start(ctx, repositoryName, "DeleteExpired")
+	deleted, err := dbIdempotency.repository.DeleteExpired(ctx, now)
+	observability.EndSpan(span, err)
+	return deleted, err
+}
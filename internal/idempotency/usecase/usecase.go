@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/config"
+	idempotencyRep "github.com/kuzkuss/url_service/internal/idempotency/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+// pendingTimeout is the time after which reservation of the key by unfinished
+// request is considered abandoned (e.g. the process was restarted).
+const pendingTimeout = time.Minute
+
+const reserveAttempts = 2
+
+type UseCaseI interface {
+	// Do runs fn at most once per idempotency key of the owner in the workspace during the window.
+	// Repeated request with the same key and fingerprint gets the stored response
+	// of the first one, reported by the second returned value.
+	Do(ctx context.Context, workspaceID string, ownerID string, key string, fingerprint string, fn func() ([]byte, error)) ([]byte, bool, error)
+	// Run removes expired keys every cleanup interval until ctx is done.
+	Run(ctx context.Context)
+}
+
+type useCase struct {
+	idempotencyRepository idempotencyRep.RepositoryI
+	conf                  config.IdempotencyConfig
+	logger                *slog.Logger
+}
+
+// New creates idempotency usecase storing responses for the window of conf.
+func New(idempotencyRepository idempotencyRep.RepositoryI, conf config.IdempotencyConfig, logger *slog.Logger) UseCaseI {
+	return &useCase{
+		idempotencyRepository: idempotencyRepository,
+		conf:                  conf,
+		logger:                logger,
+	}
+}
+
+// Fingerprint identifies request by its operation and parameters,
+// so the same key can't be reused for a different request.
+func Fingerprint(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RequestFingerprint identifies request by its operation and every field of
+// request encoded to JSON, so fields added to the request are covered too.
+func RequestFingerprint(operation string, request interface{}) (string, error) {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return "", errors.Wrap(err, "request encoding error")
+	}
+	return Fingerprint(operation, string(encoded)), nil
+}
+
+func (uc *useCase) Do(ctx context.Context, workspaceID string, ownerID string, key string, fingerprint string, fn func() ([]byte, error)) (_ []byte, _ bool, err error) {
+	ctx, span := observability.StartSpan(ctx, "idempotency.usecase.Do")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if len(key) > models.IdempotencyKeyMaxLength {
+		return nil, false, errors.Wrap(models.ErrBadRequest, "idempotency key is too long")
+	}
+
+	record, err := uc.reserve(ctx, workspaceID, ownerID, key, fingerprint)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if record.Completed {
+		return record.Response, true, nil
+	}
+
+	response, err := fn()
+	if err != nil {
+		// Failed request may be retried with the same key.
+		_ = uc.idempotencyRepository.DeleteRecord(ctx, workspaceID, ownerID, key, record.CreatedAt)
+		return nil, false, err
+	}
+
+	record.Response = response
+	err = uc.idempotencyRepository.CompleteRecord(ctx, record)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, false, errors.Wrap(err, "idempotency repository error")
+	}
+
+	return response, false, nil
+}
+
+// Run removes expired keys, expired keys found by requests are removed by them
+// meanwhile. Several instances of the service may remove keys concurrently.
+func (uc *useCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.conf.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := uc.idempotencyRepository.DeleteExpired(ctx, time.Now().UTC())
+		if err != nil {
+			if ctx.Err() == nil {
+				uc.logger.ErrorContext(ctx, "expired idempotency keys are not removed", "error", err)
+			}
+			continue
+		}
+		uc.logger.DebugContext(ctx, "expired idempotency keys removed", "count", deleted)
+	}
+}
+
+// reserve creates reservation of the key or returns the completed record
+// stored by the previous request.
+func (uc *useCase) reserve(ctx context.Context, workspaceID string, ownerID string, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	record := &models.IdempotencyRecord{
+		WorkspaceID: workspaceID,
+		OwnerID:     ownerID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(uc.conf.Window),
+	}
+
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		err := uc.idempotencyRepository.CreateRecord(ctx, record)
+		if err == nil {
+			return record, nil
+		} else if !errors.Is(err, models.ErrConflict) {
+			return nil, errors.Wrap(err, "idempotency repository error")
+		}
+
+		stored, err := uc.idempotencyRepository.SelectRecord(ctx, workspaceID, ownerID, key)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, "idempotency repository error")
+		}
+
+		if isStale(stored, now) {
+			err = uc.idempotencyRepository.DeleteRecord(ctx, workspaceID, ownerID, key, stored.CreatedAt)
+			if err != nil {
+				return nil, errors.Wrap(err, "idempotency repository error")
+			}
+			continue
+		}
+
+		if stored.Fingerprint != fingerprint {
+			return nil, models.ErrIdempotencyMismatch
+		}
+
+		if !stored.Completed {
+			return nil, models.ErrRequestInProgress
+		}
+
+		return stored, nil
+	}
+
+	return nil, models.ErrRequestInProgress
+}
+
+func isStale(record *models.IdempotencyRecord, now time.Time) bool {
+	if !now.Before(record.ExpiresAt) {
+		return true
+	}
+
+	return !record.Completed && now.Sub(record.CreatedAt) >= pendingTimeout
+}
@@ -0,0 +1,209 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	idempotencyMocks "github.com/kuzkuss/url_service/internal/idempotency/repository/mocks"
+	idempotencyUsecase "github.com/kuzkuss/url_service/internal/idempotency/usecase"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseDo struct {
+	Key string
+	Fingerprint string
+	ExpectedResponse []byte
+	Replayed bool
+	Executed bool
+	Error error
+}
+
+func TestUsecaseDo(t *testing.T) {
+	fingerprint := idempotencyUsecase.Fingerprint("CreateShortLink", "original_link")
+	now := time.Now().UTC()
+	repositoryErr := errors.New("error")
+	handlerErr := errors.New("handler error")
+
+	mockIdempotencyRepo := idempotencyMocks.NewRepositoryI(t)
+
+	withKey := func(key string) interface{} {
+		return mock.MatchedBy(func(record *models.IdempotencyRecord) bool {
+			return record.WorkspaceID == "team" && record.OwnerID == "owner" && record.Key == key && record.Fingerprint == fingerprint &&
+				record.ExpiresAt.Sub(record.CreatedAt) == time.Hour
+		})
+	}
+
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_new")).Return(nil)
+	mockIdempotencyRepo.On("CompleteRecord", mock.Anything, withKey("key_new")).Return(nil)
+
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_completed")).Return(models.ErrConflict)
+	mockIdempotencyRepo.On("SelectRecord", mock.Anything, "team", "owner", "key_completed").Return(&models.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		Response: []byte("stored"),
+		Completed: true,
+		CreatedAt: now.Add(-time.Minute),
+		ExpiresAt: now.Add(time.Hour),
+	}, nil)
+
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_mismatch")).Return(models.ErrConflict)
+	mockIdempotencyRepo.On("SelectRecord", mock.Anything, "team", "owner", "key_mismatch").Return(&models.IdempotencyRecord{
+		Fingerprint: "other",
+		Completed: true,
+		CreatedAt: now.Add(-time.Minute),
+		ExpiresAt: now.Add(time.Hour),
+	}, nil)
+
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_in_progress")).Return(models.ErrConflict)
+	mockIdempotencyRepo.On("SelectRecord", mock.Anything, "team", "owner", "key_in_progress").Return(&models.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}, nil)
+
+	expiredCreatedAt := now.Add(-2 * time.Hour)
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_expired")).Return(models.ErrConflict).Once()
+	mockIdempotencyRepo.On("SelectRecord", mock.Anything, "team", "owner", "key_expired").Return(&models.IdempotencyRecord{
+		Fingerprint: "other",
+		Completed: true,
+		CreatedAt: expiredCreatedAt,
+		ExpiresAt: expiredCreatedAt.Add(time.Hour),
+	}, nil)
+	mockIdempotencyRepo.On("DeleteRecord", mock.Anything, "team", "owner", "key_expired", expiredCreatedAt).Return(nil)
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_expired")).Return(nil).Once()
+	mockIdempotencyRepo.On("CompleteRecord", mock.Anything, withKey("key_expired")).Return(nil)
+
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_failed")).Return(nil)
+	mockIdempotencyRepo.On("DeleteRecord", mock.Anything, "team", "owner", "key_failed", mock.AnythingOfType("time.Time")).Return(nil)
+
+	mockIdempotencyRepo.On("CreateRecord", mock.Anything, withKey("key_error")).Return(repositoryErr)
+
+	usecase := idempotencyUsecase.New(mockIdempotencyRepo, config.IdempotencyConfig{Window: time.Hour, CleanupInterval: time.Hour},
+		observability.NopLogger())
+
+	cases := map[string]TestCaseDo {
+		"new": {
+			Key: "key_new",
+			ExpectedResponse: []byte("created"),
+			Executed: true,
+		},
+		"completed": {
+			Key: "key_completed",
+			ExpectedResponse: []byte("stored"),
+			Replayed: true,
+		},
+		"mismatch": {
+			Key: "key_mismatch",
+			Error: models.ErrIdempotencyMismatch,
+		},
+		"in_progress": {
+			Key: "key_in_progress",
+			Error: models.ErrRequestInProgress,
+		},
+		"expired": {
+			Key: "key_expired",
+			ExpectedResponse: []byte("created"),
+			Executed: true,
+		},
+		"handler_failed": {
+			Key: "key_failed",
+			Executed: true,
+			Error: handlerErr,
+		},
+		"repository_error": {
+			Key: "key_error",
+			Error: repositoryErr,
+		},
+		"key_too_long": {
+			Key: strings.Repeat("k", models.IdempotencyKeyMaxLength + 1),
+			Error: models.ErrBadRequest,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			executed := false
+			response, replayed, err := usecase.Do(context.Background(), "team", "owner", test.Key, fingerprint, func() ([]byte, error) {
+				executed = true
+				if test.Key == "key_failed" {
+					return nil, handlerErr
+				}
+				return []byte("created"), nil
+			})
+			require.Equal(t, test.Error, errors.Cause(err))
+			assert.Equal(t, test.ExpectedResponse, response)
+			assert.Equal(t, test.Replayed, replayed)
+			assert.Equal(t, test.Executed, executed)
+		})
+	}
+}
+
+func TestUsecaseRun(t *testing.T) {
+	mockIdempotencyRepo := idempotencyMocks.NewRepositoryI(t)
+
+	deleted := make(chan time.Time, 2)
+	record := func(args mock.Arguments) {
+		select {
+		case deleted <- args.Get(1).(time.Time):
+		default:
+		}
+	}
+	mockIdempotencyRepo.On("DeleteExpired", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(int64(0), errors.New("error")).Once().Run(record)
+	mockIdempotencyRepo.On("DeleteExpired", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return(int64(2), nil).Run(record)
+
+	usecase := idempotencyUsecase.New(mockIdempotencyRepo, config.IdempotencyConfig{Window: time.Hour,
+		CleanupInterval: 10 * time.Millisecond}, observability.NopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		usecase.Run(ctx)
+		close(done)
+	}()
+
+	// cleanup goes on after an error
+	for i := 0; i < 2; i++ {
+		select {
+		case now := <-deleted:
+			assert.WithinDuration(t, time.Now(), now, time.Second)
+		case <-time.After(5 * time.Second):
+			t.Fatal("expired keys are not removed")
+		}
+	}
+
+	cancel()
+	<-done
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, idempotencyUsecase.Fingerprint("a", "b"), idempotencyUsecase.Fingerprint("a", "b"))
+	assert.NotEqual(t, idempotencyUsecase.Fingerprint("a", "b"), idempotencyUsecase.Fingerprint("ab"))
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	fingerprint := func(link models.Link) string {
+		fingerprint, err := idempotencyUsecase.RequestFingerprint("CreateShortLink", link)
+		require.NoError(t, err)
+		return fingerprint
+	}
+
+	link := models.Link{OriginalLink: "original_link", Domain: "a.io"}
+	assert.Equal(t, fingerprint(link), fingerprint(link))
+	// every field of the request is fingerprinted
+	assert.NotEqual(t, fingerprint(link), fingerprint(models.Link{OriginalLink: "original_link"}))
+	assert.NotEqual(t, fingerprint(link), fingerprint(models.Link{OriginalLink: "original_link", Domain: "a.io",
+		ShortLink: "short_link"}))
+
+	_, err := idempotencyUsecase.RequestFingerprint("CreateShortLink", func() {})
+	require.Error(t, err)
+}
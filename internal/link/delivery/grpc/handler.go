@@ -2,40 +2,266 @@ package delivery
 
 import (
 	"context"
+	"encoding/json"
+	"log/slog"
 
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	idempotencyUsecase "github.com/kuzkuss/url_service/internal/idempotency/usecase"
 	linkUsecase "github.com/kuzkuss/url_service/internal/link/usecase"
 	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
 	link "github.com/kuzkuss/url_service/proto/link"
 )
 
+const (
+	MetadataIdempotencyKey = "idempotency-key"
+	MetadataIdempotentReplayed = "idempotent-replayed"
+	// set by the REST gateway to the Host header of the request
+	MetadataForwardedHost = "x-forwarded-host"
+	MetadataAuthority = ":authority"
+)
+
 type LinkManager struct {
 	link.UnimplementedLinksServer
 	LinkUC linkUsecase.UseCaseI
+	IdempotencyUC idempotencyUsecase.UseCaseI
+	Logger *slog.Logger
 }
 
-func New(uc linkUsecase.UseCaseI) link.LinksServer {
-	return LinkManager{LinkUC: uc}
+// New creates links service. Idempotency key metadata is ignored if idempotencyUC is nil.
+func New(uc linkUsecase.UseCaseI, idempotencyUC idempotencyUsecase.UseCaseI, logger *slog.Logger) link.LinksServer {
+	return LinkManager{LinkUC: uc, IdempotencyUC: idempotencyUC, Logger: logger}
 }
 
 func (lm LinkManager) CreateShortLink(ctx context.Context, originalLink *link.OriginalLink) (*link.ShortLink, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
 	modelLink := models.Link {
 		OriginalLink: originalLink.OriginalLink,
+		Domain: originalLink.Domain,
+	}
+	if err := pkg.Validate(&modelLink); err != nil {
+		lm.Logger.InfoContext(ctx, "invalid link data", "error", err)
+		return nil, status.Error(codes.InvalidArgument, models.ErrBadRequest.Error())
+	}
+	if err := lm.createShortLink(ctx, principal, &modelLink); err != nil {
+		return nil, lm.statusError(ctx, "link creation failed", err)
 	}
-	err := lm.LinkUC.CreateShortLink(&modelLink)
 
 	resp := &link.ShortLink {
 		ShortLink: modelLink.ShortLink,
+		Domain: modelLink.Domain,
+		ShortUrl: modelLink.ShortURL,
+	}
+
+	return resp, nil
+}
+
+// createShortLink creates link once per idempotency key if the key is given.
+// Retried request gets the link created by the first one.
+func (lm LinkManager) createShortLink(ctx context.Context, principal *models.Principal, modelLink *models.Link) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(MetadataIdempotencyKey)
+	if lm.IdempotencyUC == nil || len(keys) == 0 || keys[0] == "" {
+		return lm.LinkUC.CreateShortLink(ctx, principal, modelLink)
+	}
+
+	fingerprint, err := idempotencyUsecase.RequestFingerprint("CreateShortLink", modelLink)
+	if err != nil {
+		return err
+	}
+	response, replayed, err := lm.IdempotencyUC.Do(ctx, principal.WorkspaceID, principal.OwnerID, keys[0], fingerprint, func() ([]byte, error) {
+		if err := lm.LinkUC.CreateShortLink(ctx, principal, modelLink); err != nil {
+			return nil, err
+		}
+		return json.Marshal(modelLink)
+	})
+	if err != nil {
+		return err
+	}
+
+	if replayed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataIdempotentReplayed, "true"))
 	}
 
-	return resp, err
+	return json.Unmarshal(response, modelLink)
+}
+
+// statusError converts error of the usecase to gRPC status with the same meaning
+// as the HTTP status returned for it. Details of internal errors are only logged.
+func (lm LinkManager) statusError(ctx context.Context, msg string, err error) error {
+	var rateErr *models.RateLimitError
+	causeErr := errors.Cause(err)
+	switch {
+	case errors.Is(causeErr, models.ErrNotFound):
+		return status.Error(codes.NotFound, models.ErrNotFound.Error())
+	case errors.Is(causeErr, models.ErrBadRequest):
+		return status.Error(codes.InvalidArgument, models.ErrBadRequest.Error())
+	case errors.Is(causeErr, models.ErrConflict):
+		return status.Error(codes.AlreadyExists, models.ErrConflict.Error())
+	case errors.Is(causeErr, models.ErrForbidden):
+		return status.Error(codes.PermissionDenied, models.ErrForbidden.Error())
+	case errors.Is(causeErr, models.ErrRequestInProgress):
+		return status.Error(codes.Aborted, models.ErrRequestInProgress.Error())
+	case errors.Is(causeErr, models.ErrIdempotencyMismatch):
+		return status.Error(codes.FailedPrecondition, models.ErrIdempotencyMismatch.Error())
+	case errors.Is(causeErr, models.ErrCursorExpired):
+		return status.Error(codes.OutOfRange, models.ErrCursorExpired.Error())
+	case errors.Is(causeErr, models.ErrSlowConsumer):
+		return status.Error(codes.ResourceExhausted, models.ErrSlowConsumer.Error())
+	case errors.Is(causeErr, models.ErrServiceUnavailable):
+		return status.Error(codes.Unavailable, models.ErrServiceUnavailable.Error())
+	case errors.As(err, &rateErr):
+		// converted to ResourceExhausted with retry delay by the rate limit interceptor
+		return err
+	default:
+		lm.Logger.ErrorContext(ctx, msg, "error", err)
+		return status.Error(codes.Internal, models.ErrInternalServerError.Error())
+	}
 }
 
 func (lm LinkManager) GetOriginalLink(ctx context.Context, shortLink *link.ShortLink) (*link.OriginalLink, error) {
-	originalLink, err := lm.LinkUC.GetOriginalLink(shortLink.ShortLink)
+	var originalLink string
+	var err error
+	// the domain given in the request must be allowed, the host of the call
+	// falls back to the default domain
+	if shortLink.Domain != "" {
+		originalLink, err = lm.LinkUC.GetOriginalLinkOnDomain(ctx, shortLink.Domain, shortLink.ShortLink)
+	} else {
+		originalLink, err = lm.LinkUC.GetOriginalLink(ctx, requestHost(ctx), shortLink.ShortLink)
+	}
+	if err != nil {
+		return nil, lm.statusError(ctx, "link retrieval failed", err)
+	}
 
 	resp := &link.OriginalLink {
 		OriginalLink: originalLink,
 	}
 
-	return resp, err
+	return resp, nil
+}
+
+// requestHost returns the host of the REST gateway request or the authority of the call.
+func requestHost(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range []string{MetadataForwardedHost, MetadataAuthority} {
+		if hosts := md.Get(key); len(hosts) > 0 && hosts[0] != "" {
+			return hosts[0]
+		}
+	}
+	return ""
+}
+
+func (lm LinkManager) ListLinks(ctx context.Context, _ *link.Nothing) (*link.LinkList, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	links, err := lm.LinkUC.GetLinks(ctx, principal)
+	if err != nil {
+		return nil, lm.statusError(ctx, "links listing failed", err)
+	}
+
+	resp := &link.LinkList {
+		Links: make([]*link.Link, 0, len(links)),
+	}
+	for _, modelLink := range links {
+		resp.Links = append(resp.Links, pbLink(modelLink))
+	}
+
+	return resp, nil
+}
+
+func pbLink(modelLink models.Link) *link.Link {
+	resp := &link.Link {
+		ShortLink: modelLink.ShortLink,
+		OriginalLink: modelLink.OriginalLink,
+		Domain: modelLink.Domain,
+		ShortUrl: modelLink.ShortURL,
+	}
+	if modelLink.CreatedAt != nil {
+		resp.CreatedAt = timestamppb.New(*modelLink.CreatedAt)
+	}
+	if modelLink.UpdatedAt != nil {
+		resp.UpdatedAt = timestamppb.New(*modelLink.UpdatedAt)
+	}
+
+	return resp
+}
+
+func (lm LinkManager) UpdateLink(ctx context.Context, pbLink *link.Link) (*link.Nothing, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	modelLink := models.Link {
+		ShortLink: pbLink.ShortLink,
+		OriginalLink: pbLink.OriginalLink,
+		Domain: pbLink.Domain,
+	}
+	if err := pkg.Validate(&modelLink); err != nil {
+		lm.Logger.InfoContext(ctx, "invalid link data", "short_link", modelLink.ShortLink, "error", err)
+		return nil, status.Error(codes.InvalidArgument, models.ErrBadRequest.Error())
+	}
+	if err := lm.LinkUC.UpdateLink(ctx, principal, &modelLink); err != nil {
+		return nil, lm.statusError(ctx, "link update failed", err)
+	}
+
+	return &link.Nothing{}, nil
+}
+
+func (lm LinkManager) DeleteLink(ctx context.Context, shortLink *link.ShortLink) (*link.Nothing, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	if err := lm.LinkUC.DeleteLink(ctx, principal, shortLink.Domain, shortLink.ShortLink); err != nil {
+		return nil, lm.statusError(ctx, "link deletion failed", err)
+	}
+
+	return &link.Nothing{}, nil
+}
+
+var pbEventTypes = map[models.LinkEventType]link.LinkEvent_Type {
+	models.LinkCreated: link.LinkEvent_CREATED,
+	models.LinkUpdated: link.LinkEvent_UPDATED,
+	models.LinkDeleted: link.LinkEvent_DELETED,
+	models.LinkDisabled: link.LinkEvent_DISABLED,
+}
+
+func (lm LinkManager) WatchLinks(req *link.WatchLinksRequest, stream link.Links_WatchLinksServer) error {
+	ctx := stream.Context()
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	err := lm.LinkUC.WatchLinks(ctx, principal, req.Cursor, func(event models.LinkEvent) error {
+		return stream.Send(&link.LinkEvent {
+			Cursor: event.Cursor,
+			Type: pbEventTypes[event.Type],
+			Link: pbLink(event.Link),
+			Time: timestamppb.New(event.Time),
+		})
+	})
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil {
+		return lm.statusError(ctx, "links watching failed", err)
+	}
+
+	return nil
 }
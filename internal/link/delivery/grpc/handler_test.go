@@ -2,26 +2,43 @@ package delivery_test
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	idempotencyMocks "github.com/kuzkuss/url_service/internal/idempotency/usecase/mocks"
+	idempotencyUsecase "github.com/kuzkuss/url_service/internal/idempotency/usecase"
 	linkDelivery "github.com/kuzkuss/url_service/internal/link/delivery/grpc"
 	linkMocks "github.com/kuzkuss/url_service/internal/link/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
 	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
 	link "github.com/kuzkuss/url_service/proto/link"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
 type TestCaseGet struct {
 	ArgData *link.ShortLink
 	ExpectedRes *link.OriginalLink
-	Error error
+	Code codes.Code
 }
 
 type TestCaseCreate struct {
 	ArgData *link.OriginalLink
-	Error error
+	Code codes.Code
+}
+
+type TestCaseIdempotency struct {
+	Key string
+	ExpectedRes *link.ShortLink
+	Code codes.Code
 }
 
 func TestGrpcDeliveryCreateShortLink(t *testing.T) {
@@ -33,43 +50,155 @@ func TestGrpcDeliveryCreateShortLink(t *testing.T) {
 		OriginalLink: "original_link_error",
 	}
 
+	linkBadRequest := models.Link {
+		OriginalLink: "original_link_bad_request",
+	}
+
 	mockPbOriginalLinkSuccess := link.OriginalLink {
 		OriginalLink: linkSuccess.OriginalLink,
 	}
 	mockPbOriginalLinkError := link.OriginalLink {
 		OriginalLink: linkError.OriginalLink,
 	}
+	mockPbOriginalLinkBadRequest := link.OriginalLink {
+		OriginalLink: linkBadRequest.OriginalLink,
+	}
+
+	linkForbidden := models.Link {
+		OriginalLink: "original_link_forbidden",
+	}
+
+	mockPbOriginalLinkForbidden := link.OriginalLink {
+		OriginalLink: linkForbidden.OriginalLink,
+	}
 
 	createErr := errors.New("error")
-	ctx := context.Background()
+	principal := &models.Principal{OwnerID: "owner"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
 
 	mockLinkUsecase := linkMocks.NewUseCaseI(t)
 
-	mockLinkUsecase.On("CreateShortLink", &linkSuccess).Return(nil)
-	mockLinkUsecase.On("CreateShortLink", &linkError).Return(createErr)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &linkSuccess).Return(nil)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &linkError).Return(createErr)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &linkBadRequest).
+		Return(errors.Wrap(models.ErrBadRequest, "link validation error"))
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &linkForbidden).
+		Return(errors.Wrap(models.ErrForbidden, "role viewer does not permit links.create"))
 
-	delivery := linkDelivery.New(mockLinkUsecase)
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
 
 	cases := map[string]TestCaseCreate {
 		"success": {
 			ArgData:   &mockPbOriginalLinkSuccess,
-			Error: nil,
+			Code: codes.OK,
 		},
 		"error": {
 			ArgData:   &mockPbOriginalLinkError,
-			Error: createErr,
+			Code: codes.Internal,
+		},
+		"bad_request": {
+			ArgData:   &mockPbOriginalLinkBadRequest,
+			Code: codes.InvalidArgument,
+		},
+		"invalid": {
+			ArgData:   &link.OriginalLink{},
+			Code: codes.InvalidArgument,
+		},
+		"forbidden": {
+			ArgData:   &mockPbOriginalLinkForbidden,
+			Code: codes.PermissionDenied,
 		},
 	}
 
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
 			_, err := delivery.CreateShortLink(ctx, test.ArgData)
-			require.Equal(t, test.Error, err)
+			require.Equal(t, test.Code, status.Code(err))
 		})
 	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := delivery.CreateShortLink(context.Background(), &mockPbOriginalLinkSuccess)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
 	mockLinkUsecase.AssertExpectations(t)
 }
 
+func TestGrpcDeliveryCreateShortLinkIdempotency(t *testing.T) {
+	linkCreate := models.Link {
+		OriginalLink: "original_link",
+	}
+
+	storedResponse, err := json.Marshal(models.Link {
+		OriginalLink: "original_link",
+		ShortLink: "short_link_stored",
+		ShortURL: "https://sho.rt/short_link_stored",
+	})
+	assert.NoError(t, err)
+
+	principal := &models.Principal{OwnerID: "owner"}
+	fingerprint, err := idempotencyUsecase.RequestFingerprint("CreateShortLink", linkCreate)
+	require.NoError(t, err)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+	mockIdempotencyUsecase := idempotencyMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, principal, &linkCreate).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Link).ShortLink = "short_link_created"
+		args.Get(2).(*models.Link).ShortURL = "https://sho.rt/short_link_created"
+	})
+
+	runFn := func(ctx context.Context, workspaceID string, ownerID string, key string, fingerprint string, fn func() ([]byte, error)) []byte {
+		response, err := fn()
+		require.NoError(t, err)
+		return response
+	}
+
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_new", fingerprint, mock.Anything).Return(runFn, false, nil)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_replayed", fingerprint, mock.Anything).Return(storedResponse, true, nil)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_in_progress", fingerprint, mock.Anything).
+		Return(nil, false, models.ErrRequestInProgress)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_mismatch", fingerprint, mock.Anything).
+		Return(nil, false, models.ErrIdempotencyMismatch)
+
+	delivery := linkDelivery.New(mockLinkUsecase, mockIdempotencyUsecase, observability.NopLogger())
+
+	cases := map[string]TestCaseIdempotency {
+		"new": {
+			Key: "key_new",
+			ExpectedRes: &link.ShortLink{ShortLink: "short_link_created", ShortUrl: "https://sho.rt/short_link_created"},
+			Code: codes.OK,
+		},
+		"replayed": {
+			Key: "key_replayed",
+			ExpectedRes: &link.ShortLink{ShortLink: "short_link_stored", ShortUrl: "https://sho.rt/short_link_stored"},
+			Code: codes.OK,
+		},
+		"in_progress": {
+			Key: "key_in_progress",
+			Code: codes.Aborted,
+		},
+		"mismatch": {
+			Key: "key_mismatch",
+			Code: codes.FailedPrecondition,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := pkg.WithPrincipal(context.Background(), principal)
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(linkDelivery.MetadataIdempotencyKey, test.Key))
+
+			res, err := delivery.CreateShortLink(ctx, &link.OriginalLink{OriginalLink: linkCreate.OriginalLink})
+			require.Equal(t, test.Code, status.Code(err))
+
+			if test.Code == codes.OK {
+				assert.Equal(t, test.ExpectedRes.ShortLink, res.ShortLink)
+			}
+		})
+	}
+}
+
 func TestGrpcDeliveryGetOriginalLink(t *testing.T) {
 	mockPbShortLinkSuccess := link.ShortLink {
 		ShortLink: "short_link_success",
@@ -77,6 +206,9 @@ func TestGrpcDeliveryGetOriginalLink(t *testing.T) {
 	mockPbShortLinkError := link.ShortLink {
 		ShortLink: "short_link_error",
 	}
+	mockPbShortLinkNotFound := link.ShortLink {
+		ShortLink: "short_link_not_found",
+	}
 
 	mockPbOriginalLinkSuccess := link.OriginalLink {
 		OriginalLink: "original_link_success",
@@ -92,30 +224,35 @@ func TestGrpcDeliveryGetOriginalLink(t *testing.T) {
 
 	mockLinkUsecase := linkMocks.NewUseCaseI(t)
 
-	mockLinkUsecase.On("GetOriginalLink", mockPbShortLinkSuccess.ShortLink).
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "", mockPbShortLinkSuccess.ShortLink).
 										Return(mockPbOriginalLinkSuccess.OriginalLink, nil)
-	mockLinkUsecase.On("GetOriginalLink", mockPbShortLinkError.ShortLink).
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "", mockPbShortLinkError.ShortLink).
 										Return(mockPbOriginalLinkError.OriginalLink, getErr)
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "", mockPbShortLinkNotFound.ShortLink).
+										Return("", errors.Wrap(models.ErrNotFound, "link repository error"))
 
-	delivery := linkDelivery.New(mockLinkUsecase)
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
 
 	cases := map[string]TestCaseGet {
 		"success": {
 			ArgData:   &mockPbShortLinkSuccess,
 			ExpectedRes: &mockPbOriginalLinkSuccess,
-			Error: nil,
+			Code: codes.OK,
 		},
 		"error": {
 			ArgData:   &mockPbShortLinkError,
-			ExpectedRes: &mockPbOriginalLinkError,
-			Error: getErr,
+			Code: codes.Internal,
+		},
+		"not_found": {
+			ArgData:   &mockPbShortLinkNotFound,
+			Code: codes.NotFound,
 		},
 	}
 
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
 			actualRes, err := delivery.GetOriginalLink(ctx, test.ArgData)
-			require.Equal(t, test.Error, err)
+			require.Equal(t, test.Code, status.Code(err))
 
 			if err == nil {
 				assert.Equal(t, test.ExpectedRes, actualRes)
@@ -125,3 +262,156 @@ func TestGrpcDeliveryGetOriginalLink(t *testing.T) {
 	mockLinkUsecase.AssertExpectations(t)
 }
 
+func TestGrpcDeliveryGetOriginalLinkHost(t *testing.T) {
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("GetOriginalLinkOnDomain", mock.Anything, "b.io", "short_link").Return("original_link_b", nil)
+	mockLinkUsecase.On("GetOriginalLinkOnDomain", mock.Anything, "c.io", "short_link").
+		Return("", errors.Wrap(models.ErrBadRequest, "domain \"c.io\" is not allowed"))
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "a.io:8080", "short_link").Return("original_link_a", nil)
+
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
+
+	// the domain of the request takes precedence over the host
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(linkDelivery.MetadataForwardedHost, "a.io:8080"))
+	actualRes, err := delivery.GetOriginalLink(ctx, &link.ShortLink{ShortLink: "short_link", Domain: "b.io"})
+	require.NoError(t, err)
+	assert.Equal(t, "original_link_b", actualRes.OriginalLink)
+
+	// the domain of the request is not replaced by the default one if it is not allowed
+	_, err = delivery.GetOriginalLink(ctx, &link.ShortLink{ShortLink: "short_link", Domain: "c.io"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	actualRes, err = delivery.GetOriginalLink(ctx, &link.ShortLink{ShortLink: "short_link"})
+	require.NoError(t, err)
+	assert.Equal(t, "original_link_a", actualRes.OriginalLink)
+}
+
+func TestGrpcDeliveryListLinks(t *testing.T) {
+	createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	links := []models.Link {
+		{
+			OriginalLink: "original_link_success",
+			ShortLink: "short_link_success",
+			OwnerID: "owner",
+			CreatedAt: &createdAt,
+		},
+	}
+
+	principal := &models.Principal{OwnerID: "owner"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("GetLinks", mock.Anything, principal).Return(links, nil)
+
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
+
+	actualRes, err := delivery.ListLinks(ctx, &link.Nothing{})
+	require.NoError(t, err)
+	require.Len(t, actualRes.Links, 1)
+	assert.Equal(t, links[0].ShortLink, actualRes.Links[0].ShortLink)
+	assert.Equal(t, links[0].OriginalLink, actualRes.Links[0].OriginalLink)
+	assert.Equal(t, createdAt, actualRes.Links[0].CreatedAt.AsTime())
+	assert.Nil(t, actualRes.Links[0].UpdatedAt)
+
+	_, err = delivery.ListLinks(context.Background(), &link.Nothing{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGrpcDeliveryUpdateLink(t *testing.T) {
+	linkSuccess := models.Link {
+		OriginalLink: "original_link_success",
+		ShortLink: "short_link_success",
+	}
+
+	principal := &models.Principal{OwnerID: "owner"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("UpdateLink", mock.Anything, principal, &linkSuccess).Return(nil)
+
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
+
+	_, err := delivery.UpdateLink(ctx, &link.Link {
+		ShortLink: linkSuccess.ShortLink,
+		OriginalLink: linkSuccess.OriginalLink,
+	})
+	require.NoError(t, err)
+
+	_, err = delivery.UpdateLink(ctx, &link.Link {
+		ShortLink: linkSuccess.ShortLink,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGrpcDeliveryDeleteLink(t *testing.T) {
+	principal := &models.Principal{OwnerID: "owner"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("DeleteLink", mock.Anything, principal, "", "short_link_success").Return(nil)
+	mockLinkUsecase.On("DeleteLink", mock.Anything, principal, "", "short_link_not_found").Return(models.ErrNotFound)
+
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
+
+	_, err := delivery.DeleteLink(ctx, &link.ShortLink{ShortLink: "short_link_success"})
+	require.NoError(t, err)
+
+	_, err = delivery.DeleteLink(ctx, &link.ShortLink{ShortLink: "short_link_not_found"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+type watchStreamStub struct {
+	link.Links_WatchLinksServer
+	ctx context.Context
+	events []*link.LinkEvent
+}
+
+func (s *watchStreamStub) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStreamStub) Send(event *link.LinkEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestGrpcDeliveryWatchLinks(t *testing.T) {
+	event := models.LinkEvent {
+		Cursor: "epoch-1",
+		Type: models.LinkCreated,
+		Link: models.Link{ShortLink: "short_link_success", OriginalLink: "original_link_success"},
+		Time: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	}
+
+	principal := &models.Principal{OwnerID: "owner"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("WatchLinks", mock.Anything, principal, "", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		handle := args.Get(3).(func(models.LinkEvent) error)
+		require.NoError(t, handle(event))
+	})
+	mockLinkUsecase.On("WatchLinks", mock.Anything, principal, "epoch-1", mock.Anything).Return(models.ErrCursorExpired)
+
+	delivery := linkDelivery.New(mockLinkUsecase, nil, observability.NopLogger())
+
+	stream := &watchStreamStub{ctx: ctx}
+	err := delivery.WatchLinks(&link.WatchLinksRequest{}, stream)
+	require.NoError(t, err)
+	require.Len(t, stream.events, 1)
+	assert.Equal(t, "epoch-1", stream.events[0].Cursor)
+	assert.Equal(t, link.LinkEvent_CREATED, stream.events[0].Type)
+	assert.Equal(t, "short_link_success", stream.events[0].Link.ShortLink)
+	assert.Equal(t, event.Time, stream.events[0].Time.AsTime())
+
+	err = delivery.WatchLinks(&link.WatchLinksRequest{Cursor: "epoch-1"}, &watchStreamStub{ctx: ctx})
+	require.Equal(t, codes.OutOfRange, status.Code(err))
+
+	err = delivery.WatchLinks(&link.WatchLinksRequest{}, &watchStreamStub{ctx: context.Background()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -1,59 +1,133 @@
 package delivery
 
 import (
+	"context"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 
 	"github.com/pkg/errors"
 
+	idempotencyUsecase "github.com/kuzkuss/url_service/internal/idempotency/usecase"
 	linkUsecase "github.com/kuzkuss/url_service/internal/link/usecase"
 	"github.com/kuzkuss/url_service/models"
 	"github.com/kuzkuss/url_service/pkg"
 	"github.com/labstack/echo/v4"
-	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
 )
 
 type Delivery struct {
 	LinkUC linkUsecase.UseCaseI
+	IdempotencyUC idempotencyUsecase.UseCaseI
+	Logger *slog.Logger
 }
 
 // CreateShortLink godoc
 // @Summary      CreateShortLink
-// @Description  create short link
+// @Description  create short link in the workspace of api key on the domain given in the body, on the default domain if it is empty
 // @Tags     link
 // @Accept	 application/json
 // @Produce  application/json
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param    Idempotency-Key header string false "key making retries of the request safe, reuse with any other field of the body is rejected; only link creation accepts it, there is no batch creation"
 // @Param    original_link body models.Link true "link data"
 // @Success 201 {object} pkg.Response{body=models.Link} "short link created"
 // @Failure 405 {object} echo.HTTPError "method not allowed"
 // @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 409 {object} echo.HTTPError "request with the same idempotency key is in progress"
+// @Failure 422 {object} echo.HTTPError "idempotency key is reused with different request"
+// @Failure 429 {object} echo.HTTPError "too many requests"
 // @Failure 500 {object} echo.HTTPError "internal server error"
 // @Router   /create [post]
 func (del *Delivery) CreateShortLink(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
 	var link models.Link
 	err := c.Bind(&link)
 	if err != nil {
-		c.Logger().Error(err)
+		del.Logger.InfoContext(c.Request().Context(), "invalid link data", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
 	}
 
-	if ok, err := isRequestValid(&link); !ok {
-		c.Logger().Error(err)
+	if err := pkg.Validate(&link); err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid link data", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
 	}
 
-	err = del.LinkUC.CreateShortLink(&link)
+	replayed, err := del.createShortLink(c.Request().Context(), principal, &link,
+		c.Request().Header.Get(HeaderIdempotencyKey))
 	if err != nil {
-		c.Logger().Error(err)
-		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+		var rateErr *models.RateLimitError
+		causeErr := errors.Cause(err)
+		switch {
+		case errors.Is(causeErr, models.ErrBadRequest):
+			del.Logger.InfoContext(c.Request().Context(), "link creation rejected", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+		case errors.Is(causeErr, models.ErrForbidden):
+			del.Logger.InfoContext(c.Request().Context(), "link creation rejected", "error", err)
+			return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+		case errors.Is(causeErr, models.ErrRequestInProgress):
+			del.Logger.InfoContext(c.Request().Context(), "link creation rejected", "error", err)
+			return echo.NewHTTPError(http.StatusConflict, models.ErrRequestInProgress.Error())
+		case errors.Is(causeErr, models.ErrIdempotencyMismatch):
+			del.Logger.InfoContext(c.Request().Context(), "link creation rejected", "error", err)
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, models.ErrIdempotencyMismatch.Error())
+		case errors.As(err, &rateErr):
+			del.Logger.InfoContext(c.Request().Context(), "link creation rejected", "error", err)
+			c.Response().Header().Set(echo.HeaderRetryAfter, pkg.RetryAfterSeconds(rateErr.RetryAfter))
+			return echo.NewHTTPError(http.StatusTooManyRequests, models.ErrTooManyRequests.Error())
+		default:
+			del.Logger.ErrorContext(c.Request().Context(), "link creation failed", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+		}
+	}
+
+	if replayed {
+		c.Response().Header().Set(HeaderIdempotentReplayed, "true")
 	}
 
 	link.OriginalLink = ""
 	return c.JSON(http.StatusCreated, pkg.Response{Body: link})
 }
 
+// createShortLink creates link once per idempotency key if the key is given.
+// Retried request gets the link created by the first one.
+func (del *Delivery) createShortLink(ctx context.Context, principal *models.Principal, link *models.Link,
+	idempotencyKey string) (bool, error) {
+	if del.IdempotencyUC == nil || idempotencyKey == "" {
+		return false, del.LinkUC.CreateShortLink(ctx, principal, link)
+	}
+
+	fingerprint, err := idempotencyUsecase.RequestFingerprint("CreateShortLink", link)
+	if err != nil {
+		return false, err
+	}
+	response, replayed, err := del.IdempotencyUC.Do(ctx, principal.WorkspaceID, principal.OwnerID, idempotencyKey, fingerprint, func() ([]byte, error) {
+		if err := del.LinkUC.CreateShortLink(ctx, principal, link); err != nil {
+			return nil, err
+		}
+		return json.Marshal(link)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return replayed, json.Unmarshal(response, link)
+}
+
 // GetOriginalLink godoc
 // @Summary      GetOriginalLink
-// @Description  get original link by short link
+// @Description  get original link by short link on the domain of the Host header, the lookup is not counted as a click
 // @Tags     link
 // @Param short_link path string  true  "Short link"
 // @Produce  application/json
@@ -63,36 +137,212 @@ func (del *Delivery) CreateShortLink(c echo.Context) error {
 // @Failure 500 {object} echo.HTTPError "internal server error"
 // @Router   /get/{short_link} [get]
 func (del *Delivery) GetOriginalLink(c echo.Context) error {
-	link, err := del.LinkUC.GetOriginalLink(c.Param("short_link"))
+	link, err := del.getOriginalLink(c, del.LinkUC.GetOriginalLink)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: models.Link{OriginalLink: link}})
+}
+
+// Redirect godoc
+// @Summary      Redirect
+// @Description  redirect to original link of the short link on the domain of the Host header, counted as a click of the link
+// @Tags     link
+// @Param short_link path string  true  "Short link"
+// @Success  302 "redirect to original link"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /{short_link} [get]
+func (del *Delivery) Redirect(c echo.Context) error {
+	link, err := del.getOriginalLink(c, del.LinkUC.FollowLink)
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusFound, link)
+}
+
+// getOriginalLink resolves the short link of the request by resolve.
+func (del *Delivery) getOriginalLink(c echo.Context,
+	resolve func(ctx context.Context, host string, link string) (string, error)) (string, error) {
+	shortLink := c.Param("short_link")
+	host := c.Request().Host
+	link, err := resolve(c.Request().Context(), host, shortLink)
+	if err != nil {
+		causeErr := errors.Cause(err)
+		switch {
+		case errors.Is(causeErr, models.ErrNotFound):
+			del.Logger.InfoContext(c.Request().Context(), "link not found", "host", host, "short_link", shortLink, "error", err)
+			return "", echo.NewHTTPError(http.StatusNotFound, models.ErrNotFound.Error())
+		default:
+			del.Logger.ErrorContext(c.Request().Context(), "link retrieval failed", "host", host, "short_link", shortLink, "error", err)
+			return "", echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+		}
+	}
+
+	return link, nil
+}
+
+// GetLinks godoc
+// @Summary      GetLinks
+// @Description  get links of the workspace of api key: links of every member for members of the workspace, links created by the owner of api key otherwise
+// @Tags     link
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.Link} "success get links"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 405 {object} echo.HTTPError "method not allowed"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /list [get]
+func (del *Delivery) GetLinks(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	links, err := del.LinkUC.GetLinks(c.Request().Context(), principal)
 	if err != nil {
 		causeErr := errors.Cause(err)
 		switch {
+		case errors.Is(causeErr, models.ErrForbidden):
+			del.Logger.InfoContext(c.Request().Context(), "links listing rejected", "error", err)
+			return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+		default:
+			del.Logger.ErrorContext(c.Request().Context(), "links listing failed", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+		}
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: links})
+}
+
+// UpdateLink godoc
+// @Summary      UpdateLink
+// @Description  change original link of the short link in the workspace of api key on the domain given in the body, on the default domain if it is empty; editors change links of every member, clients which are not members only their own links
+// @Tags     link
+// @Accept	 application/json
+// @Produce  application/json
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param short_link path string  true  "Short link"
+// @Param    original_link body models.Link true "link data"
+// @Success  200 {object} pkg.Response{body=models.Link} "link updated"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 405 {object} echo.HTTPError "method not allowed"
+// @Failure 409 {object} echo.HTTPError "conflict"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /update/{short_link} [put]
+func (del *Delivery) UpdateLink(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	var link models.Link
+	err := c.Bind(&link)
+	if err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid link data", "short_link", c.Param("short_link"), "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	if err := pkg.Validate(&link); err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid link data", "short_link", c.Param("short_link"), "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	link.ShortLink = c.Param("short_link")
+	err = del.LinkUC.UpdateLink(c.Request().Context(), principal, &link)
+	if err != nil {
+		causeErr := errors.Cause(err)
+		switch {
+		case errors.Is(causeErr, models.ErrBadRequest):
+			del.Logger.InfoContext(c.Request().Context(), "link update rejected", "short_link", link.ShortLink, "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+		case errors.Is(causeErr, models.ErrForbidden):
+			del.Logger.InfoContext(c.Request().Context(), "link update rejected", "short_link", link.ShortLink, "error", err)
+			return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
 		case errors.Is(causeErr, models.ErrNotFound):
-			c.Logger().Error(err)
+			del.Logger.InfoContext(c.Request().Context(), "link not found", "short_link", link.ShortLink, "error", err)
 			return echo.NewHTTPError(http.StatusNotFound, models.ErrNotFound.Error())
+		case errors.Is(causeErr, models.ErrConflict):
+			del.Logger.InfoContext(c.Request().Context(), "link update rejected", "short_link", link.ShortLink, "error", err)
+			return echo.NewHTTPError(http.StatusConflict, models.ErrConflict.Error())
 		default:
-			c.Logger().Error(err)
+			del.Logger.ErrorContext(c.Request().Context(), "link update failed", "short_link", link.ShortLink, "error", err)
 			return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
 		}
 	}
 
-	return c.JSON(http.StatusOK, pkg.Response{Body: models.Link{OriginalLink: link}})
+	return c.JSON(http.StatusOK, pkg.Response{Body: link})
 }
 
-func isRequestValid(link interface{}) (bool, error) {
-	validate := validator.New()
-	err := validate.Struct(link)
+// DeleteLink godoc
+// @Summary      DeleteLink
+// @Description  delete short link in the workspace of api key; editors delete links of every member, clients which are not members only their own links
+// @Tags     link
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param short_link path string  true  "Short link"
+// @Param    domain query string false "domain of the short link, the default one if empty"
+// @Success  204 "link deleted"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 405 {object} echo.HTTPError "method not allowed"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /delete/{short_link} [delete]
+func (del *Delivery) DeleteLink(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	shortLink := c.Param("short_link")
+	err := del.LinkUC.DeleteLink(c.Request().Context(), principal, c.QueryParam("domain"), shortLink)
 	if err != nil {
-		return false, err
+		causeErr := errors.Cause(err)
+		switch {
+		case errors.Is(causeErr, models.ErrBadRequest):
+			del.Logger.InfoContext(c.Request().Context(), "link deletion rejected", "short_link", shortLink, "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+		case errors.Is(causeErr, models.ErrForbidden):
+			del.Logger.InfoContext(c.Request().Context(), "link deletion rejected", "short_link", shortLink, "error", err)
+			return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+		case errors.Is(causeErr, models.ErrNotFound):
+			del.Logger.InfoContext(c.Request().Context(), "link not found", "short_link", shortLink, "error", err)
+			return echo.NewHTTPError(http.StatusNotFound, models.ErrNotFound.Error())
+		default:
+			del.Logger.ErrorContext(c.Request().Context(), "link deletion failed", "short_link", shortLink, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+		}
 	}
-	return true, nil
+
+	return c.NoContent(http.StatusNoContent)
 }
 
-func New(e *echo.Echo, linkUC linkUsecase.UseCaseI) {
+// New registers link routes. Routes modifying or listing links are wrapped
+// with middleware returned by authorize for the required scope; it must store
+// principal in the request context. Idempotency-Key header is ignored if idempotencyUC is nil.
+func New(e *echo.Echo, linkUC linkUsecase.UseCaseI, idempotencyUC idempotencyUsecase.UseCaseI,
+	authorize func(scope string) echo.MiddlewareFunc, logger *slog.Logger) {
 	handler := &Delivery{
 		LinkUC: linkUC,
+		IdempotencyUC: idempotencyUC,
+		Logger: logger,
 	}
 
-	e.POST("/create", handler.CreateShortLink)
+	e.POST("/create", handler.CreateShortLink, authorize(models.ScopeLinksWrite))
 	e.GET("/get/:short_link", handler.GetOriginalLink)
+	e.GET("/list", handler.GetLinks, authorize(models.ScopeLinksRead))
+	e.PUT("/update/:short_link", handler.UpdateLink, authorize(models.ScopeLinksWrite))
+	e.DELETE("/delete/:short_link", handler.DeleteLink, authorize(models.ScopeLinksWrite))
+	// short URLs of the links, other routes take precedence
+	e.GET("/:short_link", handler.Redirect)
 }
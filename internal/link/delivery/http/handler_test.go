@@ -1,23 +1,35 @@
 package delivery_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kuzkuss/url_service/models"
 	"github.com/kuzkuss/url_service/pkg"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	idempotencyMocks "github.com/kuzkuss/url_service/internal/idempotency/usecase/mocks"
+	idempotencyUsecase "github.com/kuzkuss/url_service/internal/idempotency/usecase"
 	linkDelivery "github.com/kuzkuss/url_service/internal/link/delivery/http"
 	linkMocks "github.com/kuzkuss/url_service/internal/link/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
 )
 
+func noAuth(string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return next
+	}
+}
+
 type TestCaseCreate struct {
 	ArgData string
 	ExpectedResponse string
@@ -25,6 +37,13 @@ type TestCaseCreate struct {
 	StatusCode int
 }
 
+type TestCaseIdempotency struct {
+	Key string
+	ExpectedResponse string
+	Replayed string
+	Error error
+}
+
 type TestCaseGet struct {
 	ArgData string
 	ExpectedResponse string
@@ -42,6 +61,10 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 		OriginalLink: "original_link_internal_error",
 	}
 
+	linkQuotaExceeded := models.Link {
+		OriginalLink: "original_link_quota_exceeded",
+	}
+
 	linkInvalid := models.Link{}
 
 	jsonLinkSuccess, err := json.Marshal(linkSuccess)
@@ -53,12 +76,18 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 	jsonLinkInvalid, err := json.Marshal(linkInvalid)
 	assert.NoError(t, err)
 
+	jsonLinkQuotaExceeded, err := json.Marshal(linkQuotaExceeded)
+	assert.NoError(t, err)
+
 	createErr := errors.New("error")
+	principal := models.Principal{OwnerID: "owner"}
 
 	mockLinkUsecase := linkMocks.NewUseCaseI(t)
 
-	mockLinkUsecase.On("CreateShortLink", &linkSuccess).Return(nil)
-	mockLinkUsecase.On("CreateShortLink", &linkInternalError).Return(createErr)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, &principal, &linkSuccess).Return(nil)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, &principal, &linkInternalError).Return(createErr)
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, &principal, &linkQuotaExceeded).
+										Return(&models.RateLimitError{RetryAfter: 90 * time.Second})
 
 	response := pkg.Response {
 		Body: models.Link { ShortLink: linkSuccess.ShortLink},
@@ -67,10 +96,11 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 	assert.NoError(t, err)
 
 	e := echo.New()
-	linkDelivery.New(e, mockLinkUsecase)
+	linkDelivery.New(e, mockLinkUsecase, nil, noAuth, observability.NopLogger())
 
 	delivery := linkDelivery.Delivery {
 		LinkUC: mockLinkUsecase,
+		Logger: observability.NopLogger(),
 	}
 
 	cases := map[string]TestCaseCreate {
@@ -94,6 +124,13 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 				Message: models.ErrBadRequest.Error(),
 			},
 		},
+		"quota_exceeded": {
+			ArgData:   string(jsonLinkQuotaExceeded),
+			Error: &echo.HTTPError{
+				Code: http.StatusTooManyRequests,
+				Message: models.ErrTooManyRequests.Error(),
+			},
+		},
 		"internal_error": {
 			ArgData:   string(jsonLinkInternalErr),
 			Error: &echo.HTTPError{
@@ -107,6 +144,7 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			req := httptest.NewRequest(echo.POST, "/create", strings.NewReader(test.ArgData))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = req.WithContext(pkg.WithPrincipal(context.Background(), &principal))
 
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
@@ -115,6 +153,10 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 			err = delivery.CreateShortLink(c)
 			require.Equal(t, test.Error, err)
 
+			if name == "quota_exceeded" {
+				assert.Equal(t, "90", rec.Header().Get(echo.HeaderRetryAfter))
+			}
+
 			if err == nil {
 				assert.Equal(t, test.StatusCode, rec.Code)
 				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
@@ -122,9 +164,128 @@ func TestHttpDeliveryCreateShortLink(t *testing.T) {
 		})
 	}
 
+	t.Run("unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(echo.POST, "/create", strings.NewReader(string(jsonLinkSuccess)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/create")
+
+		err = delivery.CreateShortLink(c)
+		require.Equal(t, &echo.HTTPError{
+			Code: http.StatusUnauthorized,
+			Message: models.ErrUnauthorized.Error(),
+		}, err)
+	})
+
 	mockLinkUsecase.AssertExpectations(t)
 }
 
+func TestHttpDeliveryCreateShortLinkIdempotency(t *testing.T) {
+	linkCreate := models.Link {
+		OriginalLink: "original_link",
+	}
+
+	jsonLink, err := json.Marshal(linkCreate)
+	assert.NoError(t, err)
+
+	storedResponse, err := json.Marshal(models.Link {
+		OriginalLink: "original_link",
+		ShortLink: "short_link_stored",
+	})
+	assert.NoError(t, err)
+
+	principal := models.Principal{OwnerID: "owner"}
+	fingerprint, err := idempotencyUsecase.RequestFingerprint("CreateShortLink", linkCreate)
+	require.NoError(t, err)
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+	mockIdempotencyUsecase := idempotencyMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("CreateShortLink", mock.Anything, &principal, &linkCreate).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Link).ShortLink = "short_link_created"
+	})
+
+	runFn := func(ctx context.Context, workspaceID string, ownerID string, key string, fingerprint string, fn func() ([]byte, error)) []byte {
+		response, err := fn()
+		require.NoError(t, err)
+		return response
+	}
+
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_new", fingerprint, mock.Anything).Return(runFn, false, nil)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_replayed", fingerprint, mock.Anything).Return(storedResponse, true, nil)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_in_progress", fingerprint, mock.Anything).
+		Return(nil, false, models.ErrRequestInProgress)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_mismatch", fingerprint, mock.Anything).
+		Return(nil, false, models.ErrIdempotencyMismatch)
+	mockIdempotencyUsecase.On("Do", mock.Anything, principal.WorkspaceID, "owner", "key_too_long", fingerprint, mock.Anything).
+		Return(nil, false, errors.Wrap(models.ErrBadRequest, "idempotency key is too long"))
+
+	e := echo.New()
+
+	delivery := linkDelivery.Delivery {
+		LinkUC: mockLinkUsecase,
+		IdempotencyUC: mockIdempotencyUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	cases := map[string]TestCaseIdempotency {
+		"new": {
+			Key: "key_new",
+			ExpectedResponse: `{"body":{"short_link":"short_link_created"}}` + "\n",
+		},
+		"replayed": {
+			Key: "key_replayed",
+			ExpectedResponse: `{"body":{"short_link":"short_link_stored"}}` + "\n",
+			Replayed: "true",
+		},
+		"in_progress": {
+			Key: "key_in_progress",
+			Error: &echo.HTTPError{
+				Code: http.StatusConflict,
+				Message: models.ErrRequestInProgress.Error(),
+			},
+		},
+		"mismatch": {
+			Key: "key_mismatch",
+			Error: &echo.HTTPError{
+				Code: http.StatusUnprocessableEntity,
+				Message: models.ErrIdempotencyMismatch.Error(),
+			},
+		},
+		"key_too_long": {
+			Key: "key_too_long",
+			Error: &echo.HTTPError{
+				Code: http.StatusBadRequest,
+				Message: models.ErrBadRequest.Error(),
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/create", strings.NewReader(string(jsonLink)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(linkDelivery.HeaderIdempotencyKey, test.Key)
+			req = req.WithContext(pkg.WithPrincipal(context.Background(), &principal))
+
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/create")
+
+			err = delivery.CreateShortLink(c)
+			require.Equal(t, test.Error, err)
+
+			if err == nil {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+				assert.Equal(t, test.Replayed, rec.Header().Get(linkDelivery.HeaderIdempotentReplayed))
+			}
+		})
+	}
+}
+
 func TestHttpDeliveryGetOriginalLink(t *testing.T) {
 	linkSuccess := models.Link {
 		OriginalLink: "original_link_success",
@@ -143,11 +304,11 @@ func TestHttpDeliveryGetOriginalLink(t *testing.T) {
 
 	mockLinkUsecase := linkMocks.NewUseCaseI(t)
 
-	mockLinkUsecase.On("GetOriginalLink", linkSuccess.ShortLink).
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "example.com", linkSuccess.ShortLink).
 										Return(linkSuccess.OriginalLink, nil)
-	mockLinkUsecase.On("GetOriginalLink", linkInternalError.ShortLink).
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "example.com", linkInternalError.ShortLink).
 										Return(linkInternalError.OriginalLink, models.ErrInternalServerError)
-	mockLinkUsecase.On("GetOriginalLink", linkNotFound.ShortLink).
+	mockLinkUsecase.On("GetOriginalLink", mock.Anything, "example.com", linkNotFound.ShortLink).
 										Return(linkNotFound.OriginalLink, models.ErrNotFound)
 
 	response := pkg.Response {
@@ -157,10 +318,11 @@ func TestHttpDeliveryGetOriginalLink(t *testing.T) {
 	assert.NoError(t, err)
 
 	e := echo.New()
-	linkDelivery.New(e, mockLinkUsecase)
+	linkDelivery.New(e, mockLinkUsecase, nil, noAuth, observability.NopLogger())
 
 	delivery := linkDelivery.Delivery {
 		LinkUC: mockLinkUsecase,
+		Logger: observability.NopLogger(),
 	}
 
 	cases := map[string]TestCaseGet {
@@ -210,3 +372,265 @@ func TestHttpDeliveryGetOriginalLink(t *testing.T) {
 	mockLinkUsecase.AssertExpectations(t)
 }
 
+func TestHttpDeliveryRedirect(t *testing.T) {
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	// only redirects are counted as clicks
+	mockLinkUsecase.On("FollowLink", mock.Anything, "short.io:8080", "short_link_success").
+		Return("https://example.com/original", nil)
+	mockLinkUsecase.On("FollowLink", mock.Anything, "short.io:8080", "short_link_not_found").
+		Return("", models.ErrNotFound)
+
+	e := echo.New()
+	linkDelivery.New(e, mockLinkUsecase, nil, noAuth, observability.NopLogger())
+
+	req := httptest.NewRequest(echo.GET, "/short_link_success", nil)
+	req.Host = "short.io:8080"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com/original", rec.Header().Get(echo.HeaderLocation))
+
+	req = httptest.NewRequest(echo.GET, "/short_link_not_found", nil)
+	req.Host = "short.io:8080"
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHttpDeliveryGetLinks(t *testing.T) {
+	links := []models.Link {
+		{
+			OriginalLink: "original_link_success",
+			ShortLink: "short_link_success",
+			OwnerID: "owner",
+		},
+	}
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	mockLinkUsecase.On("GetLinks", mock.Anything, &models.Principal{OwnerID: "owner", WorkspaceID: "team"}).Return(links, nil)
+	mockLinkUsecase.On("GetLinks", mock.Anything, &models.Principal{OwnerID: "owner_error", WorkspaceID: "team"}).
+		Return(nil, models.ErrInternalServerError)
+	mockLinkUsecase.On("GetLinks", mock.Anything, &models.Principal{OwnerID: "owner_forbidden", WorkspaceID: "team"}).
+		Return(nil, errors.Wrap(models.ErrForbidden, "forbidden"))
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: links})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	delivery := linkDelivery.Delivery {
+		LinkUC: mockLinkUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	cases := map[string]TestCaseGet {
+		"success": {
+			ArgData:   "owner",
+			ExpectedResponse: string(jsonResponse) + "\n",
+			Error: nil,
+			StatusCode: http.StatusOK,
+		},
+		"internal_error": {
+			ArgData:   "owner_error",
+			Error: &echo.HTTPError{
+				Code: http.StatusInternalServerError,
+				Message: models.ErrInternalServerError.Error(),
+			},
+		},
+		"forbidden": {
+			ArgData:   "owner_forbidden",
+			Error: &echo.HTTPError{
+				Code: http.StatusForbidden,
+				Message: models.ErrForbidden.Error(),
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/list", nil)
+			req = req.WithContext(pkg.WithPrincipal(context.Background(),
+				&models.Principal{OwnerID: test.ArgData, WorkspaceID: "team"}))
+
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/list")
+
+			err = delivery.GetLinks(c)
+			require.Equal(t, test.Error, err)
+
+			if err == nil {
+				assert.Equal(t, test.StatusCode, rec.Code)
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHttpDeliveryUpdateLink(t *testing.T) {
+	linkSuccess := models.Link {
+		OriginalLink: "original_link_success",
+		ShortLink: "short_link_success",
+	}
+
+	linkNotFound := models.Link {
+		OriginalLink: "original_link_not_found",
+		ShortLink: "short_link_not_found",
+	}
+
+	linkConflict := models.Link {
+		OriginalLink: "original_link_conflict",
+		ShortLink: "short_link_conflict",
+	}
+
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	linkForbidden := models.Link {
+		OriginalLink: "original_link_forbidden",
+		ShortLink: "short_link_forbidden",
+	}
+
+	principal := &models.Principal{OwnerID: "owner", Role: models.RoleViewer}
+
+	mockLinkUsecase.On("UpdateLink", mock.Anything, principal, &linkSuccess).Return(nil)
+	mockLinkUsecase.On("UpdateLink", mock.Anything, principal, &linkNotFound).Return(models.ErrNotFound)
+	mockLinkUsecase.On("UpdateLink", mock.Anything, principal, &linkConflict).Return(models.ErrConflict)
+	mockLinkUsecase.On("UpdateLink", mock.Anything, principal, &linkForbidden).
+		Return(errors.Wrap(models.ErrForbidden, "role viewer does not permit links.write"))
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: linkSuccess})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	delivery := linkDelivery.Delivery {
+		LinkUC: mockLinkUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	type testCaseUpdate struct {
+		Link models.Link
+		TestCaseGet
+	}
+
+	cases := map[string]testCaseUpdate {
+		"success": {
+			Link: linkSuccess,
+			TestCaseGet: TestCaseGet {
+				ExpectedResponse: string(jsonResponse) + "\n",
+				StatusCode: http.StatusOK,
+			},
+		},
+		"invalid_request": {
+			Link: models.Link{ShortLink: "short_link_invalid"},
+			TestCaseGet: TestCaseGet {
+				Error: &echo.HTTPError{
+					Code: http.StatusBadRequest,
+					Message: models.ErrBadRequest.Error(),
+				},
+			},
+		},
+		"not_found": {
+			Link: linkNotFound,
+			TestCaseGet: TestCaseGet {
+				Error: &echo.HTTPError{
+					Code: http.StatusNotFound,
+					Message: models.ErrNotFound.Error(),
+				},
+			},
+		},
+		"conflict": {
+			Link: linkConflict,
+			TestCaseGet: TestCaseGet {
+				Error: &echo.HTTPError{
+					Code: http.StatusConflict,
+					Message: models.ErrConflict.Error(),
+				},
+			},
+		},
+		"forbidden": {
+			Link: linkForbidden,
+			TestCaseGet: TestCaseGet {
+				Error: &echo.HTTPError{
+					Code: http.StatusForbidden,
+					Message: models.ErrForbidden.Error(),
+				},
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			body, err := json.Marshal(models.Link{OriginalLink: test.Link.OriginalLink})
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(echo.PUT, "/update/:short_link", strings.NewReader(string(body)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = req.WithContext(pkg.WithPrincipal(context.Background(), principal))
+
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/update/:short_link")
+			c.SetParamNames("short_link")
+			c.SetParamValues(test.Link.ShortLink)
+
+			err = delivery.UpdateLink(c)
+			require.Equal(t, test.Error, err)
+
+			if err == nil {
+				assert.Equal(t, test.StatusCode, rec.Code)
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHttpDeliveryDeleteLink(t *testing.T) {
+	mockLinkUsecase := linkMocks.NewUseCaseI(t)
+
+	principal := &models.Principal{OwnerID: "owner"}
+
+	mockLinkUsecase.On("DeleteLink", mock.Anything, principal, "", "short_link_success").Return(nil)
+	mockLinkUsecase.On("DeleteLink", mock.Anything, principal, "", "short_link_not_found").Return(models.ErrNotFound)
+
+	e := echo.New()
+	delivery := linkDelivery.Delivery {
+		LinkUC: mockLinkUsecase,
+		Logger: observability.NopLogger(),
+	}
+
+	cases := map[string]TestCaseGet {
+		"success": {
+			ArgData:   "short_link_success",
+			Error: nil,
+			StatusCode: http.StatusNoContent,
+		},
+		"not_found": {
+			ArgData:   "short_link_not_found",
+			Error: &echo.HTTPError{
+				Code: http.StatusNotFound,
+				Message: models.ErrNotFound.Error(),
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/delete/:short_link", nil)
+			req = req.WithContext(pkg.WithPrincipal(context.Background(), &models.Principal{OwnerID: "owner"}))
+
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/delete/:short_link")
+			c.SetParamNames("short_link")
+			c.SetParamValues(test.ArgData)
+
+			err := delivery.DeleteLink(c)
+			require.Equal(t, test.Error, err)
+
+			if err == nil {
+				assert.Equal(t, test.StatusCode, rec.Code)
+			}
+		})
+	}
+}
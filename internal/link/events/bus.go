@@ -0,0 +1,160 @@
+package events
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+type BusI interface {
+	Publish(event models.LinkEvent)
+	Subscribe(cursor string) (*Subscription, error)
+}
+
+// Bus delivers link events to subscribers in the same process. At least the
+// last historySize events are kept, so a subscriber resumes after the cursor
+// of the last event it received. Cursors are valid until the process restarts.
+// A subscriber having bufferSize events not received yet is disconnected, so
+// slow subscribers do not delay publishers.
+type Bus struct {
+	mx          sync.Mutex
+	epoch       string
+	seq         uint64
+	history     []models.LinkEvent
+	historySize int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewBus(historySize int, bufferSize int) *Bus {
+	return &Bus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns cursor to event and sends it to every subscriber.
+func (b *Bus) Publish(event models.LinkEvent) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.seq++
+	event.Cursor = b.epoch + "-" + strconv.FormatUint(b.seq, 10)
+
+	// trimmed by whole history size, so events are not copied on every publish
+	b.history = append(b.history, event)
+	if len(b.history) >= 2*b.historySize {
+		b.history = append([]models.LinkEvent(nil), b.history[len(b.history)-b.historySize:]...)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			b.unsubscribe(sub, models.ErrSlowConsumer)
+		}
+	}
+}
+
+// Subscribe returns subscription receiving events published after the event
+// with cursor, or after the call if cursor is empty. models.ErrCursorExpired
+// is returned if events after cursor are not kept anymore.
+func (b *Bus) Subscribe(cursor string) (*Subscription, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.closed {
+		return nil, errors.Wrap(models.ErrServiceUnavailable, "link events bus is closed")
+	}
+
+	var replay []models.LinkEvent
+	if cursor != "" {
+		epoch, seqStr, _ := strings.Cut(cursor, "-")
+		seq, err := strconv.ParseUint(seqStr, 10, 64)
+		if err != nil || epoch == "" {
+			return nil, errors.Wrapf(models.ErrBadRequest, "malformed cursor %q", cursor)
+		}
+
+		first := b.seq - uint64(len(b.history)) + 1
+		switch {
+		case epoch != b.epoch:
+			return nil, errors.Wrap(models.ErrCursorExpired, "cursor was issued before restart")
+		case seq > b.seq:
+			return nil, errors.Wrapf(models.ErrBadRequest, "unknown cursor %q", cursor)
+		case seq + 1 < first:
+			return nil, errors.Wrapf(models.ErrCursorExpired, "events after cursor %q are not kept", cursor)
+		}
+		replay = b.history[seq+1-first:]
+	}
+
+	sub := &Subscription{
+		bus:    b,
+		events: make(chan models.LinkEvent, len(replay)+b.bufferSize),
+	}
+	for _, event := range replay {
+		sub.events <- event
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Close closes all subscriptions with models.ErrServiceUnavailable, e.g. on shutdown.
+// Events published after Close are dropped.
+func (b *Bus) Close() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.unsubscribe(sub, models.ErrServiceUnavailable)
+	}
+}
+
+func (b *Bus) unsubscribe(sub *Subscription, err error) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(b.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+}
+
+type Subscription struct {
+	bus    *Bus
+	events chan models.LinkEvent
+	err    error
+}
+
+// Events returns channel of the events, closed when the subscription ends.
+func (s *Subscription) Events() <-chan models.LinkEvent {
+	return s.events
+}
+
+// Err returns the reason why the events channel is closed: models.ErrSlowConsumer
+// or models.ErrServiceUnavailable, nil if it is closed by Close.
+// It must be called after the channel is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.bus.mx.Lock()
+	defer s.bus.mx.Unlock()
+
+	s.bus.unsubscribe(s, nil)
+}
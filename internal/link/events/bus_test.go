@@ -0,0 +1,140 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/internal/link/events"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseSubscribe struct {
+	Cursor string
+	ExpectedLinks []string
+	Error error
+}
+
+func publish(bus *events.Bus, shortLinks ...string) {
+	for _, shortLink := range shortLinks {
+		bus.Publish(models.LinkEvent{Type: models.LinkCreated, Link: models.Link{ShortLink: shortLink}})
+	}
+}
+
+// received returns short links of the events already sent to the subscription.
+func received(sub *events.Subscription) []string {
+	var shortLinks []string
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return shortLinks
+			}
+			shortLinks = append(shortLinks, event.Link.ShortLink)
+		default:
+			return shortLinks
+		}
+	}
+}
+
+func TestBusSubscribe(t *testing.T) {
+	bus := events.NewBus(2, 10)
+
+	sub, err := bus.Subscribe("")
+	require.NoError(t, err)
+	publish(bus, "first", "second", "third", "fourth")
+
+	var cursors []string
+	for i := 0; i < 4; i++ {
+		event := <-sub.Events()
+		cursors = append(cursors, event.Cursor)
+	}
+	sub.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.NoError(t, sub.Err())
+
+	epoch, _, _ := strings.Cut(cursors[0], "-")
+
+	cases := map[string]TestCaseSubscribe {
+		"new_events": {
+			Cursor: "",
+			ExpectedLinks: nil,
+		},
+		"resume": {
+			Cursor: cursors[1],
+			ExpectedLinks: []string{"third", "fourth"},
+		},
+		"resume_after_last": {
+			Cursor: cursors[3],
+			ExpectedLinks: nil,
+		},
+		"expired": {
+			Cursor: cursors[0],
+			Error: models.ErrCursorExpired,
+		},
+		"previous_process": {
+			Cursor: "epoch-3",
+			Error: models.ErrCursorExpired,
+		},
+		"unknown": {
+			Cursor: epoch + "-5",
+			Error: models.ErrBadRequest,
+		},
+		"malformed": {
+			Cursor: "cursor",
+			Error: models.ErrBadRequest,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			sub, err := bus.Subscribe(test.Cursor)
+			if test.Error != nil {
+				require.Equal(t, test.Error, errors.Cause(err))
+				return
+			}
+			require.NoError(t, err)
+			defer sub.Close()
+
+			assert.Equal(t, test.ExpectedLinks, received(sub))
+		})
+	}
+}
+
+func TestBusSlowConsumer(t *testing.T) {
+	bus := events.NewBus(10, 2)
+
+	slow, err := bus.Subscribe("")
+	require.NoError(t, err)
+	fast, err := bus.Subscribe("")
+	require.NoError(t, err)
+
+	publish(bus, "first", "second")
+	assert.Equal(t, []string{"first", "second"}, received(fast))
+
+	publish(bus, "third")
+	assert.Equal(t, []string{"third"}, received(fast))
+	assert.Equal(t, []string{"first", "second"}, received(slow))
+	assert.Equal(t, models.ErrSlowConsumer, slow.Err())
+}
+
+func TestBusClose(t *testing.T) {
+	bus := events.NewBus(10, 2)
+
+	sub, err := bus.Subscribe("")
+	require.NoError(t, err)
+
+	bus.Close()
+	publish(bus, "first")
+
+	assert.Empty(t, received(sub))
+	assert.Equal(t, models.ErrServiceUnavailable, sub.Err())
+	sub.Close()
+
+	_, err = bus.Subscribe("")
+	require.Equal(t, models.ErrServiceUnavailable, errors.Cause(err))
+}
@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kuzkuss/url_service/config"
+	linkEvents "github.com/kuzkuss/url_service/internal/link/events"
+	linkRep "github.com/kuzkuss/url_service/internal/link/repository"
+)
+
+// Feed publishes events appended to the feed by the relay to watchers of links
+// in the process, so watchers get events whichever instance relayed them. Reading
+// starts at the end of the feed, earlier events are not published.
+type Feed struct {
+	linkRepository linkRep.RepositoryI
+	bus            linkEvents.BusI
+	conf           config.OutboxConfig
+	logger         *slog.Logger
+	lastID         int64
+	started        bool
+}
+
+func NewFeed(linkRepository linkRep.RepositoryI, bus linkEvents.BusI, conf config.OutboxConfig,
+	logger *slog.Logger) *Feed {
+	return &Feed{
+		linkRepository: linkRepository,
+		bus:            bus,
+		conf:           conf,
+		logger:         logger,
+	}
+}
+
+// Run reads the feed every poll interval until ctx is done. Events older than
+// the feed retention are removed, so an instance lagging behind it misses them.
+func (f *Feed) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.conf.PollInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(f.conf.FeedRetention)
+	defer pruneTicker.Stop()
+
+	for {
+		f.Read(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-pruneTicker.C:
+			f.Prune(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Read publishes events appended since the previous call, the first call only
+// finds the end of the feed. Events failed to read are read by the next call.
+func (f *Feed) Read(ctx context.Context) {
+	if !f.started {
+		lastID, err := f.linkRepository.LastFeedID(ctx)
+		if err != nil {
+			f.logger.ErrorContext(ctx, "link feed reading failed", "error", err)
+			return
+		}
+		f.lastID, f.started = lastID, true
+		return
+	}
+
+	for ctx.Err() == nil {
+		events, err := f.linkRepository.SelectFeed(ctx, f.lastID, f.conf.BatchSize)
+		if err != nil {
+			f.logger.ErrorContext(ctx, "link feed reading failed", "error", err)
+			return
+		}
+
+		for _, event := range events {
+			f.bus.Publish(event.LinkEvent())
+			f.lastID = event.ID
+		}
+
+		if len(events) < f.conf.BatchSize {
+			return
+		}
+	}
+}
+
+// Prune removes events older than the feed retention.
+func (f *Feed) Prune(ctx context.Context) {
+	if err := f.linkRepository.DeleteFeed(ctx, time.Now().Add(-f.conf.FeedRetention)); err != nil {
+		f.logger.ErrorContext(ctx, "link feed pruning failed", "error", err)
+	}
+}
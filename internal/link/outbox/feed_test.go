@@ -0,0 +1,117 @@
+package outbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	linkEvents "github.com/kuzkuss/url_service/internal/link/events"
+	linkOutbox "github.com/kuzkuss/url_service/internal/link/outbox"
+	linkRep "github.com/kuzkuss/url_service/internal/link/repository/in_memory"
+	linkRepMocks "github.com/kuzkuss/url_service/internal/link/repository/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func received(sub *linkEvents.Subscription) []string {
+	var events []string
+	for len(sub.Events()) > 0 {
+		event := <-sub.Events()
+		events = append(events, string(event.Type) + " " + event.Link.ShortLink + " " + event.Link.OwnerID)
+	}
+	return events
+}
+
+func TestFeedRead(t *testing.T) {
+	repository := linkRep.New(nil)
+	ctx := context.Background()
+	sink := linkOutbox.NewFeedSink(repository)
+
+	// appended before the feed is started
+	require.NoError(t, sink.Publish(ctx, models.LinkEvent{Type: models.LinkCreated, Link: models.Link{ShortLink: "old"}}))
+
+	// instances of the service share the feed
+	buses := []*linkEvents.Bus{linkEvents.NewBus(10, 10), linkEvents.NewBus(10, 10)}
+	subs := make([]*linkEvents.Subscription, 0, len(buses))
+	feeds := make([]*linkOutbox.Feed, 0, len(buses))
+	for _, bus := range buses {
+		sub, err := bus.Subscribe("")
+		require.NoError(t, err)
+		defer sub.Close()
+		subs = append(subs, sub)
+
+		feed := linkOutbox.NewFeed(repository, bus, config.OutboxConfig{PollInterval: time.Hour, BatchSize: 2,
+			FeedRetention: time.Hour}, observability.NopLogger())
+		feed.Read(ctx)
+		feeds = append(feeds, feed)
+	}
+
+	for _, shortLink := range []string{"first", "second", "third"} {
+		event := models.LinkEvent{Type: models.LinkCreated, Link: models.Link{ShortLink: shortLink, OwnerID: "owner"}}
+		require.NoError(t, sink.Publish(ctx, event))
+	}
+
+	for i, feed := range feeds {
+		feed.Read(ctx)
+		assert.Equal(t, []string{"created first owner", "created second owner", "created third owner"}, received(subs[i]))
+	}
+
+	require.NoError(t, sink.Publish(ctx, models.LinkEvent{Type: models.LinkDeleted, Link: models.Link{ShortLink: "first"}}))
+	feeds[0].Read(ctx)
+	feeds[0].Read(ctx)
+	assert.Equal(t, []string{"deleted first "}, received(subs[0]))
+	assert.Empty(t, received(subs[1]))
+}
+
+func TestFeedReadError(t *testing.T) {
+	repository := linkRepMocks.NewRepositoryI(t)
+	bus := linkEvents.NewBus(10, 10)
+	sub, err := bus.Subscribe("")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	ctx := context.Background()
+	feed := linkOutbox.NewFeed(repository, bus, config.OutboxConfig{PollInterval: time.Hour, BatchSize: 10,
+		FeedRetention: time.Hour}, observability.NopLogger())
+
+	repository.On("LastFeedID", mock.Anything).Return(int64(0), errors.New("db error")).Once()
+	repository.On("LastFeedID", mock.Anything).Return(int64(5), nil).Once()
+	repository.On("SelectFeed", mock.Anything, int64(5), 10).Return(nil, errors.New("db error")).Once()
+	// failed events are read again
+	repository.On("SelectFeed", mock.Anything, int64(5), 10).Return([]models.LinkFeedEvent{
+		{ID: 6, Type: models.LinkCreated, OwnerID: "owner", Link: models.Link{ShortLink: "first"}},
+	}, nil).Once()
+	repository.On("SelectFeed", mock.Anything, int64(6), 10).Return([]models.LinkFeedEvent{}, nil).Once()
+
+	for i := 0; i < 5; i++ {
+		feed.Read(ctx)
+	}
+	assert.Equal(t, []string{"created first owner"}, received(sub))
+}
+
+func TestFeedPrune(t *testing.T) {
+	repository := linkRep.New(nil)
+	ctx := context.Background()
+	sink := linkOutbox.NewFeedSink(repository)
+
+	require.NoError(t, sink.Publish(ctx, models.LinkEvent{Type: models.LinkCreated, Link: models.Link{ShortLink: "first"}}))
+
+	feed := linkOutbox.NewFeed(repository, linkEvents.NewBus(10, 10), config.OutboxConfig{PollInterval: time.Millisecond,
+		BatchSize: 10, FeedRetention: 20 * time.Millisecond}, observability.NopLogger())
+	feed.Prune(ctx)
+	events, err := repository.SelectFeed(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	time.Sleep(30 * time.Millisecond)
+	feed.Prune(ctx)
+	events, err = repository.SelectFeed(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
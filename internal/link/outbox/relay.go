@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kuzkuss/url_service/config"
+	linkRep "github.com/kuzkuss/url_service/internal/link/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+// SinkI receives link events relayed from the outbox. Delivery is at least once:
+// the event is passed again if any sink fails, so sinks must tolerate duplicates.
+type SinkI interface {
+	Publish(ctx context.Context, event models.LinkEvent) error
+}
+
+// SinkFunc adapts function to SinkI.
+type SinkFunc func(ctx context.Context, event models.LinkEvent) error
+
+func (f SinkFunc) Publish(ctx context.Context, event models.LinkEvent) error {
+	return f(ctx, event)
+}
+
+// NewFeedSink appends events to the feed, every instance of the service publishes
+// them to its watchers (see Feed).
+func NewFeedSink(linkRepository linkRep.RepositoryI) SinkI {
+	return SinkFunc(func(ctx context.Context, event models.LinkEvent) error {
+		return linkRepository.AppendFeed(ctx, models.NewLinkFeedEvent(event))
+	})
+}
+
+// NewLogSink writes every event to logger.
+func NewLogSink(logger *slog.Logger) SinkI {
+	return SinkFunc(func(ctx context.Context, event models.LinkEvent) error {
+		logger.InfoContext(ctx, "link event", "type", event.Type, "short_link", event.Link.ShortLink,
+			"owner_id", event.Link.OwnerID, "event_time", event.Time)
+		return nil
+	})
+}
+
+type sink struct {
+	name string
+	SinkI
+}
+
+// Relay publishes events of the outbox to sinks in order of the outbox. If an
+// event fails in a sink, later events of the same short link wait for it, so
+// sinks get events of a link in order; events of other links are not delayed.
+type Relay struct {
+	linkRepository linkRep.RepositoryI
+	conf           config.OutboxConfig
+	sinks          []sink
+	logger         *slog.Logger
+}
+
+func NewRelay(linkRepository linkRep.RepositoryI, conf config.OutboxConfig, logger *slog.Logger) *Relay {
+	return &Relay{
+		linkRepository: linkRepository,
+		conf:           conf,
+		logger:         logger,
+	}
+}
+
+// AddSink adds sink identified by name in logs, it must be called before Run.
+func (r *Relay) AddSink(name string, sinkI SinkI) {
+	r.sinks = append(r.sinks, sink{name: name, SinkI: sinkI})
+}
+
+// Run relays events every poll interval until ctx is done. Events are removed
+// from the outbox even without sinks.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.Relay(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes events waiting in the outbox, failed ones are left for the next call.
+func (r *Relay) Relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		var published int
+		processed, err := r.linkRepository.ProcessOutbox(ctx, r.conf.BatchSize, func(events []models.OutboxEvent) []int64 {
+			ids := r.publish(ctx, events)
+			published = len(ids)
+			return ids
+		})
+		if err != nil {
+			r.logger.ErrorContext(ctx, "link outbox processing failed", "error", err)
+			return
+		}
+
+		// the rest of the batch waits for failed events
+		if processed < r.conf.BatchSize || published == 0 {
+			return
+		}
+	}
+}
+
+// publish passes events to every sink and returns ids of the events accepted by all sinks.
+func (r *Relay) publish(ctx context.Context, events []models.OutboxEvent) []int64 {
+	published := make([]int64, 0, len(events))
+	// events of other links are published after a failed one
+	type linkKey struct{ domain, shortLink string }
+	failed := make(map[linkKey]bool)
+
+	for _, event := range events {
+		key := linkKey{event.Domain, event.ShortLink}
+		if failed[key] {
+			continue
+		}
+
+		linkEvent := event.LinkEvent()
+		for _, sink := range r.sinks {
+			if err := sink.Publish(ctx, linkEvent); err != nil {
+				r.logger.WarnContext(ctx, "link event is not published, it is relayed again", "sink", sink.name,
+					"type", event.Type, "domain", event.Domain, "short_link", event.ShortLink, "error", err)
+				failed[key] = true
+				break
+			}
+		}
+
+		if !failed[key] {
+			published = append(published, event.ID)
+		}
+	}
+
+	return published
+}
@@ -0,0 +1,119 @@
+package outbox_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	linkOutbox "github.com/kuzkuss/url_service/internal/link/outbox"
+	linkRep "github.com/kuzkuss/url_service/internal/link/repository/in_memory"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+// recorder is a sink failing events accepted by fail.
+type recorder struct {
+	mx     sync.Mutex
+	events []string
+	fail   func(event models.LinkEvent) bool
+}
+
+func (r *recorder) Publish(ctx context.Context, event models.LinkEvent) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.fail != nil && r.fail(event) {
+		return errors.New("sink error")
+	}
+	r.events = append(r.events, string(event.Type) + " " + event.Link.ShortLink)
+	return nil
+}
+
+func (r *recorder) recorded() []string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return append([]string(nil), r.events...)
+}
+
+func TestRelayOrdering(t *testing.T) {
+	repository := linkRep.New(nil)
+	ctx := context.Background()
+
+	require.NoError(t, repository.CreateLink(ctx, &models.Link{ShortLink: "first", OriginalLink: "original_first", OwnerID: "owner"}, nil))
+	require.NoError(t, repository.CreateLink(ctx, &models.Link{ShortLink: "second", OriginalLink: "original_second", OwnerID: "owner"}, nil))
+	_, err := repository.UpdateLink(ctx, models.LinkScope{OwnerID: "owner"},
+		&models.Link{ShortLink: "first", OriginalLink: "original_new"}, nil)
+	require.NoError(t, err)
+	_, err = repository.DeleteLink(ctx, models.LinkScope{OwnerID: "owner"}, "", "second", nil)
+	require.NoError(t, err)
+
+	broken := true
+	sink := &recorder{
+		fail: func(event models.LinkEvent) bool {
+			return broken && event.Link.ShortLink == "first"
+		},
+	}
+	relay := linkOutbox.NewRelay(repository, config.OutboxConfig{PollInterval: time.Hour, BatchSize: 10}, observability.NopLogger())
+	relay.AddSink("bus", linkOutbox.NewFeedSink(repository))
+	relay.AddSink("recorder", sink)
+
+	// events of the first link wait for its failed creation, the second link is not delayed
+	relay.Relay(ctx)
+	assert.Equal(t, []string{"created second", "deleted second"}, sink.recorded())
+
+	relay.Relay(ctx)
+	assert.Equal(t, []string{"created second", "deleted second"}, sink.recorded())
+
+	broken = false
+	relay.Relay(ctx)
+	assert.Equal(t, []string{"created second", "deleted second", "created first", "updated first"}, sink.recorded())
+
+	// the feed got the creation every time before the recorder failed it
+	feed, err := repository.SelectFeed(ctx, 0, 10)
+	require.NoError(t, err)
+	var published []string
+	for _, event := range feed {
+		published = append(published, string(event.Type) + " " + event.Link.ShortLink)
+	}
+	assert.Equal(t, []string{"created first", "created second", "deleted second", "created first",
+		"created first", "updated first"}, published)
+
+	relay.Relay(ctx)
+	assert.Len(t, sink.recorded(), 4)
+}
+
+func TestRelayRun(t *testing.T) {
+	repository := linkRep.New(nil)
+	sink := &recorder{}
+
+	relay := linkOutbox.NewRelay(repository, config.OutboxConfig{PollInterval: 10 * time.Millisecond, BatchSize: 2},
+		observability.NopLogger())
+	relay.AddSink("recorder", sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	for _, shortLink := range []string{"first", "second", "third"} {
+		err := repository.CreateLink(context.Background(), &models.Link{ShortLink: shortLink, OriginalLink: "original_" + shortLink}, nil)
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(sink.recorded()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"created first", "created second", "created third"}, sink.recorded())
+
+	cancel()
+	<-done
+}
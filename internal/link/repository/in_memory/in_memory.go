@@ -1,48 +1,274 @@
 package in_memory
 
 import (
+	"context"
+	"sort"
 	"sync"
+	"time"
 
+	auditRep "github.com/kuzkuss/url_service/internal/audit/repository"
 	"github.com/kuzkuss/url_service/internal/link/repository"
 	"github.com/kuzkuss/url_service/models"
 )
 
+// linkKey identifies the link by its short link on the domain.
+type linkKey struct {
+	domain string
+	shortLink string
+}
+
 type linkRepository struct {
     mx sync.RWMutex
-    store  map[string]string
+    store  map[linkKey]models.Link
+    outbox []models.OutboxEvent
+    lastEventID int64
+    // held while the outbox is processed
+    outboxMx sync.Mutex
+    feed []models.LinkFeedEvent
+    lastFeedID int64
+    auditRepository auditRep.RepositoryI
 }
 
-func New() repository.RepositoryI {
+// New returns the repository writing audit records of changes to auditRepository,
+// records are dropped if it is nil.
+func New(auditRepository auditRep.RepositoryI) repository.RepositoryI {
 	return &linkRepository {
-		store: make(map[string]string),
+		store: make(map[linkKey]models.Link),
+		auditRepository: auditRepository,
 	}
 }
 
-func (dbLink *linkRepository) CreateLink(link *models.Link) error {
+func (dbLink *linkRepository) CreateLink(ctx context.Context, link *models.Link, audit *models.AuditRecord) error {
+	now := time.Now()
+	link.CreatedAt, link.UpdatedAt = &now, &now
+
 	dbLink.mx.Lock()
-    dbLink.store[link.ShortLink] = link.OriginalLink
-	dbLink.mx.Unlock()
+	defer dbLink.mx.Unlock()
+
+	key := linkKey{link.Domain, link.ShortLink}
+	if _, ok := dbLink.store[key]; ok {
+		return models.ErrConflict
+	}
+	if err := dbLink.createAuditRecord(ctx, audit, nil, *link); err != nil {
+		return err
+	}
+	dbLink.store[key] = *link
+	dbLink.addEvent(models.LinkCreated, *link)
 	return nil
 }
 
-func (dbLink *linkRepository) SelectLinkByOriginalLink(originalLink string) (string, error) {
+func (dbLink *linkRepository) SelectLinkByOriginalLink(ctx context.Context, workspaceID string, domain string,
+	originalLink string) (string, error) {
+	dbLink.mx.RLock()
+	defer dbLink.mx.RUnlock()
+
 	for key, val := range dbLink.store {
-		if val == originalLink {
-			return key, nil
+		if (workspaceID == "" || val.WorkspaceID == workspaceID) && key.domain == domain && val.OriginalLink == originalLink {
+			return key.shortLink, nil
 		}
 	}
 
 	return "", models.ErrNotFound
 }
 
-func (dbLink *linkRepository) SelectLinkByShortLink(shortLink string) (string, error) {
+func (dbLink *linkRepository) SelectLinkByShortLink(ctx context.Context, domain string, shortLink string) (string, error) {
 	dbLink.mx.RLock()
-    val, ok := dbLink.store[shortLink]
+    val, ok := dbLink.store[linkKey{domain, shortLink}]
 	dbLink.mx.RUnlock()
 	if !ok {
 		return "", models.ErrNotFound
 	}
-    return val, nil
+    return val.OriginalLink, nil
+}
+
+func (dbLink *linkRepository) SelectLinks(ctx context.Context, scope models.LinkScope) ([]models.Link, error) {
+	dbLink.mx.RLock()
+	defer dbLink.mx.RUnlock()
+
+	links := make([]models.Link, 0)
+	for _, val := range dbLink.store {
+		if scope.Includes(val) {
+			links = append(links, val)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Domain != links[j].Domain {
+			return links[i].Domain < links[j].Domain
+		}
+		return links[i].ShortLink < links[j].ShortLink
+	})
+
+	return links, nil
+}
+
+func (dbLink *linkRepository) IncrementClicks(ctx context.Context, domain string, shortLink string) (*models.Link, error) {
+	dbLink.mx.Lock()
+	defer dbLink.mx.Unlock()
+
+	key := linkKey{domain, shortLink}
+	val, ok := dbLink.store[key]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	val.Clicks++
+	dbLink.store[key] = val
+	return &val, nil
+}
+
+func (dbLink *linkRepository) UpdateLink(ctx context.Context, scope models.LinkScope, link *models.Link,
+	audit *models.AuditRecord) (*models.Link, error) {
+	dbLink.mx.Lock()
+	defer dbLink.mx.Unlock()
+
+	key := linkKey{link.Domain, link.ShortLink}
+	val, ok := dbLink.store[key]
+	if !ok || !scope.Includes(val) {
+		return nil, models.ErrNotFound
+	}
+
+	for otherKey, other := range dbLink.store {
+		if otherKey != key && otherKey.domain == key.domain && other.WorkspaceID == val.WorkspaceID &&
+			other.OriginalLink == link.OriginalLink {
+			return nil, models.ErrConflict
+		}
+	}
+
+	before := val
+	after := val
+	after.OriginalLink = link.OriginalLink
+	after.UpdatedAt = nil
+	if err := dbLink.createAuditRecord(ctx, audit, before, after); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	val.OriginalLink = link.OriginalLink
+	val.UpdatedAt = &now
+	dbLink.store[key] = val
+
+	changed := *link
+	changed.OwnerID = val.OwnerID
+	changed.WorkspaceID = val.WorkspaceID
+	dbLink.addEvent(models.LinkUpdated, changed)
+	return &before, nil
+}
+
+func (dbLink *linkRepository) DeleteLink(ctx context.Context, scope models.LinkScope, domain string,
+	shortLink string, audit *models.AuditRecord) (*models.Link, error) {
+	dbLink.mx.Lock()
+	defer dbLink.mx.Unlock()
+
+	key := linkKey{domain, shortLink}
+	val, ok := dbLink.store[key]
+	if !ok || !scope.Includes(val) {
+		return nil, models.ErrNotFound
+	}
+
+	if err := dbLink.createAuditRecord(ctx, audit, val, nil); err != nil {
+		return nil, err
+	}
+	delete(dbLink.store, key)
+	dbLink.addEvent(models.LinkDeleted,
+		models.Link{ShortLink: shortLink, Domain: domain, OwnerID: val.OwnerID, WorkspaceID: val.WorkspaceID})
+	return &val, nil
 }
 
+func (dbLink *linkRepository) ProcessOutbox(ctx context.Context, limit int,
+	handle func(events []models.OutboxEvent) []int64) (int, error) {
+	if !dbLink.outboxMx.TryLock() {
+		return 0, nil
+	}
+	defer dbLink.outboxMx.Unlock()
 
+	// handle is called without holding the store, so links can be changed meanwhile
+	dbLink.mx.RLock()
+	events := append([]models.OutboxEvent(nil), dbLink.outbox[:min(limit, len(dbLink.outbox))]...)
+	dbLink.mx.RUnlock()
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	published := make(map[int64]bool)
+	for _, id := range handle(events) {
+		published[id] = true
+	}
+
+	dbLink.mx.Lock()
+	defer dbLink.mx.Unlock()
+
+	outbox := dbLink.outbox[:0]
+	for _, event := range dbLink.outbox {
+		if !published[event.ID] {
+			outbox = append(outbox, event)
+		}
+	}
+	dbLink.outbox = outbox
+	return len(events), nil
+}
+
+func (dbLink *linkRepository) AppendFeed(ctx context.Context, event *models.LinkFeedEvent) error {
+	now := time.Now()
+	event.AppendedAt = &now
+
+	dbLink.mx.Lock()
+	dbLink.lastFeedID++
+	event.ID = dbLink.lastFeedID
+	dbLink.feed = append(dbLink.feed, *event)
+	dbLink.mx.Unlock()
+	return nil
+}
+
+func (dbLink *linkRepository) SelectFeed(ctx context.Context, afterID int64, limit int) ([]models.LinkFeedEvent, error) {
+	dbLink.mx.RLock()
+	defer dbLink.mx.RUnlock()
+
+	start := sort.Search(len(dbLink.feed), func(i int) bool {
+		return dbLink.feed[i].ID > afterID
+	})
+	events := dbLink.feed[start:]
+	return append(make([]models.LinkFeedEvent, 0), events[:min(limit, len(events))]...), nil
+}
+
+func (dbLink *linkRepository) LastFeedID(ctx context.Context) (int64, error) {
+	dbLink.mx.RLock()
+	defer dbLink.mx.RUnlock()
+
+	if len(dbLink.feed) == 0 {
+		return 0, nil
+	}
+	return dbLink.feed[len(dbLink.feed)-1].ID, nil
+}
+
+func (dbLink *linkRepository) DeleteFeed(ctx context.Context, before time.Time) error {
+	dbLink.mx.Lock()
+	defer dbLink.mx.Unlock()
+
+	start := sort.Search(len(dbLink.feed), func(i int) bool {
+		return !dbLink.feed[i].AppendedAt.Before(before)
+	})
+	dbLink.feed = append([]models.LinkFeedEvent(nil), dbLink.feed[start:]...)
+	return nil
+}
+
+// addEvent writes event of the link change to the outbox, the store must be locked.
+func (dbLink *linkRepository) addEvent(eventType models.LinkEventType, link models.Link) {
+	event := models.NewOutboxEvent(eventType, link)
+	dbLink.lastEventID++
+	event.ID = dbLink.lastEventID
+	dbLink.outbox = append(dbLink.outbox, *event)
+}
+
+// createAuditRecord writes the record of the change with states of the link unless
+// it is nil, before the change is made. The store must be locked.
+func (dbLink *linkRepository) createAuditRecord(ctx context.Context, audit *models.AuditRecord,
+	before interface{}, after interface{}) error {
+	if audit == nil || dbLink.auditRepository == nil {
+		return nil
+	}
+	if err := audit.SetStates(before, after); err != nil {
+		return err
+	}
+	return dbLink.auditRepository.CreateRecord(ctx, audit)
+}
@@ -1,13 +1,18 @@
 package in_memory_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/kuzkuss/url_service/models"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	auditInMem "github.com/kuzkuss/url_service/internal/audit/repository/in_memory"
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/repository/mocks"
 	linkRep "github.com/kuzkuss/url_service/internal/link/repository/in_memory"
 )
 
@@ -28,7 +33,7 @@ func TestUsecaseCreateLink(t *testing.T) {
 		ShortLink: "short_link_success",
 	}
 
-	repository := linkRep.New()
+	repository := linkRep.New(nil)
 
 	cases := map[string]TestCaseCreate {
 		"success": {
@@ -39,12 +44,32 @@ func TestUsecaseCreateLink(t *testing.T) {
 
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
-			err := repository.CreateLink(test.ArgData)
+			err := repository.CreateLink(context.Background(), test.ArgData, nil)
 			require.Equal(t, test.Error, errors.Cause(err))
 		})
 	}
 }
 
+func TestUsecaseCreateLinkAfterUpdate(t *testing.T) {
+	ctx := context.Background()
+	repository := linkRep.New(nil)
+
+	alice := models.Link{OriginalLink: "https://x.example", ShortLink: "short_link", OwnerID: "alice"}
+	require.NoError(t, repository.CreateLink(ctx, &alice, nil))
+	_, err := repository.UpdateLink(ctx, models.LinkScope{OwnerID: "alice"},
+		&models.Link{OriginalLink: "https://y.example", ShortLink: "short_link"}, nil)
+	require.NoError(t, err)
+
+	// the short link generated for the original link again is taken by the updated link
+	err = repository.CreateLink(ctx, &models.Link{OriginalLink: "https://x.example", ShortLink: "short_link", OwnerID: "bob"}, nil)
+	assert.Equal(t, models.ErrConflict, err)
+
+	links, err := repository.SelectLinks(ctx, models.LinkScope{OwnerID: "alice"})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://y.example", links[0].OriginalLink)
+}
+
 func TestUsecaseSelectLinkByShortLink(t *testing.T) {
 	linkSuccess := models.Link {
 		OriginalLink: "original_link_success",
@@ -56,7 +81,7 @@ func TestUsecaseSelectLinkByShortLink(t *testing.T) {
 		ShortLink: "short_link_not_found",
 	}
 
-	repository := linkRep.New()
+	repository := linkRep.New(nil)
 
 	cases := map[string]TestCaseSelect {
 		"success": {
@@ -74,9 +99,9 @@ func TestUsecaseSelectLinkByShortLink(t *testing.T) {
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
 			if name == "success" {
-				repository.CreateLink(&linkSuccess)
+				repository.CreateLink(context.Background(), &linkSuccess, nil)
 			}
-			actualRes, err := repository.SelectLinkByShortLink(test.ArgData)
+			actualRes, err := repository.SelectLinkByShortLink(context.Background(), "", test.ArgData)
 			require.Equal(t, test.Error, errors.Cause(err))
 			assert.Equal(t, test.ExpectedRes, actualRes)
 		})
@@ -94,7 +119,7 @@ func TestUsecaseSelectLinkByOriginalLink(t *testing.T) {
 		ShortLink: "",
 	}
 
-	repository := linkRep.New()
+	repository := linkRep.New(nil)
 
 	cases := map[string]TestCaseSelect {
 		"success": {
@@ -112,13 +137,435 @@ func TestUsecaseSelectLinkByOriginalLink(t *testing.T) {
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
 			if name == "success" {
-				repository.CreateLink(&linkSuccess)
+				repository.CreateLink(context.Background(), &linkSuccess, nil)
 			}
-			actualRes, err := repository.SelectLinkByOriginalLink(test.ArgData)
+			actualRes, err := repository.SelectLinkByOriginalLink(context.Background(), "", "", test.ArgData)
 			require.Equal(t, test.Error, errors.Cause(err))
 			assert.Equal(t, test.ExpectedRes, actualRes)
 		})
 	}
 }
 
+func TestUsecaseSelectLinks(t *testing.T) {
+	linkOwner := models.Link {
+		OriginalLink: "original_link_owner",
+		ShortLink: "short_link_owner",
+		OwnerID: "owner",
+	}
+
+	linkOther := models.Link {
+		OriginalLink: "original_link_other",
+		ShortLink: "short_link_other",
+		OwnerID: "other",
+	}
+
+	repository := linkRep.New(nil)
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOwner, nil))
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOther, nil))
+
+	actualRes, err := repository.SelectLinks(context.Background(), models.LinkScope{OwnerID: "owner"})
+	require.NoError(t, err)
+	assert.Equal(t, []models.Link{linkOwner}, actualRes)
+
+	actualRes, err = repository.SelectLinks(context.Background(), models.LinkScope{OwnerID: "nobody"})
+	require.NoError(t, err)
+	assert.Empty(t, actualRes)
+}
+
+func TestUsecaseIncrementClicks(t *testing.T) {
+	repository := linkRep.New(nil)
+
+	err := repository.CreateLink(context.Background(), &models.Link{ShortLink: "short_link", OriginalLink: "original_link"}, nil)
+	require.NoError(t, err)
+
+	for clicks := int64(1); clicks <= 2; clicks++ {
+		link, err := repository.IncrementClicks(context.Background(), "", "short_link")
+		require.NoError(t, err)
+		assert.Equal(t, "original_link", link.OriginalLink)
+		assert.Equal(t, clicks, link.Clicks)
+	}
+
+	_, err = repository.IncrementClicks(context.Background(), "", "short_link_not_found")
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+}
+
+func TestUsecaseLinkDomains(t *testing.T) {
+	linkFirst := models.Link {
+		OriginalLink: "original_link",
+		ShortLink: "short_link",
+		Domain: "first.io",
+		OwnerID: "owner",
+	}
+
+	linkSecond := models.Link {
+		OriginalLink: "original_link",
+		ShortLink: "short_link",
+		Domain: "second.io",
+		OwnerID: "owner",
+	}
+
+	repository := linkRep.New(nil)
+	require.NoError(t, repository.CreateLink(context.Background(), &linkFirst, nil))
+	require.NoError(t, repository.CreateLink(context.Background(), &linkSecond, nil))
+
+	_, err := repository.UpdateLink(context.Background(), models.LinkScope{OwnerID: linkFirst.OwnerID}, &models.Link {
+		OriginalLink: "original_link_updated",
+		ShortLink: linkFirst.ShortLink,
+		Domain: linkFirst.Domain,
+	}, nil)
+	require.NoError(t, err)
+
+	actualRes, err := repository.SelectLinkByShortLink(context.Background(), linkSecond.Domain, linkSecond.ShortLink)
+	require.NoError(t, err)
+	assert.Equal(t, linkSecond.OriginalLink, actualRes)
+
+	_, err = repository.SelectLinkByShortLink(context.Background(), "", linkFirst.ShortLink)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	shortLink, err := repository.SelectLinkByOriginalLink(context.Background(), "", linkSecond.Domain, linkSecond.OriginalLink)
+	require.NoError(t, err)
+	assert.Equal(t, linkSecond.ShortLink, shortLink)
+
+	deleted, err := repository.DeleteLink(context.Background(), models.LinkScope{OwnerID: linkFirst.OwnerID}, linkFirst.Domain,
+		linkFirst.ShortLink, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "original_link_updated", deleted.OriginalLink)
+
+	link, err := repository.IncrementClicks(context.Background(), linkSecond.Domain, linkSecond.ShortLink)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), link.Clicks)
+}
+
+func TestUsecaseLinkWorkspaces(t *testing.T) {
+	linkFirst := models.Link {
+		OriginalLink: "original_link",
+		ShortLink: "short_link_first",
+		OwnerID: "owner",
+		WorkspaceID: "first",
+	}
+
+	linkSecond := models.Link {
+		OriginalLink: "original_link",
+		ShortLink: "short_link_second",
+		OwnerID: "owner",
+		WorkspaceID: "second",
+	}
+
+	repository := linkRep.New(nil)
+	require.NoError(t, repository.CreateLink(context.Background(), &linkFirst, nil))
+	require.NoError(t, repository.CreateLink(context.Background(), &linkSecond, nil))
+
+	shortLink, err := repository.SelectLinkByOriginalLink(context.Background(), "second", "", "original_link")
+	require.NoError(t, err)
+	assert.Equal(t, linkSecond.ShortLink, shortLink)
+
+	_, err = repository.SelectLinkByOriginalLink(context.Background(), "third", "", "original_link")
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	links, err := repository.SelectLinks(context.Background(), models.LinkScope{WorkspaceID: "first", OwnerID: "owner"})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, linkFirst.ShortLink, links[0].ShortLink)
+
+	_, err = repository.UpdateLink(context.Background(),
+		models.LinkScope{WorkspaceID: linkSecond.WorkspaceID, OwnerID: linkFirst.OwnerID}, &models.Link {
+			OriginalLink: "original_link_updated",
+			ShortLink: linkFirst.ShortLink,
+		}, nil)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	_, err = repository.DeleteLink(context.Background(),
+		models.LinkScope{WorkspaceID: "second", OwnerID: linkFirst.OwnerID}, "", linkFirst.ShortLink, nil)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	_, err = repository.DeleteLink(context.Background(),
+		models.LinkScope{WorkspaceID: "first", OwnerID: linkFirst.OwnerID}, "", linkFirst.ShortLink, nil)
+	require.NoError(t, err)
+}
+
+func TestUsecaseLinkAnyOwner(t *testing.T) {
+	linkOwner := models.Link {
+		OriginalLink: "original_link_owner",
+		ShortLink: "short_link_owner",
+		OwnerID: "owner",
+		WorkspaceID: "team",
+	}
+
+	linkOther := models.Link {
+		OriginalLink: "original_link_other",
+		ShortLink: "short_link_other",
+		OwnerID: "other",
+		WorkspaceID: "team",
+	}
+
+	repository := linkRep.New(nil)
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOwner, nil))
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOther, nil))
+
+	// an empty owner is not a wildcard
+	links, err := repository.SelectLinks(context.Background(), models.LinkScope{WorkspaceID: "team"})
+	require.NoError(t, err)
+	assert.Empty(t, links)
+
+	_, err = repository.DeleteLink(context.Background(), models.LinkScope{WorkspaceID: "team"}, "", linkOther.ShortLink, nil)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	allOwners := models.LinkScope{WorkspaceID: "team", AllOwners: true}
+	links, err = repository.SelectLinks(context.Background(), allOwners)
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+
+	before, err := repository.UpdateLink(context.Background(), allOwners, &models.Link {
+		OriginalLink: "original_link_updated",
+		ShortLink: linkOther.ShortLink,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, linkOther.OwnerID, before.OwnerID)
+
+	deleted, err := repository.DeleteLink(context.Background(), allOwners, "", linkOther.ShortLink, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "original_link_updated", deleted.OriginalLink)
+
+	var events []models.OutboxEvent
+	_, err = repository.ProcessOutbox(context.Background(), 10, func(batch []models.OutboxEvent) []int64 {
+		events = batch
+		return nil
+	})
+	require.NoError(t, err)
+	for _, event := range events[2:] {
+		assert.Equal(t, linkOther.OwnerID, event.OwnerID)
+		assert.Equal(t, linkOther.WorkspaceID, event.WorkspaceID)
+	}
+}
+
+func TestUsecaseUpdateLink(t *testing.T) {
+	linkOwner := models.Link {
+		OriginalLink: "original_link_owner",
+		ShortLink: "short_link_owner",
+		OwnerID: "owner",
+	}
+
+	linkOther := models.Link {
+		OriginalLink: "original_link_other",
+		ShortLink: "short_link_other",
+		OwnerID: "other",
+	}
+
+	repository := linkRep.New(nil)
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOwner, nil))
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOther, nil))
+
+	cases := map[string]TestCaseCreate {
+		"success": {
+			ArgData: &models.Link {
+				OriginalLink: "original_link_updated",
+				ShortLink: linkOwner.ShortLink,
+				OwnerID: linkOwner.OwnerID,
+			},
+			Error: nil,
+		},
+		"foreign_owner": {
+			ArgData: &models.Link {
+				OriginalLink: "original_link_updated_other",
+				ShortLink: linkOther.ShortLink,
+				OwnerID: linkOwner.OwnerID,
+			},
+			Error: models.ErrNotFound,
+		},
+		"conflict": {
+			ArgData: &models.Link {
+				OriginalLink: linkOther.OriginalLink,
+				ShortLink: linkOwner.ShortLink,
+				OwnerID: linkOwner.OwnerID,
+			},
+			Error: models.ErrConflict,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			before, err := repository.UpdateLink(context.Background(), models.LinkScope{OwnerID: test.ArgData.OwnerID}, test.ArgData, nil)
+			require.Equal(t, test.Error, errors.Cause(err))
+			if err == nil {
+				assert.Equal(t, linkOwner.OriginalLink, before.OriginalLink)
+			}
+		})
+	}
+
+	actualRes, err := repository.SelectLinkByShortLink(context.Background(), "", linkOwner.ShortLink)
+	require.NoError(t, err)
+	assert.Equal(t, "original_link_updated", actualRes)
+
+	actualRes, err = repository.SelectLinkByShortLink(context.Background(), "", linkOther.ShortLink)
+	require.NoError(t, err)
+	assert.Equal(t, linkOther.OriginalLink, actualRes)
+}
+
+func TestUsecaseDeleteLink(t *testing.T) {
+	linkOwner := models.Link {
+		OriginalLink: "original_link_owner",
+		ShortLink: "short_link_owner",
+		OwnerID: "owner",
+	}
+
+	repository := linkRep.New(nil)
+	require.NoError(t, repository.CreateLink(context.Background(), &linkOwner, nil))
+
+	_, err := repository.DeleteLink(context.Background(), models.LinkScope{OwnerID: "other"}, "", linkOwner.ShortLink, nil)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	deleted, err := repository.DeleteLink(context.Background(), models.LinkScope{OwnerID: linkOwner.OwnerID}, "",
+		linkOwner.ShortLink, nil)
+	require.NoError(t, err)
+	assert.Equal(t, linkOwner, *deleted)
+
+	_, err = repository.SelectLinkByShortLink(context.Background(), "", linkOwner.ShortLink)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+}
+
+func TestUsecaseLinkAudit(t *testing.T) {
+	ctx := context.Background()
+	auditRepository := auditInMem.New()
+	repository := linkRep.New(auditRepository)
+
+	require.NoError(t, repository.CreateLink(ctx, &models.Link{ShortLink: "short_link", OriginalLink: "original_link",
+		OwnerID: "owner"}, &models.AuditRecord{Action: models.AuditLinkCreate}))
+	_, err := repository.UpdateLink(ctx, models.LinkScope{OwnerID: "owner"},
+		&models.Link{ShortLink: "short_link", OriginalLink: "original_new"}, &models.AuditRecord{Action: models.AuditLinkUpdate})
+	require.NoError(t, err)
+	_, err = repository.DeleteLink(ctx, models.LinkScope{OwnerID: "owner"}, "", "short_link",
+		&models.AuditRecord{Action: models.AuditLinkDelete})
+	require.NoError(t, err)
+
+	records, err := auditRepository.SelectRecords(ctx, models.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Nil(t, records[2].Before)
+	assert.Contains(t, string(records[2].After), `"original_link":"original_link"`)
+	assert.Contains(t, string(records[1].Before), `"original_link":"original_link"`)
+	assert.Contains(t, string(records[1].After), `"original_link":"original_new"`)
+	assert.Contains(t, string(records[0].Before), `"original_link":"original_new"`)
+	assert.Nil(t, records[0].After)
+
+	t.Run("audit_error", func(t *testing.T) {
+		auditErr := errors.New("error")
+		mockAuditRepo := auditMocks.NewRepositoryI(t)
+		mockAuditRepo.On("CreateRecord", mock.Anything, mock.Anything).Return(auditErr).Twice()
+
+		repository := linkRep.New(mockAuditRepo)
+
+		// changes which can not be recorded are not made
+		err := repository.CreateLink(ctx, &models.Link{ShortLink: "short_link", OriginalLink: "original_link"},
+			&models.AuditRecord{Action: models.AuditLinkCreate})
+		assert.Equal(t, auditErr, err)
+		_, err = repository.SelectLinkByShortLink(ctx, "", "short_link")
+		assert.Equal(t, models.ErrNotFound, err)
+
+		require.NoError(t, repository.CreateLink(ctx, &models.Link{ShortLink: "short_link", OriginalLink: "original_link"}, nil))
+		_, err = repository.DeleteLink(ctx, models.LinkScope{}, "", "short_link", &models.AuditRecord{Action: models.AuditLinkDelete})
+		assert.Equal(t, auditErr, err)
+		_, err = repository.SelectLinkByShortLink(ctx, "", "short_link")
+		assert.NoError(t, err)
+
+		processed, err := repository.ProcessOutbox(ctx, 10, func(events []models.OutboxEvent) []int64 { return nil })
+		require.NoError(t, err)
+		assert.Equal(t, 1, processed)
+	})
+}
+
+func TestUsecaseProcessOutbox(t *testing.T) {
+	repository := linkRep.New(nil)
+	ctx := context.Background()
+
+	require.NoError(t, repository.CreateLink(ctx, &models.Link{ShortLink: "first", OriginalLink: "original_first", OwnerID: "owner"}, nil))
+	require.NoError(t, repository.CreateLink(ctx, &models.Link{ShortLink: "second", OriginalLink: "original_second", OwnerID: "owner"}, nil))
+	_, err := repository.UpdateLink(ctx, models.LinkScope{OwnerID: "owner"},
+		&models.Link{ShortLink: "first", OriginalLink: "original_new"}, nil)
+	require.NoError(t, err)
+	_, err = repository.DeleteLink(ctx, models.LinkScope{OwnerID: "other"}, "", "first", nil)
+	require.Equal(t, models.ErrNotFound, err)
+	_, err = repository.DeleteLink(ctx, models.LinkScope{OwnerID: "owner"}, "", "second", nil)
+	require.NoError(t, err)
+
+	var handled []models.OutboxEvent
+	processed, err := repository.ProcessOutbox(ctx, 3, func(events []models.OutboxEvent) []int64 {
+		handled = events
+		// the update of the first link is not published
+		return []int64{events[0].ID, events[1].ID}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, processed)
+	require.Len(t, handled, 3)
+	assert.Equal(t, models.LinkCreated, handled[0].Type)
+	assert.Equal(t, "owner", handled[0].LinkEvent().Link.OwnerID)
+	assert.Equal(t, models.LinkUpdated, handled[2].Type)
+	assert.Equal(t, "original_new", handled[2].Link.OriginalLink)
+
+	processed, err = repository.ProcessOutbox(ctx, 3, func(events []models.OutboxEvent) []int64 {
+		handled = events
+		// processed by one caller at a time
+		_, err := repository.ProcessOutbox(ctx, 3, func([]models.OutboxEvent) []int64 {
+			t.Fatal("outbox is processed concurrently")
+			return nil
+		})
+		require.NoError(t, err)
+		return []int64{events[0].ID, events[1].ID}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, processed)
+	assert.Equal(t, []models.LinkEventType{models.LinkUpdated, models.LinkDeleted},
+		[]models.LinkEventType{handled[0].Type, handled[1].Type})
+
+	processed, err = repository.ProcessOutbox(ctx, 3, func([]models.OutboxEvent) []int64 {
+		t.Fatal("empty outbox is processed")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, processed)
+}
+
+func TestUsecaseFeed(t *testing.T) {
+	repository := linkRep.New(nil)
+	ctx := context.Background()
+
+	lastID, err := repository.LastFeedID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lastID)
+
+	for _, shortLink := range []string{"first", "second", "third"} {
+		event := models.NewLinkFeedEvent(models.LinkEvent{Type: models.LinkCreated, Link: models.Link{ShortLink: shortLink}})
+		require.NoError(t, repository.AppendFeed(ctx, event))
+		assert.NotZero(t, event.ID)
+		lastID = event.ID
+	}
+
+	id, err := repository.LastFeedID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, lastID, id)
+
+	events, err := repository.SelectFeed(ctx, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "first", events[0].Link.ShortLink)
+	assert.Equal(t, "second", events[1].Link.ShortLink)
+
+	events, err = repository.SelectFeed(ctx, events[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "third", events[0].Link.ShortLink)
 
+	require.NoError(t, repository.DeleteFeed(ctx, time.Now().Add(-time.Hour)))
+	events, err = repository.SelectFeed(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	require.NoError(t, repository.DeleteFeed(ctx, time.Now().Add(time.Second)))
+	events, err = repository.SelectFeed(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// ids are not reused after pruning
+	event := models.NewLinkFeedEvent(models.LinkEvent{Type: models.LinkDeleted, Link: models.Link{ShortLink: "first"}})
+	require.NoError(t, repository.AppendFeed(ctx, event))
+	assert.Greater(t, event.ID, lastID)
+}
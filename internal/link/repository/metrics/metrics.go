@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/link/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "links"
+
+type linkRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps link repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &linkRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbLink *linkRepository) SelectLinkByOriginalLink(ctx context.Context, workspaceID string, domain string,
+	originalLink string) (string, error) {
+	start := time.Now()
+	shortLink, err := dbLink.repository.SelectLinkByOriginalLink(ctx, workspaceID, domain, originalLink)
+	dbLink.metrics.Observe(repositoryName, "SelectLinkByOriginalLink", start, err)
+	return shortLink, err
+}
+
+func (dbLink *linkRepository) SelectLinkByShortLink(ctx context.Context, domain string, shortLink string) (string, error) {
+	start := time.Now()
+	originalLink, err := dbLink.repository.SelectLinkByShortLink(ctx, domain, shortLink)
+	dbLink.metrics.Observe(repositoryName, "SelectLinkByShortLink", start, err)
+	return originalLink, err
+}
+
+func (dbLink *linkRepository) SelectLinks(ctx context.Context, scope models.LinkScope) ([]models.Link, error) {
+	start := time.Now()
+	links, err := dbLink.repository.SelectLinks(ctx, scope)
+	dbLink.metrics.Observe(repositoryName, "SelectLinks", start, err)
+	return links, err
+}
+
+func (dbLink *linkRepository) IncrementClicks(ctx context.Context, domain string, shortLink string) (*models.Link, error) {
+	start := time.Now()
+	link, err := dbLink.repository.IncrementClicks(ctx, domain, shortLink)
+	dbLink.metrics.Observe(repositoryName, "IncrementClicks", start, err)
+	return link, err
+}
+
+func (dbLink *linkRepository) CreateLink(ctx context.Context, link *models.Link, audit *models.AuditRecord) error {
+	start := time.Now()
+	err := dbLink.repository.CreateLink(ctx, link, audit)
+	dbLink.metrics.Observe(repositoryName, "CreateLink", start, err)
+	return err
+}
+
+func (dbLink *linkRepository) UpdateLink(ctx context.Context, scope models.LinkScope, link *models.Link,
+	audit *models.AuditRecord) (*models.Link, error) {
+	start := time.Now()
+	before, err := dbLink.repository.UpdateLink(ctx, scope, link, audit)
+	dbLink.metrics.Observe(repositoryName, "UpdateLink", start, err)
+	return before, err
+}
+
+func (dbLink *linkRepository) DeleteLink(ctx context.Context, scope models.LinkScope, domain string,
+	shortLink string, audit *models.AuditRecord) (*models.Link, error) {
+	start := time.Now()
+	deleted, err := dbLink.repository.DeleteLink(ctx, scope, domain, shortLink, audit)
+	dbLink.metrics.Observe(repositoryName, "DeleteLink", start, err)
+	return deleted, err
+}
+
+func (dbLink *linkRepository) ProcessOutbox(ctx context.Context, limit int,
+	handle func(events []models.OutboxEvent) []int64) (int, error) {
+	start := time.Now()
+	processed, err := dbLink.repository.ProcessOutbox(ctx, limit, handle)
+	dbLink.metrics.Observe(repositoryName, "ProcessOutbox", start, err)
+	return processed, err
+}
+
+func (dbLink *linkRepository) AppendFeed(ctx context.Context, event *models.LinkFeedEvent) error {
+	start := time.Now()
+	err := dbLink.repository.AppendFeed(ctx, event)
+	dbLink.metrics.Observe(repositoryName, "AppendFeed", start, err)
+	return err
+}
+
+func (dbLink *linkRepository) SelectFeed(ctx context.Context, afterID int64, limit int) ([]models.LinkFeedEvent, error) {
+	start := time.Now()
+	events, err := dbLink.repository.SelectFeed(ctx, afterID, limit)
+	dbLink.metrics.Observe(repositoryName, "SelectFeed", start, err)
+	return events, err
+}
+
+func (dbLink *linkRepository) LastFeedID(ctx context.Context) (int64, error) {
+	start := time.Now()
+	id, err := dbLink.repository.LastFeedID(ctx)
+	dbLink.metrics.Observe(repositoryName, "LastFeedID", start, err)
+	return id, err
+}
+
+func (dbLink *linkRepository) DeleteFeed(ctx context.Context, before time.Time) error {
+	start := time.Now()
+	err := dbLink.repository.DeleteFeed(ctx, before)
+	dbLink.metrics.Observe(repositoryName, "DeleteFeed", start, err)
+	return err
+}
@@ -0,0 +1,41 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	linkMetrics "github.com/kuzkuss/url_service/internal/link/repository/metrics"
+	linkMocks "github.com/kuzkuss/url_service/internal/link/repository/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestMetricsRepository(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	mockLinkRepository := linkMocks.NewRepositoryI(t)
+	mockLinkRepository.On("SelectLinkByShortLink", mock.Anything, "", "short_link_success").Return("original_link", nil)
+	mockLinkRepository.On("SelectLinkByShortLink", mock.Anything, "", "short_link_not_found").Return("", models.ErrNotFound)
+	mockLinkRepository.On("CreateLink", mock.Anything, &models.Link{OriginalLink: "original_link"}, (*models.AuditRecord)(nil)).Return(nil)
+
+	repo := linkMetrics.New(mockLinkRepository, observability.NewRepositoryMetrics(registry, "in_memory"))
+
+	originalLink, err := repo.SelectLinkByShortLink(context.Background(), "", "short_link_success")
+	require.NoError(t, err)
+	assert.Equal(t, "original_link", originalLink)
+
+	_, err = repo.SelectLinkByShortLink(context.Background(), "", "short_link_not_found")
+	require.Equal(t, models.ErrNotFound, err)
+
+	err = repo.CreateLink(context.Background(), &models.Link{OriginalLink: "original_link"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, testutil.CollectAndCount(registry, "repository_query_duration_seconds"))
+	mockLinkRepository.AssertExpectations(t)
+}
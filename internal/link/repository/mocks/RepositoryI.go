@@ -3,8 +3,10 @@
 package mocks
 
 import (
+	context "context"
 	models "github.com/kuzkuss/url_service/models"
 	mock "github.com/stretchr/testify/mock"
+	time "time"
 )
 
 // RepositoryI is an autogenerated mock type for the RepositoryI type
@@ -12,13 +14,13 @@ type RepositoryI struct {
 	mock.Mock
 }
 
-// CreateLink provides a mock function with given fields: link
-func (_m *RepositoryI) CreateLink(link *models.Link) error {
-	ret := _m.Called(link)
+// AppendFeed provides a mock function with given fields: ctx, event
+func (_m *RepositoryI) AppendFeed(ctx context.Context, event *models.LinkFeedEvent) error {
+	ret := _m.Called(ctx, event)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.Link) error); ok {
-		r0 = rf(link)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LinkFeedEvent) error); ok {
+		r0 = rf(ctx, event)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -26,20 +28,159 @@ func (_m *RepositoryI) CreateLink(link *models.Link) error {
 	return r0
 }
 
-// SelectLinkByOriginalLink provides a mock function with given fields: originalLink
-func (_m *RepositoryI) SelectLinkByOriginalLink(originalLink string) (string, error) {
-	ret := _m.Called(originalLink)
+// CreateLink provides a mock function with given fields: ctx, link, audit
+func (_m *RepositoryI) CreateLink(ctx context.Context, link *models.Link, audit *models.AuditRecord) error {
+	ret := _m.Called(ctx, link, audit)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Link, *models.AuditRecord) error); ok {
+		r0 = rf(ctx, link, audit)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteFeed provides a mock function with given fields: ctx, before
+func (_m *RepositoryI) DeleteFeed(ctx context.Context, before time.Time) error {
+	ret := _m.Called(ctx, before)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteLink provides a mock function with given fields: ctx, scope, domain, shortLink, audit
+func (_m *RepositoryI) DeleteLink(ctx context.Context, scope models.LinkScope, domain string, shortLink string, audit *models.AuditRecord) (*models.Link, error) {
+	ret := _m.Called(ctx, scope, domain, shortLink, audit)
+
+	var r0 *models.Link
+	if rf, ok := ret.Get(0).(func(context.Context, models.LinkScope, string, string, *models.AuditRecord) *models.Link); ok {
+		r0 = rf(ctx, scope, domain, shortLink, audit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, models.LinkScope, string, string, *models.AuditRecord) error); ok {
+		r1 = rf(ctx, scope, domain, shortLink, audit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementClicks provides a mock function with given fields: ctx, domain, shortLink
+func (_m *RepositoryI) IncrementClicks(ctx context.Context, domain string, shortLink string) (*models.Link, error) {
+	ret := _m.Called(ctx, domain, shortLink)
+
+	var r0 *models.Link
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.Link); ok {
+		r0 = rf(ctx, domain, shortLink)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, shortLink)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastFeedID provides a mock function with given fields: ctx
+func (_m *RepositoryI) LastFeedID(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProcessOutbox provides a mock function with given fields: ctx, limit, handle
+func (_m *RepositoryI) ProcessOutbox(ctx context.Context, limit int, handle func(events []models.OutboxEvent) []int64) (int, error) {
+	ret := _m.Called(ctx, limit, handle)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, int, func(events []models.OutboxEvent) []int64) int); ok {
+		r0 = rf(ctx, limit, handle)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, func(events []models.OutboxEvent) []int64) error); ok {
+		r1 = rf(ctx, limit, handle)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SelectFeed provides a mock function with given fields: ctx, afterID, limit
+func (_m *RepositoryI) SelectFeed(ctx context.Context, afterID int64, limit int) ([]models.LinkFeedEvent, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []models.LinkFeedEvent
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []models.LinkFeedEvent); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.LinkFeedEvent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SelectLinkByOriginalLink provides a mock function with given fields: ctx, workspaceID, domain, originalLink
+func (_m *RepositoryI) SelectLinkByOriginalLink(ctx context.Context, workspaceID string, domain string, originalLink string) (string, error) {
+	ret := _m.Called(ctx, workspaceID, domain, originalLink)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(originalLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = rf(ctx, workspaceID, domain, originalLink)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(originalLink)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, workspaceID, domain, originalLink)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -47,20 +188,66 @@ func (_m *RepositoryI) SelectLinkByOriginalLink(originalLink string) (string, er
 	return r0, r1
 }
 
-// SelectLinkByShortLink provides a mock function with given fields: shortLink
-func (_m *RepositoryI) SelectLinkByShortLink(shortLink string) (string, error) {
-	ret := _m.Called(shortLink)
+// SelectLinkByShortLink provides a mock function with given fields: ctx, domain, shortLink
+func (_m *RepositoryI) SelectLinkByShortLink(ctx context.Context, domain string, shortLink string) (string, error) {
+	ret := _m.Called(ctx, domain, shortLink)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, domain, shortLink)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(shortLink)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, shortLink)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SelectLinks provides a mock function with given fields: ctx, scope
+func (_m *RepositoryI) SelectLinks(ctx context.Context, scope models.LinkScope) ([]models.Link, error) {
+	ret := _m.Called(ctx, scope)
+
+	var r0 []models.Link
+	if rf, ok := ret.Get(0).(func(context.Context, models.LinkScope) []models.Link); ok {
+		r0 = rf(ctx, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, models.LinkScope) error); ok {
+		r1 = rf(ctx, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLink provides a mock function with given fields: ctx, scope, link, audit
+func (_m *RepositoryI) UpdateLink(ctx context.Context, scope models.LinkScope, link *models.Link, audit *models.AuditRecord) (*models.Link, error) {
+	ret := _m.Called(ctx, scope, link, audit)
+
+	var r0 *models.Link
+	if rf, ok := ret.Get(0).(func(context.Context, models.LinkScope, *models.Link, *models.AuditRecord) *models.Link); ok {
+		r0 = rf(ctx, scope, link, audit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, models.LinkScope, *models.Link, *models.AuditRecord) error); ok {
+		r1 = rf(ctx, scope, link, audit)
 	} else {
 		r1 = ret.Error(1)
 	}
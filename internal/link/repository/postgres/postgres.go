@@ -1,11 +1,26 @@
 package postgres
 
 import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/kuzkuss/url_service/internal/link/repository"
 	"github.com/kuzkuss/url_service/models"
 	"github.com/pkg/errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const uniqueViolation = "23505"
+
+const (
+	incrementClicksQuery = `UPDATE links SET clicks = clicks + 1 WHERE domain = ? AND short_link = ? RETURNING *`
+	// the lock is held by the transaction processing the outbox, so events
+	// are not published concurrently by several instances of the service
+	lockOutboxQuery = `SELECT pg_try_advisory_xact_lock(?)`
+	outboxLockID    = 8245101931
 )
 
 type linkRepository struct {
@@ -18,19 +33,39 @@ func New(db *gorm.DB) repository.RepositoryI {
 	}
 }
 
-func (dbLink *linkRepository) CreateLink(link *models.Link) error {
-	tx := dbLink.db.Create(link)
-	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "database error (table links)")
+// Events are written to the outbox after the link is changed, so events of the same
+// link get ids in order of commits: the change waits for the lock of the link row.
+func (dbLink *linkRepository) CreateLink(ctx context.Context, link *models.Link, audit *models.AuditRecord) error {
+	err := dbLink.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(link).Error; err != nil {
+			return err
+		}
+		if err := createAuditRecord(tx, audit, nil, *link); err != nil {
+			return err
+		}
+		return tx.Create(models.NewOutboxEvent(models.LinkCreated, *link)).Error
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.ErrConflict
+	} else if err != nil {
+		return errors.Wrap(err, "database error (table links)")
 	}
 
 	return nil
 }
 
-func (dbLink *linkRepository) SelectLinkByOriginalLink(originalLink string) (string, error) {
+func (dbLink *linkRepository) SelectLinkByOriginalLink(ctx context.Context, workspaceID string, domain string,
+	originalLink string) (string, error) {
 	link := models.Link{}
 
-	tx := dbLink.db.Where("original_link = ?", originalLink).Take(&link)
+	query := dbLink.db.WithContext(ctx)
+	if workspaceID != "" {
+		query = query.Where("workspace_id = ?", workspaceID)
+	}
+
+	tx := query.Where("domain = ? AND original_link = ?", domain, originalLink).Take(&link)
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 		return "", models.ErrNotFound
 	} else if tx.Error != nil {
@@ -40,10 +75,10 @@ func (dbLink *linkRepository) SelectLinkByOriginalLink(originalLink string) (str
 	return link.ShortLink, nil
 }
 
-func (dbLink *linkRepository) SelectLinkByShortLink(shortLink string) (string, error) {
+func (dbLink *linkRepository) SelectLinkByShortLink(ctx context.Context, domain string, shortLink string) (string, error) {
 	link := models.Link{}
 
-	tx := dbLink.db.Where("short_link = ?", shortLink).Take(&link)
+	tx := dbLink.db.WithContext(ctx).Where("domain = ? AND short_link = ?", domain, shortLink).Take(&link)
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 		return "", models.ErrNotFound
 	} else if tx.Error != nil {
@@ -53,3 +88,197 @@ func (dbLink *linkRepository) SelectLinkByShortLink(shortLink string) (string, e
 	return link.OriginalLink, nil
 }
 
+func (dbLink *linkRepository) SelectLinks(ctx context.Context, scope models.LinkScope) ([]models.Link, error) {
+	links := make([]models.Link, 0)
+
+	tx := inScope(dbLink.db.WithContext(ctx), scope).
+		Order("domain, short_link").Find(&links)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table links)")
+	}
+
+	return links, nil
+}
+
+func (dbLink *linkRepository) IncrementClicks(ctx context.Context, domain string, shortLink string) (*models.Link, error) {
+	var links []models.Link
+
+	tx := dbLink.db.WithContext(ctx).Raw(incrementClicksQuery, domain, shortLink).Scan(&links)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table links)")
+	}
+
+	if len(links) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return &links[0], nil
+}
+
+func (dbLink *linkRepository) UpdateLink(ctx context.Context, scope models.LinkScope, link *models.Link,
+	audit *models.AuditRecord) (*models.Link, error) {
+	var before models.Link
+	err := dbLink.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := inScope(tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("domain = ? AND short_link = ?", link.Domain, link.ShortLink), scope).
+			Take(&before).Error
+		if err != nil {
+			return err
+		}
+
+		// the link is locked, so it is updated by its short link
+		updated := tx.Model(&models.Link{}).
+			Where("domain = ? AND short_link = ?", link.Domain, link.ShortLink).
+			Update("original_link", link.OriginalLink)
+		if updated.Error != nil {
+			return updated.Error
+		}
+
+		// the time of the update is set by the database
+		after := before
+		after.OriginalLink = link.OriginalLink
+		after.UpdatedAt = nil
+		if err := createAuditRecord(tx, audit, before, after); err != nil {
+			return err
+		}
+
+		changed := *link
+		changed.OwnerID = before.OwnerID
+		changed.WorkspaceID = before.WorkspaceID
+		return tx.Create(models.NewOutboxEvent(models.LinkUpdated, changed)).Error
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	} else if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, models.ErrConflict
+	} else if err != nil {
+		return nil, errors.Wrap(err, "database error (table links)")
+	}
+
+	return &before, nil
+}
+
+func (dbLink *linkRepository) DeleteLink(ctx context.Context, scope models.LinkScope, domain string,
+	shortLink string, audit *models.AuditRecord) (*models.Link, error) {
+	deleted := make([]models.Link, 0, 1)
+	err := dbLink.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := inScope(tx.Clauses(clause.Returning{}).
+			Where("domain = ? AND short_link = ?", domain, shortLink), scope).
+			Delete(&deleted).Error
+		if err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return models.ErrNotFound
+		}
+		if err := createAuditRecord(tx, audit, deleted[0], nil); err != nil {
+			return err
+		}
+		return tx.Create(models.NewOutboxEvent(models.LinkDeleted,
+			models.Link{ShortLink: shortLink, Domain: domain, OwnerID: deleted[0].OwnerID, WorkspaceID: deleted[0].WorkspaceID})).Error
+	})
+
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.ErrNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "database error (table links)")
+	}
+
+	return &deleted[0], nil
+}
+
+func (dbLink *linkRepository) ProcessOutbox(ctx context.Context, limit int,
+	handle func(events []models.OutboxEvent) []int64) (int, error) {
+	var processed int
+
+	err := dbLink.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw(lockOutboxQuery, outboxLockID).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		events := make([]models.OutboxEvent, 0)
+		if err := tx.Order("id").Limit(limit).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		processed = len(events)
+		published := handle(events)
+		if len(published) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", published).Delete(&models.OutboxEvent{}).Error
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "database error (table link_outbox)")
+	}
+
+	return processed, nil
+}
+
+func (dbLink *linkRepository) AppendFeed(ctx context.Context, event *models.LinkFeedEvent) error {
+	if err := dbLink.db.WithContext(ctx).Create(event).Error; err != nil {
+		return errors.Wrap(err, "database error (table link_feed)")
+	}
+
+	return nil
+}
+
+func (dbLink *linkRepository) SelectFeed(ctx context.Context, afterID int64, limit int) ([]models.LinkFeedEvent, error) {
+	events := make([]models.LinkFeedEvent, 0)
+
+	tx := dbLink.db.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&events)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table link_feed)")
+	}
+
+	return events, nil
+}
+
+func (dbLink *linkRepository) LastFeedID(ctx context.Context) (int64, error) {
+	var id int64
+
+	tx := dbLink.db.WithContext(ctx).Model(&models.LinkFeedEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id)
+	if tx.Error != nil {
+		return 0, errors.Wrap(tx.Error, "database error (table link_feed)")
+	}
+
+	return id, nil
+}
+
+func (dbLink *linkRepository) DeleteFeed(ctx context.Context, before time.Time) error {
+	tx := dbLink.db.WithContext(ctx).Where("appended_at < ?", before).Delete(&models.LinkFeedEvent{})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table link_feed)")
+	}
+
+	return nil
+}
+
+// createAuditRecord writes the record of the change with states of the link in tx unless it is nil.
+func createAuditRecord(tx *gorm.DB, audit *models.AuditRecord, before interface{}, after interface{}) error {
+	if audit == nil {
+		return nil
+	}
+	if err := audit.SetStates(before, after); err != nil {
+		return err
+	}
+	return tx.Create(audit).Error
+}
+
+// inScope restricts query to links in the scope.
+func inScope(query *gorm.DB, scope models.LinkScope) *gorm.DB {
+	query = query.Where("workspace_id = ?", scope.WorkspaceID)
+	if scope.AllOwners {
+		return query
+	}
+	return query.Where("owner_id = ?", scope.OwnerID)
+}
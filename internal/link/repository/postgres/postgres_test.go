@@ -1,13 +1,16 @@
 package postgres_test
 
 import (
+	"context"
 	"regexp"
 	"testing"
+	"time"
 
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/pkg/errors"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -21,6 +24,9 @@ type TestCaseCreate struct {
 	Error error
 }
 
+var outboxQuery = regexp.QuoteMeta(`INSERT INTO "link_outbox" ` +
+	`("type","domain","short_link","owner_id","workspace_id","link","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)
+
 type TestCaseSelect struct {
 	ArgData string
 	ExpectedRes string
@@ -50,18 +56,28 @@ func TestRepositoryCreateLink(t *testing.T) {
 	linkSuccess := models.Link {
 		OriginalLink: "original_link_success",
 		ShortLink: "short_link_success",
+		Domain: "short.io",
+		OwnerID: "owner",
+		WorkspaceID: "default",
 	}
 
 	linkError := models.Link {
 		OriginalLink: "original_link_error",
 		ShortLink: "short_link_error",
+		OwnerID: "owner",
+		WorkspaceID: "default",
 	}
 
 	mock.ExpectBegin()
 
 	mock.ExpectExec(regexp.QuoteMeta(
-		`INSERT INTO "links" ("original_link","short_link") VALUES ($1,$2)`)).WithArgs(
-			linkSuccess.OriginalLink, linkSuccess.ShortLink).WillReturnResult(sqlmock.NewResult(0, 0))
+		`INSERT INTO "links" ("original_link","short_link","domain","owner_id","workspace_id") VALUES ($1,$2,$3,$4,$5)`)).WithArgs(
+			linkSuccess.OriginalLink, linkSuccess.ShortLink, linkSuccess.Domain, linkSuccess.OwnerID, linkSuccess.WorkspaceID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectQuery(outboxQuery).WithArgs(models.LinkCreated, linkSuccess.Domain, linkSuccess.ShortLink, linkSuccess.OwnerID,
+		linkSuccess.WorkspaceID, `{"original_link":"original_link_success","short_link":"short_link_success","domain":"short.io"}`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	mock.ExpectCommit()
 
@@ -70,8 +86,9 @@ func TestRepositoryCreateLink(t *testing.T) {
 	mock.ExpectBegin()
 
 	mock.ExpectExec(regexp.QuoteMeta(
-		`INSERT INTO "links" ("original_link","short_link") VALUES ($1,$2)`)).WithArgs(
-			linkError.OriginalLink, linkError.ShortLink).WillReturnError(createErr)
+		`INSERT INTO "links" ("original_link","short_link","domain","owner_id","workspace_id") VALUES ($1,$2,$3,$4,$5)`)).WithArgs(
+			linkError.OriginalLink, linkError.ShortLink, linkError.Domain, linkError.OwnerID, linkError.WorkspaceID).
+		WillReturnError(createErr)
 
 	mock.ExpectRollback()
 
@@ -89,12 +106,12 @@ func TestRepositoryCreateLink(t *testing.T) {
 	}
 
 	t.Run("success", func(t *testing.T) {
-		err := repository.CreateLink(cases["success"].ArgData)
+		err := repository.CreateLink(context.Background(), cases["success"].ArgData, nil)
 		require.Equal(t, cases["success"].Error, errors.Cause(err))
 	})
 
 	t.Run("error", func(t *testing.T) {
-		err := repository.CreateLink(cases["error"].ArgData)
+		err := repository.CreateLink(context.Background(), cases["error"].ArgData, nil)
 		require.Equal(t, cases["error"].Error, errors.Cause(err))
 	})
 
@@ -133,14 +150,14 @@ func TestRepositorySelectLinkByShortLink(t *testing.T) {
 	}
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "links" WHERE short_link = $1 LIMIT 1`)).WithArgs(linkSuccess.ShortLink).
+		`SELECT * FROM "links" WHERE domain = $1 AND short_link = $2 LIMIT 1`)).WithArgs("short.io", linkSuccess.ShortLink).
 		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link"}).
 		AddRow(linkSuccess.ShortLink, linkSuccess.OriginalLink))
 
 	getErr := errors.New("error")
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "links" WHERE short_link = $1 LIMIT 1`)).WithArgs(linkError.ShortLink).
+		`SELECT * FROM "links" WHERE domain = $1 AND short_link = $2 LIMIT 1`)).WithArgs("short.io", linkError.ShortLink).
 		WillReturnError(getErr)
 
 	repository := linkRep.New(gdb)
@@ -159,13 +176,13 @@ func TestRepositorySelectLinkByShortLink(t *testing.T) {
 	}
 
 	t.Run("success", func(t *testing.T) {
-		actualRes, err := repository.SelectLinkByShortLink(cases["success"].ArgData)
+		actualRes, err := repository.SelectLinkByShortLink(context.Background(), "short.io", cases["success"].ArgData)
 		require.Equal(t, cases["success"].Error, errors.Cause(err))
 		assert.Equal(t, cases["success"].ExpectedRes, actualRes)
 	})
 
 	t.Run("error", func(t *testing.T) {
-		actualRes, err := repository.SelectLinkByShortLink(cases["error"].ArgData)
+		actualRes, err := repository.SelectLinkByShortLink(context.Background(), "short.io", cases["error"].ArgData)
 		require.Equal(t, cases["error"].Error, errors.Cause(err))
 		assert.Equal(t, cases["error"].ExpectedRes, actualRes)
 	})
@@ -204,14 +221,15 @@ func TestRepositorySelectLinkByOriginalLink(t *testing.T) {
 	}
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "links" WHERE original_link = $1 LIMIT 1`)).WithArgs(linkSuccess.OriginalLink).
+		`SELECT * FROM "links" WHERE workspace_id = $1 AND (domain = $2 AND original_link = $3) LIMIT 1`)).
+		WithArgs("team", "short.io", linkSuccess.OriginalLink).
 		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link"}).
 		AddRow(linkSuccess.ShortLink, linkSuccess.OriginalLink))
 
 	getErr := errors.New("error")
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "links" WHERE original_link = $1 LIMIT 1`)).WithArgs(linkError.OriginalLink).
+		`SELECT * FROM "links" WHERE domain = $1 AND original_link = $2 LIMIT 1`)).WithArgs("short.io", linkError.OriginalLink).
 		WillReturnError(getErr)
 
 	repository := linkRep.New(gdb)
@@ -230,13 +248,13 @@ func TestRepositorySelectLinkByOriginalLink(t *testing.T) {
 	}
 
 	t.Run("success", func(t *testing.T) {
-		actualRes, err := repository.SelectLinkByOriginalLink(cases["success"].ArgData)
+		actualRes, err := repository.SelectLinkByOriginalLink(context.Background(), "team", "short.io", cases["success"].ArgData)
 		require.Equal(t, cases["success"].Error, errors.Cause(err))
 		assert.Equal(t, cases["success"].ExpectedRes, actualRes)
 	})
 
 	t.Run("error", func(t *testing.T) {
-		actualRes, err := repository.SelectLinkByOriginalLink(cases["error"].ArgData)
+		actualRes, err := repository.SelectLinkByOriginalLink(context.Background(), "", "short.io", cases["error"].ArgData)
 		require.Equal(t, cases["error"].Error, errors.Cause(err))
 		assert.Equal(t, cases["error"].ExpectedRes, actualRes)
 	})
@@ -244,3 +262,532 @@ func TestRepositorySelectLinkByOriginalLink(t *testing.T) {
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
 }
+
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	return gdb, mock
+}
+
+func TestRepositorySelectLinks(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	links := []models.Link {
+		{
+			OriginalLink: "original_link_first",
+			ShortLink: "short_link_first",
+			OwnerID: "owner",
+		},
+		{
+			OriginalLink: "original_link_second",
+			ShortLink: "short_link_second",
+			OwnerID: "owner",
+		},
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "links" WHERE workspace_id = $1 AND owner_id = $2 ORDER BY domain, short_link`)).
+		WithArgs("default", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link", "owner_id"}).
+		AddRow(links[0].ShortLink, links[0].OriginalLink, links[0].OwnerID).
+		AddRow(links[1].ShortLink, links[1].OriginalLink, links[1].OwnerID))
+
+	getErr := errors.New("error")
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "links" WHERE workspace_id = $1 AND owner_id = $2 ORDER BY domain, short_link`)).
+		WithArgs("default", "owner_error").
+		WillReturnError(getErr)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "links" WHERE workspace_id = $1 ORDER BY domain, short_link`)).
+		WithArgs("team").
+		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link", "owner_id"}).
+		AddRow(links[0].ShortLink, links[0].OriginalLink, "other"))
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		actualRes, err := repository.SelectLinks(context.Background(), models.LinkScope{WorkspaceID: "default", OwnerID: "owner"})
+		require.NoError(t, err)
+		assert.Equal(t, links, actualRes)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := repository.SelectLinks(context.Background(),
+			models.LinkScope{WorkspaceID: "default", OwnerID: "owner_error"})
+		require.Equal(t, getErr, errors.Cause(err))
+	})
+
+	t.Run("any_owner", func(t *testing.T) {
+		actualRes, err := repository.SelectLinks(context.Background(), models.LinkScope{WorkspaceID: "team", AllOwners: true})
+		require.NoError(t, err)
+		require.Len(t, actualRes, 1)
+		assert.Equal(t, "other", actualRes[0].OwnerID)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryIncrementClicks(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	linkSuccess := models.Link {
+		OriginalLink: "original_link_success",
+		ShortLink: "short_link_success",
+		OwnerID: "owner",
+		Clicks: 1000,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE links SET clicks = clicks + 1 WHERE domain = $1 AND short_link = $2 RETURNING *`)).WithArgs("", linkSuccess.ShortLink).
+		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link", "owner_id", "clicks"}).
+		AddRow(linkSuccess.ShortLink, linkSuccess.OriginalLink, linkSuccess.OwnerID, linkSuccess.Clicks))
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE links SET clicks = clicks + 1 WHERE domain = $1 AND short_link = $2 RETURNING *`)).WithArgs("", "short_link_not_found").
+		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link", "owner_id", "clicks"}))
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		actualRes, err := repository.IncrementClicks(context.Background(), "", linkSuccess.ShortLink)
+		require.NoError(t, err)
+		assert.Equal(t, &linkSuccess, actualRes)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := repository.IncrementClicks(context.Background(), "", "short_link_not_found")
+		require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryUpdateLink(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	linkSuccess := models.Link {
+		OriginalLink: "original_link_success",
+		ShortLink: "short_link_success",
+		OwnerID: "owner",
+		WorkspaceID: "default",
+	}
+
+	linkNotFound := models.Link {
+		OriginalLink: "original_link_not_found",
+		ShortLink: "short_link_not_found",
+		OwnerID: "owner",
+		WorkspaceID: "default",
+	}
+
+	linkConflict := models.Link {
+		OriginalLink: "original_link_conflict",
+		ShortLink: "short_link_conflict",
+		OwnerID: "owner",
+		WorkspaceID: "default",
+	}
+
+	selectQuery := regexp.QuoteMeta(
+		`SELECT * FROM "links" WHERE (domain = $1 AND short_link = $2) AND workspace_id = $3 AND owner_id = $4 LIMIT 1 FOR UPDATE`)
+	query := regexp.QuoteMeta(
+		`UPDATE "links" SET "original_link"=$1 WHERE domain = $2 AND short_link = $3`)
+	rows := func(link models.Link) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"original_link", "short_link", "owner_id", "workspace_id"}).
+			AddRow("original_link_before", link.ShortLink, link.OwnerID, link.WorkspaceID)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(linkSuccess.Domain, linkSuccess.ShortLink, linkSuccess.WorkspaceID, linkSuccess.OwnerID).
+		WillReturnRows(rows(linkSuccess))
+	mock.ExpectExec(query).WithArgs(linkSuccess.OriginalLink, linkSuccess.Domain, linkSuccess.ShortLink).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(outboxQuery).WithArgs(models.LinkUpdated, linkSuccess.Domain, linkSuccess.ShortLink, linkSuccess.OwnerID,
+		linkSuccess.WorkspaceID, `{"original_link":"original_link_success","short_link":"short_link_success"}`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(linkNotFound.Domain, linkNotFound.ShortLink, linkNotFound.WorkspaceID, linkNotFound.OwnerID).
+		WillReturnRows(sqlmock.NewRows([]string{"original_link", "short_link", "owner_id"}))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs(linkConflict.Domain, linkConflict.ShortLink, linkConflict.WorkspaceID, linkConflict.OwnerID).
+		WillReturnRows(rows(linkConflict))
+	mock.ExpectExec(query).WithArgs(linkConflict.OriginalLink, linkConflict.Domain, linkConflict.ShortLink).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	repository := linkRep.New(gdb)
+
+	cases := map[string]TestCaseCreate {
+		"success": {
+			ArgData:   &linkSuccess,
+			Error: nil,
+		},
+		"not_found": {
+			ArgData:   &linkNotFound,
+			Error: models.ErrNotFound,
+		},
+		"conflict": {
+			ArgData:   &linkConflict,
+			Error: models.ErrConflict,
+		},
+	}
+
+	for _, name := range []string{"success", "not_found", "conflict"} {
+		t.Run(name, func(t *testing.T) {
+			link := cases[name].ArgData
+			before, err := repository.UpdateLink(context.Background(),
+				models.LinkScope{WorkspaceID: link.WorkspaceID, OwnerID: link.OwnerID}, link, nil)
+			require.Equal(t, cases[name].Error, errors.Cause(err))
+			if err == nil {
+				assert.Equal(t, "original_link_before", before.OriginalLink)
+			}
+		})
+	}
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryCreateLinkAfterUpdate(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	selectQuery := regexp.QuoteMeta(
+		`SELECT * FROM "links" WHERE (domain = $1 AND short_link = $2) AND workspace_id = $3 AND owner_id = $4 LIMIT 1 FOR UPDATE`)
+	updateQuery := regexp.QuoteMeta(`UPDATE "links" SET "original_link"=$1 WHERE domain = $2 AND short_link = $3`)
+	insertQuery := regexp.QuoteMeta(
+		`INSERT INTO "links" ("original_link","short_link","domain","owner_id","workspace_id") VALUES ($1,$2,$3,$4,$5)`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectQuery).WithArgs("short.io", "short_link", "default", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"original_link", "short_link", "domain", "owner_id", "workspace_id"}).
+			AddRow("https://x.example", "short_link", "short.io", "alice", "default"))
+	mock.ExpectExec(updateQuery).WithArgs("https://y.example", "short.io", "short_link").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(outboxQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	// the short link generated for the original link again is taken by the updated link
+	mock.ExpectBegin()
+	mock.ExpectExec(insertQuery).WithArgs("https://x.example", "short_link", "short.io", "bob", "default").
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "links_pkey"})
+	mock.ExpectRollback()
+
+	repository := linkRep.New(gdb)
+
+	_, err := repository.UpdateLink(context.Background(), models.LinkScope{WorkspaceID: "default", OwnerID: "alice"},
+		&models.Link{OriginalLink: "https://y.example", ShortLink: "short_link", Domain: "short.io"}, nil)
+	require.NoError(t, err)
+
+	err = repository.CreateLink(context.Background(), &models.Link{OriginalLink: "https://x.example", ShortLink: "short_link",
+		Domain: "short.io", OwnerID: "bob", WorkspaceID: "default"}, nil)
+	assert.Equal(t, models.ErrConflict, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDeleteLink(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	query := regexp.QuoteMeta(
+		`DELETE FROM "links" WHERE (domain = $1 AND short_link = $2) AND workspace_id = $3 AND owner_id = $4 RETURNING *`)
+	anyOwnerQuery := regexp.QuoteMeta(`DELETE FROM "links" WHERE (domain = $1 AND short_link = $2) AND workspace_id = $3 RETURNING *`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("short.io", "short_link_success", "default", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"original_link", "short_link", "owner_id", "workspace_id"}).
+			AddRow("original_link_success", "short_link_success", "owner", "default"))
+	mock.ExpectQuery(outboxQuery).WithArgs(models.LinkDeleted, "short.io", "short_link_success", "owner", "default",
+		`{"short_link":"short_link_success","domain":"short.io"}`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("short.io", "short_link_not_found", "default", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"original_link", "short_link", "owner_id"}))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(anyOwnerQuery).WithArgs("short.io", "short_link_other", "default").
+		WillReturnRows(sqlmock.NewRows([]string{"original_link", "short_link", "owner_id", "workspace_id"}).
+			AddRow("original_link_other", "short_link_other", "other", "default"))
+	mock.ExpectQuery(outboxQuery).WithArgs(models.LinkDeleted, "short.io", "short_link_other", "other", "default",
+		`{"short_link":"short_link_other","domain":"short.io"}`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		deleted, err := repository.DeleteLink(context.Background(), models.LinkScope{WorkspaceID: "default", OwnerID: "owner"},
+			"short.io", "short_link_success", nil)
+		require.NoError(t, err)
+		assert.Equal(t, &models.Link{OriginalLink: "original_link_success", ShortLink: "short_link_success",
+			OwnerID: "owner", WorkspaceID: "default"}, deleted)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := repository.DeleteLink(context.Background(), models.LinkScope{WorkspaceID: "default", OwnerID: "owner"},
+			"short.io", "short_link_not_found", nil)
+		require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("any_owner", func(t *testing.T) {
+		deleted, err := repository.DeleteLink(context.Background(), models.LinkScope{WorkspaceID: "default", AllOwners: true},
+			"short.io", "short_link_other", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "other", deleted.OwnerID)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryDeleteLinkAudit(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	query := regexp.QuoteMeta(
+		`DELETE FROM "links" WHERE (domain = $1 AND short_link = $2) AND workspace_id = $3 AND owner_id = $4 RETURNING *`)
+	auditQuery := regexp.QuoteMeta(`INSERT INTO "audit_log" ("created_at","actor","action","resource","before_state",` +
+		`"after_state","transport","client_ip","request_id") VALUES ($1,$2,$3,$4,$5,(NULL),$6,$7,$8) RETURNING "id"`)
+	deletedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"original_link", "short_link", "owner_id", "workspace_id"}).
+			AddRow("original_link", "short_link", "owner", "default")
+	}
+	now := time.Now()
+	auditErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("short.io", "short_link", "default", "owner").WillReturnRows(deletedRows())
+	mock.ExpectQuery(auditQuery).
+		WithArgs(now, "owner", models.AuditLinkDelete, "short_link",
+			[]byte(`{"original_link":"original_link","short_link":"short_link"}`), "", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(outboxQuery).WithArgs(models.LinkDeleted, "short.io", "short_link", "owner", "default",
+		`{"short_link":"short_link","domain":"short.io"}`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	// the link is kept if the deletion can not be recorded
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("short.io", "short_link", "default", "owner").WillReturnRows(deletedRows())
+	mock.ExpectQuery(auditQuery).WillReturnError(auditErr)
+	mock.ExpectRollback()
+
+	repository := linkRep.New(gdb)
+	scope := models.LinkScope{WorkspaceID: "default", OwnerID: "owner"}
+
+	record := &models.AuditRecord{Time: now, Actor: "owner", Action: models.AuditLinkDelete, Resource: "short_link"}
+	_, err := repository.DeleteLink(context.Background(), scope, "short.io", "short_link", record)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), record.ID)
+
+	_, err = repository.DeleteLink(context.Background(), scope, "short.io", "short_link",
+		&models.AuditRecord{Time: now, Actor: "owner", Action: models.AuditLinkDelete, Resource: "short_link"})
+	assert.Equal(t, auditErr, errors.Cause(err))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryProcessOutbox(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	createdAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "type", "short_link", "owner_id", "link", "created_at"}
+
+	lockQuery := regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)
+	selectQuery := regexp.QuoteMeta(`SELECT * FROM "link_outbox" ORDER BY id LIMIT 10`)
+	deleteQuery := regexp.QuoteMeta(`DELETE FROM "link_outbox" WHERE id IN ($1,$2)`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(selectQuery).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, models.LinkCreated, "short_link", "owner", `{"original_link":"original_link","short_link":"short_link"}`, createdAt).
+		AddRow(2, models.LinkUpdated, "short_link", "owner", `{"original_link":"original_link_new","short_link":"short_link"}`, createdAt).
+		AddRow(3, models.LinkDeleted, "short_link_other", "", `{"short_link":"short_link_other"}`, createdAt))
+	mock.ExpectExec(deleteQuery).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+	mock.ExpectCommit()
+
+	processErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(selectQuery).WillReturnError(processErr)
+	mock.ExpectRollback()
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		var handled []models.OutboxEvent
+		processed, err := repository.ProcessOutbox(context.Background(), 10, func(events []models.OutboxEvent) []int64 {
+			handled = events
+			return []int64{1, 3}
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, processed)
+		require.Len(t, handled, 3)
+
+		event := handled[1].LinkEvent()
+		assert.Equal(t, models.LinkUpdated, event.Type)
+		assert.Equal(t, models.Link{OriginalLink: "original_link_new", ShortLink: "short_link", OwnerID: "owner"}, event.Link)
+		assert.Equal(t, createdAt, event.Time)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		processed, err := repository.ProcessOutbox(context.Background(), 10, func(events []models.OutboxEvent) []int64 {
+			t.Fatal("outbox locked by another relay is processed")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 0, processed)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := repository.ProcessOutbox(context.Background(), 10, func(events []models.OutboxEvent) []int64 {
+			return nil
+		})
+		require.Equal(t, processErr, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryAppendFeed(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := models.NewLinkFeedEvent(models.LinkEvent{Type: models.LinkCreated, Time: created,
+		Link: models.Link{ShortLink: "short_link", OriginalLink: "original_link", OwnerID: "owner", WorkspaceID: "workspace"}})
+
+	feedQuery := regexp.QuoteMeta(`INSERT INTO "link_feed" ("type","owner_id","workspace_id","link","created_at") ` +
+		`VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)
+	mock.ExpectBegin()
+	mock.ExpectQuery(feedQuery).
+		WithArgs(models.LinkCreated, "owner", "workspace", sqlmock.AnyArg(), created).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(feedQuery).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, repository.AppendFeed(context.Background(), event))
+		assert.Equal(t, int64(7), event.ID)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		require.Error(t, repository.AppendFeed(context.Background(), models.NewLinkFeedEvent(models.LinkEvent{})))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositorySelectFeed(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	feedQuery := regexp.QuoteMeta(`SELECT * FROM "link_feed" WHERE id > $1 ORDER BY id LIMIT 2`)
+	mock.ExpectQuery(feedQuery).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "owner_id", "workspace_id", "link"}).
+		AddRow(6, "created", "owner", "workspace", `{"short_link":"first"}`).
+		AddRow(7, "deleted", "owner", "workspace", `{"short_link":"second"}`))
+	mock.ExpectQuery(feedQuery).WithArgs(7).WillReturnError(errors.New("db error"))
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		events, err := repository.SelectFeed(context.Background(), 5, 2)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, int64(6), events[0].ID)
+		assert.Equal(t, models.LinkDeleted, events[1].Type)
+		assert.Equal(t, "second", events[1].LinkEvent().Link.ShortLink)
+		assert.Equal(t, "owner", events[1].LinkEvent().Link.OwnerID)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := repository.SelectFeed(context.Background(), 7, 2)
+		require.Error(t, err)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryLastFeedID(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	lastQuery := regexp.QuoteMeta(`SELECT COALESCE(MAX(id), 0) FROM "link_feed"`)
+	mock.ExpectQuery(lastQuery).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(12))
+	mock.ExpectQuery(lastQuery).WillReturnError(errors.New("db error"))
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		id, err := repository.LastFeedID(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), id)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := repository.LastFeedID(context.Background())
+		require.Error(t, err)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryDeleteFeed(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	before := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deleteQuery := regexp.QuoteMeta(`DELETE FROM "link_feed" WHERE appended_at < $1`)
+	mock.ExpectBegin()
+	mock.ExpectExec(deleteQuery).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(deleteQuery).WithArgs(before).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	repository := linkRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, repository.DeleteFeed(context.Background(), before))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		require.Error(t, repository.DeleteFeed(context.Background(), before))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -1,11 +1,43 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/kuzkuss/url_service/models"
 )
 
+// RepositoryI stores links identified by the short link on its domain. Every link
+// belongs to a workspace and is listed, updated and deleted within the scope of the client
+// (see models.LinkScope).
 type RepositoryI interface {
-	SelectLinkByOriginalLink(originalLink string) (string, error)
-	SelectLinkByShortLink(shortLink string) (string, error)
-	CreateLink(link *models.Link) (error)
+	// SelectLinkByOriginalLink looks for the link in the workspace, in every workspace
+	// if workspaceID is empty.
+	SelectLinkByOriginalLink(ctx context.Context, workspaceID string, domain string, originalLink string) (string, error)
+	SelectLinkByShortLink(ctx context.Context, domain string, shortLink string) (string, error)
+	SelectLinks(ctx context.Context, scope models.LinkScope) ([]models.Link, error)
+	// IncrementClicks counts click on the link and returns it with the updated number of clicks.
+	IncrementClicks(ctx context.Context, domain string, shortLink string) (*models.Link, error)
+	// CreateLink, UpdateLink and DeleteLink write event of the change to the outbox
+	// atomically with the change, and the audit record with states of the link unless
+	// it is nil, so the change fails if it can not be recorded. UpdateLink returns
+	// the link as it was before the update, DeleteLink returns the deleted link.
+	// CreateLink fails with models.ErrConflict if the short link exists on the domain.
+	CreateLink(ctx context.Context, link *models.Link, audit *models.AuditRecord) (error)
+	UpdateLink(ctx context.Context, scope models.LinkScope, link *models.Link, audit *models.AuditRecord) (*models.Link, error)
+	DeleteLink(ctx context.Context, scope models.LinkScope, domain string, shortLink string,
+		audit *models.AuditRecord) (*models.Link, error)
+	// ProcessOutbox passes at most limit oldest events of the outbox, ordered by id,
+	// to handle and removes the events whose ids handle returns. The outbox is processed
+	// by one caller at a time, others get no events. It returns number of events passed to handle.
+	ProcessOutbox(ctx context.Context, limit int, handle func(events []models.OutboxEvent) []int64) (int, error)
+	// AppendFeed adds the event relayed from the outbox to the feed read by every instance
+	// of the service and sets its id. Events are appended by the relay only.
+	AppendFeed(ctx context.Context, event *models.LinkFeedEvent) error
+	// SelectFeed returns at most limit events of the feed after the one with afterID, ordered by id.
+	SelectFeed(ctx context.Context, afterID int64, limit int) ([]models.LinkFeedEvent, error)
+	// LastFeedID returns id of the last event of the feed, 0 if the feed is empty.
+	LastFeedID(ctx context.Context) (int64, error)
+	// DeleteFeed removes events appended to the feed before the time.
+	DeleteFeed(ctx context.Context, before time.Time) error
 }
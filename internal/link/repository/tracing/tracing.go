@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/link/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "links"
+
+type linkRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps link repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &linkRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbLink *linkRepository) SelectLinkByOriginalLink(ctx context.Context, workspaceID string, domain string,
+	originalLink string) (string, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "SelectLinkByOriginalLink")
+	shortLink, err := dbLink.repository.SelectLinkByOriginalLink(ctx, workspaceID, domain, originalLink)
+	observability.EndSpan(span, err)
+	return shortLink, err
+}
+
+func (dbLink *linkRepository) SelectLinkByShortLink(ctx context.Context, domain string, shortLink string) (string, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "SelectLinkByShortLink")
+	originalLink, err := dbLink.repository.SelectLinkByShortLink(ctx, domain, shortLink)
+	observability.EndSpan(span, err)
+	return originalLink, err
+}
+
+func (dbLink *linkRepository) SelectLinks(ctx context.Context, scope models.LinkScope) ([]models.Link, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "SelectLinks")
+	links, err := dbLink.repository.SelectLinks(ctx, scope)
+	observability.EndSpan(span, err)
+	return links, err
+}
+
+func (dbLink *linkRepository) IncrementClicks(ctx context.Context, domain string, shortLink string) (*models.Link, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "IncrementClicks")
+	link, err := dbLink.repository.IncrementClicks(ctx, domain, shortLink)
+	observability.EndSpan(span, err)
+	return link, err
+}
+
+func (dbLink *linkRepository) CreateLink(ctx context.Context, link *models.Link, audit *models.AuditRecord) error {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "CreateLink")
+	err := dbLink.repository.CreateLink(ctx, link, audit)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbLink *linkRepository) UpdateLink(ctx context.Context, scope models.LinkScope, link *models.Link,
+	audit *models.AuditRecord) (*models.Link, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "UpdateLink")
+	before, err := dbLink.repository.UpdateLink(ctx, scope, link, audit)
+	observability.EndSpan(span, err)
+	return before, err
+}
+
+func (dbLink *linkRepository) DeleteLink(ctx context.Context, scope models.LinkScope, domain string,
+	shortLink string, audit *models.AuditRecord) (*models.Link, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "DeleteLink")
+	deleted, err := dbLink.repository.DeleteLink(ctx, scope, domain, shortLink, audit)
+	observability.EndSpan(span, err)
+	return deleted, err
+}
+
+func (dbLink *linkRepository) ProcessOutbox(ctx context.Context, limit int,
+	handle func(events []models.OutboxEvent) []int64) (int, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "ProcessOutbox")
+	processed, err := dbLink.repository.ProcessOutbox(ctx, limit, handle)
+	observability.EndSpan(span, err)
+	return processed, err
+}
+
+func (dbLink *linkRepository) AppendFeed(ctx context.Context, event *models.LinkFeedEvent) error {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "AppendFeed")
+	err := dbLink.repository.AppendFeed(ctx, event)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbLink *linkRepository) SelectFeed(ctx context.Context, afterID int64, limit int) ([]models.LinkFeedEvent, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "SelectFeed")
+	events, err := dbLink.repository.SelectFeed(ctx, afterID, limit)
+	observability.EndSpan(span, err)
+	return events, err
+}
+
+func (dbLink *linkRepository) LastFeedID(ctx context.Context) (int64, error) {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "LastFeedID")
+	id, err := dbLink.repository.LastFeedID(ctx)
+	observability.EndSpan(span, err)
+	return id, err
+}
+
+func (dbLink *linkRepository) DeleteFeed(ctx context.Context, before time.Time) error {
+	ctx, span := dbLink.tracing.Start(ctx, repositoryName, "DeleteFeed")
+	err := dbLink.repository.DeleteFeed(ctx, before)
+	observability.EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,61 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	linkMocks "github.com/kuzkuss/url_service/internal/link/repository/mocks"
+	linkTracing "github.com/kuzkuss/url_service/internal/link/repository/tracing"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestTracingRepository(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	createErr := errors.New("error")
+
+	var repositorySpan trace.SpanContext
+	mockLinkRepository := linkMocks.NewRepositoryI(t)
+	mockLinkRepository.On("SelectLinkByShortLink", mock.Anything, "", "short_link").Return("original_link", nil).
+		Run(func(args mock.Arguments) {
+			repositorySpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+		})
+	mockLinkRepository.On("CreateLink", mock.Anything, &models.Link{OriginalLink: "original_link"}, (*models.AuditRecord)(nil)).Return(createErr)
+
+	repo := linkTracing.New(mockLinkRepository, observability.NewRepositoryTracing("postgres"))
+
+	ctx, parent := observability.StartSpan(context.Background(), "link.usecase.GetOriginalLink")
+
+	originalLink, err := repo.SelectLinkByShortLink(ctx, "", "short_link")
+	require.NoError(t, err)
+	assert.Equal(t, "original_link", originalLink)
+
+	err = repo.CreateLink(ctx, &models.Link{OriginalLink: "original_link"}, nil)
+	require.Equal(t, createErr, err)
+
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, "links.SelectLinkByShortLink", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), repositorySpan.SpanID())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "links.CreateLink", spans[1].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
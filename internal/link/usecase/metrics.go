@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics counts outcomes of link creations and lookups.
+// Nil Metrics counts nothing.
+type Metrics struct {
+	creations *prometheus.CounterVec
+	lookups   *prometheus.CounterVec
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	metrics := &Metrics{
+		creations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "link_creations_total",
+			Help: "Total number of create requests by result: created new link or deduplicated by original link.",
+		}, []string{"result"}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "link_lookups_total",
+			Help: "Total number of original link lookups by result: hit or miss.",
+		}, []string{"result"}),
+	}
+
+	reg.MustRegister(metrics.creations, metrics.lookups)
+
+	return metrics
+}
+
+func (m *Metrics) creation(result string) {
+	if m != nil {
+		m.creations.WithLabelValues(result).Inc()
+	}
+}
+
+func (m *Metrics) lookup(result string) {
+	if m != nil {
+		m.lookups.WithLabelValues(result).Inc()
+	}
+}
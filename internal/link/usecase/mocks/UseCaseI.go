@@ -3,6 +3,7 @@
 package mocks
 
 import (
+	context "context"
 	models "github.com/kuzkuss/url_service/models"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,13 +13,13 @@ type UseCaseI struct {
 	mock.Mock
 }
 
-// CreateShortLink provides a mock function with given fields: link
-func (_m *UseCaseI) CreateShortLink(link *models.Link) error {
-	ret := _m.Called(link)
+// CreateShortLink provides a mock function with given fields: ctx, principal, link
+func (_m *UseCaseI) CreateShortLink(ctx context.Context, principal *models.Principal, link *models.Link) error {
+	ret := _m.Called(ctx, principal, link)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.Link) error); ok {
-		r0 = rf(link)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Principal, *models.Link) error); ok {
+		r0 = rf(ctx, principal, link)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -26,20 +27,57 @@ func (_m *UseCaseI) CreateShortLink(link *models.Link) error {
 	return r0
 }
 
-// GetOriginalLink provides a mock function with given fields: link
-func (_m *UseCaseI) GetOriginalLink(link string) (string, error) {
-	ret := _m.Called(link)
+// DeleteLink provides a mock function with given fields: ctx, principal, domain, shortLink
+func (_m *UseCaseI) DeleteLink(ctx context.Context, principal *models.Principal, domain string, shortLink string) error {
+	ret := _m.Called(ctx, principal, domain, shortLink)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Principal, string, string) error); ok {
+		r0 = rf(ctx, principal, domain, shortLink)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FollowLink provides a mock function with given fields: ctx, host, link
+func (_m *UseCaseI) FollowLink(ctx context.Context, host string, link string) (string, error) {
+	ret := _m.Called(ctx, host, link)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(link)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, host, link)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(link)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, host, link)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLinks provides a mock function with given fields: ctx, principal
+func (_m *UseCaseI) GetLinks(ctx context.Context, principal *models.Principal) ([]models.Link, error) {
+	ret := _m.Called(ctx, principal)
+
+	var r0 []models.Link
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Principal) []models.Link); ok {
+		r0 = rf(ctx, principal)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Principal) error); ok {
+		r1 = rf(ctx, principal)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -47,6 +85,76 @@ func (_m *UseCaseI) GetOriginalLink(link string) (string, error) {
 	return r0, r1
 }
 
+// GetOriginalLink provides a mock function with given fields: ctx, host, link
+func (_m *UseCaseI) GetOriginalLink(ctx context.Context, host string, link string) (string, error) {
+	ret := _m.Called(ctx, host, link)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, host, link)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, host, link)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOriginalLinkOnDomain provides a mock function with given fields: ctx, domain, link
+func (_m *UseCaseI) GetOriginalLinkOnDomain(ctx context.Context, domain string, link string) (string, error) {
+	ret := _m.Called(ctx, domain, link)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, domain, link)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, link)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLink provides a mock function with given fields: ctx, principal, link
+func (_m *UseCaseI) UpdateLink(ctx context.Context, principal *models.Principal, link *models.Link) error {
+	ret := _m.Called(ctx, principal, link)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Principal, *models.Link) error); ok {
+		r0 = rf(ctx, principal, link)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WatchLinks provides a mock function with given fields: ctx, principal, cursor, handle
+func (_m *UseCaseI) WatchLinks(ctx context.Context, principal *models.Principal, cursor string, handle func(models.LinkEvent) error) error {
+	ret := _m.Called(ctx, principal, cursor, handle)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Principal, string, func(models.LinkEvent) error) error); ok {
+		r0 = rf(ctx, principal, cursor, handle)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewUseCaseI interface {
 	mock.TestingT
 	Cleanup(func())
@@ -2,65 +2,456 @@ package usecase
 
 import (
 	"github.com/pkg/errors"
+	"context"
 	"crypto/sha256"
+	"log/slog"
 	"math/big"
 	"math/rand"
+	"net"
+	"strconv"
+	"strings"
 
+	"github.com/kuzkuss/url_service/config"
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	linkEvents "github.com/kuzkuss/url_service/internal/link/events"
 	linkRep "github.com/kuzkuss/url_service/internal/link/repository"
+	"github.com/kuzkuss/url_service/internal/observability"
+	quotaUsecase "github.com/kuzkuss/url_service/internal/quota/usecase"
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
 	"github.com/kuzkuss/url_service/models"
 )
 
+const (
+	resultCreated      = "created"
+	resultDeduplicated = "deduplicated"
+	resultHit          = "hit"
+	resultMiss         = "miss"
+)
+
+// maxGenerationAttempts limits short links generated for a new link when they are taken.
+const maxGenerationAttempts = 5
+
 var alphabet = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_")
 
 type UseCaseI interface {
-	GetOriginalLink(link string) (string, error)
-	CreateShortLink(link *models.Link) (error)
+	// GetOriginalLink resolves the short link on the domain of host, which may
+	// include port, or on the default domain if the host is not allowed.
+	GetOriginalLink(ctx context.Context, host string, link string) (string, error)
+	// GetOriginalLinkOnDomain resolves the short link on the domain given explicitly,
+	// it rejects domains which are not allowed; empty domain is the default one.
+	GetOriginalLinkOnDomain(ctx context.Context, domain string, link string) (string, error)
+	// FollowLink resolves the short link like GetOriginalLink for the redirect to
+	// the original link and counts the click, other lookups are not counted.
+	FollowLink(ctx context.Context, host string, link string) (string, error)
+	// CreateShortLink, UpdateLink and DeleteLink reject domains which are not allowed,
+	// empty domain is the default one. Links are created, listed and changed in the workspace
+	// of the principal, empty workspace is the default one. The role of the principal in
+	// the workspace is checked by workspaceUsecase.Authorize: members get links of every
+	// member of the workspace, others only their own links.
+	CreateShortLink(ctx context.Context, principal *models.Principal, link *models.Link) (error)
+	GetLinks(ctx context.Context, principal *models.Principal) ([]models.Link, error)
+	UpdateLink(ctx context.Context, principal *models.Principal, link *models.Link) (error)
+	DeleteLink(ctx context.Context, principal *models.Principal, domain string, shortLink string) (error)
+	WatchLinks(ctx context.Context, principal *models.Principal, cursor string,
+		handle func(models.LinkEvent) error) (error)
+}
+
+// ClickObserverI is notified about every click on the link, which holds the updated number of clicks.
+type ClickObserverI interface {
+	LinkClicked(ctx context.Context, link models.Link)
 }
 
 type useCase struct {
 	linkRepository linkRep.RepositoryI
+	quotaUC quotaUsecase.UseCaseI
+	auditUC auditUsecase.UseCaseI
+	events linkEvents.BusI
+	clicks ClickObserverI
+	// allowed domains by their names in lower case
+	domains map[string]string
+	defaultDomain string
+	// public base URLs of short links by domain
+	baseURLs map[string]string
+	// original links are deduplicated across workspaces
+	globalDedup bool
+	metrics *Metrics
+	logger *slog.Logger
 }
 
-func New(linkRepository linkRep.RepositoryI) UseCaseI {
+// New creates link usecase. Creation of new links is accounted by quotaUC and
+// changes of links are recorded in the audit log by auditUC unless they are nil.
+// Changes of links are watched in events, where they are relayed from the outbox
+// of the repository, and clicks are reported to clicks unless they are nil. Links are
+// created on domains allowed by domainsConf and get short URLs starting with its base URLs.
+// Original links are deduplicated in the scope set by workspacesConf.
+// Outcomes of operations are counted by metrics unless it is nil.
+func New(linkRepository linkRep.RepositoryI, quotaUC quotaUsecase.UseCaseI, auditUC auditUsecase.UseCaseI,
+	events linkEvents.BusI, clicks ClickObserverI, domainsConf config.DomainsConfig,
+	workspacesConf config.WorkspacesConfig, metrics *Metrics, logger *slog.Logger) UseCaseI {
+	domains := make(map[string]string, len(domainsConf.Allowed))
+	baseURLs := make(map[string]string, len(domainsConf.Allowed) + 1)
+	for _, domain := range domainsConf.Allowed {
+		domains[strings.ToLower(domain)] = domain
+		baseURLs[domain] = "https://" + domain
+	}
+	for domain, baseURL := range domainsConf.BaseURLs {
+		baseURLs[domain] = strings.TrimSuffix(baseURL, "/")
+	}
+	if domainsConf.BaseURL != "" {
+		baseURLs[""] = strings.TrimSuffix(domainsConf.BaseURL, "/")
+	}
+
 	return &useCase{
 		linkRepository: linkRepository,
+		quotaUC: quotaUC,
+		auditUC: auditUC,
+		events: events,
+		clicks: clicks,
+		domains: domains,
+		defaultDomain: domainsConf.Default,
+		baseURLs: baseURLs,
+		globalDedup: workspacesConf.Scope == config.WorkspaceScopeGlobal,
+		metrics: metrics,
+		logger: logger,
 	}
 }
 
-func (uc *useCase) CreateShortLink(link *models.Link) (error) {
-	shortLink, err := uc.linkRepository.SelectLinkByOriginalLink(link.OriginalLink)
+func (uc *useCase) CreateShortLink(ctx context.Context, principal *models.Principal, link *models.Link) (err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.CreateShortLink")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if _, err = scope(principal, workspaceUsecase.ActionLinksCreate); err != nil {
+		return err
+	}
+
+	link.Domain, err = uc.domain(link.Domain)
+	if err != nil {
+		return err
+	}
+	link.OwnerID = principal.OwnerID
+	link.WorkspaceID = workspace(principal.WorkspaceID)
+
+	dedupWorkspaceID := link.WorkspaceID
+	if uc.globalDedup {
+		dedupWorkspaceID = ""
+	}
+
+	shortLink, err := uc.linkRepository.SelectLinkByOriginalLink(ctx, dedupWorkspaceID, link.Domain, link.OriginalLink)
 	if err != nil && !errors.Is(err, models.ErrNotFound) {
 		return errors.Wrap(err, "link repository error")
 	} else if err == nil {
 		link.ShortLink = shortLink
+		link.ShortURL = uc.shortURL(link)
+		uc.metrics.creation(resultDeduplicated)
+		uc.logger.DebugContext(ctx, "existing short link returned", "domain", link.Domain, "short_link", shortLink)
 		return nil
 	}
 
-	link.ShortLink, err = generateShortLink(link.OriginalLink)
-	if err != nil {
-		return errors.Wrap(err, "generation short link error")
+	var consumed []models.QuotaLimit
+	if uc.quotaUC != nil {
+		consumed, err = uc.quotaUC.ConsumeLinkCreation(ctx, link.OwnerID)
+		if err != nil {
+			return errors.Wrap(err, "link quota error")
+		}
 	}
 
-	err = uc.linkRepository.CreateLink(link)
+	// the short link generated for the original link may be taken by another link,
+	// e.g. one updated to point elsewhere, so the seed is salted on conflicts
+	for attempt := 0; attempt < maxGenerationAttempts; attempt++ {
+		link.ShortLink, err = generateShortLink(saltSeed(uc.shortLinkSeed(link), attempt))
+		if err != nil {
+			uc.refundQuota(ctx, link.OwnerID, consumed)
+			return errors.Wrap(err, "generation short link error")
+		}
+
+		err = uc.linkRepository.CreateLink(ctx, link,
+			uc.auditRecord(ctx, models.AuditLinkCreate, linkResource(link.Domain, link.ShortLink)))
+		if !errors.Is(err, models.ErrConflict) {
+			break
+		}
+		uc.logger.DebugContext(ctx, "generated short link is taken", "domain", link.Domain, "short_link", link.ShortLink)
+	}
 	if err != nil {
+		uc.refundQuota(ctx, link.OwnerID, consumed)
 		return errors.Wrap(err, "link repository error")
 	}
 
+	link.ShortURL = uc.shortURL(link)
+	uc.metrics.creation(resultCreated)
+	uc.logger.InfoContext(ctx, "short link created", "domain", link.Domain, "short_link", link.ShortLink)
 	return nil
 }
 
-func (uc *useCase) GetOriginalLink(link string) (string, error) {
-	gotLink, err := uc.linkRepository.SelectLinkByShortLink(link)
+func (uc *useCase) GetOriginalLink(ctx context.Context, host string, link string) (_ string, err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.GetOriginalLink")
+	defer func() { observability.EndSpan(span, err) }()
+
+	return uc.lookup(ctx, uc.hostDomain(host), link)
+}
+
+func (uc *useCase) GetOriginalLinkOnDomain(ctx context.Context, domain string, link string) (_ string, err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.GetOriginalLinkOnDomain")
+	defer func() { observability.EndSpan(span, err) }()
+
+	domain, err = uc.domain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	return uc.lookup(ctx, domain, link)
+}
+
+// lookup returns the original link of the short link on the allowed domain without counting the click.
+func (uc *useCase) lookup(ctx context.Context, domain string, link string) (string, error) {
+	originalLink, err := uc.linkRepository.SelectLinkByShortLink(ctx, domain, link)
+	if errors.Is(err, models.ErrNotFound) {
+		uc.metrics.lookup(resultMiss)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "link repository error")
+	}
+
+	uc.metrics.lookup(resultHit)
+	return originalLink, nil
+}
+
+func (uc *useCase) FollowLink(ctx context.Context, host string, link string) (_ string, err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.FollowLink")
+	defer func() { observability.EndSpan(span, err) }()
+
+	gotLink, err := uc.linkRepository.IncrementClicks(ctx, uc.hostDomain(host), link)
+	if errors.Is(err, models.ErrNotFound) {
+		uc.metrics.lookup(resultMiss)
+	}
 	if err != nil {
 		return "", errors.Wrap(err, "link repository error")
 	}
 
-	return gotLink, nil
+	uc.metrics.lookup(resultHit)
+	if uc.clicks != nil {
+		uc.clicks.LinkClicked(ctx, *gotLink)
+	}
+	return gotLink.OriginalLink, nil
+}
+
+func (uc *useCase) GetLinks(ctx context.Context, principal *models.Principal) (_ []models.Link, err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.GetLinks")
+	defer func() { observability.EndSpan(span, err) }()
+
+	linkScope, err := scope(principal, workspaceUsecase.ActionLinksRead)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := uc.linkRepository.SelectLinks(ctx, linkScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "link repository error")
+	}
+
+	for idx := range links {
+		links[idx].ShortURL = uc.shortURL(&links[idx])
+	}
+	return links, nil
+}
+
+func (uc *useCase) UpdateLink(ctx context.Context, principal *models.Principal, link *models.Link) (err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.UpdateLink")
+	defer func() { observability.EndSpan(span, err) }()
+
+	linkScope, err := scope(principal, workspaceUsecase.ActionLinksWrite)
+	if err != nil {
+		return err
+	}
+	link.WorkspaceID = linkScope.WorkspaceID
+
+	link.Domain, err = uc.domain(link.Domain)
+	if err != nil {
+		return err
+	}
+
+	before, err := uc.linkRepository.UpdateLink(ctx, linkScope, link,
+		uc.auditRecord(ctx, models.AuditLinkUpdate, linkResource(link.Domain, link.ShortLink)))
+	if err != nil {
+		return errors.Wrap(err, "link repository error")
+	}
+	link.OwnerID = before.OwnerID
+	link.ShortURL = uc.shortURL(link)
+	uc.logger.InfoContext(ctx, "link updated", "domain", link.Domain, "short_link", link.ShortLink)
+	return nil
+}
+
+func (uc *useCase) DeleteLink(ctx context.Context, principal *models.Principal, domain string,
+	shortLink string) (err error) {
+	ctx, span := observability.StartSpan(ctx, "link.usecase.DeleteLink")
+	defer func() { observability.EndSpan(span, err) }()
+
+	linkScope, err := scope(principal, workspaceUsecase.ActionLinksWrite)
+	if err != nil {
+		return err
+	}
+
+	domain, err = uc.domain(domain)
+	if err != nil {
+		return err
+	}
+
+	_, err = uc.linkRepository.DeleteLink(ctx, linkScope, domain, shortLink,
+		uc.auditRecord(ctx, models.AuditLinkDelete, linkResource(domain, shortLink)))
+	if err != nil {
+		return errors.Wrap(err, "link repository error")
+	}
+
+	uc.logger.InfoContext(ctx, "link deleted", "domain", domain, "short_link", shortLink)
+	return nil
+}
+
+// WatchLinks calls handle for every event of links readable by principal published
+// after cursor (see linkEvents.Bus.Subscribe); administrators watch links of every
+// workspace. It returns when ctx is done, handle fails or events can not be delivered anymore.
+func (uc *useCase) WatchLinks(ctx context.Context, principal *models.Principal, cursor string,
+	handle func(models.LinkEvent) error) error {
+	linkScope, err := scope(principal, workspaceUsecase.ActionLinksRead)
+	if err != nil {
+		return err
+	}
+	allWorkspaces := principal.HasScope(models.ScopeAdmin)
+
+	if uc.events == nil {
+		return errors.Wrap(models.ErrServiceUnavailable, "link events are not published")
+	}
+
+	sub, err := uc.events.Subscribe(cursor)
+	if err != nil {
+		return errors.Wrap(err, "link events error")
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return errors.Wrap(sub.Err(), "link events error")
+			}
+			if !allWorkspaces && !linkScope.Includes(event.Link) {
+				continue
+			}
+			if err := handle(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// auditRecord returns the record of the change written by the repository with the change,
+// nil if changes are not recorded.
+func (uc *useCase) auditRecord(ctx context.Context, action string, resource string) *models.AuditRecord {
+	if uc.auditUC == nil {
+		return nil
+	}
+	return uc.auditUC.NewRecord(ctx, action, resource)
+}
+
+// refundQuota returns quota consumed for the link that has not been created.
+func (uc *useCase) refundQuota(ctx context.Context, ownerID string, consumed []models.QuotaLimit) {
+	if uc.quotaUC == nil {
+		return
+	}
+	if err := uc.quotaUC.RefundLinkCreation(ctx, ownerID, consumed); err != nil {
+		uc.logger.ErrorContext(ctx, "link quota refund failed", "owner_id", ownerID, "error", err)
+	}
+}
+
+// domain returns the allowed domain named by the client, the default one if it is empty.
+func (uc *useCase) domain(domain string) (string, error) {
+	if domain == "" {
+		return uc.defaultDomain, nil
+	}
+
+	allowed, ok := uc.domains[strings.ToLower(domain)]
+	if !ok {
+		return "", errors.Wrapf(models.ErrBadRequest, "domain %q is not allowed", domain)
+	}
+	return allowed, nil
+}
+
+// hostDomain returns the allowed domain of the host, the default one if the host is not allowed.
+func (uc *useCase) hostDomain(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	if allowed, ok := uc.domains[strings.ToLower(host)]; ok {
+		return allowed
+	}
+	return uc.defaultDomain
+}
+
+// shortURL returns public URL redirecting to the original link, empty if the domain
+// of the link has no base URL.
+func (uc *useCase) shortURL(link *models.Link) string {
+	baseURL, ok := uc.baseURLs[link.Domain]
+	if !ok {
+		return ""
+	}
+	return baseURL + "/" + link.ShortLink
+}
+
+// shortLinkSeed returns the value the short link of a new link is derived from. Links
+// of other workspaces get distinct short links for the same original link, while
+// links of the default workspace keep short links they got before workspaces.
+func (uc *useCase) shortLinkSeed(link *models.Link) string {
+	if uc.globalDedup || link.WorkspaceID == models.DefaultWorkspace {
+		return link.OriginalLink
+	}
+	return link.WorkspaceID + " " + link.OriginalLink
+}
+
+// scope authorizes action of principal and returns the scope of links the action applies to:
+// links of every owner of the workspace for its members and administrators, own links for others.
+func scope(principal *models.Principal, action workspaceUsecase.Action) (models.LinkScope, error) {
+	allMembers, err := workspaceUsecase.Authorize(principal, action)
+	if err != nil {
+		return models.LinkScope{}, err
+	}
+
+	return models.LinkScope{
+		WorkspaceID: workspace(principal.WorkspaceID),
+		OwnerID:     principal.OwnerID,
+		AllOwners:   allMembers,
+	}, nil
+}
+
+// linkResource identifies the link in the audit log as <domain>/<short_link>, links
+// without domain are identified by the short link.
+func linkResource(domain string, shortLink string) string {
+	if domain == "" {
+		return shortLink
+	}
+	return domain + "/" + shortLink
+}
+
+// workspace returns the workspace of the client, the default one if it is empty.
+func workspace(workspaceID string) string {
+	if workspaceID == "" {
+		return models.DefaultWorkspace
+	}
+	return workspaceID
+}
+
+// saltSeed returns the seed of the attempt to generate a short link which is not taken,
+// the first attempt uses the seed as is.
+func saltSeed(seed string, attempt int) string {
+	if attempt == 0 {
+		return seed
+	}
+	return seed + " " + strconv.Itoa(attempt)
 }
 
-func generateShortLink(originalLink string) (string, error) {
+func generateShortLink(seed string) (string, error) {
 	h := sha256.New()
-	_, err := h.Write([]byte(originalLink))
+	_, err := h.Write([]byte(seed))
 	if err != nil {
 		return "", err
 	}
@@ -1,14 +1,30 @@
 package usecase_test
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/kuzkuss/url_service/config"
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/usecase/mocks"
+	linkEvents "github.com/kuzkuss/url_service/internal/link/events"
 	linkUsecase "github.com/kuzkuss/url_service/internal/link/usecase"
 	linkMocks "github.com/kuzkuss/url_service/internal/link/repository/mocks"
+	linkUsecaseMocks "github.com/kuzkuss/url_service/internal/link/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	quotaMocks "github.com/kuzkuss/url_service/internal/quota/usecase/mocks"
 	"github.com/kuzkuss/url_service/models"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	otelCodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TestCaseGet struct {
@@ -42,13 +58,18 @@ func TestUsecaseCreateShortLink(t *testing.T) {
 
 	mockLinkRepo := linkMocks.NewRepositoryI(t)
 
-	mockLinkRepo.On("SelectLinkByOriginalLink", linkSuccess.OriginalLink).Return("", models.ErrNotFound)
-	mockLinkRepo.On("CreateLink", &linkSuccess).Return(nil)
-	mockLinkRepo.On("SelectLinkByOriginalLink", linkConflict.OriginalLink).Return(linkConflict.ShortLink, nil)
-	mockLinkRepo.On("SelectLinkByOriginalLink", linkError.OriginalLink).Return("", models.ErrNotFound)
-	mockLinkRepo.On("CreateLink", &linkError).Return(createErr)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkSuccess.OriginalLink).Return("", models.ErrNotFound)
+	// the record is written by the repository with the link
+	record := &models.AuditRecord{Action: models.AuditLinkCreate}
+	mockLinkRepo.On("CreateLink", mock.Anything, &linkSuccess, record).Return(nil)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkConflict.OriginalLink).Return(linkConflict.ShortLink, nil)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkError.OriginalLink).Return("", models.ErrNotFound)
+	mockLinkRepo.On("CreateLink", mock.Anything, &linkError, record).Return(createErr)
 
-	usecase := linkUsecase.New(mockLinkRepo)
+	mockAudit := auditMocks.NewUseCaseI(t)
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkCreate, mock.Anything).Return(record).Twice()
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, mockAudit, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
 
 	cases := map[string]TestCaseCreate {
 		"success": {
@@ -67,17 +88,155 @@ func TestUsecaseCreateShortLink(t *testing.T) {
 
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
-			err := usecase.CreateShortLink(test.ArgData)
+			err := usecase.CreateShortLink(context.Background(), &models.Principal{OwnerID: test.ArgData.OwnerID}, test.ArgData)
 			require.Equal(t, test.Error, errors.Cause(err))
 		})
 	}
 	mockLinkRepo.AssertExpectations(t)
 }
 
+func TestUsecaseCreateShortLinkQuota(t *testing.T) {
+	linkSuccess := models.Link {
+		OriginalLink: "original_link_success",
+		OwnerID: "owner",
+	}
+
+	linkExceeded := models.Link {
+		OriginalLink: "original_link_exceeded",
+		OwnerID: "owner_exceeded",
+	}
+
+	linkExisting := models.Link {
+		OriginalLink: "original_link_existing",
+		OwnerID: "owner_exceeded",
+	}
+
+	linkFailed := models.Link {
+		OriginalLink: "original_link_failed",
+		OwnerID: "owner_failed",
+	}
+
+	quotaErr := &models.RateLimitError{Reason: "day link quota exceeded"}
+	createErr := errors.New("error")
+	consumed := []models.QuotaLimit{{Period: models.QuotaPeriodDay, Limit: 10}}
+
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+	mockQuota := quotaMocks.NewUseCaseI(t)
+
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkSuccess.OriginalLink).Return("", models.ErrNotFound)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkExceeded.OriginalLink).Return("", models.ErrNotFound)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkExisting.OriginalLink).Return("short_link_existing", nil)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", linkFailed.OriginalLink).Return("", models.ErrNotFound)
+	mockLinkRepo.On("CreateLink", mock.Anything, &linkSuccess, mock.Anything).Return(nil)
+	mockLinkRepo.On("CreateLink", mock.Anything, &linkFailed, mock.Anything).Return(createErr)
+	mockQuota.On("ConsumeLinkCreation", mock.Anything, linkSuccess.OwnerID).Return(consumed, nil)
+	mockQuota.On("ConsumeLinkCreation", mock.Anything, linkExceeded.OwnerID).Return(nil, quotaErr)
+	mockQuota.On("ConsumeLinkCreation", mock.Anything, linkFailed.OwnerID).Return(consumed, nil)
+	mockQuota.On("RefundLinkCreation", mock.Anything, linkFailed.OwnerID, consumed).Return(nil).Once()
+
+	usecase := linkUsecase.New(mockLinkRepo, mockQuota, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	cases := map[string]TestCaseCreate {
+		"success": {
+			ArgData:   &linkSuccess,
+			Error: nil,
+		},
+		"exceeded": {
+			ArgData:   &linkExceeded,
+			Error: quotaErr,
+		},
+		"existing_not_accounted": {
+			ArgData:   &linkExisting,
+			Error: nil,
+		},
+		"refunded_on_failure": {
+			ArgData:   &linkFailed,
+			Error: createErr,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := usecase.CreateShortLink(context.Background(), &models.Principal{OwnerID: test.ArgData.OwnerID}, test.ArgData)
+			require.Equal(t, test.Error, errors.Cause(err))
+		})
+	}
+}
+
+func TestUsecaseCreateShortLinkTaken(t *testing.T) {
+	link := models.Link {
+		OriginalLink: "original_link_taken",
+	}
+
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	var generated []string
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", link.OriginalLink).Return("", models.ErrNotFound)
+	mockLinkRepo.On("CreateLink", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { generated = append(generated, args.Get(1).(*models.Link).ShortLink) }).
+		Return(models.ErrConflict).Once()
+	mockLinkRepo.On("CreateLink", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { generated = append(generated, args.Get(1).(*models.Link).ShortLink) }).
+		Return(nil).Once()
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	err := usecase.CreateShortLink(context.Background(), &models.Principal{}, &link)
+	require.NoError(t, err)
+	require.Len(t, generated, 2)
+	assert.NotEqual(t, generated[0], generated[1])
+	assert.Equal(t, generated[1], link.ShortLink)
+}
+
 func TestUsecaseGetOriginalLink(t *testing.T) {
+	getErr := errors.New("error")
+
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	// lookups are not counted as clicks
+	mockLinkRepo.On("SelectLinkByShortLink", mock.Anything, "", "short_link_success").Return("original_link_success", nil)
+	mockLinkRepo.On("SelectLinkByShortLink", mock.Anything, "", "short_link_error").Return("", getErr)
+	mockLinkRepo.On("SelectLinkByShortLink", mock.Anything, "", "short_link_not_found").Return("", models.ErrNotFound)
+
+	mockClicks := linkUsecaseMocks.NewClickObserverI(t)
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, mockClicks, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	cases := map[string]TestCaseGet {
+		"success": {
+			ArgData:   "short_link_success",
+			ExpectedRes: "original_link_success",
+			Error: nil,
+		},
+		"error": {
+			ArgData:   "short_link_error",
+			Error: getErr,
+		},
+		"not_found": {
+			ArgData:   "short_link_not_found",
+			Error: models.ErrNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			actualRes, err := usecase.GetOriginalLink(context.Background(), "", test.ArgData)
+			require.Equal(t, test.Error, errors.Cause(err))
+
+			if err == nil {
+				assert.Equal(t, test.ExpectedRes, actualRes)
+			}
+		})
+	}
+	mockLinkRepo.AssertExpectations(t)
+}
+
+func TestUsecaseFollowLink(t *testing.T) {
 	linkSuccess := models.Link {
 		OriginalLink: "original_link_success",
 		ShortLink: "short_link_success",
+		OwnerID: "owner",
+		Clicks: 1000,
 	}
 
 	linkError := models.Link {
@@ -94,11 +253,14 @@ func TestUsecaseGetOriginalLink(t *testing.T) {
 
 	mockLinkRepo := linkMocks.NewRepositoryI(t)
 
-	mockLinkRepo.On("SelectLinkByShortLink", linkSuccess.ShortLink).Return(linkSuccess.OriginalLink, nil)
-	mockLinkRepo.On("SelectLinkByShortLink", linkError.ShortLink).Return(linkError.OriginalLink, getErr)
-	mockLinkRepo.On("SelectLinkByShortLink", linkNotFound.ShortLink).Return(linkNotFound.OriginalLink, models.ErrNotFound)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", linkSuccess.ShortLink).Return(&linkSuccess, nil)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", linkError.ShortLink).Return(nil, getErr)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", linkNotFound.ShortLink).Return(nil, models.ErrNotFound)
 
-	usecase := linkUsecase.New(mockLinkRepo)
+	mockClicks := linkUsecaseMocks.NewClickObserverI(t)
+	mockClicks.On("LinkClicked", mock.Anything, linkSuccess).Return()
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, mockClicks, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
 
 	cases := map[string]TestCaseGet {
 		"success": {
@@ -120,7 +282,7 @@ func TestUsecaseGetOriginalLink(t *testing.T) {
 
 	for name, test := range cases {
 		t.Run(name, func(t *testing.T) {
-			actualRes, err := usecase.GetOriginalLink(test.ArgData)
+			actualRes, err := usecase.FollowLink(context.Background(), "", test.ArgData)
 			require.Equal(t, test.Error, errors.Cause(err))
 
 			if err == nil {
@@ -131,3 +293,436 @@ func TestUsecaseGetOriginalLink(t *testing.T) {
 	mockLinkRepo.AssertExpectations(t)
 }
 
+func TestUsecaseGetLinks(t *testing.T) {
+	links := []models.Link {
+		{
+			OriginalLink: "original_link_success",
+			ShortLink: "short_link_success",
+			OwnerID: "owner",
+		},
+	}
+
+	getErr := errors.New("error")
+
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	mockLinkRepo.On("SelectLinks", mock.Anything, models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}).Return(links, nil)
+	mockLinkRepo.On("SelectLinks", mock.Anything,
+		models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner_error"}).Return(nil, getErr)
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	actualRes, err := usecase.GetLinks(context.Background(), &models.Principal{OwnerID: "owner"})
+	require.NoError(t, err)
+	assert.Equal(t, links, actualRes)
+
+	_, err = usecase.GetLinks(context.Background(), &models.Principal{OwnerID: "owner_error"})
+	require.Equal(t, getErr, errors.Cause(err))
+}
+
+func TestUsecaseUpdateLink(t *testing.T) {
+	linkSuccess := models.Link {
+		OriginalLink: "original_link_success",
+		ShortLink: "short_link_success",
+		OwnerID: "owner",
+	}
+
+	linkNotFound := models.Link {
+		OriginalLink: "original_link_not_found",
+		ShortLink: "short_link_not_found",
+		OwnerID: "owner",
+	}
+
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	before := models.Link{OriginalLink: "original_link_before", ShortLink: linkSuccess.ShortLink}
+	record := &models.AuditRecord{Action: models.AuditLinkUpdate, Resource: linkSuccess.ShortLink}
+	notFoundRecord := &models.AuditRecord{Action: models.AuditLinkUpdate, Resource: linkNotFound.ShortLink}
+
+	ownLinks := models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}
+	mockLinkRepo.On("UpdateLink", mock.Anything, ownLinks, &linkSuccess, record).Return(&before, nil)
+	mockLinkRepo.On("UpdateLink", mock.Anything, ownLinks, &linkNotFound, notFoundRecord).Return(nil, models.ErrNotFound)
+
+	mockAudit := auditMocks.NewUseCaseI(t)
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkUpdate, linkSuccess.ShortLink).Return(record).Once()
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkUpdate, linkNotFound.ShortLink).Return(notFoundRecord).Once()
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, mockAudit, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	cases := map[string]TestCaseCreate {
+		"success": {
+			ArgData:   &linkSuccess,
+			Error: nil,
+		},
+		"not_found": {
+			ArgData:   &linkNotFound,
+			Error: models.ErrNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := usecase.UpdateLink(context.Background(), &models.Principal{OwnerID: test.ArgData.OwnerID}, test.ArgData)
+			require.Equal(t, test.Error, errors.Cause(err))
+		})
+	}
+}
+
+func TestUsecaseDeleteLink(t *testing.T) {
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	deleted := &models.Link{OriginalLink: "original_link_success", ShortLink: "short_link_success"}
+
+	record := &models.AuditRecord{Action: models.AuditLinkDelete, Resource: "short_link_success"}
+	notFoundRecord := &models.AuditRecord{Action: models.AuditLinkDelete, Resource: "short_link_not_found"}
+
+	ownLinks := models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}
+	mockLinkRepo.On("DeleteLink", mock.Anything, ownLinks, "", "short_link_success", record).Return(deleted, nil)
+	mockLinkRepo.On("DeleteLink", mock.Anything, ownLinks, "", "short_link_not_found", notFoundRecord).
+		Return(nil, models.ErrNotFound)
+
+	mockAudit := auditMocks.NewUseCaseI(t)
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkDelete, "short_link_success").Return(record).Once()
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkDelete, "short_link_not_found").Return(notFoundRecord).Once()
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, mockAudit, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	err := usecase.DeleteLink(context.Background(), &models.Principal{OwnerID: "owner"}, "", "short_link_success")
+	require.NoError(t, err)
+
+	err = usecase.DeleteLink(context.Background(), &models.Principal{OwnerID: "owner"}, "", "short_link_not_found")
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+}
+
+func TestUsecaseLinkDomains(t *testing.T) {
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "a.io", "original_link").Return("", models.ErrNotFound)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "b.io", "original_link").Return("short_link", nil)
+	mockLinkRepo.On("CreateLink", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockLinkRepo.On("SelectLinkByShortLink", mock.Anything, "b.io", "short_link").Return("original_link_b", nil)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "a.io", "short_link").
+		Return(&models.Link{ShortLink: "short_link", Domain: "a.io", OriginalLink: "original_link_a"}, nil)
+	mockLinkRepo.On("DeleteLink", mock.Anything, models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}, "a.io", "short_link", mock.Anything).
+		Return(&models.Link{}, nil)
+
+	// links are recorded in the audit log with their domains
+	mockAudit := auditMocks.NewUseCaseI(t)
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkCreate, mock.MatchedBy(func(resource string) bool {
+		return strings.HasPrefix(resource, "a.io/")
+	})).Return(&models.AuditRecord{}).Once()
+	mockAudit.On("NewRecord", mock.Anything, models.AuditLinkDelete, "a.io/short_link").Return(&models.AuditRecord{}).Once()
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, mockAudit, nil, nil,
+		config.DomainsConfig{Allowed: []string{"a.io", "b.io"}, Default: "a.io"}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	t.Run("create_default", func(t *testing.T) {
+		link := models.Link{OriginalLink: "original_link"}
+		require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{}, &link))
+		assert.Equal(t, "a.io", link.Domain)
+		assert.Equal(t, "https://a.io/" + link.ShortLink, link.ShortURL)
+	})
+
+	t.Run("create_allowed", func(t *testing.T) {
+		link := models.Link{OriginalLink: "original_link", Domain: "B.io"}
+		require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{}, &link))
+		assert.Equal(t, models.Link{OriginalLink: "original_link", ShortLink: "short_link", Domain: "b.io",
+			ShortURL: "https://b.io/short_link", WorkspaceID: models.DefaultWorkspace}, link)
+	})
+
+	t.Run("create_not_allowed", func(t *testing.T) {
+		err := usecase.CreateShortLink(context.Background(), &models.Principal{},
+			&models.Link{OriginalLink: "original_link", Domain: "c.io"})
+		require.Equal(t, models.ErrBadRequest, errors.Cause(err))
+	})
+
+	t.Run("get_by_host", func(t *testing.T) {
+		originalLink, err := usecase.GetOriginalLink(context.Background(), "b.io:8080", "short_link")
+		require.NoError(t, err)
+		assert.Equal(t, "original_link_b", originalLink)
+	})
+
+	t.Run("get_on_domain", func(t *testing.T) {
+		originalLink, err := usecase.GetOriginalLinkOnDomain(context.Background(), "B.io", "short_link")
+		require.NoError(t, err)
+		assert.Equal(t, "original_link_b", originalLink)
+
+		// unlike the host, the domain given explicitly must be allowed
+		_, err = usecase.GetOriginalLinkOnDomain(context.Background(), "c.io", "short_link")
+		require.Equal(t, models.ErrBadRequest, errors.Cause(err))
+	})
+
+	t.Run("get_unknown_host", func(t *testing.T) {
+		originalLink, err := usecase.FollowLink(context.Background(), "localhost", "short_link")
+		require.NoError(t, err)
+		assert.Equal(t, "original_link_a", originalLink)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		principal := &models.Principal{OwnerID: "owner"}
+		require.NoError(t, usecase.DeleteLink(context.Background(), principal, "", "short_link"))
+		err := usecase.DeleteLink(context.Background(), principal, "c.io", "short_link")
+		require.Equal(t, models.ErrBadRequest, errors.Cause(err))
+	})
+}
+
+func TestUsecaseLinkWorkspaces(t *testing.T) {
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", "original_link").
+		Return("", models.ErrNotFound)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, "team", "", "original_link").Return("", models.ErrNotFound)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, "", "", "original_link").Return("short_link", nil)
+	mockLinkRepo.On("CreateLink", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	t.Run("workspace_scope", func(t *testing.T) {
+		usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{},
+			config.WorkspacesConfig{Scope: config.WorkspaceScopeWorkspace}, nil, observability.NopLogger())
+
+		linkDefault := models.Link{OriginalLink: "original_link"}
+		require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{}, &linkDefault))
+		assert.Equal(t, models.DefaultWorkspace, linkDefault.WorkspaceID)
+
+		linkTeam := models.Link{OriginalLink: "original_link"}
+		require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{WorkspaceID: "team", Role: models.RoleEditor}, &linkTeam))
+		assert.Equal(t, "team", linkTeam.WorkspaceID)
+		assert.NotEqual(t, linkDefault.ShortLink, linkTeam.ShortLink)
+	})
+
+	t.Run("global_scope", func(t *testing.T) {
+		usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{},
+			config.WorkspacesConfig{Scope: config.WorkspaceScopeGlobal}, nil, observability.NopLogger())
+
+		link := models.Link{OriginalLink: "original_link"}
+		require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{WorkspaceID: "team", Role: models.RoleEditor}, &link))
+		assert.Equal(t, "short_link", link.ShortLink)
+	})
+}
+
+func TestUsecaseShortURL(t *testing.T) {
+	links := []models.Link {
+		{ShortLink: "short_link_a", Domain: "a.io"},
+		{ShortLink: "short_link_b", Domain: "b.io"},
+		{ShortLink: "short_link"},
+	}
+
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+	mockLinkRepo.On("SelectLinks", mock.Anything, models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}).Return(links, nil)
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig {
+		Allowed: []string{"a.io", "b.io"},
+		Default: "a.io",
+		BaseURL: "http://localhost:8080/",
+		BaseURLs: map[string]string{"b.io": "https://b.io/s/"},
+	}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	actualRes, err := usecase.GetLinks(context.Background(), &models.Principal{OwnerID: "owner"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://a.io/short_link_a", "https://b.io/s/short_link_b", "http://localhost:8080/short_link"},
+		[]string{actualRes[0].ShortURL, actualRes[1].ShortURL, actualRes[2].ShortURL})
+
+	usecase = linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	actualRes, err = usecase.GetLinks(context.Background(), &models.Principal{OwnerID: "owner"})
+	require.NoError(t, err)
+	assert.Empty(t, actualRes[2].ShortURL)
+}
+
+func TestUsecaseMetrics(t *testing.T) {
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", "original_link_new").Return("", models.ErrNotFound)
+	mockLinkRepo.On("SelectLinkByOriginalLink", mock.Anything, models.DefaultWorkspace, "", "original_link_existing").Return("short_link_existing", nil)
+	mockLinkRepo.On("CreateLink", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockLinkRepo.On("SelectLinkByShortLink", mock.Anything, "", "short_link_existing").Return("original_link_existing", nil)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", "short_link_missing").Return(nil, models.ErrNotFound)
+
+	registry := prometheus.NewRegistry()
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{},
+		linkUsecase.NewMetrics(registry), observability.NopLogger())
+
+	require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{}, &models.Link{OriginalLink: "original_link_new"}))
+	require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{}, &models.Link{OriginalLink: "original_link_existing"}))
+	require.NoError(t, usecase.CreateShortLink(context.Background(), &models.Principal{}, &models.Link{OriginalLink: "original_link_existing"}))
+
+	_, err := usecase.GetOriginalLink(context.Background(), "", "short_link_existing")
+	require.NoError(t, err)
+	_, err = usecase.FollowLink(context.Background(), "", "short_link_missing")
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+
+	expected := `
+# HELP link_creations_total Total number of create requests by result: created new link or deduplicated by original link.
+# TYPE link_creations_total counter
+link_creations_total{result="created"} 1
+link_creations_total{result="deduplicated"} 2
+# HELP link_lookups_total Total number of original link lookups by result: hit or miss.
+# TYPE link_lookups_total counter
+link_lookups_total{result="hit"} 1
+link_lookups_total{result="miss"} 1
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected))
+	assert.NoError(t, err)
+}
+
+func TestUsecaseTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	getErr := errors.New("error")
+
+	var repositorySpan trace.SpanContext
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", "short_link_success").
+		Return(&models.Link{ShortLink: "short_link_success", OriginalLink: "original_link", Clicks: 1}, nil).
+		Run(func(args mock.Arguments) {
+			repositorySpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+		})
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", "short_link_not_found").Return(nil, models.ErrNotFound)
+	mockLinkRepo.On("IncrementClicks", mock.Anything, "", "short_link_error").Return(nil, getErr)
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	_, err := usecase.FollowLink(context.Background(), "", "short_link_success")
+	require.NoError(t, err)
+	_, err = usecase.FollowLink(context.Background(), "", "short_link_not_found")
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	_, err = usecase.FollowLink(context.Background(), "", "short_link_error")
+	require.Equal(t, getErr, errors.Cause(err))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for _, span := range spans {
+		assert.Equal(t, "link.usecase.FollowLink", span.Name())
+	}
+	assert.Equal(t, spans[0].SpanContext().SpanID(), repositorySpan.SpanID())
+	assert.Equal(t, otelCodes.Unset, spans[0].Status().Code)
+	assert.Equal(t, otelCodes.Unset, spans[1].Status().Code)
+	assert.Equal(t, otelCodes.Error, spans[2].Status().Code)
+}
+
+func TestUsecaseWatchLinks(t *testing.T) {
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	bus := linkEvents.NewBus(10, 10)
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, bus, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	sub, err := bus.Subscribe("")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// events are published by the outbox relay
+	ctx := context.Background()
+	for _, event := range []models.LinkEvent {
+		{Type: models.LinkCreated, Link: models.Link{ShortLink: "short_link", OriginalLink: "original_link", OwnerID: "owner"}},
+		{Type: models.LinkCreated, Link: models.Link{ShortLink: "other", OriginalLink: "original_link_other", OwnerID: "other"}},
+		{Type: models.LinkUpdated, Link: models.Link{ShortLink: "short_link", OriginalLink: "original_link_new", OwnerID: "owner"}},
+		{Type: models.LinkDeleted, Link: models.Link{ShortLink: "short_link", OwnerID: "owner"}},
+	} {
+		event.Link.WorkspaceID = models.DefaultWorkspace
+		bus.Publish(event)
+	}
+
+	var published []models.LinkEvent
+	for i := 0; i < 4; i++ {
+		published = append(published, <-sub.Events())
+	}
+
+	// resumed after the creation, skipping the link of the other owner
+	stop := errors.New("stop")
+	var watched []models.LinkEventType
+	principal := &models.Principal{OwnerID: "owner"}
+	err = usecase.WatchLinks(ctx, principal, published[0].Cursor, func(event models.LinkEvent) error {
+		watched = append(watched, event.Type)
+		if len(watched) == 2 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, stop, err)
+	assert.Equal(t, []models.LinkEventType{models.LinkUpdated, models.LinkDeleted}, watched)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = usecase.WatchLinks(cancelled, principal, "", func(models.LinkEvent) error { return nil })
+	require.Equal(t, context.Canceled, err)
+
+	err = usecase.WatchLinks(ctx, principal, "cursor", func(models.LinkEvent) error { return nil })
+	require.Equal(t, models.ErrBadRequest, errors.Cause(err))
+}
+
+func TestUsecaseLinkRoles(t *testing.T) {
+	mockLinkRepo := linkMocks.NewRepositoryI(t)
+
+	links := []models.Link{{ShortLink: "short_link", OwnerID: "other", WorkspaceID: "team"}}
+	allOwners := models.LinkScope{WorkspaceID: "team", OwnerID: "owner", AllOwners: true}
+	ownLinks := models.LinkScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}
+	mockLinkRepo.On("SelectLinks", mock.Anything, allOwners).Return(links, nil)
+	mockLinkRepo.On("SelectLinks", mock.Anything, ownLinks).Return(nil, nil)
+	mockLinkRepo.On("SelectLinks", mock.Anything, models.LinkScope{WorkspaceID: "team", AllOwners: true}).Return(links, nil)
+	mockLinkRepo.On("UpdateLink", mock.Anything, allOwners, mock.MatchedBy(func(link *models.Link) bool {
+		return link.WorkspaceID == "team"
+	}), mock.Anything).Return(&models.Link{ShortLink: "short_link", OwnerID: "other"}, nil)
+	mockLinkRepo.On("DeleteLink", mock.Anything, ownLinks, "", "short_link", mock.Anything).Return(nil, models.ErrNotFound)
+
+	usecase := linkUsecase.New(mockLinkRepo, nil, nil, nil, nil, config.DomainsConfig{}, config.WorkspacesConfig{}, nil, observability.NopLogger())
+
+	viewer := &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleViewer}
+	editor := &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleEditor}
+	notMember := &models.Principal{OwnerID: "owner", WorkspaceID: "team"}
+	notMemberOfDefault := &models.Principal{OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}
+
+	t.Run("viewer_lists_every_member", func(t *testing.T) {
+		actualRes, err := usecase.GetLinks(context.Background(), viewer)
+		require.NoError(t, err)
+		assert.Equal(t, links, actualRes)
+	})
+
+	t.Run("viewer_can_not_create", func(t *testing.T) {
+		err := usecase.CreateShortLink(context.Background(), viewer, &models.Link{OriginalLink: "original_link"})
+		require.Equal(t, models.ErrForbidden, errors.Cause(err))
+	})
+
+	t.Run("viewer_can_not_delete", func(t *testing.T) {
+		err := usecase.DeleteLink(context.Background(), viewer, "", "short_link")
+		require.Equal(t, models.ErrForbidden, errors.Cause(err))
+	})
+
+	t.Run("editor_updates_link_of_other_member", func(t *testing.T) {
+		link := models.Link{ShortLink: "short_link", OriginalLink: "original_link_new"}
+		require.NoError(t, usecase.UpdateLink(context.Background(), editor, &link))
+		assert.Equal(t, "other", link.OwnerID)
+	})
+
+	t.Run("not_member_can_not_list", func(t *testing.T) {
+		_, err := usecase.GetLinks(context.Background(), notMember)
+		require.Equal(t, models.ErrForbidden, errors.Cause(err))
+	})
+
+	t.Run("removed_member_can_not_create", func(t *testing.T) {
+		err := usecase.CreateShortLink(context.Background(), notMember, &models.Link{OriginalLink: "original_link"})
+		require.Equal(t, models.ErrForbidden, errors.Cause(err))
+	})
+
+	t.Run("not_member_of_default_workspace_lists_own_links", func(t *testing.T) {
+		actualRes, err := usecase.GetLinks(context.Background(), notMemberOfDefault)
+		require.NoError(t, err)
+		assert.Empty(t, actualRes)
+	})
+
+	t.Run("not_member_of_default_workspace_deletes_own_links", func(t *testing.T) {
+		err := usecase.DeleteLink(context.Background(), notMemberOfDefault, "", "short_link")
+		require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("administrator_lists_every_member", func(t *testing.T) {
+		administrator := &models.Principal{WorkspaceID: "team", Scopes: []string{models.ScopeAdmin}}
+		actualRes, err := usecase.GetLinks(context.Background(), administrator)
+		require.NoError(t, err)
+		assert.Equal(t, links, actualRes)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := usecase.GetLinks(context.Background(), nil)
+		require.Equal(t, models.ErrUnauthorized, errors.Cause(err))
+	})
+}
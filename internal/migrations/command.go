@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const CommandUsage = "migrate [up | down [steps] | status]"
+
+// Command runs migrate subcommand given by args: "up" (default) applies pending
+// migrations, "down" reverts the given number of migrations (one by default),
+// "status" prints every migration with time it was applied.
+func Command(ctx context.Context, m *Migrator, args []string, out io.Writer) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+		args = args[1:]
+	}
+
+	switch {
+	case action == "up" && len(args) == 0:
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "applied %d migrations\n", applied)
+	case action == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps <= 0 {
+				return errors.Errorf("steps %q is not a positive number", args[0])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "reverted %d migrations\n", reverted)
+	case action == "status" && len(args) == 0:
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d %-30s %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		return errors.New("usage: " + CommandUsage)
+	}
+
+	return nil
+}
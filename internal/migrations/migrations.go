@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// fileNameRegexp matches names like 0002_add_link_owners.up.sql.
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration changes the schema from version Version-1 to Version (Up) and back (Down).
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Embedded returns migrations built into the binary.
+func Embedded() ([]Migration, error) {
+	fsys, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, errors.Wrap(err, "embedded migrations error")
+	}
+	return Load(fsys)
+}
+
+// Load reads migrations from files <version>_<name>.up.sql and <version>_<name>.down.sql
+// in the root of fsys. Versions must start from 1 and have no gaps, every migration
+// must have both files.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "migrations directory error")
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		groups := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if groups == nil {
+			return nil, errors.Errorf("migration file %s is not named <version>_<name>.(up|down).sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(groups[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "migration file %s version error", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "migration file %s error", entry.Name())
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: groups[2]}
+			byVersion[version] = migration
+		} else if migration.Name != groups[2] {
+			return nil, errors.Errorf("migrations %s and %s have the same version", migration.Name, groups[2])
+		}
+
+		if groups[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if migration.Version != int64(i + 1) {
+			return nil, errors.Errorf("migration version %d is missing", i + 1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, errors.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+	}
+
+	return migrations, nil
+}
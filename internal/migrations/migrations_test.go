@@ -0,0 +1,80 @@
+package migrations_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/internal/migrations"
+)
+
+type TestCaseLoad struct {
+	Files fstest.MapFS
+	ExpectedError string
+}
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func TestEmbedded(t *testing.T) {
+	list, err := migrations.Embedded()
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	assert.Equal(t, int64(1), list[0].Version)
+	assert.Equal(t, "create_links", list[0].Name)
+	assert.Contains(t, list[0].Up, "CREATE TABLE IF NOT EXISTS links")
+}
+
+func TestLoad(t *testing.T) {
+	list, err := migrations.Load(fstest.MapFS{
+		"0002_second.up.sql":   file("up 2"),
+		"0002_second.down.sql": file("down 2"),
+		"0001_first.up.sql":    file("up 1"),
+		"0001_first.down.sql":  file("down 1"),
+		"README.md":            file("not a migration"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []migrations.Migration{
+		{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+	}, list)
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := map[string]TestCaseLoad {
+		"bad_name": {
+			Files: fstest.MapFS{"first.up.sql": file("up")},
+			ExpectedError: "is not named",
+		},
+		"missing_down": {
+			Files: fstest.MapFS{"0001_first.up.sql": file("up")},
+			ExpectedError: "must have both up and down files",
+		},
+		"gap": {
+			Files: fstest.MapFS{
+				"0002_second.up.sql":   file("up"),
+				"0002_second.down.sql": file("down"),
+			},
+			ExpectedError: "migration version 1 is missing",
+		},
+		"duplicate_version": {
+			Files: fstest.MapFS{
+				"0001_first.up.sql":  file("up"),
+				"0001_other.down.sql": file("down"),
+			},
+			ExpectedError: "have the same version",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := migrations.Load(test.Files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.ExpectedError)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockID identifies the Postgres advisory lock held while migrations are applied,
+// so replicas starting at the same time apply every migration once.
+const lockID = 7315460219
+
+const (
+	createVersionTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (` +
+		`version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`
+	selectVersionsQuery = `SELECT version, applied_at FROM schema_migrations ORDER BY version`
+	insertVersionQuery  = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	deleteVersionQuery  = `DELETE FROM schema_migrations WHERE version = $1`
+	lockQuery           = `SELECT pg_advisory_lock($1)`
+	unlockQuery         = `SELECT pg_advisory_unlock($1)`
+)
+
+// Status of a migration: AppliedAt is nil if the migration is pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations to Postgres and records applied versions
+// in table schema_migrations. Every migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+func New(db *sql.DB, migrations []Migration, logger *slog.Logger) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}
+}
+
+// Up applies pending migrations and returns their number.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn, versions map[int64]time.Time) error {
+		for version := range versions {
+			if version > int64(len(m.migrations)) {
+				m.logger.Warn("database schema is newer than the service", "version", version)
+				break
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.Up, insertVersionQuery, migration.Version, migration.Name); err != nil {
+				return errors.Wrapf(err, "migration %d_%s up error", migration.Version, migration.Name)
+			}
+			m.logger.Info("migration applied", "version", migration.Version, "name", migration.Name)
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns their number.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+
+	err := m.withLock(ctx, func(conn *sql.Conn, versions map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.Down, deleteVersionQuery, migration.Version); err != nil {
+				return errors.Wrapf(err, "migration %d_%s down error", migration.Version, migration.Name)
+			}
+			m.logger.Info("migration reverted", "version", migration.Version, "name", migration.Name)
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every known migration with time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn, versions map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs f on a connection holding the advisory lock, passing versions
+// of applied migrations.
+func (m *Migrator) withLock(ctx context.Context, f func(conn *sql.Conn, versions map[int64]time.Time) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "database connection error")
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, lockQuery, lockID); err != nil {
+		return errors.Wrap(err, "migration lock error")
+	}
+	defer func() {
+		// the lock is released with the session anyway, so the error is only logged
+		if _, err := conn.ExecContext(context.Background(), unlockQuery, lockID); err != nil {
+			m.logger.Warn("migration unlock error", "error", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createVersionTableQuery); err != nil {
+		return errors.Wrap(err, "database error (table schema_migrations)")
+	}
+
+	versions, err := selectVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return f(conn, versions)
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, versionQuery string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "database error")
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return errors.Wrap(err, "database error")
+	}
+
+	if _, err := tx.ExecContext(ctx, versionQuery, args...); err != nil {
+		_ = tx.Rollback()
+		return errors.Wrap(err, "database error (table schema_migrations)")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "database error")
+	}
+
+	return nil
+}
+
+func selectVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, selectVersionsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "database error (table schema_migrations)")
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, errors.Wrap(err, "database error (table schema_migrations)")
+		}
+		versions[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "database error (table schema_migrations)")
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,158 @@
+package migrations_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"github.com/kuzkuss/url_service/internal/migrations"
+	"github.com/kuzkuss/url_service/internal/observability"
+)
+
+type TestCaseCommand struct {
+	Args []string
+	ExpectedError string
+}
+
+var testMigrations = []migrations.Migration{
+	{Version: 1, Name: "first", Up: "CREATE TABLE first (id INT)", Down: "DROP TABLE first"},
+	{Version: 2, Name: "second", Up: "CREATE TABLE second (id INT)", Down: "DROP TABLE second"},
+}
+
+var (
+	lockQuery         = regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)
+	unlockQuery       = regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)
+	createTableQuery  = regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)
+	selectVersionsQuery = regexp.QuoteMeta(`SELECT version, applied_at FROM schema_migrations ORDER BY version`)
+	insertVersionQuery  = regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`)
+	deleteVersionQuery  = regexp.QuoteMeta(`DELETE FROM schema_migrations WHERE version = $1`)
+)
+
+func newMigrator(t *testing.T) (*migrations.Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return migrations.New(db, testMigrations, observability.NopLogger()), mock
+}
+
+func expectLock(mock sqlmock.Sqlmock, appliedVersions ...int64) {
+	mock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createTableQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range appliedVersions {
+		rows.AddRow(version, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery(selectVersionsQuery).WillReturnRows(rows)
+}
+
+func TestMigratorUp(t *testing.T) {
+	migrator, mock := newMigrator(t)
+
+	expectLock(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(testMigrations[1].Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insertVersionQuery).WithArgs(int64(2), "second").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(unlockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorUpError(t *testing.T) {
+	migrator, mock := newMigrator(t)
+
+	expectLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(testMigrations[0].Up)).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	mock.ExpectExec(unlockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration 1_first up error")
+	assert.Equal(t, 0, applied)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorLockError(t *testing.T) {
+	migrator, mock := newMigrator(t)
+
+	mock.ExpectExec(lockQuery).WillReturnError(sql.ErrConnDone)
+
+	_, err := migrator.Up(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration lock error")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorDown(t *testing.T) {
+	migrator, mock := newMigrator(t)
+
+	expectLock(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(testMigrations[1].Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteVersionQuery).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(unlockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := migrator.Down(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCommandStatus(t *testing.T) {
+	migrator, mock := newMigrator(t)
+
+	expectLock(mock, 1)
+	mock.ExpectExec(unlockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var out bytes.Buffer
+	require.NoError(t, migrations.Command(context.Background(), migrator, []string{"status"}, &out))
+	assert.Equal(t,
+		"0001 first                          applied 2026-10-19T00:00:00Z\n" +
+		"0002 second                         pending\n",
+		out.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCommandUsage(t *testing.T) {
+	migrator, _ := newMigrator(t)
+
+	cases := map[string]TestCaseCommand {
+		"unknown_action": {
+			Args: []string{"redo"},
+			ExpectedError: "usage: " + migrations.CommandUsage,
+		},
+		"bad_steps": {
+			Args: []string{"down", "all"},
+			ExpectedError: `steps "all" is not a positive number`,
+		},
+		"extra_args": {
+			Args: []string{"up", "2"},
+			ExpectedError: "usage: " + migrations.CommandUsage,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := migrations.Command(context.Background(), migrator, test.Args, &bytes.Buffer{})
+			require.EqualError(t, err, test.ExpectedError)
+		})
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS api_keys;
+
+DROP INDEX IF EXISTS index_links_owner_id;
+
+ALTER TABLE links DROP COLUMN IF EXISTS owner_id;
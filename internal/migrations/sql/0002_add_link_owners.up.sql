@@ -0,0 +1,8 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS owner_id VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS index_links_owner_id ON links (owner_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	key_hash VARCHAR(64) PRIMARY KEY,
+	owner_id VARCHAR(64) NOT NULL
+);
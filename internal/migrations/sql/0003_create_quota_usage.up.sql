@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS quota_usage (
+	owner_id VARCHAR(64) NOT NULL,
+	period VARCHAR(8) NOT NULL,
+	period_start TIMESTAMP NOT NULL,
+	count BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (owner_id, period, period_start)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	owner_id VARCHAR(64) NOT NULL,
+	idempotency_key VARCHAR(255) NOT NULL,
+	fingerprint VARCHAR(64) NOT NULL,
+	response BYTEA,
+	completed BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (owner_id, idempotency_key)
+);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trigger_links_updated_at ON links;
+DROP FUNCTION IF EXISTS set_links_updated_at();
+
+ALTER TABLE links DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE links DROP COLUMN IF EXISTS created_at;
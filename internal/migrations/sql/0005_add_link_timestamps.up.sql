@@ -0,0 +1,13 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE links ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE OR REPLACE FUNCTION set_links_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_links_updated_at ON links;
+CREATE TRIGGER trigger_links_updated_at BEFORE UPDATE ON links
+	FOR EACH ROW EXECUTE FUNCTION set_links_updated_at();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trigger_links_updated_at ON links;
+CREATE TRIGGER trigger_links_updated_at BEFORE UPDATE ON links
+	FOR EACH ROW EXECUTE FUNCTION set_links_updated_at();
+
+ALTER TABLE links DROP COLUMN IF EXISTS clicks;
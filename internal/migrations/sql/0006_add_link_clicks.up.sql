@@ -0,0 +1,6 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+
+-- counting clicks must not change time of the last update of the link
+DROP TRIGGER IF EXISTS trigger_links_updated_at ON links;
+CREATE TRIGGER trigger_links_updated_at BEFORE UPDATE OF original_link ON links
+	FOR EACH ROW EXECUTE FUNCTION set_links_updated_at();
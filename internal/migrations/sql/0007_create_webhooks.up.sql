@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	id VARCHAR(32) PRIMARY KEY,
+	owner_id VARCHAR(64) NOT NULL,
+	url TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	events TEXT NOT NULL DEFAULT '[]',
+	click_threshold BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_subscriptions_owner_id ON webhook_subscriptions (owner_id);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+	id BIGSERIAL PRIMARY KEY,
+	subscription_id VARCHAR(32) NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+	delivery_id VARCHAR(32) NOT NULL,
+	event VARCHAR(32) NOT NULL,
+	attempt INTEGER NOT NULL,
+	delivered BOOLEAN NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	duration_ms BIGINT NOT NULL,
+	attempted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_attempts_subscription_id ON webhook_attempts (subscription_id, id);
+
+CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+	id VARCHAR(32) PRIMARY KEY,
+	subscription_id VARCHAR(32) NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+	event VARCHAR(32) NOT NULL,
+	payload BYTEA NOT NULL,
+	attempts INTEGER NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	failed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_dead_letters_subscription_id ON webhook_dead_letters (subscription_id);
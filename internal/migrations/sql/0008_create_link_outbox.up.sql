@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS link_outbox (
+	id BIGSERIAL PRIMARY KEY,
+	type VARCHAR(16) NOT NULL,
+	short_link VARCHAR(10) NOT NULL,
+	owner_id VARCHAR(64) NOT NULL DEFAULT '',
+	link TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	actor VARCHAR(64) NOT NULL,
+	action VARCHAR(32) NOT NULL,
+	resource VARCHAR(64) NOT NULL,
+	before_state BYTEA,
+	after_state BYTEA,
+	transport VARCHAR(8) NOT NULL DEFAULT '',
+	client_ip VARCHAR(64) NOT NULL DEFAULT '',
+	request_id VARCHAR(128) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS index_audit_log_actor ON audit_log (actor, id);
+CREATE INDEX IF NOT EXISTS index_audit_log_resource ON audit_log (resource, id);
+CREATE INDEX IF NOT EXISTS index_audit_log_created_at ON audit_log (created_at);
+
+-- the audit log is append-only
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,8 @@
+-- fails if the same short link or original link exists on several domains
+ALTER TABLE link_outbox DROP COLUMN IF EXISTS domain;
+
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_domain_original_link_key;
+ALTER TABLE links ADD CONSTRAINT links_original_link_key UNIQUE (original_link);
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_pkey;
+ALTER TABLE links ADD CONSTRAINT links_pkey PRIMARY KEY (short_link);
+ALTER TABLE links DROP COLUMN IF EXISTS domain;
@@ -0,0 +1,9 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS domain VARCHAR(253) NOT NULL DEFAULT '';
+
+-- the same short link and original link may exist on different domains
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_pkey;
+ALTER TABLE links ADD CONSTRAINT links_pkey PRIMARY KEY (domain, short_link);
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_original_link_key;
+ALTER TABLE links ADD CONSTRAINT links_domain_original_link_key UNIQUE (domain, original_link);
+
+ALTER TABLE link_outbox ADD COLUMN IF NOT EXISTS domain VARCHAR(253) NOT NULL DEFAULT '';
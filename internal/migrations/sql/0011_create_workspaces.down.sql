@@ -0,0 +1,13 @@
+-- fails if the same original link is shortened on a domain in several workspaces
+ALTER TABLE link_outbox DROP COLUMN IF EXISTS workspace_id;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS workspace_id;
+
+DROP INDEX IF EXISTS index_links_workspace_owner_id;
+CREATE INDEX IF NOT EXISTS index_links_owner_id ON links (owner_id);
+
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_workspace_domain_original_link_key;
+ALTER TABLE links ADD CONSTRAINT links_domain_original_link_key UNIQUE (domain, original_link);
+ALTER TABLE links DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspaces;
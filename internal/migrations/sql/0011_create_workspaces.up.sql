@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+	id VARCHAR(64) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- existing links and api keys belong to the default workspace
+INSERT INTO workspaces (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES workspaces (id);
+ALTER TABLE links ALTER COLUMN workspace_id DROP DEFAULT;
+
+-- the same original link may be shortened in every workspace
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_domain_original_link_key;
+ALTER TABLE links ADD CONSTRAINT links_workspace_domain_original_link_key UNIQUE (workspace_id, domain, original_link);
+
+DROP INDEX IF EXISTS index_links_owner_id;
+CREATE INDEX IF NOT EXISTS index_links_workspace_owner_id ON links (workspace_id, owner_id);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES workspaces (id);
+ALTER TABLE api_keys ALTER COLUMN workspace_id DROP DEFAULT;
+
+ALTER TABLE link_outbox ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) NOT NULL DEFAULT '';
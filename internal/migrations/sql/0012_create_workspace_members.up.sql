@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS workspace_members (
+	workspace_id VARCHAR(64) NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+	owner_id VARCHAR(64) NOT NULL,
+	role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'editor', 'admin', 'owner')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (workspace_id, owner_id)
+);
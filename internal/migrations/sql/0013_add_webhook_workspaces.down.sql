@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS index_webhook_subscriptions_workspace_owner_id;
+CREATE INDEX IF NOT EXISTS index_webhook_subscriptions_owner_id ON webhook_subscriptions (owner_id);
+
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS workspace_id;
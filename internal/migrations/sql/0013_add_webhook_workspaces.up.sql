@@ -0,0 +1,6 @@
+-- existing subscriptions belong to the default workspace like links of their owners
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES workspaces (id);
+ALTER TABLE webhook_subscriptions ALTER COLUMN workspace_id DROP DEFAULT;
+
+DROP INDEX IF EXISTS index_webhook_subscriptions_owner_id;
+CREATE INDEX IF NOT EXISTS index_webhook_subscriptions_workspace_owner_id ON webhook_subscriptions (workspace_id, owner_id);
@@ -0,0 +1,7 @@
+-- keys of the same owner in several workspaces can't be kept
+DELETE FROM idempotency_keys WHERE workspace_id <> 'default';
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (owner_id, idempotency_key);
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS workspace_id;
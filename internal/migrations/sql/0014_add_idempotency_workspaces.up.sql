@@ -0,0 +1,6 @@
+-- existing keys belong to the default workspace like api keys of their owners
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE idempotency_keys ALTER COLUMN workspace_id DROP DEFAULT;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (workspace_id, owner_id, idempotency_key);
@@ -0,0 +1 @@
+ALTER TABLE audit_log ALTER COLUMN resource TYPE VARCHAR(64) USING left(resource, 64);
@@ -0,0 +1,2 @@
+-- members are recorded as <workspace_id>/<owner_id>, which does not fit into 64 characters
+ALTER TABLE audit_log ALTER COLUMN resource TYPE TEXT;
@@ -0,0 +1,17 @@
+-- deliveries waiting for the next attempt, a delivery of a subscription is attempted
+-- only when the earlier ones (by seq) are delivered or moved to dead letters;
+-- locked_until is set while a worker sends the delivery
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	seq BIGSERIAL PRIMARY KEY,
+	id VARCHAR(32) NOT NULL UNIQUE,
+	subscription_id VARCHAR(32) NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+	event VARCHAR(32) NOT NULL,
+	payload BYTEA NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	next_attempt_at TIMESTAMPTZ NOT NULL,
+	locked_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, seq);
@@ -0,0 +1,13 @@
+-- link events relayed from the outbox, read by every instance of the service;
+-- events are appended by one relay at a time, so ids grow in order of commits
+CREATE TABLE IF NOT EXISTS link_feed (
+	id BIGSERIAL PRIMARY KEY,
+	type VARCHAR(16) NOT NULL,
+	owner_id VARCHAR(64) NOT NULL DEFAULT '',
+	workspace_id VARCHAR(64) NOT NULL DEFAULT '',
+	link TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	appended_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS index_link_feed_appended_at ON link_feed (appended_at);
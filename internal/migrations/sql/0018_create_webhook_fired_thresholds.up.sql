@@ -0,0 +1,11 @@
+-- links for which the click threshold of the subscription fired, the subscription
+-- is notified about clicks on a link once
+CREATE TABLE IF NOT EXISTS webhook_fired_thresholds (
+	subscription_id VARCHAR(32) NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+	domain VARCHAR(253) NOT NULL,
+	short_link VARCHAR(10) NOT NULL,
+	fired_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (subscription_id, domain, short_link)
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_fired_thresholds_link ON webhook_fired_thresholds (domain, short_link);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS index_idempotency_keys_expires_at;
@@ -0,0 +1,2 @@
+-- expired keys are removed periodically
+CREATE INDEX IF NOT EXISTS index_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,248 @@
+package delivery_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	otelCodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/internal/observability"
+	observabilityDelivery "github.com/kuzkuss/url_service/internal/observability/delivery/grpc"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/link.Links/CreateShortLink"}
+
+type streamStub struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *streamStub) Context() context.Context {
+	return ss.ctx
+}
+
+func TestGrpcUnaryRequestID(t *testing.T) {
+	var gotRequestID string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		gotRequestID = pkg.RequestIDFromContext(ctx)
+		return nil, nil
+	}
+
+	t.Run("received", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(observabilityDelivery.MetadataRequestID, "request_id"))
+
+		_, err := observabilityDelivery.UnaryRequestID(ctx, nil, unaryInfo, handler)
+		require.NoError(t, err)
+		assert.Equal(t, "request_id", gotRequestID)
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		_, err := observabilityDelivery.UnaryRequestID(context.Background(), nil, unaryInfo, handler)
+		require.NoError(t, err)
+		assert.Len(t, gotRequestID, 32)
+	})
+
+	t.Run("too_long", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(observabilityDelivery.MetadataRequestID, strings.Repeat("a", 200)))
+
+		_, err := observabilityDelivery.UnaryRequestID(ctx, nil, unaryInfo, handler)
+		require.NoError(t, err)
+		assert.Len(t, gotRequestID, 32)
+	})
+}
+
+func TestGrpcStreamRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(observabilityDelivery.MetadataRequestID, "request_id"))
+
+	var gotRequestID string
+	err := observabilityDelivery.StreamRequestID(nil, &streamStub{ctx: ctx}, &grpc.StreamServerInfo{},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			gotRequestID = pkg.RequestIDFromContext(ss.Context())
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "request_id", gotRequestID)
+}
+
+func TestGrpcSource(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+	expected := pkg.Source{Transport: pkg.TransportGRPC, ClientIP: "192.0.2.1"}
+
+	var gotSource pkg.Source
+	_, err := observabilityDelivery.UnarySource(ctx, nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		gotSource = pkg.SourceFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, gotSource)
+
+	gotSource = pkg.Source{}
+	err = observabilityDelivery.StreamSource(nil, &streamStub{ctx: ctx}, &grpc.StreamServerInfo{},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			gotSource = pkg.SourceFromContext(ss.Context())
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, expected, gotSource)
+}
+
+func TestGrpcRecovery(t *testing.T) {
+	panicHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("handler panic")
+	}
+
+	_, err := observabilityDelivery.UnaryRecovery(observability.NopLogger())(context.Background(), nil, unaryInfo, panicHandler)
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	err = observabilityDelivery.StreamRecovery(observability.NopLogger())(nil, &streamStub{ctx: context.Background()}, &grpc.StreamServerInfo{},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			panic("handler panic")
+		})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	resp, err := observabilityDelivery.UnaryRecovery(observability.NopLogger())(context.Background(), nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "response", nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "response", resp)
+}
+
+func TestGrpcAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := observability.NewLogger(&buf, config.LogConfig{Level: "info", Format: "json"})
+	require.NoError(t, err)
+
+	ctx := pkg.WithRequestID(context.Background(), "request_id")
+	_, err = observabilityDelivery.UnaryAccessLog(logger)(ctx, nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Internal, "internal error")
+		})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	record := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "request_id", record["request_id"])
+	assert.Equal(t, unaryInfo.FullMethod, record["method"])
+	assert.Equal(t, codes.Internal.String(), record["code"])
+}
+
+func TestGrpcMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := observabilityDelivery.NewMetrics(registry)
+
+	okHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	notFoundHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+
+	_, _ = metrics.Unary(context.Background(), nil, unaryInfo, okHandler)
+	_, _ = metrics.Unary(context.Background(), nil, unaryInfo, okHandler)
+	_, _ = metrics.Unary(context.Background(), nil, unaryInfo, notFoundHandler)
+
+	expected := `
+# HELP grpc_server_handled_total Total number of gRPC calls completed on the server by method and status code.
+# TYPE grpc_server_handled_total counter
+grpc_server_handled_total{code="NotFound",method="/link.Links/CreateShortLink"} 1
+grpc_server_handled_total{code="OK",method="/link.Links/CreateShortLink"} 2
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_server_handled_total")
+	require.NoError(t, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "grpc_server_handling_seconds"))
+}
+
+func TestGrpcTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var handlerSpan trace.SpanContext
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+
+	_, err := observabilityDelivery.UnaryTracing(ctx, nil, unaryInfo, handler)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "link.Links/CreateShortLink", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), handlerSpan.SpanID())
+	assert.Equal(t, otelCodes.Error, spans[0].Status().Code)
+
+	attrs := make(map[string]string)
+	for _, attr := range spans[0].Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, "link.Links", attrs["rpc.service"])
+	assert.Equal(t, "CreateShortLink", attrs["rpc.method"])
+	assert.Equal(t, "5", attrs["rpc.grpc.status_code"])
+
+	streamHandler := func(srv interface{}, ss grpc.ServerStream) error {
+		handlerSpan = trace.SpanContextFromContext(ss.Context())
+		return nil
+	}
+	err = observabilityDelivery.StreamTracing(nil, &streamStub{ctx: ctx},
+		&grpc.StreamServerInfo{FullMethod: "/link.Links/ListLinks"}, streamHandler)
+	require.NoError(t, err)
+
+	spans = recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, spans[1].SpanContext().SpanID(), handlerSpan.SpanID())
+	assert.Equal(t, otelCodes.Unset, spans[1].Status().Code)
+}
+
+func TestGrpcChain(t *testing.T) {
+	metrics := observabilityDelivery.NewMetrics(prometheus.NewRegistry())
+
+	unary, stream := observabilityDelivery.Chain(config.InterceptorsConfig{
+		RequestID: true,
+		AccessLog: true,
+		Metrics: true,
+		Recovery: true,
+		Tracing: true,
+	}, metrics, observability.NopLogger())
+	assert.Len(t, unary, 5)
+	assert.Len(t, stream, 5)
+
+	unary, stream = observabilityDelivery.Chain(config.InterceptorsConfig{Recovery: true}, metrics, observability.NopLogger())
+	assert.Len(t, unary, 1)
+	assert.Len(t, stream, 1)
+
+	unary, stream = observabilityDelivery.Chain(config.InterceptorsConfig{Metrics: true}, nil, observability.NopLogger())
+	assert.Empty(t, unary)
+	assert.Empty(t, stream)
+}
@@ -0,0 +1,69 @@
+package delivery
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+)
+
+// AccessLog writes record of every request with its status, size and latency.
+// Client address is stored in the request context, so records logged by handlers
+// with this context get it too.
+func AccessLog(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			req := c.Request()
+			ctx := observability.WithLogAttrs(req.Context(), slog.String("client_ip", c.RealIP()))
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// error handler writes the response, so its status and size are logged
+				c.Error(err)
+			}
+
+			res := c.Response()
+			level := slog.LevelInfo
+			if res.Status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("uri", req.RequestURI),
+				slog.String("route", routeLabel(c, res.Status)),
+				slog.Int("status", res.Status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int64("bytes_out", res.Size),
+				slog.String("user_agent", req.UserAgent()),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+
+			// context of the request handled by the next middlewares has principal
+			logger.LogAttrs(c.Request().Context(), level, "http request", attrs...)
+
+			return err
+		}
+	}
+}
+
+// Recover converts panic in the handler into Internal Server Error
+// and logs it with the stack.
+func Recover(logger *slog.Logger) echo.MiddlewareFunc {
+	return echoMiddleware.RecoverWithConfig(echoMiddleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			logger.ErrorContext(c.Request().Context(), "http handler panic",
+				"error", err, "stack", string(stack))
+			return err
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package delivery_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/internal/observability"
+	observabilityDelivery "github.com/kuzkuss/url_service/internal/observability/delivery/http"
+)
+
+type TestCaseAccessLog struct {
+	URL string
+	ExpectedLevel string
+	ExpectedStatus float64
+	ExpectedRoute string
+}
+
+func TestHttpAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := observability.NewLogger(&buf, config.LogConfig{Level: "info", Format: "json"})
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(observabilityDelivery.RequestID())
+	e.Use(observabilityDelivery.AccessLog(logger))
+	e.GET("/get/:short_link", func(c echo.Context) error {
+		if c.Param("short_link") == "error" {
+			return echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	cases := map[string]TestCaseAccessLog {
+		"success": {
+			URL: "/get/short_link",
+			ExpectedLevel: "INFO",
+			ExpectedStatus: http.StatusOK,
+			ExpectedRoute: "/get/:short_link",
+		},
+		"error": {
+			URL: "/get/error",
+			ExpectedLevel: "ERROR",
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedRoute: "/get/:short_link",
+		},
+		"not_found": {
+			URL: "/unknown",
+			ExpectedLevel: "INFO",
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedRoute: "unknown",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+
+			req := httptest.NewRequest(echo.GET, test.URL, nil)
+			req.Header.Set(echo.HeaderXRequestID, "request_id")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, int(test.ExpectedStatus), rec.Code)
+
+			record := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, test.ExpectedLevel, record["level"])
+			assert.Equal(t, test.ExpectedStatus, record["status"])
+			assert.Equal(t, test.ExpectedRoute, record["route"])
+			assert.Equal(t, "request_id", record["request_id"])
+			assert.Equal(t, "192.0.2.1", record["client_ip"])
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const unknownRoute = "unknown"
+
+// Metrics counts handled requests by route and status code and observes their latency.
+type Metrics struct {
+	handled *prometheus.CounterVec
+	latency *prometheus.HistogramVec
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	metrics := &Metrics{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests handled by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	reg.MustRegister(metrics.handled, metrics.latency)
+
+	return metrics
+}
+
+// Middleware observes every request. Routes are labeled by their pattern
+// (e.g. /get/:short_link) to keep the number of series bounded.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			code := statusCode(c, err)
+			route := routeLabel(c, code)
+
+			method := c.Request().Method
+			m.handled.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+			m.latency.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// routeLabel returns pattern of the matched route. Echo sets path of unmatched
+// requests to the raw request path, so it is labeled as unknown route.
+func routeLabel(c echo.Context, code int) string {
+	route := c.Path()
+	if route == "" {
+		return unknownRoute
+	}
+
+	if code == http.StatusNotFound || code == http.StatusMethodNotAllowed {
+		for _, registered := range c.Echo().Routes() {
+			if registered.Path == route {
+				return route
+			}
+		}
+		return unknownRoute
+	}
+
+	return route
+}
+
+// statusCode returns code of the response, which is not written yet if the handler
+// returned error: it is written later by echo error handler.
+func statusCode(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
+
+// RegisterMetricsEndpoint serves metrics collected by gatherer on GET /metrics.
+func RegisterMetricsEndpoint(e *echo.Echo, gatherer prometheus.Gatherer) {
+	e.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})))
+}
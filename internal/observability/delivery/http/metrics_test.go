@@ -0,0 +1,49 @@
+package delivery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	observabilityDelivery "github.com/kuzkuss/url_service/internal/observability/delivery/http"
+)
+
+func TestHttpMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	e := echo.New()
+	e.Use(observabilityDelivery.NewMetrics(registry).Middleware())
+	e.GET("/get/:short_link", func(c echo.Context) error {
+		if c.Param("short_link") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	observabilityDelivery.RegisterMetricsEndpoint(e, registry)
+
+	for _, target := range []string{"/get/first", "/get/second", "/get/missing", "/unknown"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(echo.GET, target, nil))
+	}
+
+	expected := `
+# HELP http_requests_total Total number of HTTP requests handled by method, route and status code.
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="GET",route="/get/:short_link"} 2
+http_requests_total{code="404",method="GET",route="/get/:short_link"} 1
+http_requests_total{code="404",method="GET",route="unknown"} 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "http_requests_total")
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `http_requests_total{code="200",method="GET",route="/get/:short_link"} 2`)
+}
@@ -0,0 +1,37 @@
+package delivery
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+// RequestID takes request id from X-Request-ID header or generates it, stores it
+// in the request context and returns it to the client in the same header.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			requestID := pkg.RequestID(req.Header.Get(echo.HeaderXRequestID))
+
+			req.Header.Set(echo.HeaderXRequestID, requestID)
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+			c.SetRequest(req.WithContext(pkg.WithRequestID(req.Context(), requestID)))
+
+			return next(c)
+		}
+	}
+}
+
+// Source stores transport and IP address of the client in the request context.
+func Source() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			source := pkg.Source{Transport: pkg.TransportHTTP, ClientIP: c.RealIP()}
+			c.SetRequest(req.WithContext(pkg.WithSource(req.Context(), source)))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package delivery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	observabilityDelivery "github.com/kuzkuss/url_service/internal/observability/delivery/http"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+func TestHttpRequestID(t *testing.T) {
+	e := echo.New()
+
+	var gotRequestID string
+	handler := observabilityDelivery.RequestID()(func(c echo.Context) error {
+		gotRequestID = pkg.RequestIDFromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	})
+
+	t.Run("received", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(echo.HeaderXRequestID, "request_id")
+		rec := httptest.NewRecorder()
+
+		err := handler(e.NewContext(req, rec))
+		require.NoError(t, err)
+		assert.Equal(t, "request_id", gotRequestID)
+		assert.Equal(t, "request_id", rec.Header().Get(echo.HeaderXRequestID))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+
+		err := handler(e.NewContext(req, rec))
+		require.NoError(t, err)
+		assert.Len(t, gotRequestID, 32)
+		assert.Equal(t, gotRequestID, rec.Header().Get(echo.HeaderXRequestID))
+	})
+}
+
+func TestHttpSource(t *testing.T) {
+	e := echo.New()
+
+	var gotSource pkg.Source
+	handler := observabilityDelivery.Source()(func(c echo.Context) error {
+		gotSource = pkg.SourceFromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+
+	err := handler(e.NewContext(req, rec))
+	require.NoError(t, err)
+	assert.Equal(t, pkg.Source{Transport: pkg.TransportHTTP, ClientIP: "192.0.2.1"}, gotSource)
+}
@@ -0,0 +1,82 @@
+package observability
+
+import (
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "observability:span"
+
+var rowsAffectedKey = attribute.Key("db.rows_affected")
+
+// GormTracing is a gorm plugin starting span for every SQL statement executed
+// with context (db.WithContext) and recording the statement as its attribute.
+// Values of the statement arguments are not recorded.
+type GormTracing struct{}
+
+func (GormTracing) Name() string {
+	return "observability:tracing"
+}
+
+func (GormTracing) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("observability:before_create", startStatementSpan("create")),
+		callback.Create().After("gorm:create").Register("observability:after_create", endStatementSpan),
+		callback.Query().Before("gorm:query").Register("observability:before_query", startStatementSpan("query")),
+		callback.Query().After("gorm:query").Register("observability:after_query", endStatementSpan),
+		callback.Update().Before("gorm:update").Register("observability:before_update", startStatementSpan("update")),
+		callback.Update().After("gorm:update").Register("observability:after_update", endStatementSpan),
+		callback.Delete().Before("gorm:delete").Register("observability:before_delete", startStatementSpan("delete")),
+		callback.Delete().After("gorm:delete").Register("observability:after_delete", endStatementSpan),
+		callback.Row().Before("gorm:row").Register("observability:before_row", startStatementSpan("row")),
+		callback.Row().After("gorm:row").Register("observability:after_row", endStatementSpan),
+		callback.Raw().Before("gorm:raw").Register("observability:before_raw", startStatementSpan("raw")),
+		callback.Raw().After("gorm:raw").Register("observability:after_raw", endStatementSpan),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "gorm callback registration error")
+		}
+	}
+
+	return nil
+}
+
+func startStatementSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+
+		ctx, span := StartSpan(db.Statement.Context, "gorm."+operation,
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(operation),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endStatementSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+
+	span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		semconv.DBSQLTable(db.Statement.Table),
+		rowsAffectedKey.Int64(db.Statement.RowsAffected),
+	)
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	EndSpan(span, err)
+}
@@ -0,0 +1,152 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+
+	redacted = "[REDACTED]"
+)
+
+// sensitiveKeys are attributes whose values are never written to the log.
+var sensitiveKeys = map[string]struct{}{
+	"api_key":       {},
+	"admin_api_key": {},
+	"authorization": {},
+	"hmac_secret":   {},
+	"password":      {},
+	"token":         {},
+	"secret":        {},
+}
+
+var dsnPasswordRegexp = regexp.MustCompile(`(password=)\S+|(://[^:/@]+:)[^@]+(@)`)
+
+// NewLogger creates logger writing records of conf.Level and above to w in conf.Format.
+// Records logged with context get request id, trace id, owner and client attributes
+// stored in the context.
+func NewLogger(w io.Writer, conf config.LogConfig) (*slog.Logger, error) {
+	level := &slog.LevelVar{}
+	if conf.Level != "" {
+		if err := level.UnmarshalText([]byte(conf.Level)); err != nil {
+			return nil, errors.Wrap(err, "log level error")
+		}
+	}
+
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	switch conf.Format {
+	case LogFormatJSON, "":
+		handler = slog.NewJSONHandler(w, options)
+	case LogFormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, errors.Errorf("unknown log format %q", conf.Format)
+	}
+
+	return slog.New(&contextHandler{Handler: handler, level: level}), nil
+}
+
+// SetLogLevel changes level of logger created by NewLogger and loggers derived from it.
+func SetLogLevel(logger *slog.Logger, level string) error {
+	handler, ok := logger.Handler().(*contextHandler)
+	if !ok {
+		return errors.New("logger level can not be changed")
+	}
+
+	if err := handler.level.UnmarshalText([]byte(level)); err != nil {
+		return errors.Wrap(err, "log level error")
+	}
+	return nil
+}
+
+// NopLogger returns logger discarding every record.
+func NopLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// RedactDSN hides password in the connection string given as key=value pairs or URL.
+func RedactDSN(dsn string) string {
+	return dsnPasswordRegexp.ReplaceAllStringFunc(dsn, func(match string) string {
+		groups := dsnPasswordRegexp.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return groups[1] + redacted
+		}
+		return groups[2] + redacted + groups[3]
+	})
+}
+
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	if _, ok := sensitiveKeys[key]; ok {
+		return slog.String(attr.Key, redacted)
+	}
+	if key == "dsn" {
+		return slog.String(attr.Key, RedactDSN(attr.Value.String()))
+	}
+	// text handler formats errors with %+v, which prints stack of wrapped errors
+	if err, ok := attr.Value.Any().(error); ok {
+		return slog.String(attr.Key, err.Error())
+	}
+	return attr
+}
+
+type logAttrsKey struct{}
+
+// WithLogAttrs stores attributes added to every record logged with the returned context.
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	stored, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(stored)+len(attrs))
+	merged = append(merged, stored...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+// contextHandler adds attributes of the request stored in the context to records.
+type contextHandler struct {
+	slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestID := pkg.RequestIDFromContext(ctx); requestID != "" {
+			record.AddAttrs(slog.String("request_id", requestID))
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+		}
+		if principal, ok := pkg.PrincipalFromContext(ctx); ok && principal.OwnerID != "" {
+			record.AddAttrs(slog.String("owner_id", principal.OwnerID))
+		}
+		if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+			record.AddAttrs(attrs...)
+		}
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,50 @@
+package observability
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+const (
+	resultOK       = "ok"
+	resultNotFound = "not_found"
+	resultError    = "error"
+)
+
+// RepositoryMetrics observes latency of repository queries to the backend.
+type RepositoryMetrics struct {
+	backend string
+	latency *prometheus.HistogramVec
+}
+
+func NewRepositoryMetrics(reg prometheus.Registerer, backend string) *RepositoryMetrics {
+	metrics := &RepositoryMetrics{
+		backend: backend,
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_query_duration_seconds",
+			Help:    "Latency of repository queries by backend, repository, method and result.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"backend", "repository", "method", "result"}),
+	}
+
+	reg.MustRegister(metrics.latency)
+
+	return metrics
+}
+
+// Observe records query of the repository method started at start and finished with err.
+func (m *RepositoryMetrics) Observe(repository string, method string, start time.Time, err error) {
+	result := resultOK
+	switch {
+	case errors.Is(errors.Cause(err), models.ErrNotFound):
+		result = resultNotFound
+	case err != nil:
+		result = resultError
+	}
+
+	m.latency.WithLabelValues(m.backend, repository, method, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,71 @@
+package observability_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseObserve struct {
+	Method string
+	Error error
+	ExpectedResult string
+}
+
+func TestRepositoryMetricsObserve(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := observability.NewRepositoryMetrics(registry, "postgres")
+
+	cases := map[string]TestCaseObserve {
+		"ok": {
+			Method: "CreateLink",
+			Error: nil,
+			ExpectedResult: "ok",
+		},
+		"not_found": {
+			Method: "SelectLinkByShortLink",
+			Error: errors.Wrap(models.ErrNotFound, "select link"),
+			ExpectedResult: "not_found",
+		},
+		"error": {
+			Method: "SelectLinksByOwner",
+			Error: errors.New("error"),
+			ExpectedResult: "error",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			metrics.Observe("links", test.Method, time.Now(), test.Error)
+		})
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Equal(t, len(cases), testutil.CollectAndCount(registry, "repository_query_duration_seconds"))
+
+	results := map[string]string{}
+	for _, metric := range families[0].GetMetric() {
+		labels := map[string]string{}
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		assert.Equal(t, "postgres", labels["backend"])
+		assert.Equal(t, "links", labels["repository"])
+		assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+		results[labels["method"]] = labels["result"]
+	}
+
+	for _, test := range cases {
+		assert.Equal(t, test.ExpectedResult, results[test.Method])
+	}
+}
@@ -0,0 +1,105 @@
+package observability
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/models"
+)
+
+var (
+	repositoryBackendKey = attribute.Key("repository.backend")
+	repositoryNameKey    = attribute.Key("repository.name")
+)
+
+const (
+	TracerName = "github.com/kuzkuss/url_service"
+
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// SetupTracing installs W3C trace context propagator and, if an exporter is
+// configured, tracer provider exporting spans with it. Returned function flushes
+// and stops the exporter.
+func SetupTracing(ctx context.Context, conf config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch conf.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.OTLPEndpoint)}
+		if conf.OTLPInsecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, errors.Errorf("unknown tracing exporter %q", conf.Exporter)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "tracing exporter error")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(conf.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts span of the service tracer as a child of the span stored in ctx.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan marks span as failed if err is not nil and ends it.
+// Missing entity is a regular result, not a failure.
+func EndSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(errors.Cause(err), models.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RepositoryTracing starts spans of repository queries to the backend.
+type RepositoryTracing struct {
+	backend string
+}
+
+func NewRepositoryTracing(backend string) *RepositoryTracing {
+	return &RepositoryTracing{
+		backend: backend,
+	}
+}
+
+// Start starts span of the repository method, named like "links.CreateLink".
+func (t *RepositoryTracing) Start(ctx context.Context, repository string, method string) (context.Context, trace.Span) {
+	return StartSpan(ctx, repository+"."+method,
+		repositoryBackendKey.String(t.backend),
+		repositoryNameKey.String(repository),
+	)
+}
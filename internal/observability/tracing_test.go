@@ -0,0 +1,166 @@
+package observability_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/kuzkuss/url_service/config"
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseEndSpan struct {
+	Error error
+	ExpectedCode codes.Code
+}
+
+func newRecorder() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestEndSpan(t *testing.T) {
+	cases := map[string]TestCaseEndSpan {
+		"success": {
+			Error: nil,
+			ExpectedCode: codes.Unset,
+		},
+		"not_found": {
+			Error: errors.Wrap(models.ErrNotFound, "link repository error"),
+			ExpectedCode: codes.Unset,
+		},
+		"error": {
+			Error: errors.New("error"),
+			ExpectedCode: codes.Error,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			recorder := newRecorder()
+
+			_, span := observability.StartSpan(context.Background(), name)
+			observability.EndSpan(span, test.Error)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, name, spans[0].Name())
+			assert.Equal(t, test.ExpectedCode, spans[0].Status().Code)
+		})
+	}
+}
+
+func TestRepositoryTracingStart(t *testing.T) {
+	recorder := newRecorder()
+
+	ctx, parent := observability.StartSpan(context.Background(), "parent")
+	_, span := observability.NewRepositoryTracing("in_memory").Start(ctx, "links", "CreateLink")
+	span.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "links.CreateLink", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+
+	attrs := spanAttributes(spans[0])
+	assert.Equal(t, "in_memory", attrs["repository.backend"].AsString())
+	assert.Equal(t, "links", attrs["repository.name"].AsString())
+}
+
+func TestSetupTracing(t *testing.T) {
+	shutdown, err := observability.SetupTracing(context.Background(), config.TracingConfig{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	shutdown, err = observability.SetupTracing(context.Background(), config.TracingConfig {
+		Exporter: observability.ExporterStdout,
+		ServiceName: "url_service",
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = observability.SetupTracing(context.Background(), config.TracingConfig{Exporter: "unknown"})
+	require.Error(t, err)
+}
+
+func TestGormTracing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	require.NoError(t, gdb.Use(observability.GormTracing{}))
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "links" WHERE short_link = $1 LIMIT 1`)).WithArgs("short_link").
+		WillReturnRows(sqlmock.NewRows([]string{"short_link", "original_link"}).
+		AddRow("short_link", "original_link"))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "links" WHERE short_link = $1 LIMIT 1`)).WithArgs("short_link_error").
+		WillReturnError(errors.New("error"))
+
+	recorder := newRecorder()
+
+	ctx, parent := observability.StartSpan(context.Background(), "parent")
+
+	link := models.Link{}
+	tx := gdb.WithContext(ctx).Where("short_link = ?", "short_link").Take(&link)
+	require.NoError(t, tx.Error)
+
+	tx = gdb.WithContext(ctx).Where("short_link = ?", "short_link_error").Take(&link)
+	require.Error(t, tx.Error)
+
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	for _, span := range spans[:2] {
+		assert.Equal(t, "gorm.query", span.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+
+		attrs := spanAttributes(span)
+		assert.Equal(t, `SELECT * FROM "links" WHERE short_link = $1 LIMIT 1`, attrs["db.statement"].AsString())
+		assert.Equal(t, "links", attrs["db.sql.table"].AsString())
+		assert.Equal(t, "postgresql", attrs["db.system"].AsString())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
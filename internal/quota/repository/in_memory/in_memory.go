@@ -0,0 +1,89 @@
+package in_memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/quota/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type usageKey struct {
+	ownerID     string
+	period      string
+	periodStart time.Time
+}
+
+type quotaRepository struct {
+	mx    sync.Mutex
+	store map[usageKey]int64
+	// start of the latest period seen by period name, usage of earlier periods is pruned
+	periodStarts map[string]time.Time
+}
+
+func New() repository.RepositoryI {
+	return &quotaRepository{
+		store:        make(map[usageKey]int64),
+		periodStarts: make(map[string]time.Time),
+	}
+}
+
+func (dbQuota *quotaRepository) ConsumeQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) (*models.QuotaLimit, error) {
+	dbQuota.mx.Lock()
+	defer dbQuota.mx.Unlock()
+
+	for _, limit := range limits {
+		dbQuota.prune(limit)
+	}
+
+	for idx := range limits {
+		if dbQuota.store[key(ownerID, limits[idx])] >= limits[idx].Limit {
+			return &limits[idx], nil
+		}
+	}
+
+	for _, limit := range limits {
+		dbQuota.store[key(ownerID, limit)]++
+	}
+
+	return nil, nil
+}
+
+func (dbQuota *quotaRepository) RefundQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) error {
+	dbQuota.mx.Lock()
+	defer dbQuota.mx.Unlock()
+
+	for _, limit := range limits {
+		usageKey := key(ownerID, limit)
+		if count, ok := dbQuota.store[usageKey]; ok && count > 1 {
+			dbQuota.store[usageKey]--
+		} else if ok {
+			delete(dbQuota.store, usageKey)
+		}
+	}
+
+	return nil
+}
+
+// prune removes usage of periods preceding the period of limit once it starts.
+func (dbQuota *quotaRepository) prune(limit models.QuotaLimit) {
+	if !limit.PeriodStart.After(dbQuota.periodStarts[limit.Period]) {
+		return
+	}
+	dbQuota.periodStarts[limit.Period] = limit.PeriodStart
+
+	for usageKey := range dbQuota.store {
+		if usageKey.period == limit.Period && usageKey.periodStart.Before(limit.PeriodStart) {
+			delete(dbQuota.store, usageKey)
+		}
+	}
+}
+
+func key(ownerID string, limit models.QuotaLimit) usageKey {
+	return usageKey{
+		ownerID:     ownerID,
+		period:      limit.Period,
+		periodStart: limit.PeriodStart,
+	}
+}
@@ -0,0 +1,102 @@
+package in_memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	quotaRep "github.com/kuzkuss/url_service/internal/quota/repository/in_memory"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestRepositoryConsumeQuota(t *testing.T) {
+	dayStart := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	limits := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: dayStart, Limit: 2},
+		{Period: models.QuotaPeriodMonth, PeriodStart: monthStart, Limit: 3},
+	}
+
+	repository := quotaRep.New()
+
+	for i := 0; i < 2; i++ {
+		exceeded, err := repository.ConsumeQuota(context.Background(), "owner", limits)
+		require.NoError(t, err)
+		assert.Nil(t, exceeded)
+	}
+
+	exceeded, err := repository.ConsumeQuota(context.Background(), "owner", limits)
+	require.NoError(t, err)
+	assert.Equal(t, &limits[0], exceeded)
+
+	nextDay := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: dayStart.AddDate(0, 0, 1), Limit: 2},
+		limits[1],
+	}
+
+	exceeded, err = repository.ConsumeQuota(context.Background(), "owner", nextDay)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+
+	exceeded, err = repository.ConsumeQuota(context.Background(), "owner", nextDay)
+	require.NoError(t, err)
+	assert.Equal(t, &nextDay[1], exceeded)
+
+	exceeded, err = repository.ConsumeQuota(context.Background(), "other_owner", limits)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+}
+
+func TestRepositoryRefundQuota(t *testing.T) {
+	dayStart := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	limits := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: dayStart, Limit: 1},
+	}
+
+	repository := quotaRep.New()
+
+	exceeded, err := repository.ConsumeQuota(context.Background(), "owner", limits)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+
+	err = repository.RefundQuota(context.Background(), "owner", limits)
+	require.NoError(t, err)
+
+	exceeded, err = repository.ConsumeQuota(context.Background(), "owner", limits)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+
+	err = repository.RefundQuota(context.Background(), "other_owner", limits)
+	require.NoError(t, err)
+}
+
+func TestRepositoryPrunePastPeriods(t *testing.T) {
+	dayStart := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	limits := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: dayStart, Limit: 1},
+	}
+	nextDay := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: dayStart.AddDate(0, 0, 1), Limit: 1},
+	}
+
+	repository := quotaRep.New()
+
+	exceeded, err := repository.ConsumeQuota(context.Background(), "owner", limits)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+
+	exceeded, err = repository.ConsumeQuota(context.Background(), "other_owner", nextDay)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+
+	// usage of the past day is forgotten once the next day starts
+	exceeded, err = repository.ConsumeQuota(context.Background(), "owner", limits)
+	require.NoError(t, err)
+	assert.Nil(t, exceeded)
+}
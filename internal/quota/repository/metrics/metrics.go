@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/internal/quota/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "quota_usage"
+
+type quotaRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps quota repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &quotaRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbQuota *quotaRepository) ConsumeQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) (*models.QuotaLimit, error) {
+	start := time.Now()
+	exceeded, err := dbQuota.repository.ConsumeQuota(ctx, ownerID, limits)
+	dbQuota.metrics.Observe(repositoryName, "ConsumeQuota", start, err)
+	return exceeded, err
+}
+
+func (dbQuota *quotaRepository) RefundQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) error {
+	start := time.Now()
+	err := dbQuota.repository.RefundQuota(ctx, ownerID, limits)
+	dbQuota.metrics.Observe(repositoryName, "RefundQuota", start, err)
+	return err
+}
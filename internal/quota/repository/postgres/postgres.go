@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/quota/repository"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+const consumeQuery = `INSERT INTO quota_usage (owner_id, period, period_start, count) VALUES (?, ?, ?, 1) ` +
+	`ON CONFLICT (owner_id, period, period_start) DO UPDATE SET count = quota_usage.count + 1 ` +
+	`WHERE quota_usage.count < ? RETURNING count`
+
+const refundQuery = `UPDATE quota_usage SET count = count - 1 ` +
+	`WHERE owner_id = ? AND period = ? AND period_start = ? AND count > 0`
+
+var errQuotaExceeded = errors.New("quota exceeded")
+
+type quotaRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &quotaRepository{
+		db: db,
+	}
+}
+
+func (dbQuota *quotaRepository) ConsumeQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) (*models.QuotaLimit, error) {
+	var exceeded *models.QuotaLimit
+
+	err := dbQuota.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for idx := range limits {
+			var usage []models.QuotaUsage
+			res := tx.Raw(consumeQuery, ownerID, limits[idx].Period, limits[idx].PeriodStart, limits[idx].Limit).
+				Scan(&usage)
+			if res.Error != nil {
+				return res.Error
+			}
+
+			if len(usage) == 0 {
+				exceeded = &limits[idx]
+				return errQuotaExceeded
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errQuotaExceeded) {
+		return exceeded, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "database error (table quota_usage)")
+	}
+
+	return nil, nil
+}
+
+func (dbQuota *quotaRepository) RefundQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) error {
+	err := dbQuota.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, limit := range limits {
+			res := tx.Exec(refundQuery, ownerID, limit.Period, limit.PeriodStart)
+			if res.Error != nil {
+				return res.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "database error (table quota_usage)")
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kuzkuss/url_service/models"
+	quotaRep "github.com/kuzkuss/url_service/internal/quota/repository/postgres"
+)
+
+func TestRepositoryConsumeQuota(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	limits := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), Limit: 10},
+		{Period: models.QuotaPeriodMonth, PeriodStart: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Limit: 100},
+	}
+
+	query := regexp.QuoteMeta(`INSERT INTO quota_usage (owner_id, period, period_start, count) VALUES ($1, $2, $3, 1) ` +
+		`ON CONFLICT (owner_id, period, period_start) DO UPDATE SET count = quota_usage.count + 1 ` +
+		`WHERE quota_usage.count < $4 RETURNING count`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("owner", limits[0].Period, limits[0].PeriodStart, limits[0].Limit).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(query).WithArgs("owner", limits[1].Period, limits[1].PeriodStart, limits[1].Limit).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("owner_exceeded", limits[0].Period, limits[0].PeriodStart, limits[0].Limit).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}))
+	mock.ExpectRollback()
+
+	consumeErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("owner_error", limits[0].Period, limits[0].PeriodStart, limits[0].Limit).
+		WillReturnError(consumeErr)
+	mock.ExpectRollback()
+
+	repository := quotaRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		exceeded, err := repository.ConsumeQuota(context.Background(), "owner", limits)
+		require.NoError(t, err)
+		assert.Nil(t, exceeded)
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		exceeded, err := repository.ConsumeQuota(context.Background(), "owner_exceeded", limits)
+		require.NoError(t, err)
+		assert.Equal(t, &limits[0], exceeded)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := repository.ConsumeQuota(context.Background(), "owner_error", limits)
+		require.Equal(t, consumeErr, errors.Cause(err))
+	})
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryRefundQuota(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	limits := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), Limit: 10},
+		{Period: models.QuotaPeriodMonth, PeriodStart: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Limit: 100},
+	}
+
+	query := regexp.QuoteMeta(`UPDATE quota_usage SET count = count - 1 ` +
+		`WHERE owner_id = $1 AND period = $2 AND period_start = $3 AND count > 0`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs("owner", limits[0].Period, limits[0].PeriodStart).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs("owner", limits[1].Period, limits[1].PeriodStart).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	refundErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs("owner_error", limits[0].Period, limits[0].PeriodStart).
+		WillReturnError(refundErr)
+	mock.ExpectRollback()
+
+	repository := quotaRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		err := repository.RefundQuota(context.Background(), "owner", limits)
+		require.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := repository.RefundQuota(context.Background(), "owner_error", limits)
+		require.Equal(t, refundErr, errors.Cause(err))
+	})
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+type RepositoryI interface {
+	// ConsumeQuota atomically increments owner's usage in every period of limits.
+	// If any limit has already been reached nothing is incremented and that limit is returned.
+	ConsumeQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) (*models.QuotaLimit, error)
+	// RefundQuota decrements owner's usage in every period of limits consumed before.
+	RefundQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) error
+}
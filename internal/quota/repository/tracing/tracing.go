@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/internal/quota/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "quota_usage"
+
+type quotaRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps quota repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &quotaRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbQuota *quotaRepository) ConsumeQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) (*models.QuotaLimit, error) {
+	ctx, span := dbQuota.tracing.Start(ctx, repositoryName, "ConsumeQuota")
+	exceeded, err := dbQuota.repository.ConsumeQuota(ctx, ownerID, limits)
+	observability.EndSpan(span, err)
+	return exceeded, err
+}
+
+func (dbQuota *quotaRepository) RefundQuota(ctx context.Context, ownerID string, limits []models.QuotaLimit) error {
+	ctx, span := dbQuota.tracing.Start(ctx, repositoryName, "RefundQuota")
+	err := dbQuota.repository.RefundQuota(ctx, ownerID, limits)
+	observability.EndSpan(span, err)
+	return err
+}
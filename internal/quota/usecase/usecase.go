@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	quotaRep "github.com/kuzkuss/url_service/internal/quota/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type UseCaseI interface {
+	// ConsumeLinkCreation returns the limits consumed, that are to be refunded
+	// by RefundLinkCreation if the link is not created after all.
+	ConsumeLinkCreation(ctx context.Context, ownerID string) ([]models.QuotaLimit, error)
+	RefundLinkCreation(ctx context.Context, ownerID string, consumed []models.QuotaLimit) error
+	// SetLimits changes quotas applied to the next link creations.
+	SetLimits(dailyLinks int64, monthlyLinks int64)
+}
+
+type useCase struct {
+	quotaRepository quotaRep.RepositoryI
+	dailyLinks      atomic.Int64
+	monthlyLinks    atomic.Int64
+	logger          *slog.Logger
+}
+
+// New creates quota usecase limiting links created by an owner per
+// calendar day and month (UTC). Zero limit disables the corresponding quota.
+func New(quotaRepository quotaRep.RepositoryI, dailyLinks int64, monthlyLinks int64, logger *slog.Logger) UseCaseI {
+	uc := &useCase{
+		quotaRepository: quotaRepository,
+		logger:          logger,
+	}
+	uc.SetLimits(dailyLinks, monthlyLinks)
+
+	return uc
+}
+
+func (uc *useCase) SetLimits(dailyLinks int64, monthlyLinks int64) {
+	uc.dailyLinks.Store(dailyLinks)
+	uc.monthlyLinks.Store(monthlyLinks)
+}
+
+// ConsumeLinkCreation accounts link creation by owner. Links created without
+// owner (by administrator) are not limited.
+func (uc *useCase) ConsumeLinkCreation(ctx context.Context, ownerID string) (_ []models.QuotaLimit, err error) {
+	ctx, span := observability.StartSpan(ctx, "quota.usecase.ConsumeLinkCreation")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if ownerID == "" {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	limits := make([]models.QuotaLimit, 0, 2)
+
+	dailyLinks, monthlyLinks := uc.dailyLinks.Load(), uc.monthlyLinks.Load()
+
+	if dailyLinks > 0 {
+		dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		limits = append(limits, models.QuotaLimit{
+			Period:      models.QuotaPeriodDay,
+			PeriodStart: dayStart,
+			PeriodEnd:   dayStart.AddDate(0, 0, 1),
+			Limit:       dailyLinks,
+		})
+	}
+
+	if monthlyLinks > 0 {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		limits = append(limits, models.QuotaLimit{
+			Period:      models.QuotaPeriodMonth,
+			PeriodStart: monthStart,
+			PeriodEnd:   monthStart.AddDate(0, 1, 0),
+			Limit:       monthlyLinks,
+		})
+	}
+
+	if len(limits) == 0 {
+		return nil, nil
+	}
+
+	exceeded, err := uc.quotaRepository.ConsumeQuota(ctx, ownerID, limits)
+	if err != nil {
+		return nil, errors.Wrap(err, "quota repository error")
+	}
+
+	if exceeded != nil {
+		uc.logger.WarnContext(ctx, "link quota exceeded",
+			"owner_id", ownerID, "period", exceeded.Period, "limit", exceeded.Limit)
+		return nil, &models.RateLimitError{
+			Reason:     exceeded.Period + " link quota exceeded",
+			RetryAfter: exceeded.PeriodEnd.Sub(now),
+		}
+	}
+
+	return limits, nil
+}
+
+func (uc *useCase) RefundLinkCreation(ctx context.Context, ownerID string, consumed []models.QuotaLimit) (err error) {
+	ctx, span := observability.StartSpan(ctx, "quota.usecase.RefundLinkCreation")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if len(consumed) == 0 {
+		return nil
+	}
+
+	err = uc.quotaRepository.RefundQuota(ctx, ownerID, consumed)
+	if err != nil {
+		return errors.Wrap(err, "quota repository error")
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	quotaMocks "github.com/kuzkuss/url_service/internal/quota/repository/mocks"
+	quotaUsecase "github.com/kuzkuss/url_service/internal/quota/usecase"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestUsecaseConsumeLinkCreation(t *testing.T) {
+	consumeErr := errors.New("error")
+
+	mockQuotaRepo := quotaMocks.NewRepositoryI(t)
+
+	hasPeriods := func(periods ...string) interface{} {
+		return mock.MatchedBy(func(limits []models.QuotaLimit) bool {
+			if len(limits) != len(periods) {
+				return false
+			}
+			for idx, limit := range limits {
+				if limit.Period != periods[idx] || !limit.PeriodEnd.After(limit.PeriodStart) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+
+	mockQuotaRepo.On("ConsumeQuota", mock.Anything, "owner", hasPeriods(models.QuotaPeriodDay, models.QuotaPeriodMonth)).
+		Return(nil, nil)
+	mockQuotaRepo.On("ConsumeQuota", mock.Anything, "owner_exceeded", hasPeriods(models.QuotaPeriodDay, models.QuotaPeriodMonth)).
+		Return(func(ctx context.Context, ownerID string, limits []models.QuotaLimit) *models.QuotaLimit {
+			return &limits[1]
+		}, nil)
+	mockQuotaRepo.On("ConsumeQuota", mock.Anything, "owner_error", hasPeriods(models.QuotaPeriodDay, models.QuotaPeriodMonth)).
+		Return(nil, consumeErr)
+
+	usecase := quotaUsecase.New(mockQuotaRepo, 10, 100, observability.NopLogger())
+
+	t.Run("success", func(t *testing.T) {
+		consumed, err := usecase.ConsumeLinkCreation(context.Background(), "owner")
+		require.NoError(t, err)
+		assert.Len(t, consumed, 2)
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		_, err := usecase.ConsumeLinkCreation(context.Background(), "owner_exceeded")
+		require.True(t, errors.Is(err, models.ErrTooManyRequests))
+
+		var rateErr *models.RateLimitError
+		require.True(t, errors.As(err, &rateErr))
+		assert.True(t, rateErr.RetryAfter > 0 && rateErr.RetryAfter <= 31 * 24 * time.Hour)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := usecase.ConsumeLinkCreation(context.Background(), "owner_error")
+		require.Equal(t, consumeErr, errors.Cause(err))
+	})
+
+	t.Run("without_owner", func(t *testing.T) {
+		_, err := usecase.ConsumeLinkCreation(context.Background(), "")
+		require.NoError(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := quotaUsecase.New(mockQuotaRepo, 0, 0, observability.NopLogger()).ConsumeLinkCreation(context.Background(), "owner")
+		require.NoError(t, err)
+	})
+
+	t.Run("limits_changed", func(t *testing.T) {
+		mockQuotaRepo.On("ConsumeQuota", mock.Anything, "owner_daily", hasPeriods(models.QuotaPeriodDay)).
+			Return(nil, nil).Once()
+
+		usecase := quotaUsecase.New(mockQuotaRepo, 0, 0, observability.NopLogger())
+		usecase.SetLimits(10, 0)
+		_, err := usecase.ConsumeLinkCreation(context.Background(), "owner_daily")
+		require.NoError(t, err)
+	})
+}
+
+func TestUsecaseRefundLinkCreation(t *testing.T) {
+	refundErr := errors.New("error")
+
+	consumed := []models.QuotaLimit {
+		{Period: models.QuotaPeriodDay, PeriodStart: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), Limit: 10},
+	}
+
+	mockQuotaRepo := quotaMocks.NewRepositoryI(t)
+
+	mockQuotaRepo.On("RefundQuota", mock.Anything, "owner", consumed).Return(nil)
+	mockQuotaRepo.On("RefundQuota", mock.Anything, "owner_error", consumed).Return(refundErr)
+
+	usecase := quotaUsecase.New(mockQuotaRepo, 10, 0, observability.NopLogger())
+
+	t.Run("success", func(t *testing.T) {
+		err := usecase.RefundLinkCreation(context.Background(), "owner", consumed)
+		require.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := usecase.RefundLinkCreation(context.Background(), "owner_error", consumed)
+		require.Equal(t, refundErr, errors.Cause(err))
+	})
+
+	t.Run("nothing_consumed", func(t *testing.T) {
+		err := usecase.RefundLinkCreation(context.Background(), "owner", nil)
+		require.NoError(t, err)
+	})
+}
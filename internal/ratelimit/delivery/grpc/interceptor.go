@@ -0,0 +1,116 @@
+package delivery
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/kuzkuss/url_service/internal/ratelimit"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+const MetadataRetryAfter = "retry-after"
+
+type RateLimitInterceptor struct {
+	limiter ratelimit.LimiterI
+	key     func(ctx context.Context) string
+}
+
+// New creates interceptor limiting call rate of every client. Clients are identified
+// by the principal authenticated by the preceding interceptor and anonymous ones
+// by peer IP address.
+// The interceptor also converts models.RateLimitError returned by handlers
+// (e.g. exceeded quota) into ResourceExhausted status; if limiter is nil,
+// calls are not limited and only the errors are converted.
+func New(limiter ratelimit.LimiterI) *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		limiter: limiter,
+		key:     clientKey,
+	}
+}
+
+// NewIP creates interceptor limiting call rate of every peer IP address. It precedes
+// identification of clients, so calls with made up credentials are limited before
+// the credentials are looked up.
+func NewIP(limiter ratelimit.LimiterI) *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		limiter: limiter,
+		key:     ipKey,
+	}
+}
+
+func (ri *RateLimitInterceptor) Unary(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if ri.limiter != nil {
+		if allowed, retryAfter := ri.limiter.Allow(ri.key(ctx)); !allowed {
+			return nil, resourceExhausted(ctx, &models.RateLimitError{
+				Reason:     "rate limit exceeded",
+				RetryAfter: retryAfter,
+			})
+		}
+	}
+
+	resp, err := handler(ctx, req)
+
+	var rateErr *models.RateLimitError
+	if errors.As(err, &rateErr) {
+		return resp, resourceExhausted(ctx, rateErr)
+	}
+
+	return resp, err
+}
+
+// Stream limits rate of opening streams, messages of the open streams are not limited.
+func (ri *RateLimitInterceptor) Stream(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if ri.limiter != nil {
+		if allowed, retryAfter := ri.limiter.Allow(ri.key(ss.Context())); !allowed {
+			return resourceExhausted(ss.Context(), &models.RateLimitError{
+				Reason:     "rate limit exceeded",
+				RetryAfter: retryAfter,
+			})
+		}
+	}
+
+	return handler(srv, ss)
+}
+
+func resourceExhausted(ctx context.Context, rateErr *models.RateLimitError) error {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRetryAfter, pkg.RetryAfterSeconds(rateErr.RetryAfter)))
+
+	st := status.New(codes.ResourceExhausted, rateErr.Error())
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(rateErr.RetryAfter)}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+func clientKey(ctx context.Context) string {
+	principal, _ := pkg.PrincipalFromContext(ctx)
+	return ratelimit.ClientKey(principal, peerIP(ctx))
+}
+
+func ipKey(ctx context.Context) string {
+	return ratelimit.ClientKey(nil, peerIP(ctx))
+}
+
+func peerIP(ctx context.Context) string {
+	ip := "unknown"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return ip
+}
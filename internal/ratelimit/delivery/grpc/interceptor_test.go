@@ -0,0 +1,127 @@
+package delivery_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	rateLimitDelivery "github.com/kuzkuss/url_service/internal/ratelimit/delivery/grpc"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type limiterStub map[string]time.Duration
+
+func (l limiterStub) Allow(key string) (bool, time.Duration) {
+	retryAfter, limited := l[key]
+	return !limited, retryAfter
+}
+
+func (l limiterStub) SetLimit(requestsPerMinute int, burst int) {}
+
+type TestCaseUnary struct {
+	Ctx context.Context
+	HandlerErr error
+	Code codes.Code
+	RetryAfter time.Duration
+}
+
+func TestGrpcRateLimitInterceptorUnary(t *testing.T) {
+	limiter := limiterStub{
+		"principal:default/limited_owner": 2 * time.Second,
+		"ip:10.0.0.2": time.Minute,
+	}
+
+	interceptor := rateLimitDelivery.New(limiter)
+
+	peerCtx := func(ip string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234},
+		})
+	}
+
+	cases := map[string]TestCaseUnary {
+		"allowed": {
+			Ctx: peerCtx("10.0.0.1"),
+			Code: codes.OK,
+		},
+		"limited_principal": {
+			Ctx: pkg.WithPrincipal(peerCtx("10.0.0.1"),
+				&models.Principal{OwnerID: "limited_owner", WorkspaceID: models.DefaultWorkspace}),
+			Code: codes.ResourceExhausted,
+			RetryAfter: 2 * time.Second,
+		},
+		"unauthenticated_key": {
+			Ctx: metadata.NewIncomingContext(peerCtx("10.0.0.2"), metadata.Pairs("x-api-key", "made_up_key")),
+			Code: codes.ResourceExhausted,
+			RetryAfter: time.Minute,
+		},
+		"limited_ip": {
+			Ctx: peerCtx("10.0.0.2"),
+			Code: codes.ResourceExhausted,
+			RetryAfter: time.Minute,
+		},
+		"quota_exceeded": {
+			Ctx: peerCtx("10.0.0.1"),
+			HandlerErr: errors.Wrap(&models.RateLimitError{RetryAfter: time.Hour}, "link quota error"),
+			Code: codes.ResourceExhausted,
+			RetryAfter: time.Hour,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, test.HandlerErr
+			}
+
+			_, err := interceptor.Unary(test.Ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/link.Links/CreateShortLink"}, handler)
+			st := status.Convert(err)
+			require.Equal(t, test.Code, st.Code())
+
+			if test.Code == codes.ResourceExhausted {
+				require.Len(t, st.Details(), 1)
+				retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+				require.True(t, ok)
+				assert.Equal(t, test.RetryAfter, retryInfo.RetryDelay.AsDuration())
+			}
+		})
+	}
+}
+
+func TestGrpcRateLimitInterceptorIP(t *testing.T) {
+	limiter := limiterStub{
+		"ip:10.0.0.2": time.Minute,
+	}
+
+	interceptor := rateLimitDelivery.NewIP(limiter)
+
+	peerCtx := func(ip string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234},
+		})
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/link.Links/CreateShortLink"}
+
+	// the address is limited whatever principal the call would be identified as
+	_, err := interceptor.Unary(pkg.WithPrincipal(peerCtx("10.0.0.2"),
+		&models.Principal{OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}), nil, info, handler)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = interceptor.Unary(peerCtx("10.0.0.1"), nil, info, handler)
+	require.NoError(t, err)
+}
@@ -0,0 +1,45 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/kuzkuss/url_service/internal/ratelimit"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+// New returns middleware limiting request rate of every client. Clients are
+// identified by the principal authenticated by the preceding middleware and
+// anonymous ones by IP address returned by c.RealIP, so the IP extractor of echo
+// must trust forwarding headers of known proxies only.
+func New(limiter ratelimit.LimiterI) echo.MiddlewareFunc {
+	return limit(limiter, func(c echo.Context) string {
+		principal, _ := pkg.PrincipalFromContext(c.Request().Context())
+		return ratelimit.ClientKey(principal, c.RealIP())
+	})
+}
+
+// NewIP returns middleware limiting request rate of every IP address returned by
+// c.RealIP. It precedes identification of clients, so requests with made up
+// credentials are limited before the credentials are looked up.
+func NewIP(limiter ratelimit.LimiterI) echo.MiddlewareFunc {
+	return limit(limiter, func(c echo.Context) string {
+		return ratelimit.ClientKey(nil, c.RealIP())
+	})
+}
+
+// limit returns middleware limiting request rate of every client identified by key.
+func limit(limiter ratelimit.LimiterI, key func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			allowed, retryAfter := limiter.Allow(key(c))
+			if !allowed {
+				c.Response().Header().Set(echo.HeaderRetryAfter, pkg.RetryAfterSeconds(retryAfter))
+				return echo.NewHTTPError(http.StatusTooManyRequests, models.ErrTooManyRequests.Error())
+			}
+			return next(c)
+		}
+	}
+}
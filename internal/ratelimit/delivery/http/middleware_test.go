@@ -0,0 +1,136 @@
+package delivery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rateLimitDelivery "github.com/kuzkuss/url_service/internal/ratelimit/delivery/http"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type limiterStub map[string]time.Duration
+
+func (l limiterStub) Allow(key string) (bool, time.Duration) {
+	retryAfter, limited := l[key]
+	return !limited, retryAfter
+}
+
+func (l limiterStub) SetLimit(requestsPerMinute int, burst int) {}
+
+type TestCaseRequest struct {
+	Principal *models.Principal
+	APIKey string
+	RemoteAddr string
+	ForwardedFor string
+	RetryAfter string
+	Error error
+}
+
+func TestHttpRateLimit(t *testing.T) {
+	limiter := limiterStub{
+		"principal:default/limited_owner": 1500 * time.Millisecond,
+		"ip:10.0.0.2": time.Minute,
+	}
+
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	handler := rateLimitDelivery.New(limiter)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	tooManyRequests := &echo.HTTPError{
+		Code: http.StatusTooManyRequests,
+		Message: models.ErrTooManyRequests.Error(),
+	}
+
+	cases := map[string]TestCaseRequest {
+		"allowed": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: models.DefaultWorkspace},
+			RemoteAddr: "10.0.0.2:1234",
+		},
+		"limited_principal": {
+			Principal: &models.Principal{OwnerID: "limited_owner", WorkspaceID: models.DefaultWorkspace},
+			RemoteAddr: "10.0.0.1:1234",
+			RetryAfter: "2",
+			Error: tooManyRequests,
+		},
+		"limited_ip": {
+			RemoteAddr: "10.0.0.2:1234",
+			RetryAfter: "60",
+			Error: tooManyRequests,
+		},
+		"unauthenticated_key": {
+			APIKey: "made_up_key",
+			RemoteAddr: "10.0.0.2:1234",
+			RetryAfter: "60",
+			Error: tooManyRequests,
+		},
+		"spoofed_ip": {
+			RemoteAddr: "10.0.0.2:1234",
+			ForwardedFor: "10.0.0.3",
+			RetryAfter: "60",
+			Error: tooManyRequests,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.RemoteAddr = test.RemoteAddr
+			if test.APIKey != "" {
+				req.Header.Set("X-API-Key", test.APIKey)
+			}
+			if test.ForwardedFor != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, test.ForwardedFor)
+			}
+			if test.Principal != nil {
+				req = req.WithContext(pkg.WithPrincipal(req.Context(), test.Principal))
+			}
+			rec := httptest.NewRecorder()
+
+			err := handler(e.NewContext(req, rec))
+			require.Equal(t, test.Error, err)
+			assert.Equal(t, test.RetryAfter, rec.Header().Get(echo.HeaderRetryAfter))
+		})
+	}
+}
+
+func TestHttpRateLimitIP(t *testing.T) {
+	limiter := limiterStub{
+		"ip:10.0.0.2": time.Minute,
+	}
+
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	handler := rateLimitDelivery.NewIP(limiter)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	// the address is limited whatever principal the request would be identified as
+	for _, principal := range []*models.Principal{nil, {OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}} {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.RemoteAddr = "10.0.0.2:1234"
+		if principal != nil {
+			req = req.WithContext(pkg.WithPrincipal(req.Context(), principal))
+		}
+
+		rec := httptest.NewRecorder()
+		err := handler(e.NewContext(req, rec))
+		require.Equal(t, &echo.HTTPError{
+			Code: http.StatusTooManyRequests,
+			Message: models.ErrTooManyRequests.Error(),
+		}, err)
+		assert.Equal(t, "60", rec.Header().Get(echo.HeaderRetryAfter))
+	}
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	require.NoError(t, handler(e.NewContext(req, httptest.NewRecorder())))
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+const (
+	idleTimeout   = 10 * time.Minute
+	sweepInterval = time.Minute
+)
+
+type LimiterI interface {
+	// Allow reports whether request of client identified by key may proceed
+	// and, if not, how long the client should wait before retrying.
+	Allow(key string) (bool, time.Duration)
+	// SetLimit changes rate and burst of every client.
+	SetLimit(requestsPerMinute int, burst int)
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type limiter struct {
+	mx        sync.Mutex
+	buckets   map[string]*bucket
+	limit     rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+// New creates token bucket limiter refilled with requestsPerMinute tokens
+// per minute and holding at most burst tokens per client. Zero requestsPerMinute
+// allows every request.
+func New(requestsPerMinute int, burst int) LimiterI {
+	l := &limiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+	l.SetLimit(requestsPerMinute, burst)
+
+	return l
+}
+
+func (l *limiter) SetLimit(requestsPerMinute int, burst int) {
+	limit := rate.Limit(float64(requestsPerMinute) / 60)
+	if requestsPerMinute <= 0 {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.limit = limit
+	l.burst = burst
+	now := time.Now()
+	for _, b := range l.buckets {
+		b.limiter.SetLimitAt(now, limit)
+		b.limiter.SetBurstAt(now, burst)
+	}
+}
+
+func (l *limiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mx.Lock()
+	if l.limit == rate.Inf {
+		l.mx.Unlock()
+		return true, 0
+	}
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	l.mx.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep forgets clients idle for a long time so that the map does not grow unbounded.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ClientKey returns key identifying the client in the limiter: the authenticated
+// principal or, for anonymous clients, their IP address. Credentials themselves are
+// never used, so clients can not get fresh buckets by sending made up keys.
+func ClientKey(principal *models.Principal, ip string) string {
+	if principal != nil {
+		return "principal:" + principal.WorkspaceID + "/" + principal.OwnerID
+	}
+	return "ip:" + ip
+}
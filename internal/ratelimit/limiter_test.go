@@ -0,0 +1,47 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kuzkuss/url_service/internal/ratelimit"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	limiter := ratelimit.New(60, 2)
+
+	allowed, _ := limiter.Allow("client")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("client")
+	assert.True(t, allowed)
+
+	allowed, retryAfter := limiter.Allow("client")
+	assert.False(t, allowed)
+	assert.True(t, retryAfter > 0 && retryAfter <= time.Second)
+
+	allowed, _ = limiter.Allow("other_client")
+	assert.True(t, allowed)
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	limiter := ratelimit.New(60, 1)
+
+	allowed, _ := limiter.Allow("client")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("client")
+	assert.False(t, allowed)
+
+	limiter.SetLimit(0, 0)
+	for i := 0; i < 10; i++ {
+		allowed, _ = limiter.Allow("client")
+		assert.True(t, allowed)
+	}
+
+	limiter.SetLimit(60, 1)
+	allowed, _ = limiter.Allow("new_client")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("new_client")
+	assert.False(t, allowed)
+}
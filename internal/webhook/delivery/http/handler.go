@@ -0,0 +1,241 @@
+package delivery
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	webhookUsecase "github.com/kuzkuss/url_service/internal/webhook/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type Delivery struct {
+	WebhookUC webhookUsecase.UseCaseI
+	Logger *slog.Logger
+}
+
+// CreateSubscription godoc
+// @Summary      CreateSubscription
+// @Description  subscribe url to events of links of the owner of api key; empty events subscribe to all events, link.clicks is sent once for a link when number of clicks reaches click_threshold; url must not point to loopback, private, link-local or metadata address. Secret signing notifications is generated unless it is given and is returned only in this response
+// @Tags     webhook
+// @Accept	 application/json
+// @Produce  application/json
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param    subscription body models.WebhookSubscription true "subscription data"
+// @Success 201 {object} pkg.Response{body=models.WebhookSubscription} "subscription created"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /webhooks [post]
+func (del *Delivery) CreateSubscription(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	var subscription models.WebhookSubscription
+	err := c.Bind(&subscription)
+	if err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid webhook subscription data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	if err := pkg.Validate(&subscription); err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid webhook subscription data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	subscription.OwnerID = principal.OwnerID
+	subscription.WorkspaceID = scope(principal).WorkspaceID
+	err = del.WebhookUC.CreateSubscription(c.Request().Context(), &subscription)
+	if err != nil {
+		return del.httpError(c.Request().Context(), "webhook subscription creation failed", err)
+	}
+
+	return c.JSON(http.StatusCreated, pkg.Response{Body: subscription})
+}
+
+// GetSubscriptions godoc
+// @Summary      GetSubscriptions
+// @Description  get webhook subscriptions of the owner of api key
+// @Tags     webhook
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.WebhookSubscription} "success get subscriptions"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /webhooks [get]
+func (del *Delivery) GetSubscriptions(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	subscriptions, err := del.WebhookUC.GetSubscriptions(c.Request().Context(), scope(principal))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "webhook subscriptions listing failed", err)
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: subscriptions})
+}
+
+// DeleteSubscription godoc
+// @Summary      DeleteSubscription
+// @Description  delete webhook subscription with its delivery log and dead letters
+// @Tags     webhook
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Subscription id"
+// @Success  204 "subscription deleted"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /webhooks/{id} [delete]
+func (del *Delivery) DeleteSubscription(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	err := del.WebhookUC.DeleteSubscription(c.Request().Context(), c.Param("id"), scope(principal))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "webhook subscription deletion failed", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetDeliveryLog godoc
+// @Summary      GetDeliveryLog
+// @Description  get the latest attempts to deliver notifications to the subscription, the latest first
+// @Tags     webhook
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Subscription id"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.WebhookAttempt} "success get delivery log"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /webhooks/{id}/deliveries [get]
+func (del *Delivery) GetDeliveryLog(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	attempts, err := del.WebhookUC.GetDeliveryLog(c.Request().Context(), c.Param("id"), scope(principal))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "webhook delivery log loading failed", err)
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: attempts})
+}
+
+// GetDeadLetters godoc
+// @Summary      GetDeadLetters
+// @Description  get notifications which were not delivered to the subscription in the allowed number of attempts
+// @Tags     webhook
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Subscription id"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.WebhookDelivery} "success get dead letters"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /webhooks/{id}/dead_letters [get]
+func (del *Delivery) GetDeadLetters(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	deliveries, err := del.WebhookUC.GetDeadLetters(c.Request().Context(), c.Param("id"), scope(principal))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "webhook dead letters loading failed", err)
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: deliveries})
+}
+
+// Redeliver godoc
+// @Summary      Redeliver
+// @Description  send the dead letter to the subscription again with the same id
+// @Tags     webhook
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Subscription id"
+// @Param delivery_id path string  true  "Delivery id"
+// @Success  202 "notification queued"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /webhooks/{id}/dead_letters/{delivery_id}/redeliver [post]
+func (del *Delivery) Redeliver(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	err := del.WebhookUC.Redeliver(c.Request().Context(), c.Param("id"), scope(principal), c.Param("delivery_id"))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "webhook redelivery failed", err)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (del *Delivery) httpError(ctx context.Context, msg string, err error) error {
+	causeErr := errors.Cause(err)
+	switch {
+	case errors.Is(causeErr, models.ErrBadRequest):
+		del.Logger.InfoContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(causeErr, models.ErrNotFound):
+		del.Logger.InfoContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrNotFound.Error())
+	default:
+		del.Logger.ErrorContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+	}
+}
+
+// scope returns scope of subscriptions of the principal, clients not bound to
+// a workspace manage subscriptions in the default one.
+func scope(principal *models.Principal) models.WebhookScope {
+	workspaceID := principal.WorkspaceID
+	if workspaceID == "" {
+		workspaceID = models.DefaultWorkspace
+	}
+	return models.WebhookScope{WorkspaceID: workspaceID, OwnerID: principal.OwnerID}
+}
+
+// New registers webhook routes wrapped with middleware returned by authorize
+// for the required scope; it must store principal in the request context.
+func New(e *echo.Echo, webhookUC webhookUsecase.UseCaseI, authorize func(scope string) echo.MiddlewareFunc,
+	logger *slog.Logger) {
+	handler := &Delivery{
+		WebhookUC: webhookUC,
+		Logger: logger,
+	}
+
+	e.POST("/webhooks", handler.CreateSubscription, authorize(models.ScopeLinksWrite))
+	e.GET("/webhooks", handler.GetSubscriptions, authorize(models.ScopeLinksRead))
+	e.DELETE("/webhooks/:id", handler.DeleteSubscription, authorize(models.ScopeLinksWrite))
+	e.GET("/webhooks/:id/deliveries", handler.GetDeliveryLog, authorize(models.ScopeLinksRead))
+	e.GET("/webhooks/:id/dead_letters", handler.GetDeadLetters, authorize(models.ScopeLinksRead))
+	e.POST("/webhooks/:id/dead_letters/:delivery_id/redeliver", handler.Redeliver, authorize(models.ScopeLinksWrite))
+}
@@ -0,0 +1,155 @@
+package delivery_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	webhookDelivery "github.com/kuzkuss/url_service/internal/webhook/delivery/http"
+	webhookMocks "github.com/kuzkuss/url_service/internal/webhook/usecase/mocks"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type TestCaseRequest struct {
+	Method string
+	Target string
+	ArgData string
+	ExpectedResponse string
+	StatusCode int
+}
+
+// withOwner authenticates every request as the owner.
+func withOwner(string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := pkg.WithPrincipal(c.Request().Context(), &models.Principal{OwnerID: "owner", WorkspaceID: "team"})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+func TestHttpDeliveryWebhooks(t *testing.T) {
+	created := models.WebhookSubscription {
+		ID: "subscription",
+		URL: "https://crm.example.com/hooks",
+		Secret: "generated_secret",
+		Events: []string{models.WebhookLinkClicks},
+		ClickThreshold: 1000,
+	}
+	attempts := []models.WebhookAttempt {
+		{DeliveryID: "delivery", Event: models.WebhookLinkCreated, Attempt: 1, Delivered: true, StatusCode: http.StatusOK},
+	}
+
+	owner := models.WebhookScope{WorkspaceID: "team", OwnerID: "owner"}
+
+	mockWebhookUsecase := webhookMocks.NewUseCaseI(t)
+
+	mockWebhookUsecase.On("CreateSubscription", mock.Anything, &models.WebhookSubscription{
+		OwnerID: "owner",
+		WorkspaceID: "team",
+		URL: created.URL,
+		Events: created.Events,
+		ClickThreshold: created.ClickThreshold,
+	}).Run(func(args mock.Arguments) {
+		subscription := args.Get(1).(*models.WebhookSubscription)
+		subscription.ID, subscription.Secret = created.ID, created.Secret
+	}).Return(nil)
+	mockWebhookUsecase.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(subscription *models.WebhookSubscription) bool {
+		return subscription.URL == "ftp://crm.example.com"
+	})).Return(errors.Wrap(models.ErrBadRequest, "webhook url must be absolute http or https url"))
+	mockWebhookUsecase.On("DeleteSubscription", mock.Anything, "subscription", owner).Return(nil)
+	mockWebhookUsecase.On("DeleteSubscription", mock.Anything, "unknown", owner).Return(models.ErrNotFound)
+	mockWebhookUsecase.On("GetDeliveryLog", mock.Anything, "subscription", owner).Return(attempts, nil)
+	mockWebhookUsecase.On("Redeliver", mock.Anything, "subscription", owner, "delivery").Return(nil)
+	mockWebhookUsecase.On("Redeliver", mock.Anything, "subscription", owner, "unknown").Return(models.ErrNotFound)
+
+	createdResponse, err := json.Marshal(pkg.Response{Body: created})
+	require.NoError(t, err)
+	attemptsResponse, err := json.Marshal(pkg.Response{Body: attempts})
+	require.NoError(t, err)
+
+	e := echo.New()
+	webhookDelivery.New(e, mockWebhookUsecase, withOwner, observability.NopLogger())
+
+	cases := map[string]TestCaseRequest {
+		"create": {
+			Method: echo.POST,
+			Target: "/webhooks",
+			ArgData: `{"url":"https://crm.example.com/hooks","events":["link.clicks"],"click_threshold":1000}`,
+			ExpectedResponse: string(createdResponse) + "\n",
+			StatusCode: http.StatusCreated,
+		},
+		"create_unknown_event": {
+			Method: echo.POST,
+			Target: "/webhooks",
+			ArgData: `{"url":"https://crm.example.com/hooks","events":["link.visited"]}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"create_short_secret": {
+			Method: echo.POST,
+			Target: "/webhooks",
+			ArgData: `{"url":"https://crm.example.com/hooks","secret":"short"}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"create_bad_url": {
+			Method: echo.POST,
+			Target: "/webhooks",
+			ArgData: `{"url":"ftp://crm.example.com"}`,
+			ExpectedResponse: `{"message":"webhook url must be absolute http or https url: bad request"}` + "\n",
+			StatusCode: http.StatusBadRequest,
+		},
+		"delete": {
+			Method: echo.DELETE,
+			Target: "/webhooks/subscription",
+			StatusCode: http.StatusNoContent,
+		},
+		"delete_not_found": {
+			Method: echo.DELETE,
+			Target: "/webhooks/unknown",
+			StatusCode: http.StatusNotFound,
+		},
+		"delivery_log": {
+			Method: echo.GET,
+			Target: "/webhooks/subscription/deliveries",
+			ExpectedResponse: string(attemptsResponse) + "\n",
+			StatusCode: http.StatusOK,
+		},
+		"redeliver": {
+			Method: echo.POST,
+			Target: "/webhooks/subscription/dead_letters/delivery/redeliver",
+			StatusCode: http.StatusAccepted,
+		},
+		"redeliver_not_found": {
+			Method: echo.POST,
+			Target: "/webhooks/subscription/dead_letters/unknown/redeliver",
+			StatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(test.Method, test.Target, strings.NewReader(test.ArgData))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req.WithContext(context.Background()))
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+}
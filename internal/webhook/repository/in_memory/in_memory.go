@@ -0,0 +1,331 @@
+package in_memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/webhook/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+// queuedDelivery is a delivery in the queue of the subscription, it is claimed
+// by a worker until lockedUntil.
+type queuedDelivery struct {
+	delivery    models.QueuedWebhookDelivery
+	lockedUntil time.Time
+}
+
+// linkKey identifies the link by its short link on the domain.
+type linkKey struct {
+	domain    string
+	shortLink string
+}
+
+type webhookRepository struct {
+	mx            sync.RWMutex
+	subscriptions map[string]models.WebhookSubscription
+	attempts      map[string][]models.WebhookAttempt
+	queues        map[string][]*queuedDelivery
+	deadLetters   map[string]map[string]models.WebhookDelivery
+	// links for which click thresholds of subscriptions fired
+	fired         map[string]map[linkKey]time.Time
+	lastAttemptID int64
+}
+
+func New() repository.RepositoryI {
+	return &webhookRepository{
+		subscriptions: make(map[string]models.WebhookSubscription),
+		attempts:      make(map[string][]models.WebhookAttempt),
+		queues:        make(map[string][]*queuedDelivery),
+		deadLetters:   make(map[string]map[string]models.WebhookDelivery),
+		fired:         make(map[string]map[linkKey]time.Time),
+	}
+}
+
+func (dbWebhook *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	if _, ok := dbWebhook.subscriptions[subscription.ID]; ok {
+		return models.ErrConflict
+	}
+
+	now := time.Now()
+	subscription.CreatedAt = &now
+
+	dbWebhook.subscriptions[subscription.ID] = *subscription
+	return nil
+}
+
+func (dbWebhook *webhookRepository) SelectSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	dbWebhook.mx.RLock()
+	defer dbWebhook.mx.RUnlock()
+
+	subscription, ok := dbWebhook.subscriptions[id]
+	if !ok || !scope.Includes(subscription) {
+		return nil, models.ErrNotFound
+	}
+
+	return &subscription, nil
+}
+
+func (dbWebhook *webhookRepository) SelectSubscriptions(ctx context.Context, scope models.WebhookScope) ([]models.WebhookSubscription, error) {
+	dbWebhook.mx.RLock()
+	defer dbWebhook.mx.RUnlock()
+
+	subscriptions := make([]models.WebhookSubscription, 0)
+	for _, subscription := range dbWebhook.subscriptions {
+		if scope.Includes(subscription) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(*subscriptions[j].CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (dbWebhook *webhookRepository) DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	subscription, ok := dbWebhook.subscriptions[id]
+	if !ok || !scope.Includes(subscription) {
+		return nil, models.ErrNotFound
+	}
+
+	delete(dbWebhook.subscriptions, id)
+	delete(dbWebhook.attempts, id)
+	delete(dbWebhook.queues, id)
+	delete(dbWebhook.deadLetters, id)
+	delete(dbWebhook.fired, id)
+	return &subscription, nil
+}
+
+func (dbWebhook *webhookRepository) CreateAttempt(ctx context.Context, attempt *models.WebhookAttempt, keep int) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	if _, ok := dbWebhook.subscriptions[attempt.SubscriptionID]; !ok {
+		return models.ErrNotFound
+	}
+
+	dbWebhook.lastAttemptID++
+	attempt.ID = dbWebhook.lastAttemptID
+
+	attempts := append(dbWebhook.attempts[attempt.SubscriptionID], *attempt)
+	if len(attempts) > keep {
+		attempts = append([]models.WebhookAttempt(nil), attempts[len(attempts)-keep:]...)
+	}
+	dbWebhook.attempts[attempt.SubscriptionID] = attempts
+	return nil
+}
+
+func (dbWebhook *webhookRepository) SelectAttempts(ctx context.Context, subscriptionID string) ([]models.WebhookAttempt, error) {
+	dbWebhook.mx.RLock()
+	defer dbWebhook.mx.RUnlock()
+
+	stored := dbWebhook.attempts[subscriptionID]
+	attempts := make([]models.WebhookAttempt, 0, len(stored))
+	for idx := len(stored) - 1; idx >= 0; idx-- {
+		attempts = append(attempts, stored[idx])
+	}
+
+	return attempts, nil
+}
+
+func (dbWebhook *webhookRepository) QueueDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	if _, ok := dbWebhook.subscriptions[delivery.SubscriptionID]; !ok {
+		return models.ErrNotFound
+	}
+	if _, ok := dbWebhook.findDelivery(delivery.ID); ok {
+		return nil
+	}
+
+	dbWebhook.queues[delivery.SubscriptionID] = append(dbWebhook.queues[delivery.SubscriptionID],
+		&queuedDelivery{delivery: *delivery})
+	return nil
+}
+
+func (dbWebhook *webhookRepository) QueueClicksDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery,
+	domain string, shortLink string) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	if _, ok := dbWebhook.subscriptions[delivery.SubscriptionID]; !ok {
+		return models.ErrNotFound
+	}
+	key := linkKey{domain, shortLink}
+	if _, ok := dbWebhook.fired[delivery.SubscriptionID][key]; ok {
+		return models.ErrConflict
+	}
+	if _, ok := dbWebhook.findDelivery(delivery.ID); ok {
+		return models.ErrConflict
+	}
+
+	if dbWebhook.fired[delivery.SubscriptionID] == nil {
+		dbWebhook.fired[delivery.SubscriptionID] = make(map[linkKey]time.Time)
+	}
+	dbWebhook.fired[delivery.SubscriptionID][key] = delivery.CreatedAt
+	dbWebhook.queues[delivery.SubscriptionID] = append(dbWebhook.queues[delivery.SubscriptionID],
+		&queuedDelivery{delivery: *delivery})
+	return nil
+}
+
+func (dbWebhook *webhookRepository) DeleteFiredThresholds(ctx context.Context, domain string, shortLink string) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	for _, fired := range dbWebhook.fired {
+		delete(fired, linkKey{domain, shortLink})
+	}
+	return nil
+}
+
+func (dbWebhook *webhookRepository) ClaimDelivery(ctx context.Context, now time.Time,
+	lockedUntil time.Time) (*models.WebhookSubscription, *models.QueuedWebhookDelivery, error) {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	var claimed *queuedDelivery
+	for _, queue := range dbWebhook.queues {
+		first := queue[0]
+		if first.delivery.NextAttemptAt.After(now) || first.lockedUntil.After(now) {
+			continue
+		}
+		if claimed == nil || first.delivery.NextAttemptAt.Before(claimed.delivery.NextAttemptAt) {
+			claimed = first
+		}
+	}
+	if claimed == nil {
+		return nil, nil, models.ErrNotFound
+	}
+
+	claimed.lockedUntil = lockedUntil
+	subscription := dbWebhook.subscriptions[claimed.delivery.SubscriptionID]
+	delivery := claimed.delivery
+	return &subscription, &delivery, nil
+}
+
+func (dbWebhook *webhookRepository) RetryDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	queued, ok := dbWebhook.findDelivery(delivery.ID)
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	queued.delivery.Attempts = delivery.Attempts
+	queued.delivery.LastError = delivery.LastError
+	queued.delivery.NextAttemptAt = delivery.NextAttemptAt
+	queued.lockedUntil = time.Time{}
+	return nil
+}
+
+func (dbWebhook *webhookRepository) DeleteDelivery(ctx context.Context, id string) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	dbWebhook.removeDelivery(id)
+	return nil
+}
+
+func (dbWebhook *webhookRepository) CreateDeadLetter(ctx context.Context, delivery *models.WebhookDelivery) error {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	queued, ok := dbWebhook.findDelivery(delivery.ID)
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	deadLetters, ok := dbWebhook.deadLetters[queued.delivery.SubscriptionID]
+	if !ok {
+		deadLetters = make(map[string]models.WebhookDelivery)
+		dbWebhook.deadLetters[queued.delivery.SubscriptionID] = deadLetters
+	}
+	if _, ok := deadLetters[delivery.ID]; ok {
+		return models.ErrConflict
+	}
+
+	deadLetter := queued.delivery.WebhookDelivery
+	deadLetter.Attempts = delivery.Attempts
+	deadLetter.LastError = delivery.LastError
+	deadLetter.FailedAt = delivery.FailedAt
+	deadLetters[delivery.ID] = deadLetter
+	dbWebhook.removeDelivery(delivery.ID)
+	return nil
+}
+
+func (dbWebhook *webhookRepository) SelectDeadLetters(ctx context.Context, subscriptionID string) ([]models.WebhookDelivery, error) {
+	dbWebhook.mx.RLock()
+	defer dbWebhook.mx.RUnlock()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, delivery := range dbWebhook.deadLetters[subscriptionID] {
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (dbWebhook *webhookRepository) RedeliverDeadLetter(ctx context.Context, subscriptionID string, id string,
+	now time.Time) (*models.WebhookDelivery, error) {
+	dbWebhook.mx.Lock()
+	defer dbWebhook.mx.Unlock()
+
+	delivery, ok := dbWebhook.deadLetters[subscriptionID][id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	delete(dbWebhook.deadLetters[subscriptionID], id)
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.FailedAt = nil
+	dbWebhook.queues[subscriptionID] = append(dbWebhook.queues[subscriptionID], &queuedDelivery{
+		delivery: models.QueuedWebhookDelivery{WebhookDelivery: delivery, NextAttemptAt: now},
+	})
+	return &delivery, nil
+}
+
+// findDelivery returns the queued delivery, the mutex must be held.
+func (dbWebhook *webhookRepository) findDelivery(id string) (*queuedDelivery, bool) {
+	for _, queue := range dbWebhook.queues {
+		for _, queued := range queue {
+			if queued.delivery.ID == id {
+				return queued, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// removeDelivery removes the delivery from its queue, the mutex must be held.
+func (dbWebhook *webhookRepository) removeDelivery(id string) {
+	for subscriptionID, queue := range dbWebhook.queues {
+		for idx, queued := range queue {
+			if queued.delivery.ID != id {
+				continue
+			}
+			if len(queue) == 1 {
+				delete(dbWebhook.queues, subscriptionID)
+			} else {
+				dbWebhook.queues[subscriptionID] = append(queue[:idx:idx], queue[idx+1:]...)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package in_memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	webhookRep "github.com/kuzkuss/url_service/internal/webhook/repository/in_memory"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestRepositorySubscriptions(t *testing.T) {
+	repository := webhookRep.New()
+
+	owner := models.WebhookScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}
+	subscription := &models.WebhookSubscription{ID: "subscription", OwnerID: owner.OwnerID, WorkspaceID: owner.WorkspaceID,
+		URL: "https://crm.example.com/hooks"}
+	require.NoError(t, repository.CreateSubscription(context.Background(), subscription))
+	require.Equal(t, models.ErrConflict, repository.CreateSubscription(context.Background(), subscription))
+
+	selected, err := repository.SelectSubscription(context.Background(), "subscription", owner)
+	require.NoError(t, err)
+	assert.Equal(t, subscription, selected)
+
+	other := models.WebhookScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "other"}
+	otherWorkspace := models.WebhookScope{WorkspaceID: "other", OwnerID: "owner"}
+
+	_, err = repository.SelectSubscription(context.Background(), "subscription", other)
+	require.Equal(t, models.ErrNotFound, err)
+	_, err = repository.SelectSubscription(context.Background(), "subscription", otherWorkspace)
+	require.Equal(t, models.ErrNotFound, err)
+
+	subscriptions, err := repository.SelectSubscriptions(context.Background(), owner)
+	require.NoError(t, err)
+	assert.Equal(t, []models.WebhookSubscription{*subscription}, subscriptions)
+
+	subscriptions, err = repository.SelectSubscriptions(context.Background(), otherWorkspace)
+	require.NoError(t, err)
+	assert.Empty(t, subscriptions)
+
+	_, err = repository.DeleteSubscription(context.Background(), "subscription", otherWorkspace)
+	require.Equal(t, models.ErrNotFound, err)
+	deleted, err := repository.DeleteSubscription(context.Background(), "subscription", owner)
+	require.NoError(t, err)
+	assert.Equal(t, subscription, deleted)
+	_, err = repository.DeleteSubscription(context.Background(), "subscription", owner)
+	require.Equal(t, models.ErrNotFound, err)
+}
+
+func TestRepositoryAttempts(t *testing.T) {
+	repository := webhookRep.New()
+
+	require.NoError(t, repository.CreateSubscription(context.Background(),
+		&models.WebhookSubscription{ID: "subscription", OwnerID: "owner"}))
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		err := repository.CreateAttempt(context.Background(),
+			&models.WebhookAttempt{SubscriptionID: "subscription", DeliveryID: "delivery", Attempt: attempt}, 2)
+		require.NoError(t, err)
+	}
+
+	attempts, err := repository.SelectAttempts(context.Background(), "subscription")
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, 3, attempts[0].Attempt)
+	assert.Equal(t, 2, attempts[1].Attempt)
+
+	err = repository.CreateAttempt(context.Background(), &models.WebhookAttempt{SubscriptionID: "unknown"}, 2)
+	require.Equal(t, models.ErrNotFound, err)
+}
+
+func TestRepositoryDeliveryQueue(t *testing.T) {
+	repository := webhookRep.New()
+	ctx := context.Background()
+
+	for _, id := range []string{"subscription", "other"} {
+		require.NoError(t, repository.CreateSubscription(ctx, &models.WebhookSubscription{ID: id, OwnerID: "owner"}))
+	}
+
+	now := time.Now()
+	queue := func(id string, subscriptionID string, due time.Time) {
+		require.NoError(t, repository.QueueDelivery(ctx, &models.QueuedWebhookDelivery{
+			WebhookDelivery: models.WebhookDelivery{ID: id, SubscriptionID: subscriptionID, CreatedAt: now},
+			NextAttemptAt: due,
+		}))
+	}
+	queue("first", "subscription", now)
+	queue("second", "subscription", now)
+	queue("later", "other", now.Add(time.Minute))
+	// the delivery queued again is ignored
+	require.NoError(t, repository.QueueDelivery(ctx,
+		&models.QueuedWebhookDelivery{WebhookDelivery: models.WebhookDelivery{ID: "first", SubscriptionID: "subscription"}}))
+	require.Equal(t, models.ErrNotFound, repository.QueueDelivery(ctx,
+		&models.QueuedWebhookDelivery{WebhookDelivery: models.WebhookDelivery{ID: "unknown", SubscriptionID: "unknown"}}))
+
+	subscription, claimed, err := repository.ClaimDelivery(ctx, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "subscription", subscription.ID)
+	assert.Equal(t, "first", claimed.ID)
+
+	// the second delivery waits for the claimed one, the delivery of other subscription is not due
+	_, _, err = repository.ClaimDelivery(ctx, now, now.Add(time.Minute))
+	require.Equal(t, models.ErrNotFound, err)
+
+	claimed.Attempts = 1
+	claimed.LastError = "receiver responded with status 500"
+	claimed.NextAttemptAt = now.Add(time.Second)
+	require.NoError(t, repository.RetryDelivery(ctx, claimed))
+	_, _, err = repository.ClaimDelivery(ctx, now, now.Add(time.Minute))
+	require.Equal(t, models.ErrNotFound, err)
+
+	_, claimed, err = repository.ClaimDelivery(ctx, now.Add(time.Second), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "first", claimed.ID)
+	assert.Equal(t, 1, claimed.Attempts)
+	assert.Equal(t, "receiver responded with status 500", claimed.LastError)
+
+	require.NoError(t, repository.DeleteDelivery(ctx, "first"))
+	_, claimed, err = repository.ClaimDelivery(ctx, now.Add(time.Second), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "second", claimed.ID)
+
+	// the claim expires if the worker does not release the delivery
+	_, claimed, err = repository.ClaimDelivery(ctx, now.Add(2*time.Minute), now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "second", claimed.ID)
+
+	_, err = repository.DeleteSubscription(ctx, "subscription", models.WebhookScope{OwnerID: "owner"})
+	require.NoError(t, err)
+	require.Equal(t, models.ErrNotFound, repository.RetryDelivery(ctx, claimed))
+
+	_, claimed, err = repository.ClaimDelivery(ctx, now.Add(3*time.Minute), now.Add(4*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "later", claimed.ID)
+}
+
+func TestRepositoryFiredThresholds(t *testing.T) {
+	repository := webhookRep.New()
+	ctx := context.Background()
+
+	require.NoError(t, repository.CreateSubscription(ctx, &models.WebhookSubscription{ID: "subscription", OwnerID: "owner"}))
+
+	queue := func(id string, shortLink string) error {
+		return repository.QueueClicksDelivery(ctx, &models.QueuedWebhookDelivery{
+			WebhookDelivery: models.WebhookDelivery{ID: id, SubscriptionID: "subscription", CreatedAt: time.Now()},
+		}, "short.io", shortLink)
+	}
+	require.NoError(t, queue("first", "short_link"))
+	require.Equal(t, models.ErrConflict, queue("second", "short_link"))
+	require.NoError(t, queue("other", "other"))
+
+	// the link is deleted, the threshold fires for the link created again
+	require.NoError(t, repository.DeleteFiredThresholds(ctx, "short.io", "short_link"))
+	require.NoError(t, queue("third", "short_link"))
+
+	var queued []string
+	for {
+		_, claimed, err := repository.ClaimDelivery(ctx, time.Now(), time.Now().Add(time.Minute))
+		if err == models.ErrNotFound {
+			break
+		}
+		require.NoError(t, err)
+		queued = append(queued, claimed.ID)
+		require.NoError(t, repository.DeleteDelivery(ctx, claimed.ID))
+	}
+	assert.Equal(t, []string{"first", "other", "third"}, queued)
+
+	_, err := repository.DeleteSubscription(ctx, "subscription", models.WebhookScope{OwnerID: "owner"})
+	require.NoError(t, err)
+	require.Equal(t, models.ErrNotFound, queue("fourth", "short_link"))
+}
+
+func TestRepositoryDeadLetters(t *testing.T) {
+	repository := webhookRep.New()
+	ctx := context.Background()
+
+	require.NoError(t, repository.CreateSubscription(ctx,
+		&models.WebhookSubscription{ID: "subscription", OwnerID: "owner"}))
+
+	now := time.Now()
+	first := &models.WebhookDelivery{ID: "first", SubscriptionID: "subscription", CreatedAt: now}
+	second := &models.WebhookDelivery{ID: "second", SubscriptionID: "subscription", CreatedAt: now.Add(time.Second)}
+	for _, delivery := range []*models.WebhookDelivery{first, second} {
+		require.NoError(t, repository.QueueDelivery(ctx, &models.QueuedWebhookDelivery{WebhookDelivery: *delivery, NextAttemptAt: now}))
+		delivery.Attempts = 3
+		delivery.LastError = "receiver responded with status 500"
+		delivery.FailedAt = &now
+	}
+
+	require.NoError(t, repository.CreateDeadLetter(ctx, second))
+	require.NoError(t, repository.CreateDeadLetter(ctx, first))
+	require.Equal(t, models.ErrNotFound, repository.CreateDeadLetter(ctx, first))
+
+	_, _, err := repository.ClaimDelivery(ctx, now, now.Add(time.Minute))
+	require.Equal(t, models.ErrNotFound, err)
+
+	deliveries, err := repository.SelectDeadLetters(ctx, "subscription")
+	require.NoError(t, err)
+	assert.Equal(t, []models.WebhookDelivery{*first, *second}, deliveries)
+
+	redelivered, err := repository.RedeliverDeadLetter(ctx, "subscription", "first", now)
+	require.NoError(t, err)
+	assert.Equal(t, "first", redelivered.ID)
+	assert.Zero(t, redelivered.Attempts)
+	assert.Empty(t, redelivered.LastError)
+
+	_, err = repository.RedeliverDeadLetter(ctx, "subscription", "first", now)
+	require.Equal(t, models.ErrNotFound, err)
+
+	_, claimed, err := repository.ClaimDelivery(ctx, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "first", claimed.ID)
+	assert.Zero(t, claimed.Attempts)
+
+	_, err = repository.DeleteSubscription(ctx, "subscription", models.WebhookScope{OwnerID: "owner"})
+	require.NoError(t, err)
+	deliveries, err = repository.SelectDeadLetters(ctx, "subscription")
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/internal/webhook/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "webhooks"
+
+type webhookRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps webhook repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &webhookRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbWebhook *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	start := time.Now()
+	err := dbWebhook.repository.CreateSubscription(ctx, subscription)
+	dbWebhook.metrics.Observe(repositoryName, "CreateSubscription", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) SelectSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	start := time.Now()
+	subscription, err := dbWebhook.repository.SelectSubscription(ctx, id, scope)
+	dbWebhook.metrics.Observe(repositoryName, "SelectSubscription", start, err)
+	return subscription, err
+}
+
+func (dbWebhook *webhookRepository) SelectSubscriptions(ctx context.Context, scope models.WebhookScope) ([]models.WebhookSubscription, error) {
+	start := time.Now()
+	subscriptions, err := dbWebhook.repository.SelectSubscriptions(ctx, scope)
+	dbWebhook.metrics.Observe(repositoryName, "SelectSubscriptions", start, err)
+	return subscriptions, err
+}
+
+func (dbWebhook *webhookRepository) DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	start := time.Now()
+	subscription, err := dbWebhook.repository.DeleteSubscription(ctx, id, scope)
+	dbWebhook.metrics.Observe(repositoryName, "DeleteSubscription", start, err)
+	return subscription, err
+}
+
+func (dbWebhook *webhookRepository) CreateAttempt(ctx context.Context, attempt *models.WebhookAttempt, keep int) error {
+	start := time.Now()
+	err := dbWebhook.repository.CreateAttempt(ctx, attempt, keep)
+	dbWebhook.metrics.Observe(repositoryName, "CreateAttempt", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) SelectAttempts(ctx context.Context, subscriptionID string) ([]models.WebhookAttempt, error) {
+	start := time.Now()
+	attempts, err := dbWebhook.repository.SelectAttempts(ctx, subscriptionID)
+	dbWebhook.metrics.Observe(repositoryName, "SelectAttempts", start, err)
+	return attempts, err
+}
+
+func (dbWebhook *webhookRepository) QueueDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	start := time.Now()
+	err := dbWebhook.repository.QueueDelivery(ctx, delivery)
+	dbWebhook.metrics.Observe(repositoryName, "QueueDelivery", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) QueueClicksDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery,
+	domain string, shortLink string) error {
+	start := time.Now()
+	err := dbWebhook.repository.QueueClicksDelivery(ctx, delivery, domain, shortLink)
+	dbWebhook.metrics.Observe(repositoryName, "QueueClicksDelivery", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) DeleteFiredThresholds(ctx context.Context, domain string, shortLink string) error {
+	start := time.Now()
+	err := dbWebhook.repository.DeleteFiredThresholds(ctx, domain, shortLink)
+	dbWebhook.metrics.Observe(repositoryName, "DeleteFiredThresholds", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) ClaimDelivery(ctx context.Context, now time.Time,
+	lockedUntil time.Time) (*models.WebhookSubscription, *models.QueuedWebhookDelivery, error) {
+	start := time.Now()
+	subscription, delivery, err := dbWebhook.repository.ClaimDelivery(ctx, now, lockedUntil)
+	dbWebhook.metrics.Observe(repositoryName, "ClaimDelivery", start, err)
+	return subscription, delivery, err
+}
+
+func (dbWebhook *webhookRepository) RetryDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	start := time.Now()
+	err := dbWebhook.repository.RetryDelivery(ctx, delivery)
+	dbWebhook.metrics.Observe(repositoryName, "RetryDelivery", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) DeleteDelivery(ctx context.Context, id string) error {
+	start := time.Now()
+	err := dbWebhook.repository.DeleteDelivery(ctx, id)
+	dbWebhook.metrics.Observe(repositoryName, "DeleteDelivery", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) CreateDeadLetter(ctx context.Context, delivery *models.WebhookDelivery) error {
+	start := time.Now()
+	err := dbWebhook.repository.CreateDeadLetter(ctx, delivery)
+	dbWebhook.metrics.Observe(repositoryName, "CreateDeadLetter", start, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) SelectDeadLetters(ctx context.Context, subscriptionID string) ([]models.WebhookDelivery, error) {
+	start := time.Now()
+	deliveries, err := dbWebhook.repository.SelectDeadLetters(ctx, subscriptionID)
+	dbWebhook.metrics.Observe(repositoryName, "SelectDeadLetters", start, err)
+	return deliveries, err
+}
+
+func (dbWebhook *webhookRepository) RedeliverDeadLetter(ctx context.Context, subscriptionID string, id string,
+	now time.Time) (*models.WebhookDelivery, error) {
+	start := time.Now()
+	delivery, err := dbWebhook.repository.RedeliverDeadLetter(ctx, subscriptionID, id, now)
+	dbWebhook.metrics.Observe(repositoryName, "RedeliverDeadLetter", start, err)
+	return delivery, err
+}
@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kuzkuss/url_service/internal/webhook/repository"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+const (
+	trimAttemptsQuery = `DELETE FROM webhook_attempts WHERE subscription_id = ? AND id <= ` +
+		`(SELECT id FROM webhook_attempts WHERE subscription_id = ? ORDER BY id DESC OFFSET ? LIMIT 1)`
+	deleteSubscriptionQuery = `DELETE FROM webhook_subscriptions WHERE id = ? AND workspace_id = ? AND owner_id = ? RETURNING *`
+	// the first due delivery of a subscription which is not claimed, rows claimed by
+	// concurrent workers are skipped
+	claimDeliveryQuery = `UPDATE webhook_deliveries SET locked_until = ? WHERE seq = (` +
+		`SELECT seq FROM webhook_deliveries queued WHERE next_attempt_at <= ? AND (locked_until IS NULL OR locked_until <= ?) ` +
+		`AND NOT EXISTS (SELECT 1 FROM webhook_deliveries earlier WHERE earlier.subscription_id = queued.subscription_id AND earlier.seq < queued.seq) ` +
+		`ORDER BY next_attempt_at, seq LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING *`
+	retryDeliveryQuery = `UPDATE webhook_deliveries SET attempts = ?, last_error = ?, next_attempt_at = ?, locked_until = NULL WHERE id = ?`
+	deadLetterQuery    = `WITH queued AS (DELETE FROM webhook_deliveries WHERE id = ? RETURNING *) ` +
+		`INSERT INTO webhook_dead_letters (id, subscription_id, event, payload, attempts, last_error, created_at, failed_at) ` +
+		`SELECT id, subscription_id, event, payload, ?, ?, created_at, ? FROM queued`
+	redeliverQuery = `WITH dead AS (DELETE FROM webhook_dead_letters WHERE subscription_id = ? AND id = ? RETURNING *) ` +
+		`INSERT INTO webhook_deliveries (id, subscription_id, event, payload, created_at, next_attempt_at) ` +
+		`SELECT id, subscription_id, event, payload, created_at, ? FROM dead ` +
+		`RETURNING id, subscription_id, event, payload, attempts, last_error, created_at`
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (dbWebhook *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	tx := dbWebhook.db.WithContext(ctx).Create(subscription)
+
+	var pgErr *pgconn.PgError
+	if errors.As(tx.Error, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.ErrConflict
+	} else if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table webhook_subscriptions)")
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) SelectSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	subscription := models.WebhookSubscription{}
+
+	tx := dbWebhook.db.WithContext(ctx).Where("id = ? AND workspace_id = ? AND owner_id = ?", id, scope.WorkspaceID, scope.OwnerID).Take(&subscription)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	} else if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table webhook_subscriptions)")
+	}
+
+	return &subscription, nil
+}
+
+func (dbWebhook *webhookRepository) SelectSubscriptions(ctx context.Context, scope models.WebhookScope) ([]models.WebhookSubscription, error) {
+	subscriptions := make([]models.WebhookSubscription, 0)
+
+	tx := dbWebhook.db.WithContext(ctx).Where("workspace_id = ? AND owner_id = ?", scope.WorkspaceID, scope.OwnerID).Order("created_at").Find(&subscriptions)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table webhook_subscriptions)")
+	}
+
+	return subscriptions, nil
+}
+
+func (dbWebhook *webhookRepository) DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	subscriptions := make([]models.WebhookSubscription, 0, 1)
+
+	tx := dbWebhook.db.WithContext(ctx).Raw(deleteSubscriptionQuery, id, scope.WorkspaceID, scope.OwnerID).Scan(&subscriptions)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table webhook_subscriptions)")
+	}
+
+	if len(subscriptions) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return &subscriptions[0], nil
+}
+
+func (dbWebhook *webhookRepository) CreateAttempt(ctx context.Context, attempt *models.WebhookAttempt, keep int) error {
+	err := dbWebhook.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return tx.Exec(trimAttemptsQuery, attempt.SubscriptionID, attempt.SubscriptionID, keep).Error
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return models.ErrNotFound
+	} else if err != nil {
+		return errors.Wrap(err, "database error (table webhook_attempts)")
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) SelectAttempts(ctx context.Context, subscriptionID string) ([]models.WebhookAttempt, error) {
+	attempts := make([]models.WebhookAttempt, 0)
+
+	tx := dbWebhook.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("id DESC").Find(&attempts)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table webhook_attempts)")
+	}
+
+	return attempts, nil
+}
+
+func (dbWebhook *webhookRepository) QueueDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	tx := dbWebhook.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoNothing: true,
+	}).Omit("failed_at").Create(delivery)
+
+	var pgErr *pgconn.PgError
+	if errors.As(tx.Error, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return models.ErrNotFound
+	} else if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table webhook_deliveries)")
+	}
+
+	return nil
+}
+
+// The threshold is recorded first, so of concurrent notifications about clicks
+// on the link only one is queued: the others wait for its commit and find it.
+func (dbWebhook *webhookRepository) QueueClicksDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery,
+	domain string, shortLink string) error {
+	err := dbWebhook.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fired := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.WebhookFiredThreshold{
+			SubscriptionID: delivery.SubscriptionID,
+			Domain:         domain,
+			ShortLink:      shortLink,
+			FiredAt:        delivery.CreatedAt,
+		})
+		if fired.Error != nil {
+			return fired.Error
+		}
+		if fired.RowsAffected == 0 {
+			return models.ErrConflict
+		}
+		return tx.Omit("failed_at").Create(delivery).Error
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.Is(err, models.ErrConflict) || (errors.As(err, &pgErr) && pgErr.Code == uniqueViolation) {
+		return models.ErrConflict
+	} else if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return models.ErrNotFound
+	} else if err != nil {
+		return errors.Wrap(err, "database error (table webhook_deliveries)")
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) DeleteFiredThresholds(ctx context.Context, domain string, shortLink string) error {
+	tx := dbWebhook.db.WithContext(ctx).Where("domain = ? AND short_link = ?", domain, shortLink).
+		Delete(&models.WebhookFiredThreshold{})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table webhook_fired_thresholds)")
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) ClaimDelivery(ctx context.Context, now time.Time,
+	lockedUntil time.Time) (*models.WebhookSubscription, *models.QueuedWebhookDelivery, error) {
+	deliveries := make([]models.QueuedWebhookDelivery, 0, 1)
+	subscription := models.WebhookSubscription{}
+
+	err := dbWebhook.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(claimDeliveryQuery, lockedUntil, now, now).Scan(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		return tx.Where("id = ?", deliveries[0].SubscriptionID).Take(&subscription).Error
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "database error (table webhook_deliveries)")
+	}
+
+	if len(deliveries) == 0 {
+		return nil, nil, models.ErrNotFound
+	}
+
+	return &subscription, &deliveries[0], nil
+}
+
+func (dbWebhook *webhookRepository) RetryDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	tx := dbWebhook.db.WithContext(ctx).Exec(retryDeliveryQuery, delivery.Attempts, delivery.LastError,
+		delivery.NextAttemptAt, delivery.ID)
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table webhook_deliveries)")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) DeleteDelivery(ctx context.Context, id string) error {
+	tx := dbWebhook.db.WithContext(ctx).Where("id = ?", id).Delete(&models.QueuedWebhookDelivery{})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table webhook_deliveries)")
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) CreateDeadLetter(ctx context.Context, delivery *models.WebhookDelivery) error {
+	tx := dbWebhook.db.WithContext(ctx).Exec(deadLetterQuery, delivery.ID, delivery.Attempts, delivery.LastError,
+		delivery.FailedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(tx.Error, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.ErrConflict
+	} else if tx.Error != nil {
+		return errors.Wrap(tx.Error, "database error (table webhook_dead_letters)")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (dbWebhook *webhookRepository) SelectDeadLetters(ctx context.Context, subscriptionID string) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+
+	tx := dbWebhook.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("created_at").Find(&deliveries)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table webhook_dead_letters)")
+	}
+
+	return deliveries, nil
+}
+
+func (dbWebhook *webhookRepository) RedeliverDeadLetter(ctx context.Context, subscriptionID string, id string,
+	now time.Time) (*models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	tx := dbWebhook.db.WithContext(ctx).Raw(redeliverQuery, subscriptionID, id, now).Scan(&deliveries)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table webhook_dead_letters)")
+	}
+
+	if len(deliveries) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return &deliveries[0], nil
+}
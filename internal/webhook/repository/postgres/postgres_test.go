@@ -0,0 +1,424 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pkg/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kuzkuss/url_service/models"
+	webhookRep "github.com/kuzkuss/url_service/internal/webhook/repository/postgres"
+)
+
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	return gdb, mock
+}
+
+func TestRepositoryCreateSubscription(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	subscription := &models.WebhookSubscription {
+		ID: "subscription",
+		OwnerID: "owner",
+		WorkspaceID: models.DefaultWorkspace,
+		URL: "https://crm.example.com/hooks",
+		Secret: "subscription_secret",
+		Events: []string{models.WebhookLinkClicks},
+		ClickThreshold: 1000,
+	}
+
+	query := regexp.QuoteMeta(`INSERT INTO "webhook_subscriptions" ` +
+		`("owner_id","workspace_id","url","secret","events","click_threshold","id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(subscription.OwnerID, subscription.WorkspaceID, subscription.URL, subscription.Secret,
+		`["link.clicks"]`, subscription.ClickThreshold, subscription.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(subscription.ID))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	repository := webhookRep.New(gdb)
+
+	require.NoError(t, repository.CreateSubscription(context.Background(), subscription))
+	require.Equal(t, models.ErrConflict, repository.CreateSubscription(context.Background(), subscription))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryDeleteSubscription(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	columns := []string{"id", "owner_id", "workspace_id", "url", "secret", "events", "click_threshold"}
+
+	query := regexp.QuoteMeta(`DELETE FROM webhook_subscriptions WHERE id = $1 AND workspace_id = $2 AND owner_id = $3 RETURNING *`)
+
+	mock.ExpectQuery(query).WithArgs("subscription", models.DefaultWorkspace, "owner").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("subscription", "owner", models.DefaultWorkspace, "https://crm.example.com/hooks", "subscription_secret", `["link.clicks"]`, 1000))
+	mock.ExpectQuery(query).WithArgs("subscription", "other", "owner").WillReturnRows(sqlmock.NewRows(columns))
+
+	repository := webhookRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		subscription, err := repository.DeleteSubscription(context.Background(), "subscription",
+			models.WebhookScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"})
+		require.NoError(t, err)
+		assert.Equal(t, &models.WebhookSubscription{
+			ID: "subscription",
+			OwnerID: "owner",
+			WorkspaceID: models.DefaultWorkspace,
+			URL: "https://crm.example.com/hooks",
+			Secret: "subscription_secret",
+			Events: []string{models.WebhookLinkClicks},
+			ClickThreshold: 1000,
+		}, subscription)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := repository.DeleteSubscription(context.Background(), "subscription",
+			models.WebhookScope{WorkspaceID: "other", OwnerID: "owner"})
+		require.Equal(t, models.ErrNotFound, err)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryCreateAttempt(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	attempt := &models.WebhookAttempt {
+		SubscriptionID: "subscription",
+		DeliveryID: "delivery",
+		Event: models.WebhookLinkCreated,
+		Attempt: 1,
+		Delivered: true,
+		StatusCode: 200,
+		DurationMS: 15,
+		Time: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	insertQuery := regexp.QuoteMeta(`INSERT INTO "webhook_attempts" ` +
+		`("subscription_id","delivery_id","event","attempt","delivered","status_code","error","duration_ms","attempted_at") ` +
+		`VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`)
+	trimQuery := regexp.QuoteMeta(`DELETE FROM webhook_attempts WHERE subscription_id = $1 AND id <= ` +
+		`(SELECT id FROM webhook_attempts WHERE subscription_id = $2 ORDER BY id DESC OFFSET $3 LIMIT 1)`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertQuery).WithArgs(attempt.SubscriptionID, attempt.DeliveryID, attempt.Event, attempt.Attempt,
+		attempt.Delivered, attempt.StatusCode, attempt.Error, attempt.DurationMS, attempt.Time).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(trimQuery).WithArgs(attempt.SubscriptionID, attempt.SubscriptionID, 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertQuery).WillReturnError(&pgconn.PgError{Code: "23503"})
+	mock.ExpectRollback()
+
+	createErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectExec(trimQuery).WillReturnError(createErr)
+	mock.ExpectRollback()
+
+	repository := webhookRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, repository.CreateAttempt(context.Background(), attempt, 100))
+		assert.Equal(t, int64(7), attempt.ID)
+	})
+
+	t.Run("subscription_deleted", func(t *testing.T) {
+		err := repository.CreateAttempt(context.Background(), &models.WebhookAttempt{SubscriptionID: "deleted"}, 100)
+		require.Equal(t, models.ErrNotFound, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := repository.CreateAttempt(context.Background(), &models.WebhookAttempt{SubscriptionID: "subscription"}, 100)
+		require.Equal(t, createErr, errors.Cause(err))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryQueueDelivery(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	createdAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	delivery := &models.QueuedWebhookDelivery {
+		WebhookDelivery: models.WebhookDelivery {
+			ID: "delivery",
+			SubscriptionID: "subscription",
+			Event: models.WebhookLinkCreated,
+			Payload: []byte(`{"id":"delivery"}`),
+			CreatedAt: createdAt,
+		},
+		NextAttemptAt: createdAt,
+	}
+
+	query := regexp.QuoteMeta(`INSERT INTO "webhook_deliveries" ` +
+		`("subscription_id","event","payload","attempts","last_error","created_at","next_attempt_at","id") ` +
+		`VALUES ($1,$2,$3,$4,$5,$6,$7,$8) ON CONFLICT ("id") DO NOTHING RETURNING "id"`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(delivery.SubscriptionID, delivery.Event, delivery.Payload,
+		0, "", createdAt, createdAt, delivery.ID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(delivery.ID))
+	mock.ExpectCommit()
+
+	// the delivery queued again is ignored
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WillReturnError(&pgconn.PgError{Code: "23503"})
+	mock.ExpectRollback()
+
+	repository := webhookRep.New(gdb)
+
+	require.NoError(t, repository.QueueDelivery(context.Background(), delivery))
+	require.NoError(t, repository.QueueDelivery(context.Background(), delivery))
+	require.Equal(t, models.ErrNotFound, repository.QueueDelivery(context.Background(), delivery))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryQueueClicksDelivery(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	createdAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	delivery := &models.QueuedWebhookDelivery {
+		WebhookDelivery: models.WebhookDelivery {
+			ID: "delivery",
+			SubscriptionID: "subscription",
+			Event: models.WebhookLinkClicks,
+			Payload: []byte(`{"id":"delivery"}`),
+			CreatedAt: createdAt,
+		},
+		NextAttemptAt: createdAt,
+	}
+
+	firedQuery := regexp.QuoteMeta(`INSERT INTO "webhook_fired_thresholds" ("subscription_id","domain","short_link","fired_at") ` +
+		`VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`)
+	queueQuery := regexp.QuoteMeta(`INSERT INTO "webhook_deliveries" ` +
+		`("subscription_id","event","payload","attempts","last_error","created_at","next_attempt_at","id") ` +
+		`VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(firedQuery).WithArgs(delivery.SubscriptionID, "short.io", "short_link", createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(queueQuery).WithArgs(delivery.SubscriptionID, delivery.Event, delivery.Payload,
+		0, "", createdAt, createdAt, delivery.ID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(delivery.ID))
+	mock.ExpectCommit()
+
+	// the threshold already fired for the link
+	mock.ExpectBegin()
+	mock.ExpectExec(firedQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(firedQuery).WillReturnError(&pgconn.PgError{Code: "23503"})
+	mock.ExpectRollback()
+
+	repository := webhookRep.New(gdb)
+
+	require.NoError(t, repository.QueueClicksDelivery(context.Background(), delivery, "short.io", "short_link"))
+	require.Equal(t, models.ErrConflict, repository.QueueClicksDelivery(context.Background(), delivery, "short.io", "short_link"))
+	require.Equal(t, models.ErrNotFound, repository.QueueClicksDelivery(context.Background(), delivery, "short.io", "short_link"))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryDeleteFiredThresholds(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	query := regexp.QuoteMeta(`DELETE FROM "webhook_fired_thresholds" WHERE domain = $1 AND short_link = $2`)
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs("short.io", "short_link").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repository := webhookRep.New(gdb)
+
+	require.NoError(t, repository.DeleteFiredThresholds(context.Background(), "short.io", "short_link"))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryClaimDelivery(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	now := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(time.Minute)
+	columns := []string{"seq", "id", "subscription_id", "event", "payload", "attempts", "last_error",
+		"created_at", "next_attempt_at", "locked_until"}
+
+	claimQuery := regexp.QuoteMeta(`UPDATE webhook_deliveries SET locked_until = $1 WHERE seq = (` +
+		`SELECT seq FROM webhook_deliveries queued WHERE next_attempt_at <= $2 AND (locked_until IS NULL OR locked_until <= $3) ` +
+		`AND NOT EXISTS (SELECT 1 FROM webhook_deliveries earlier WHERE earlier.subscription_id = queued.subscription_id AND earlier.seq < queued.seq) ` +
+		`ORDER BY next_attempt_at, seq LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING *`)
+	subscriptionQuery := regexp.QuoteMeta(`SELECT * FROM "webhook_subscriptions" WHERE id = $1 LIMIT 1`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimQuery).WithArgs(lockedUntil, now, now).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(3, "delivery", "subscription", models.WebhookLinkCreated, []byte(`{"id":"delivery"}`), 1,
+			"receiver responded with status 500", now, now, lockedUntil))
+	mock.ExpectQuery(subscriptionQuery).WithArgs("subscription").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret"}).
+			AddRow("subscription", "https://crm.example.com/hooks", "subscription_secret"))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(claimQuery).WithArgs(lockedUntil, now, now).WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectCommit()
+
+	repository := webhookRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		subscription, delivery, err := repository.ClaimDelivery(context.Background(), now, lockedUntil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://crm.example.com/hooks", subscription.URL)
+		assert.Equal(t, "subscription_secret", subscription.Secret)
+		assert.Equal(t, "delivery", delivery.ID)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, now, delivery.NextAttemptAt)
+	})
+
+	t.Run("not_due", func(t *testing.T) {
+		_, _, err := repository.ClaimDelivery(context.Background(), now, lockedUntil)
+		require.Equal(t, models.ErrNotFound, err)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryRetryDelivery(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	nextAttemptAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	delivery := &models.QueuedWebhookDelivery {
+		WebhookDelivery: models.WebhookDelivery{ID: "delivery", Attempts: 2, LastError: "receiver responded with status 500"},
+		NextAttemptAt: nextAttemptAt,
+	}
+
+	query := regexp.QuoteMeta(`UPDATE webhook_deliveries SET attempts = $1, last_error = $2, next_attempt_at = $3, ` +
+		`locked_until = NULL WHERE id = $4`)
+
+	mock.ExpectExec(query).WithArgs(2, "receiver responded with status 500", nextAttemptAt, "delivery").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repository := webhookRep.New(gdb)
+
+	require.NoError(t, repository.RetryDelivery(context.Background(), delivery))
+	require.Equal(t, models.ErrNotFound, repository.RetryDelivery(context.Background(), delivery))
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryCreateDeadLetter(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	failedAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	delivery := &models.WebhookDelivery {
+		ID: "delivery",
+		SubscriptionID: "subscription",
+		Attempts: 8,
+		LastError: "receiver responded with status 500",
+		FailedAt: &failedAt,
+	}
+
+	query := regexp.QuoteMeta(`WITH queued AS (DELETE FROM webhook_deliveries WHERE id = $1 RETURNING *) ` +
+		`INSERT INTO webhook_dead_letters (id, subscription_id, event, payload, attempts, last_error, created_at, failed_at) ` +
+		`SELECT id, subscription_id, event, payload, $2, $3, created_at, $4 FROM queued`)
+
+	mock.ExpectExec(query).WithArgs("delivery", 8, "receiver responded with status 500", &failedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repository := webhookRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, repository.CreateDeadLetter(context.Background(), delivery))
+	})
+
+	t.Run("not_queued", func(t *testing.T) {
+		require.Equal(t, models.ErrNotFound, repository.CreateDeadLetter(context.Background(), delivery))
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestRepositoryRedeliverDeadLetter(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	createdAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	now := createdAt.Add(time.Hour)
+	columns := []string{"id", "subscription_id", "event", "payload", "attempts", "last_error", "created_at"}
+
+	query := regexp.QuoteMeta(`WITH dead AS (DELETE FROM webhook_dead_letters WHERE subscription_id = $1 AND id = $2 RETURNING *) ` +
+		`INSERT INTO webhook_deliveries (id, subscription_id, event, payload, created_at, next_attempt_at) ` +
+		`SELECT id, subscription_id, event, payload, created_at, $3 FROM dead ` +
+		`RETURNING id, subscription_id, event, payload, attempts, last_error, created_at`)
+
+	mock.ExpectQuery(query).WithArgs("subscription", "delivery", now).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("delivery", "subscription", models.WebhookLinkCreated, []byte(`{"id":"delivery"}`), 0, "", createdAt))
+	mock.ExpectQuery(query).WithArgs("subscription", "unknown", now).WillReturnRows(sqlmock.NewRows(columns))
+
+	repository := webhookRep.New(gdb)
+
+	t.Run("success", func(t *testing.T) {
+		delivery, err := repository.RedeliverDeadLetter(context.Background(), "subscription", "delivery", now)
+		require.NoError(t, err)
+		assert.Equal(t, "delivery", delivery.ID)
+		assert.Zero(t, delivery.Attempts)
+		assert.JSONEq(t, `{"id":"delivery"}`, string(delivery.Payload))
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := repository.RedeliverDeadLetter(context.Background(), "subscription", "unknown", now)
+		require.Equal(t, models.ErrNotFound, err)
+	})
+
+	err := mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
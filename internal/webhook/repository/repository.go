@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+type RepositoryI interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) (error)
+	SelectSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error)
+	SelectSubscriptions(ctx context.Context, scope models.WebhookScope) ([]models.WebhookSubscription, error)
+	// DeleteSubscription removes the subscription with its delivery log and dead letters
+	// and returns it.
+	DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error)
+	// CreateAttempt adds attempt to the delivery log of the subscription,
+	// keeping only the last keep attempts.
+	CreateAttempt(ctx context.Context, attempt *models.WebhookAttempt, keep int) (error)
+	// SelectAttempts returns the delivery log of the subscription, the latest attempts first.
+	SelectAttempts(ctx context.Context, subscriptionID string) ([]models.WebhookAttempt, error)
+	// QueueDelivery appends the delivery to the queue of its subscription. The delivery
+	// is ignored if a delivery with its id is already queued. It returns
+	// models.ErrNotFound if the subscription is deleted.
+	QueueDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) (error)
+	// QueueClicksDelivery queues the delivery about clicks on the link and records that
+	// the click threshold of the subscription fired for the link. It returns models.ErrConflict
+	// if the threshold already fired for the link and models.ErrNotFound if the subscription
+	// is deleted.
+	QueueClicksDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery, domain string, shortLink string) (error)
+	// DeleteFiredThresholds forgets click thresholds fired for the deleted link, so
+	// a link created later with the same short link is notified about clicks again.
+	DeleteFiredThresholds(ctx context.Context, domain string, shortLink string) (error)
+	// ClaimDelivery returns the first delivery in the queue of a subscription which is due
+	// at now and is not claimed by another worker, together with the subscription, and
+	// claims it until lockedUntil. Later deliveries of the subscription are not returned
+	// until the first one leaves the queue. It returns models.ErrNotFound if no delivery is due.
+	ClaimDelivery(ctx context.Context, now time.Time, lockedUntil time.Time) (*models.WebhookSubscription, *models.QueuedWebhookDelivery, error)
+	// RetryDelivery stores attempts of the claimed delivery and releases it until its next attempt.
+	RetryDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) (error)
+	// DeleteDelivery removes the delivered delivery from the queue.
+	DeleteDelivery(ctx context.Context, id string) (error)
+	// CreateDeadLetter moves the delivery from the queue to dead letters. It returns
+	// models.ErrNotFound if the delivery is not queued, e.g. its subscription is deleted.
+	CreateDeadLetter(ctx context.Context, delivery *models.WebhookDelivery) (error)
+	SelectDeadLetters(ctx context.Context, subscriptionID string) ([]models.WebhookDelivery, error)
+	// RedeliverDeadLetter moves the dead letter to the end of the queue of the subscription
+	// with no attempts made, due at now, and returns it.
+	RedeliverDeadLetter(ctx context.Context, subscriptionID string, id string, now time.Time) (*models.WebhookDelivery, error)
+}
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/internal/webhook/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "webhooks"
+
+type webhookRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps webhook repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &webhookRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbWebhook *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "CreateSubscription")
+	err := dbWebhook.repository.CreateSubscription(ctx, subscription)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) SelectSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "SelectSubscription")
+	subscription, err := dbWebhook.repository.SelectSubscription(ctx, id, scope)
+	observability.EndSpan(span, err)
+	return subscription, err
+}
+
+func (dbWebhook *webhookRepository) SelectSubscriptions(ctx context.Context, scope models.WebhookScope) ([]models.WebhookSubscription, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "SelectSubscriptions")
+	subscriptions, err := dbWebhook.repository.SelectSubscriptions(ctx, scope)
+	observability.EndSpan(span, err)
+	return subscriptions, err
+}
+
+func (dbWebhook *webhookRepository) DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (*models.WebhookSubscription, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "DeleteSubscription")
+	subscription, err := dbWebhook.repository.DeleteSubscription(ctx, id, scope)
+	observability.EndSpan(span, err)
+	return subscription, err
+}
+
+func (dbWebhook *webhookRepository) CreateAttempt(ctx context.Context, attempt *models.WebhookAttempt, keep int) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "CreateAttempt")
+	err := dbWebhook.repository.CreateAttempt(ctx, attempt, keep)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) SelectAttempts(ctx context.Context, subscriptionID string) ([]models.WebhookAttempt, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "SelectAttempts")
+	attempts, err := dbWebhook.repository.SelectAttempts(ctx, subscriptionID)
+	observability.EndSpan(span, err)
+	return attempts, err
+}
+
+func (dbWebhook *webhookRepository) QueueDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "QueueDelivery")
+	err := dbWebhook.repository.QueueDelivery(ctx, delivery)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) QueueClicksDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery,
+	domain string, shortLink string) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "QueueClicksDelivery")
+	err := dbWebhook.repository.QueueClicksDelivery(ctx, delivery, domain, shortLink)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) DeleteFiredThresholds(ctx context.Context, domain string, shortLink string) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "DeleteFiredThresholds")
+	err := dbWebhook.repository.DeleteFiredThresholds(ctx, domain, shortLink)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) ClaimDelivery(ctx context.Context, now time.Time,
+	lockedUntil time.Time) (*models.WebhookSubscription, *models.QueuedWebhookDelivery, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "ClaimDelivery")
+	subscription, delivery, err := dbWebhook.repository.ClaimDelivery(ctx, now, lockedUntil)
+	observability.EndSpan(span, err)
+	return subscription, delivery, err
+}
+
+func (dbWebhook *webhookRepository) RetryDelivery(ctx context.Context, delivery *models.QueuedWebhookDelivery) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "RetryDelivery")
+	err := dbWebhook.repository.RetryDelivery(ctx, delivery)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) DeleteDelivery(ctx context.Context, id string) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "DeleteDelivery")
+	err := dbWebhook.repository.DeleteDelivery(ctx, id)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) CreateDeadLetter(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "CreateDeadLetter")
+	err := dbWebhook.repository.CreateDeadLetter(ctx, delivery)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWebhook *webhookRepository) SelectDeadLetters(ctx context.Context, subscriptionID string) ([]models.WebhookDelivery, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "SelectDeadLetters")
+	deliveries, err := dbWebhook.repository.SelectDeadLetters(ctx, subscriptionID)
+	observability.EndSpan(span, err)
+	return deliveries, err
+}
+
+func (dbWebhook *webhookRepository) RedeliverDeadLetter(ctx context.Context, subscriptionID string, id string,
+	now time.Time) (*models.WebhookDelivery, error) {
+	ctx, span := dbWebhook.tracing.Start(ctx, repositoryName, "RedeliverDeadLetter")
+	delivery, err := dbWebhook.repository.RedeliverDeadLetter(ctx, subscriptionID, id, now)
+	observability.EndSpan(span, err)
+	return delivery, err
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/config"
+)
+
+// errBlockedAddress is the error of the attempt to send notification to an address
+// notifications are not sent to.
+var errBlockedAddress = errors.New("webhook address is not allowed")
+
+// metadataHosts are names of cloud metadata endpoints, their addresses are link-local
+// except for the IPv6 endpoint of AWS.
+var (
+	metadataHosts   = map[string]bool{"metadata.google.internal": true, "metadata.goog": true}
+	metadataAddress = netip.MustParseAddr("fd00:ec2::254")
+)
+
+// targetPolicy tells addresses notifications are sent to.
+type targetPolicy struct {
+	allowLoopback bool
+	// private networks notifications are sent to
+	allowedPrivate []netip.Prefix
+}
+
+// newTargetPolicy returns policy set by conf. Allowed private networks are checked
+// by config validation, invalid ones are ignored.
+func newTargetPolicy(conf config.WebhooksConfig) targetPolicy {
+	policy := targetPolicy{allowLoopback: conf.AllowLoopback}
+	for _, network := range conf.AllowedPrivateNetworks {
+		if prefix, err := netip.ParsePrefix(network); err == nil {
+			policy.allowedPrivate = append(policy.allowedPrivate, prefix.Masked())
+		}
+	}
+	return policy
+}
+
+// blockedAddress reports whether notifications are not sent to addr: loopback
+// unless allowLoopback is set, private ones outside of allowed private networks,
+// link-local, multicast, unspecified and metadata ones.
+func (p targetPolicy) blockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback():
+		return !p.allowLoopback
+	case addr == metadataAddress:
+		return true
+	case addr.IsPrivate():
+		for _, prefix := range p.allowedPrivate {
+			if prefix.Contains(addr) {
+				return false
+			}
+		}
+		return true
+	}
+	return addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified()
+}
+
+// blockedHost reports whether host of the subscription URL is known to be an address
+// notifications are not sent to. Other names are checked when connecting.
+func (p targetPolicy) blockedHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.blockedAddress(addr)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return !p.allowLoopback
+	}
+	return metadataHosts[host]
+}
+
+// newClient returns client sending notifications only to allowed addresses: the address
+// is checked after the name is resolved, so names resolved to blocked addresses are
+// rejected too. Proxies are not used, they would connect to the address instead.
+func newClient(timeout time.Duration, policy targetPolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return errors.Wrap(err, "webhook address parsing error")
+			}
+			if policy.blockedAddress(addrPort.Addr()) {
+				return errors.Wrap(errBlockedAddress, addrPort.Addr().String())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// redirects are not followed, the receiver must respond itself
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,599 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/config"
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	"github.com/kuzkuss/url_service/internal/observability"
+	webhookRep "github.com/kuzkuss/url_service/internal/webhook/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const (
+	HeaderWebhookID        = "X-Webhook-ID"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+	userAgent       = "url_service-webhooks"
+	idLength        = 16
+	secretLength    = 32
+	// response bodies are read up to the limit only to reuse connections
+	responseBodyLimit = 64 << 10
+	// a claimed delivery is kept by the worker for the request timeout and the time
+	// to store its result, then it is attempted by another worker
+	claimMargin = 30 * time.Second
+)
+
+var linkEventTypes = map[models.LinkEventType]string{
+	models.LinkCreated: models.WebhookLinkCreated,
+	models.LinkUpdated: models.WebhookLinkUpdated,
+	models.LinkDeleted: models.WebhookLinkDeleted,
+}
+
+type UseCaseI interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) (error)
+	GetSubscriptions(ctx context.Context, scope models.WebhookScope) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (error)
+	GetDeliveryLog(ctx context.Context, id string, scope models.WebhookScope) ([]models.WebhookAttempt, error)
+	GetDeadLetters(ctx context.Context, id string, scope models.WebhookScope) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id string, scope models.WebhookScope, deliveryID string) (error)
+	// LinkChanged stores notifications about the event in the delivery queues of
+	// subscriptions of the owner of the link in its workspace.
+	LinkChanged(ctx context.Context, event models.LinkEvent) (error)
+	// LinkClicked notifies subscriptions whose click threshold is reached by the link.
+	LinkClicked(ctx context.Context, link models.Link)
+	// Run notifies subscriptions about clicks and delivers queued notifications until ctx is done.
+	Run(ctx context.Context)
+}
+
+type useCase struct {
+	webhookRepository webhookRep.RepositoryI
+	auditUC           auditUsecase.UseCaseI
+	conf              config.WebhooksConfig
+	targets           targetPolicy
+	client            *http.Client
+	clicks            chan models.Link
+	// wakes a worker waiting for the next poll when a delivery is queued
+	queued            chan struct{}
+	logger            *slog.Logger
+}
+
+// New creates webhook usecase notifying subscriptions about link events passed
+// to LinkChanged and about clicks reported by LinkClicked. Changes of subscriptions
+// are recorded in the audit log by auditUC unless it is nil.
+func New(webhookRepository webhookRep.RepositoryI, auditUC auditUsecase.UseCaseI, conf config.WebhooksConfig,
+	logger *slog.Logger) UseCaseI {
+	targets := newTargetPolicy(conf)
+	return &useCase{
+		webhookRepository: webhookRepository,
+		auditUC:           auditUC,
+		conf:              conf,
+		targets:           targets,
+		client:            newClient(conf.Timeout, targets),
+		clicks: make(chan models.Link, conf.QueueSize),
+		queued: make(chan struct{}, 1),
+		logger: logger,
+	}
+}
+
+// Sign returns signature of the notification body sent at timestamp (unix seconds):
+// "sha256=" followed by hex encoded HMAC-SHA256 of "<timestamp>.<body>" keyed by secret.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateSubscription stores subscription with generated id. Secret is generated
+// unless it is given.
+func (uc *useCase) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) (err error) {
+	ctx, span := observability.StartSpan(ctx, "webhook.usecase.CreateSubscription")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if err := checkSubscription(subscription, uc.targets); err != nil {
+		return err
+	}
+
+	subscription.ID, err = randomString(idLength, hex.EncodeToString)
+	if err != nil {
+		return errors.Wrap(err, "generation subscription id error")
+	}
+	if subscription.Secret == "" {
+		subscription.Secret, err = randomString(secretLength, base64.RawURLEncoding.EncodeToString)
+		if err != nil {
+			return errors.Wrap(err, "generation secret error")
+		}
+	}
+
+	err = uc.webhookRepository.CreateSubscription(ctx, subscription)
+	if err != nil {
+		return errors.Wrap(err, "webhook repository error")
+	}
+
+	uc.audit(ctx, models.AuditWebhookCreate, subscription.ID, nil, withoutSecret(subscription))
+	uc.logger.InfoContext(ctx, "webhook subscription created", "subscription_id", subscription.ID)
+	return nil
+}
+
+// GetSubscriptions returns subscriptions of the owner without their secrets.
+func (uc *useCase) GetSubscriptions(ctx context.Context, scope models.WebhookScope) (_ []models.WebhookSubscription, err error) {
+	ctx, span := observability.StartSpan(ctx, "webhook.usecase.GetSubscriptions")
+	defer func() { observability.EndSpan(span, err) }()
+
+	subscriptions, err := uc.webhookRepository.SelectSubscriptions(ctx, scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook repository error")
+	}
+
+	for idx := range subscriptions {
+		subscriptions[idx].Secret = ""
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteSubscription deletes subscription with its delivery log and dead letters.
+// Its deliveries waiting for retry are dropped.
+func (uc *useCase) DeleteSubscription(ctx context.Context, id string, scope models.WebhookScope) (err error) {
+	ctx, span := observability.StartSpan(ctx, "webhook.usecase.DeleteSubscription")
+	defer func() { observability.EndSpan(span, err) }()
+
+	deleted, err := uc.webhookRepository.DeleteSubscription(ctx, id, scope)
+	if err != nil {
+		return errors.Wrap(err, "webhook repository error")
+	}
+
+	uc.audit(ctx, models.AuditWebhookDelete, id, withoutSecret(deleted), nil)
+	uc.logger.InfoContext(ctx, "webhook subscription deleted", "subscription_id", id)
+	return nil
+}
+
+func (uc *useCase) GetDeliveryLog(ctx context.Context, id string, scope models.WebhookScope) (_ []models.WebhookAttempt, err error) {
+	ctx, span := observability.StartSpan(ctx, "webhook.usecase.GetDeliveryLog")
+	defer func() { observability.EndSpan(span, err) }()
+
+	_, err = uc.webhookRepository.SelectSubscription(ctx, id, scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook repository error")
+	}
+
+	attempts, err := uc.webhookRepository.SelectAttempts(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook repository error")
+	}
+
+	return attempts, nil
+}
+
+func (uc *useCase) GetDeadLetters(ctx context.Context, id string, scope models.WebhookScope) (_ []models.WebhookDelivery, err error) {
+	ctx, span := observability.StartSpan(ctx, "webhook.usecase.GetDeadLetters")
+	defer func() { observability.EndSpan(span, err) }()
+
+	_, err = uc.webhookRepository.SelectSubscription(ctx, id, scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook repository error")
+	}
+
+	deliveries, err := uc.webhookRepository.SelectDeadLetters(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook repository error")
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver moves the dead letter back to the end of the delivery queue, it is sent
+// again with the same id and the full number of attempts.
+func (uc *useCase) Redeliver(ctx context.Context, id string, scope models.WebhookScope, deliveryID string) (err error) {
+	ctx, span := observability.StartSpan(ctx, "webhook.usecase.Redeliver")
+	defer func() { observability.EndSpan(span, err) }()
+
+	_, err = uc.webhookRepository.SelectSubscription(ctx, id, scope)
+	if err != nil {
+		return errors.Wrap(err, "webhook repository error")
+	}
+
+	delivery, err := uc.webhookRepository.RedeliverDeadLetter(ctx, id, deliveryID, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "webhook repository error")
+	}
+	uc.wake()
+
+	uc.audit(ctx, models.AuditWebhookRedeliver, id, delivery, nil)
+	uc.logger.InfoContext(ctx, "webhook notification redelivered", "subscription_id", id, "delivery_id", deliveryID)
+	return nil
+}
+
+// LinkChanged is a sink of the link events outbox. The event is acknowledged once
+// its notifications are stored in the delivery queues, so it is relayed again if
+// they can not be stored.
+func (uc *useCase) LinkChanged(ctx context.Context, event models.LinkEvent) error {
+	eventType, ok := linkEventTypes[event.Type]
+	if !ok {
+		return nil
+	}
+
+	if event.Type == models.LinkDeleted {
+		err := uc.webhookRepository.DeleteFiredThresholds(ctx, event.Link.Domain, event.Link.ShortLink)
+		if err != nil {
+			return errors.Wrap(err, "webhook repository error")
+		}
+	}
+
+	return uc.notify(ctx, eventType, event.Link, nil, func(subscription models.WebhookSubscription) (string, error) {
+		return linkEventDeliveryID(event.OutboxID, subscription.ID)
+	})
+}
+
+// LinkClicked passes the click to Run without waiting, the click is not
+// checked against thresholds if too many clicks are waiting.
+func (uc *useCase) LinkClicked(ctx context.Context, link models.Link) {
+	select {
+	case uc.clicks <- link:
+	default:
+		uc.logger.WarnContext(ctx, "webhook click is dropped, too many clicks are waiting", "short_link", link.ShortLink)
+	}
+}
+
+// Run starts workers delivering notifications and watches clicks. Notifications
+// which are not delivered yet stay in the queues and are sent after restart.
+func (uc *useCase) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < uc.conf.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uc.work(ctx)
+		}()
+	}
+
+	uc.watchClicks(ctx)
+	wg.Wait()
+}
+
+// watchClicks notifies subscriptions about clicks until ctx is done. A subscription
+// is notified once about a link, by the first click checked after its threshold is
+// reached, so clicks dropped or counted by other instances do not skip it.
+func (uc *useCase) watchClicks(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case link := <-uc.clicks:
+			err := uc.notify(ctx, models.WebhookLinkClicks, link, func(subscription models.WebhookSubscription) bool {
+				return subscription.ClickThreshold > 0 && link.Clicks >= subscription.ClickThreshold
+			}, randomDeliveryID)
+			if err != nil {
+				uc.logger.Error("webhook notification about clicks failed", "short_link", link.ShortLink, "error", err)
+			}
+		}
+	}
+}
+
+// notify queues notification about event of the link for subscriptions of its
+// owner in its workspace accepting the event and, unless filter is nil, passing filter.
+// Notifications are identified by deliveryID.
+func (uc *useCase) notify(ctx context.Context, event string, link models.Link,
+	filter func(models.WebhookSubscription) bool,
+	deliveryID func(models.WebhookSubscription) (string, error)) error {
+	// links created by administrator have no owner to subscribe
+	if link.OwnerID == "" {
+		return nil
+	}
+
+	subscriptions, err := uc.webhookRepository.SelectSubscriptions(ctx, models.WebhookScope{
+		WorkspaceID: link.WorkspaceID,
+		OwnerID:     link.OwnerID,
+	})
+	if err != nil {
+		return errors.Wrap(err, "webhook repository error")
+	}
+
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		if !subscription.Accepts(event) || (filter != nil && !filter(subscription)) {
+			continue
+		}
+
+		id, err := deliveryID(subscription)
+		if err != nil {
+			return errors.Wrap(err, "generation delivery id error")
+		}
+		payload, err := json.Marshal(models.WebhookNotification{
+			ID:    id,
+			Event: event,
+			Time:  now,
+			Link:  link,
+		})
+		if err != nil {
+			return errors.Wrap(err, "webhook notification encoding error")
+		}
+
+		delivery := &models.QueuedWebhookDelivery{
+			WebhookDelivery: models.WebhookDelivery{
+				ID:             id,
+				SubscriptionID: subscription.ID,
+				Event:          event,
+				Payload:        payload,
+				CreatedAt:      now,
+			},
+			NextAttemptAt: now,
+		}
+		if event == models.WebhookLinkClicks {
+			err = uc.webhookRepository.QueueClicksDelivery(ctx, delivery, link.Domain, link.ShortLink)
+		} else {
+			err = uc.webhookRepository.QueueDelivery(ctx, delivery)
+		}
+		// the subscription is deleted meanwhile or its threshold already fired for the link
+		if errors.Is(err, models.ErrNotFound) || (event == models.WebhookLinkClicks && errors.Is(err, models.ErrConflict)) {
+			continue
+		} else if err != nil {
+			return errors.Wrap(err, "webhook repository error")
+		}
+		uc.wake()
+	}
+
+	return nil
+}
+
+// wake makes a waiting worker look for queued deliveries without waiting for the next poll.
+func (uc *useCase) wake() {
+	select {
+	case uc.queued <- struct{}{}:
+	default:
+	}
+}
+
+// work delivers due notifications, looking for them every poll interval and
+// when they are queued.
+func (uc *useCase) work(ctx context.Context) {
+	ticker := time.NewTicker(uc.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && uc.deliverNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-uc.queued:
+		}
+	}
+}
+
+// deliverNext makes the next attempt of a due delivery, it returns false if no
+// delivery is due.
+func (uc *useCase) deliverNext(ctx context.Context) bool {
+	now := time.Now()
+	subscription, delivery, err := uc.webhookRepository.ClaimDelivery(ctx, now, now.Add(uc.conf.Timeout+claimMargin))
+	if errors.Is(err, models.ErrNotFound) {
+		return false
+	} else if err != nil {
+		if ctx.Err() == nil {
+			uc.logger.Error("webhook delivery queue error", "error", err)
+		}
+		return false
+	}
+
+	uc.deliver(ctx, subscription, delivery)
+	return true
+}
+
+// deliver makes the next attempt to send the claimed notification. It is removed
+// from the queue when it is delivered or moved to dead letters, otherwise it is
+// released until the retry. Results are stored even if ctx is done while the request is sent.
+func (uc *useCase) deliver(ctx context.Context, subscription *models.WebhookSubscription,
+	queued *models.QueuedWebhookDelivery) {
+	queued.Attempts++
+	attempt := uc.send(ctx, subscription, &queued.WebhookDelivery)
+
+	storeCtx := context.WithoutCancel(ctx)
+	if !attempt.Delivered && ctx.Err() != nil {
+		// the attempt interrupted by shutdown is made again after restart
+		queued.Attempts--
+		queued.NextAttemptAt = time.Now()
+		uc.retry(storeCtx, queued)
+		return
+	}
+
+	err := uc.webhookRepository.CreateAttempt(storeCtx, &attempt, uc.conf.DeliveryLogSize)
+	if errors.Is(err, models.ErrNotFound) {
+		uc.logger.Info("webhook subscription is deleted, notification is dropped",
+			"subscription_id", subscription.ID, "delivery_id", queued.ID)
+		return
+	} else if err != nil {
+		uc.logger.Error("webhook delivery log error", "subscription_id", subscription.ID, "error", err)
+	}
+
+	if attempt.Delivered {
+		if err := uc.webhookRepository.DeleteDelivery(storeCtx, queued.ID); err != nil {
+			uc.logger.Error("delivered webhook notification is not removed from the queue, it is sent again",
+				"subscription_id", subscription.ID, "delivery_id", queued.ID, "error", err)
+			return
+		}
+		uc.logger.Debug("webhook notification delivered", "subscription_id", subscription.ID,
+			"delivery_id", queued.ID, "attempt", attempt.Attempt)
+		return
+	}
+
+	queued.LastError = attempt.Error
+	if queued.Attempts >= uc.conf.MaxAttempts {
+		uc.deadLetter(storeCtx, &queued.WebhookDelivery)
+		return
+	}
+
+	queued.NextAttemptAt = time.Now().Add(uc.backoff(queued.Attempts))
+	uc.retry(storeCtx, queued)
+}
+
+// retry releases the delivery until its next attempt.
+func (uc *useCase) retry(ctx context.Context, queued *models.QueuedWebhookDelivery) {
+	err := uc.webhookRepository.RetryDelivery(ctx, queued)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		// the delivery is attempted again when its claim expires
+		uc.logger.Error("webhook delivery queue error", "subscription_id", queued.SubscriptionID,
+			"delivery_id", queued.ID, "error", err)
+	}
+}
+
+// backoff returns delay after the given number of failed attempts.
+func (uc *useCase) backoff(attempts int) time.Duration {
+	delay := uc.conf.InitialBackoff
+	for i := 1; i < attempts && delay < uc.conf.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > uc.conf.MaxBackoff {
+		delay = uc.conf.MaxBackoff
+	}
+	return delay
+}
+
+// send posts the notification signed with the subscription secret and
+// returns the attempt for the delivery log.
+func (uc *useCase) send(ctx context.Context, subscription *models.WebhookSubscription,
+	delivery *models.WebhookDelivery) models.WebhookAttempt {
+	start := time.Now()
+	attempt := models.WebhookAttempt{
+		SubscriptionID: subscription.ID,
+		DeliveryID:     delivery.ID,
+		Event:          delivery.Event,
+		Attempt:        delivery.Attempts,
+		Time:           start,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL,
+		bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderWebhookID, delivery.ID)
+	req.Header.Set(HeaderWebhookEvent, delivery.Event)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := uc.client.Do(req)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, responseBodyLimit))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	attempt.Delivered = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !attempt.Delivered {
+		attempt.Error = fmt.Sprintf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return attempt
+}
+
+// deadLetter moves the delivery which will not be attempted anymore to dead letters.
+func (uc *useCase) deadLetter(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.FailedAt = &now
+
+	err := uc.webhookRepository.CreateDeadLetter(ctx, delivery)
+	if errors.Is(err, models.ErrNotFound) {
+		return
+	} else if err != nil {
+		// the delivery stays in the queue and is attempted again when its claim expires
+		uc.logger.Error("webhook notification is not moved to dead letters", "subscription_id", delivery.SubscriptionID,
+			"delivery_id", delivery.ID, "error", err)
+		return
+	}
+
+	uc.logger.Warn("webhook notification moved to dead letters", "subscription_id", delivery.SubscriptionID,
+		"delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", delivery.LastError)
+}
+
+func (uc *useCase) audit(ctx context.Context, action string, subscriptionID string, before interface{}, after interface{}) {
+	if uc.auditUC != nil {
+		uc.auditUC.Record(ctx, action, subscriptionID, before, after)
+	}
+}
+
+// withoutSecret returns copy of the subscription to record in the audit log.
+func withoutSecret(subscription *models.WebhookSubscription) models.WebhookSubscription {
+	recorded := *subscription
+	recorded.Secret = ""
+	return recorded
+}
+
+// checkSubscription rejects URLs which are not http(s) or point to blocked addresses
+// and click threshold inconsistent with the events.
+func checkSubscription(subscription *models.WebhookSubscription, targets targetPolicy) error {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.Wrap(models.ErrBadRequest, "webhook url must be absolute http or https url")
+	}
+	if targets.blockedHost(target.Hostname()) {
+		return errors.Wrap(models.ErrBadRequest, "webhook url must not point to loopback, private, link-local or metadata address")
+	}
+
+	clicks := false
+	for _, event := range subscription.Events {
+		clicks = clicks || event == models.WebhookLinkClicks
+	}
+	switch {
+	case clicks && subscription.ClickThreshold == 0:
+		return errors.Wrap(models.ErrBadRequest, "click_threshold is required by link.clicks event")
+	case len(subscription.Events) > 0 && !clicks && subscription.ClickThreshold > 0:
+		return errors.Wrap(models.ErrBadRequest, "click_threshold is set without link.clicks event")
+	}
+
+	return nil
+}
+
+// linkEventDeliveryID returns id of the notification of the subscription about
+// the outbox event, so the event relayed again is queued under the same id and
+// reaches the receiver with the same X-Webhook-ID. Events without outbox id get
+// random ids.
+func linkEventDeliveryID(outboxID int64, subscriptionID string) (string, error) {
+	if outboxID == 0 {
+		return randomDeliveryID(models.WebhookSubscription{})
+	}
+	sum := sha256.Sum256([]byte(strconv.FormatInt(outboxID, 10) + "." + subscriptionID))
+	return hex.EncodeToString(sum[:idLength]), nil
+}
+
+func randomDeliveryID(models.WebhookSubscription) (string, error) {
+	return randomString(idLength, hex.EncodeToString)
+}
+
+func randomString(length int, encode func([]byte) string) (string, error) {
+	raw := make([]byte, length)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return encode(raw), nil
+}
@@ -0,0 +1,538 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/config"
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/usecase/mocks"
+	"github.com/kuzkuss/url_service/internal/observability"
+	webhookInMem "github.com/kuzkuss/url_service/internal/webhook/repository/in_memory"
+	webhookMocks "github.com/kuzkuss/url_service/internal/webhook/repository/mocks"
+	webhookUsecase "github.com/kuzkuss/url_service/internal/webhook/usecase"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type TestCaseCreate struct {
+	ArgData *models.WebhookSubscription
+	Error error
+}
+
+var testConfig = config.WebhooksConfig {
+	Workers: 2,
+	PollInterval: 10 * time.Millisecond,
+	QueueSize: 10,
+	Timeout: time.Second,
+	MaxAttempts: 3,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff: 20 * time.Millisecond,
+	DeliveryLogSize: 10,
+	// receivers of the tests listen on loopback
+	AllowLoopback: true,
+}
+
+// receiver is a webhook endpoint responding with the given statuses in turn,
+// the last one is repeated.
+type receiver struct {
+	t             *testing.T
+	secret        string
+	mx            sync.Mutex
+	statuses      []int
+	notifications chan models.WebhookNotification
+}
+
+func newReceiver(t *testing.T, secret string, statuses ...int) (*receiver, *httptest.Server) {
+	r := &receiver{
+		t:             t,
+		secret:        secret,
+		statuses:      statuses,
+		notifications: make(chan models.WebhookNotification, 10),
+	}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	require.NoError(r.t, err)
+
+	timestamp := req.Header.Get(webhookUsecase.HeaderWebhookTimestamp)
+	assert.Equal(r.t, webhookUsecase.Sign(r.secret, timestamp, body), req.Header.Get(webhookUsecase.HeaderWebhookSignature))
+
+	var notification models.WebhookNotification
+	require.NoError(r.t, json.Unmarshal(body, &notification))
+	assert.Equal(r.t, notification.ID, req.Header.Get(webhookUsecase.HeaderWebhookID))
+	assert.Equal(r.t, notification.Event, req.Header.Get(webhookUsecase.HeaderWebhookEvent))
+
+	r.mx.Lock()
+	status := r.statuses[0]
+	if len(r.statuses) > 1 {
+		r.statuses = r.statuses[1:]
+	}
+	r.mx.Unlock()
+
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		r.notifications <- notification
+	}
+}
+
+func (r *receiver) next(t *testing.T) models.WebhookNotification {
+	select {
+	case notification := <-r.notifications:
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification is not received")
+		return models.WebhookNotification{}
+	}
+}
+
+// run starts usecase until the test ends, waiting for its shutdown.
+func run(t *testing.T, usecase webhookUsecase.UseCaseI) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		usecase.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+var owner = models.WebhookScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "owner"}
+
+func subscribe(t *testing.T, usecase webhookUsecase.UseCaseI, subscription models.WebhookSubscription) models.WebhookSubscription {
+	subscription.OwnerID = owner.OwnerID
+	subscription.WorkspaceID = owner.WorkspaceID
+	require.NoError(t, usecase.CreateSubscription(context.Background(), &subscription))
+	return subscription
+}
+
+func TestUsecaseCreateSubscription(t *testing.T) {
+	createErr := errors.New("error")
+
+	mockWebhookRepo := webhookMocks.NewRepositoryI(t)
+
+	mockWebhookRepo.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(subscription *models.WebhookSubscription) bool {
+		return subscription.URL == "https://crm.example.com/hooks"
+	})).Return(nil)
+	mockWebhookRepo.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(subscription *models.WebhookSubscription) bool {
+		return subscription.URL == "https://crm.example.com/error"
+	})).Return(createErr)
+
+	// the secret of the subscription is not recorded
+	mockAudit := auditMocks.NewUseCaseI(t)
+	mockAudit.On("Record", mock.Anything, models.AuditWebhookCreate, mock.Anything, nil,
+		mock.MatchedBy(func(subscription models.WebhookSubscription) bool {
+			return subscription.ID != "" && subscription.Secret == ""
+		})).Return().Twice()
+
+	usecase := webhookUsecase.New(mockWebhookRepo, mockAudit, testConfig, observability.NopLogger())
+
+	cases := map[string]TestCaseCreate {
+		"success": {
+			ArgData: &models.WebhookSubscription{URL: "https://crm.example.com/hooks"},
+		},
+		"clicks": {
+			ArgData: &models.WebhookSubscription{
+				URL: "https://crm.example.com/hooks",
+				Events: []string{models.WebhookLinkCreated, models.WebhookLinkClicks},
+				ClickThreshold: 1000,
+			},
+		},
+		"not_http": {
+			ArgData: &models.WebhookSubscription{URL: "ftp://crm.example.com/hooks"},
+			Error: models.ErrBadRequest,
+		},
+		"link_local": {
+			ArgData: &models.WebhookSubscription{URL: "http://169.254.169.254/latest/meta-data"},
+			Error: models.ErrBadRequest,
+		},
+		"metadata_name": {
+			ArgData: &models.WebhookSubscription{URL: "http://metadata.google.internal/computeMetadata/v1"},
+			Error: models.ErrBadRequest,
+		},
+		"metadata_ipv6": {
+			ArgData: &models.WebhookSubscription{URL: "http://[fd00:ec2::254]/latest"},
+			Error: models.ErrBadRequest,
+		},
+		"unspecified": {
+			ArgData: &models.WebhookSubscription{URL: "http://0.0.0.0:8080/hooks"},
+			Error: models.ErrBadRequest,
+		},
+		"clicks_without_threshold": {
+			ArgData: &models.WebhookSubscription{
+				URL: "https://crm.example.com/hooks",
+				Events: []string{models.WebhookLinkClicks},
+			},
+			Error: models.ErrBadRequest,
+		},
+		"threshold_without_clicks": {
+			ArgData: &models.WebhookSubscription{
+				URL: "https://crm.example.com/hooks",
+				Events: []string{models.WebhookLinkCreated},
+				ClickThreshold: 1000,
+			},
+			Error: models.ErrBadRequest,
+		},
+		"repository_error": {
+			ArgData: &models.WebhookSubscription{URL: "https://crm.example.com/error"},
+			Error: createErr,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := usecase.CreateSubscription(context.Background(), test.ArgData)
+			require.Equal(t, test.Error, errors.Cause(err))
+
+			if err == nil {
+				assert.Len(t, test.ArgData.ID, 32)
+				assert.NotEmpty(t, test.ArgData.Secret)
+			}
+		})
+	}
+}
+
+func TestUsecaseLinkChanged(t *testing.T) {
+	loadErr := errors.New("error")
+
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+
+	created, server := newReceiver(t, "created_secret_value", http.StatusOK)
+	subscribe(t, usecase, models.WebhookSubscription{
+		URL: server.URL,
+		Secret: "created_secret_value",
+		Events: []string{models.WebhookLinkCreated},
+	})
+	all, server := newReceiver(t, "", http.StatusOK)
+	allSubscription := subscribe(t, usecase, models.WebhookSubscription{URL: server.URL})
+	all.secret = allSubscription.Secret
+
+	run(t, usecase)
+
+	ctx := context.Background()
+	link := models.Link{ShortLink: "short_link", OriginalLink: "https://go.dev", OwnerID: "owner",
+		WorkspaceID: models.DefaultWorkspace}
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkCreated, Link: link}))
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkCreated,
+		Link: models.Link{ShortLink: "other", OwnerID: "other", WorkspaceID: models.DefaultWorkspace}}))
+	// the same owner id in another workspace is another owner
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkCreated,
+		Link: models.Link{ShortLink: "other_workspace", OwnerID: "owner", WorkspaceID: "other"}}))
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkDisabled, Link: link}))
+
+	notification := created.next(t)
+	assert.Equal(t, models.WebhookLinkCreated, notification.Event)
+	assert.Equal(t, link.ShortLink, notification.Link.ShortLink)
+	assert.Equal(t, link.OriginalLink, notification.Link.OriginalLink)
+	assert.Equal(t, models.WebhookLinkCreated, all.next(t).Event)
+
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkDeleted, Link: link}))
+	assert.Equal(t, models.WebhookLinkDeleted, all.next(t).Event)
+
+	require.Eventually(t, func() bool {
+		attempts, err := usecase.GetDeliveryLog(ctx, allSubscription.ID, owner)
+		require.NoError(t, err)
+		return len(attempts) == 2 && attempts[0].Event == models.WebhookLinkDeleted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, created.notifications)
+
+	mockWebhookRepo := webhookMocks.NewRepositoryI(t)
+	mockWebhookRepo.On("SelectSubscriptions", mock.Anything, owner).Return(nil, loadErr)
+
+	usecase = webhookUsecase.New(mockWebhookRepo, nil, testConfig, observability.NopLogger())
+	err := usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkCreated, Link: link})
+	require.Equal(t, loadErr, errors.Cause(err))
+
+	// the event is relayed again if its notifications are not stored
+	queueErr := errors.New("error")
+	mockWebhookRepo = webhookMocks.NewRepositoryI(t)
+	mockWebhookRepo.On("SelectSubscriptions", mock.Anything, owner).
+		Return([]models.WebhookSubscription{allSubscription}, nil)
+	mockWebhookRepo.On("QueueDelivery", mock.Anything, mock.Anything).Return(queueErr)
+
+	usecase = webhookUsecase.New(mockWebhookRepo, nil, testConfig, observability.NopLogger())
+	err = usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkCreated, Link: link})
+	require.Equal(t, queueErr, errors.Cause(err))
+}
+
+func TestUsecaseDeliveryOrder(t *testing.T) {
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+
+	// the first notification is retried, the later ones wait for it
+	receiver, server := newReceiver(t, "ordered_secret_value", http.StatusInternalServerError, http.StatusOK)
+	subscribe(t, usecase, models.WebhookSubscription{URL: server.URL, Secret: "ordered_secret_value"})
+
+	ctx := context.Background()
+	link := models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}
+	for _, eventType := range []models.LinkEventType{models.LinkCreated, models.LinkUpdated, models.LinkDeleted} {
+		require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: eventType, Link: link}))
+	}
+	run(t, usecase)
+
+	assert.Equal(t, models.WebhookLinkCreated, receiver.next(t).Event)
+	assert.Equal(t, models.WebhookLinkUpdated, receiver.next(t).Event)
+	assert.Equal(t, models.WebhookLinkDeleted, receiver.next(t).Event)
+}
+
+func TestUsecaseLinkChangedRelayedAgain(t *testing.T) {
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+
+	receiver, server := newReceiver(t, "relayed_secret_value", http.StatusOK)
+	subscribe(t, usecase, models.WebhookSubscription{URL: server.URL, Secret: "relayed_secret_value"})
+
+	ctx := context.Background()
+	event := models.LinkEvent{OutboxID: 7, Type: models.LinkCreated,
+		Link: models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}}
+	// the event relayed again before its notification is sent is queued once
+	require.NoError(t, usecase.LinkChanged(ctx, event))
+	require.NoError(t, usecase.LinkChanged(ctx, event))
+	run(t, usecase)
+
+	first := receiver.next(t)
+	select {
+	case notification := <-receiver.notifications:
+		t.Fatalf("notification %s is sent twice", notification.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// after it is sent the receiver can tell the notification by its id
+	require.NoError(t, usecase.LinkChanged(ctx, event))
+	assert.Equal(t, first.ID, receiver.next(t).ID)
+
+	event.OutboxID = 8
+	require.NoError(t, usecase.LinkChanged(ctx, event))
+	assert.NotEqual(t, first.ID, receiver.next(t).ID)
+}
+
+func TestUsecaseClickThreshold(t *testing.T) {
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+
+	clicks, server := newReceiver(t, "clicks_secret_value", http.StatusOK)
+	subscribe(t, usecase, models.WebhookSubscription{
+		URL: server.URL,
+		Secret: "clicks_secret_value",
+		Events: []string{models.WebhookLinkClicks},
+		ClickThreshold: 3,
+	})
+	run(t, usecase)
+
+	// the click reaching the threshold is missed, e.g. it is counted by another instance
+	for _, count := range []int64{1, 2, 4, 5} {
+		usecase.LinkClicked(context.Background(), models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace, Clicks: count})
+	}
+
+	notification := clicks.next(t)
+	assert.Equal(t, models.WebhookLinkClicks, notification.Event)
+	assert.Equal(t, "short_link", notification.Link.ShortLink)
+	assert.Equal(t, int64(4), notification.Link.Clicks)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, clicks.notifications)
+
+	// the threshold fires for every link once
+	usecase.LinkClicked(context.Background(), models.Link{ShortLink: "other", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace, Clicks: 3})
+	assert.Equal(t, "other", clicks.next(t).Link.ShortLink)
+
+	// a link created again after deletion is notified again
+	ctx := context.Background()
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkDeleted,
+		Link: models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}}))
+	usecase.LinkClicked(ctx, models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace, Clicks: 3})
+	notification = clicks.next(t)
+	assert.Equal(t, "short_link", notification.Link.ShortLink)
+	assert.Equal(t, int64(3), notification.Link.Clicks)
+}
+
+func TestUsecaseBlockedAddresses(t *testing.T) {
+	conf := testConfig
+	conf.AllowLoopback = false
+
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, conf, observability.NopLogger())
+
+	for _, url := range []string{"http://127.0.0.1:8080/hooks", "http://localhost/hooks", "http://api.localhost/hooks",
+		"http://[::1]/hooks", "http://[::ffff:127.0.0.1]/hooks", "http://169.254.169.254/latest/meta-data"} {
+		err := usecase.CreateSubscription(context.Background(), &models.WebhookSubscription{URL: url,
+			OwnerID: owner.OwnerID, WorkspaceID: owner.WorkspaceID})
+		require.Equal(t, models.ErrBadRequest, errors.Cause(err), url)
+	}
+
+	// the address is checked when connecting too, e.g. if the name is resolved to loopback
+	receiver, server := newReceiver(t, "blocked_secret_value", http.StatusOK)
+	require.NoError(t, repo.CreateSubscription(context.Background(), &models.WebhookSubscription{
+		ID: "blocked",
+		OwnerID: owner.OwnerID,
+		WorkspaceID: owner.WorkspaceID,
+		URL: server.URL,
+		Secret: "blocked_secret_value",
+	}))
+	run(t, usecase)
+
+	ctx := context.Background()
+	require.NoError(t, usecase.LinkChanged(ctx, models.LinkEvent{Type: models.LinkCreated,
+		Link: models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace}}))
+
+	require.Eventually(t, func() bool {
+		deadLetters, err := usecase.GetDeadLetters(ctx, "blocked", owner)
+		require.NoError(t, err)
+		return len(deadLetters) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	attempts, err := usecase.GetDeliveryLog(ctx, "blocked", owner)
+	require.NoError(t, err)
+	require.Len(t, attempts, conf.MaxAttempts)
+	assert.Contains(t, attempts[0].Error, "webhook address is not allowed")
+	assert.Empty(t, receiver.notifications)
+}
+
+func TestUsecasePrivateAddresses(t *testing.T) {
+	conf := testConfig
+	conf.AllowedPrivateNetworks = []string{"10.1.0.0/16"}
+
+	usecase := webhookUsecase.New(webhookInMem.New(), nil, conf, observability.NopLogger())
+
+	for _, url := range []string{"http://10.0.0.5/hooks", "http://172.16.0.1/hooks", "http://192.168.1.10/hooks",
+		"http://[fd12::1]/hooks", "http://[::ffff:10.0.0.5]/hooks"} {
+		err := usecase.CreateSubscription(context.Background(), &models.WebhookSubscription{URL: url,
+			OwnerID: owner.OwnerID, WorkspaceID: owner.WorkspaceID})
+		require.Equal(t, models.ErrBadRequest, errors.Cause(err), url)
+	}
+
+	// private addresses in the allowed networks are accepted
+	require.NoError(t, usecase.CreateSubscription(context.Background(), &models.WebhookSubscription{
+		URL: "http://10.1.2.3/hooks", OwnerID: owner.OwnerID, WorkspaceID: owner.WorkspaceID}))
+}
+
+func TestUsecaseRetries(t *testing.T) {
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+
+	failing, server := newReceiver(t, "failing_secret_value",
+		http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+	subscription := subscribe(t, usecase, models.WebhookSubscription{
+		URL: server.URL,
+		Secret: "failing_secret_value",
+		Events: []string{models.WebhookLinkClicks},
+		ClickThreshold: 1,
+	})
+	run(t, usecase)
+
+	usecase.LinkClicked(context.Background(), models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace, Clicks: 1})
+
+	var deadLetters []models.WebhookDelivery
+	require.Eventually(t, func() bool {
+		var err error
+		deadLetters, err = usecase.GetDeadLetters(context.Background(), subscription.ID, owner)
+		require.NoError(t, err)
+		return len(deadLetters) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3, deadLetters[0].Attempts)
+	assert.Equal(t, "receiver responded with status 502", deadLetters[0].LastError)
+
+	attempts, err := usecase.GetDeliveryLog(context.Background(), subscription.ID, owner)
+	require.NoError(t, err)
+	require.Len(t, attempts, 3)
+	for idx, attempt := range attempts {
+		assert.Equal(t, 3 - idx, attempt.Attempt)
+		assert.False(t, attempt.Delivered)
+		assert.Equal(t, deadLetters[0].ID, attempt.DeliveryID)
+	}
+
+	err = usecase.Redeliver(context.Background(), subscription.ID, owner, deadLetters[0].ID)
+	require.NoError(t, err)
+
+	notification := failing.next(t)
+	assert.Equal(t, deadLetters[0].ID, notification.ID)
+
+	deadLetters, err = usecase.GetDeadLetters(context.Background(), subscription.ID, owner)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	err = usecase.Redeliver(context.Background(), subscription.ID, owner, notification.ID)
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+	_, err = usecase.GetDeliveryLog(context.Background(), subscription.ID, models.WebhookScope{WorkspaceID: models.DefaultWorkspace, OwnerID: "other"})
+	require.Equal(t, models.ErrNotFound, errors.Cause(err))
+}
+
+func TestUsecaseShutdown(t *testing.T) {
+	repo := webhookInMem.New()
+	usecase := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+
+	// the first request is answered after the shutdown only
+	started := make(chan struct{})
+	var once sync.Once
+	receiver, server := newReceiver(t, "restart_secret_value", http.StatusOK)
+	interrupting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		first := false
+		once.Do(func() { first = true })
+		if first {
+			// the body is read, so the server notices when the client cancels the request
+			_, _ = io.Copy(io.Discard, req.Body)
+			close(started)
+			<-req.Context().Done()
+			return
+		}
+		receiver.ServeHTTP(w, req)
+	}))
+	t.Cleanup(interrupting.Close)
+	t.Cleanup(server.Close)
+
+	subscription := subscribe(t, usecase, models.WebhookSubscription{
+		URL: interrupting.URL,
+		Secret: "restart_secret_value",
+		Events: []string{models.WebhookLinkClicks},
+		ClickThreshold: 1,
+	})
+	stop := run(t, usecase)
+
+	usecase.LinkClicked(context.Background(), models.Link{ShortLink: "short_link", OwnerID: "owner", WorkspaceID: models.DefaultWorkspace, Clicks: 1})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification is not sent")
+	}
+	stop()
+
+	// the interrupted attempt is neither logged nor moved to dead letters
+	attempts, err := usecase.GetDeliveryLog(context.Background(), subscription.ID, owner)
+	require.NoError(t, err)
+	assert.Empty(t, attempts)
+	deadLetters, err := usecase.GetDeadLetters(context.Background(), subscription.ID, owner)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	restarted := webhookUsecase.New(repo, nil, testConfig, observability.NopLogger())
+	run(t, restarted)
+
+	notification := receiver.next(t)
+	assert.Equal(t, models.WebhookLinkClicks, notification.Event)
+	require.Eventually(t, func() bool {
+		attempts, err := restarted.GetDeliveryLog(context.Background(), subscription.ID, owner)
+		require.NoError(t, err)
+		return len(attempts) == 1 && attempts[0].Attempt == 1 && attempts[0].Delivered
+	}, 5*time.Second, 10*time.Millisecond)
+}
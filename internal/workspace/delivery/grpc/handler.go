@@ -0,0 +1,120 @@
+package delivery
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+	link "github.com/kuzkuss/url_service/proto/link"
+)
+
+type WorkspaceManager struct {
+	link.UnimplementedWorkspacesServer
+	WorkspaceUC workspaceUsecase.UseCaseI
+	Logger *slog.Logger
+}
+
+// New creates workspaces service managing members according to roles in the workspace.
+func New(uc workspaceUsecase.UseCaseI, logger *slog.Logger) link.WorkspacesServer {
+	return WorkspaceManager{WorkspaceUC: uc, Logger: logger}
+}
+
+func (wm WorkspaceManager) ListMembers(ctx context.Context, req *link.MembersRequest) (*link.MemberList, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	members, err := wm.WorkspaceUC.GetMembers(ctx, principal, req.WorkspaceId)
+	if err != nil {
+		return nil, wm.statusError(ctx, "workspace members listing failed", err)
+	}
+
+	resp := &link.MemberList {
+		Members: make([]*link.Member, 0, len(members)),
+	}
+	for _, member := range members {
+		resp.Members = append(resp.Members, pbMember(member))
+	}
+
+	return resp, nil
+}
+
+func pbMember(member models.Member) *link.Member {
+	resp := &link.Member {
+		WorkspaceId: member.WorkspaceID,
+		OwnerId: member.OwnerID,
+		Role: string(member.Role),
+	}
+	if member.CreatedAt != nil {
+		resp.CreatedAt = timestamppb.New(*member.CreatedAt)
+	}
+
+	return resp
+}
+
+func (wm WorkspaceManager) SaveMember(ctx context.Context, pbMember *link.Member) (*link.Nothing, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	member := models.Member {
+		WorkspaceID: pbMember.WorkspaceId,
+		OwnerID: pbMember.OwnerId,
+		Role: models.Role(pbMember.Role),
+	}
+	if err := pkg.Validate(&member); err != nil {
+		wm.Logger.InfoContext(ctx, "invalid member data", "error", err)
+		return nil, status.Error(codes.InvalidArgument, models.ErrBadRequest.Error())
+	}
+
+	if err := wm.WorkspaceUC.SaveMember(ctx, principal, &member); err != nil {
+		return nil, wm.statusError(ctx, "workspace member saving failed", err)
+	}
+
+	return &link.Nothing{}, nil
+}
+
+func (wm WorkspaceManager) DeleteMember(ctx context.Context, pbMember *link.Member) (*link.Nothing, error) {
+	principal, ok := pkg.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, models.ErrUnauthorized.Error())
+	}
+
+	if err := wm.WorkspaceUC.DeleteMember(ctx, principal, pbMember.WorkspaceId, pbMember.OwnerId); err != nil {
+		return nil, wm.statusError(ctx, "workspace member deletion failed", err)
+	}
+
+	return &link.Nothing{}, nil
+}
+
+// statusError converts error of the usecase to gRPC status with the same meaning
+// as the HTTP status returned for it. Details of internal errors are only logged.
+func (wm WorkspaceManager) statusError(ctx context.Context, msg string, err error) error {
+	causeErr := errors.Cause(err)
+	switch {
+	case errors.Is(causeErr, models.ErrBadRequest):
+		wm.Logger.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.InvalidArgument, models.ErrBadRequest.Error())
+	case errors.Is(causeErr, models.ErrForbidden):
+		wm.Logger.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.PermissionDenied, models.ErrForbidden.Error())
+	case errors.Is(causeErr, models.ErrNotFound):
+		wm.Logger.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.NotFound, models.ErrNotFound.Error())
+	case errors.Is(causeErr, models.ErrConflict):
+		wm.Logger.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.FailedPrecondition, models.ErrConflict.Error())
+	default:
+		wm.Logger.ErrorContext(ctx, msg, "error", err)
+		return status.Error(codes.Internal, models.ErrInternalServerError.Error())
+	}
+}
@@ -0,0 +1,129 @@
+package delivery_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	workspaceDelivery "github.com/kuzkuss/url_service/internal/workspace/delivery/grpc"
+	workspaceMocks "github.com/kuzkuss/url_service/internal/workspace/usecase/mocks"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+	link "github.com/kuzkuss/url_service/proto/link"
+)
+
+type TestCaseMember struct {
+	ArgData *link.Member
+	Code codes.Code
+}
+
+func TestGrpcDeliveryListMembers(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	principal := &models.Principal{OwnerID: "owner", WorkspaceID: "team"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("GetMembers", mock.Anything, principal, "team").
+		Return([]models.Member{{WorkspaceID: "team", OwnerID: "owner", Role: models.RoleOwner, CreatedAt: &createdAt}}, nil)
+	mockWorkspaceUsecase.On("GetMembers", mock.Anything, principal, "other").
+		Return(nil, errors.Wrap(models.ErrForbidden, "role viewer does not permit members.read"))
+
+	delivery := workspaceDelivery.New(mockWorkspaceUsecase, observability.NopLogger())
+
+	resp, err := delivery.ListMembers(ctx, &link.MembersRequest{WorkspaceId: "team"})
+	require.NoError(t, err)
+	require.Len(t, resp.Members, 1)
+	require.Equal(t, "owner", resp.Members[0].OwnerId)
+	require.Equal(t, string(models.RoleOwner), resp.Members[0].Role)
+	require.Equal(t, createdAt, resp.Members[0].CreatedAt.AsTime())
+
+	_, err = delivery.ListMembers(ctx, &link.MembersRequest{WorkspaceId: "other"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = delivery.ListMembers(context.Background(), &link.MembersRequest{WorkspaceId: "team"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGrpcDeliverySaveMember(t *testing.T) {
+	principal := &models.Principal{OwnerID: "owner", WorkspaceID: "team"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor}).Return(nil)
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "team", OwnerID: "owner", Role: models.RoleViewer}).
+		Return(errors.Wrap(models.ErrConflict, "the last owner can not be demoted"))
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "missing", OwnerID: "editor", Role: models.RoleEditor}).
+		Return(errors.Wrap(models.ErrNotFound, "workspace missing"))
+
+	delivery := workspaceDelivery.New(mockWorkspaceUsecase, observability.NopLogger())
+
+	cases := map[string]TestCaseMember {
+		"success": {
+			ArgData: &link.Member{WorkspaceId: "team", OwnerId: "editor", Role: "editor"},
+			Code: codes.OK,
+		},
+		"last_owner": {
+			ArgData: &link.Member{WorkspaceId: "team", OwnerId: "owner", Role: "viewer"},
+			Code: codes.FailedPrecondition,
+		},
+		"not_found": {
+			ArgData: &link.Member{WorkspaceId: "missing", OwnerId: "editor", Role: "editor"},
+			Code: codes.NotFound,
+		},
+		"invalid_role": {
+			ArgData: &link.Member{WorkspaceId: "team", OwnerId: "editor", Role: "root"},
+			Code: codes.InvalidArgument,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := delivery.SaveMember(ctx, test.ArgData)
+			require.Equal(t, test.Code, status.Code(err))
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := delivery.SaveMember(context.Background(), &link.Member{WorkspaceId: "team", OwnerId: "editor", Role: "editor"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestGrpcDeliveryDeleteMember(t *testing.T) {
+	principal := &models.Principal{OwnerID: "owner", WorkspaceID: "team"}
+	ctx := pkg.WithPrincipal(context.Background(), principal)
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("DeleteMember", mock.Anything, principal, "team", "editor").Return(nil)
+	mockWorkspaceUsecase.On("DeleteMember", mock.Anything, principal, "team", "error").Return(errors.New("error"))
+
+	delivery := workspaceDelivery.New(mockWorkspaceUsecase, observability.NopLogger())
+
+	cases := map[string]TestCaseMember {
+		"success": {
+			ArgData: &link.Member{WorkspaceId: "team", OwnerId: "editor"},
+			Code: codes.OK,
+		},
+		"error": {
+			ArgData: &link.Member{WorkspaceId: "team", OwnerId: "error"},
+			Code: codes.Internal,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := delivery.DeleteMember(ctx, test.ArgData)
+			require.Equal(t, test.Code, status.Code(err))
+		})
+	}
+}
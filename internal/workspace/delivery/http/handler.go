@@ -0,0 +1,224 @@
+package delivery
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type Delivery struct {
+	WorkspaceUC workspaceUsecase.UseCaseI
+	Logger *slog.Logger
+}
+
+// CreateWorkspace godoc
+// @Summary      CreateWorkspace
+// @Description  create workspace isolating links of a team, available to administrator only; api keys are bound to the workspace on creation
+// @Tags     workspaces
+// @Accept	 application/json
+// @Produce  application/json
+// @Param    X-API-Key header string false "administrator api key"
+// @Param    Authorization header string false "bearer token with admin scope"
+// @Param    workspace body models.Workspace true "workspace"
+// @Success 201 {object} pkg.Response{body=models.Workspace} "workspace created"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 409 {object} echo.HTTPError "workspace already exists"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /workspaces [post]
+func (del *Delivery) CreateWorkspace(c echo.Context) error {
+	var workspace models.Workspace
+	err := c.Bind(&workspace)
+	if err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid workspace data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	if err := pkg.Validate(&workspace); err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid workspace data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	err = del.WorkspaceUC.CreateWorkspace(c.Request().Context(), &workspace)
+	if errors.Is(errors.Cause(err), models.ErrConflict) {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrConflict.Error())
+	} else if err != nil {
+		del.Logger.ErrorContext(c.Request().Context(), "workspace creation failed", "workspace_id", workspace.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+	}
+
+	return c.JSON(http.StatusCreated, pkg.Response{Body: workspace})
+}
+
+// GetWorkspaces godoc
+// @Summary      GetWorkspaces
+// @Description  get all workspaces ordered by id, available to administrator only
+// @Tags     workspaces
+// @Param    X-API-Key header string false "administrator api key"
+// @Param    Authorization header string false "bearer token with admin scope"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.Workspace} "success get workspaces"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /workspaces [get]
+func (del *Delivery) GetWorkspaces(c echo.Context) error {
+	workspaces, err := del.WorkspaceUC.GetWorkspaces(c.Request().Context())
+	if err != nil {
+		del.Logger.ErrorContext(c.Request().Context(), "workspaces loading failed", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: workspaces})
+}
+
+// GetMembers godoc
+// @Summary      GetMembers
+// @Description  get members of the workspace ordered by owner id, available to members of the workspace and administrators
+// @Tags     workspaces
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Workspace id"
+// @Produce  application/json
+// @Success  200 {object} pkg.Response{body=[]models.Member} "success get members"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /workspaces/{id}/members [get]
+func (del *Delivery) GetMembers(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	members, err := del.WorkspaceUC.GetMembers(c.Request().Context(), principal, c.Param("id"))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "workspace members loading failed", err)
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: members})
+}
+
+// SaveMember godoc
+// @Summary      SaveMember
+// @Description  grant role in the workspace to the owner of api keys or change it, available to admins and owners of the workspace and administrators; only owners grant, change and revoke the owner role, the last owner can not be demoted
+// @Tags     workspaces
+// @Accept	 application/json
+// @Produce  application/json
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Workspace id"
+// @Param owner_id path string  true  "Owner id of the member"
+// @Param    member body models.Member true "role of the member"
+// @Success  200 {object} pkg.Response{body=models.Member} "member saved"
+// @Failure 400 {object} echo.HTTPError "bad request"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "workspace not found"
+// @Failure 409 {object} echo.HTTPError "the last owner can not be demoted"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /workspaces/{id}/members/{owner_id} [put]
+func (del *Delivery) SaveMember(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	var member models.Member
+	err := c.Bind(&member)
+	if err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid member data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	if err := pkg.Validate(&member); err != nil {
+		del.Logger.InfoContext(c.Request().Context(), "invalid member data", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	}
+
+	member.WorkspaceID = c.Param("id")
+	member.OwnerID = c.Param("owner_id")
+	member.CreatedAt = nil
+	err = del.WorkspaceUC.SaveMember(c.Request().Context(), principal, &member)
+	if err != nil {
+		return del.httpError(c.Request().Context(), "workspace member saving failed", err)
+	}
+
+	return c.JSON(http.StatusOK, pkg.Response{Body: member})
+}
+
+// DeleteMember godoc
+// @Summary      DeleteMember
+// @Description  revoke role in the workspace, available to admins and owners of the workspace and administrators; only owners revoke the owner role, the last owner can not be removed
+// @Tags     workspaces
+// @Param    X-API-Key header string false "api key"
+// @Param    Authorization header string false "bearer token"
+// @Param id path string  true  "Workspace id"
+// @Param owner_id path string  true  "Owner id of the member"
+// @Success  204 "member deleted"
+// @Failure 401 {object} echo.HTTPError "unauthorized"
+// @Failure 403 {object} echo.HTTPError "forbidden"
+// @Failure 404 {object} echo.HTTPError "not found"
+// @Failure 409 {object} echo.HTTPError "the last owner can not be removed"
+// @Failure 500 {object} echo.HTTPError "internal server error"
+// @Router   /workspaces/{id}/members/{owner_id} [delete]
+func (del *Delivery) DeleteMember(c echo.Context) error {
+	principal, ok := pkg.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, models.ErrUnauthorized.Error())
+	}
+
+	err := del.WorkspaceUC.DeleteMember(c.Request().Context(), principal, c.Param("id"), c.Param("owner_id"))
+	if err != nil {
+		return del.httpError(c.Request().Context(), "workspace member deletion failed", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (del *Delivery) httpError(ctx context.Context, msg string, err error) error {
+	causeErr := errors.Cause(err)
+	switch {
+	case errors.Is(causeErr, models.ErrBadRequest):
+		del.Logger.InfoContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, models.ErrBadRequest.Error())
+	case errors.Is(causeErr, models.ErrForbidden):
+		del.Logger.InfoContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+	case errors.Is(causeErr, models.ErrNotFound):
+		del.Logger.InfoContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrNotFound.Error())
+	case errors.Is(causeErr, models.ErrConflict):
+		del.Logger.InfoContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusConflict, models.ErrConflict.Error())
+	default:
+		del.Logger.ErrorContext(ctx, msg, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, models.ErrInternalServerError.Error())
+	}
+}
+
+// New registers workspace routes wrapped with middleware returned by authorize
+// for the required scope; it must store principal in the request context. Only
+// administrators manage workspaces, members are managed according to roles in the workspace.
+func New(e *echo.Echo, workspaceUC workspaceUsecase.UseCaseI, authorize func(scope string) echo.MiddlewareFunc,
+	logger *slog.Logger) {
+	handler := &Delivery{
+		WorkspaceUC: workspaceUC,
+		Logger: logger,
+	}
+
+	e.POST("/workspaces", handler.CreateWorkspace, authorize(models.ScopeAdmin))
+	e.GET("/workspaces", handler.GetWorkspaces, authorize(models.ScopeAdmin))
+	e.GET("/workspaces/:id/members", handler.GetMembers, authorize(models.ScopeLinksRead))
+	e.PUT("/workspaces/:id/members/:owner_id", handler.SaveMember, authorize(models.ScopeLinksWrite))
+	e.DELETE("/workspaces/:id/members/:owner_id", handler.DeleteMember, authorize(models.ScopeLinksWrite))
+}
@@ -0,0 +1,328 @@
+package delivery_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	workspaceDelivery "github.com/kuzkuss/url_service/internal/workspace/delivery/http"
+	workspaceMocks "github.com/kuzkuss/url_service/internal/workspace/usecase/mocks"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/kuzkuss/url_service/pkg"
+)
+
+type TestCaseRequest struct {
+	Body string
+	ExpectedResponse string
+	StatusCode int
+}
+
+// adminOnly authorizes requests made with the admin header.
+func adminOnly(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if scope != models.ScopeAdmin || c.Request().Header.Get("X-Admin") == "" {
+				return echo.NewHTTPError(http.StatusForbidden, models.ErrForbidden.Error())
+			}
+			return next(c)
+		}
+	}
+}
+
+func TestHttpDeliveryCreateWorkspace(t *testing.T) {
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+
+	mockWorkspaceUsecase.On("CreateWorkspace", mock.Anything, &models.Workspace{ID: "team", Name: "Team"}).Return(nil)
+	mockWorkspaceUsecase.On("CreateWorkspace", mock.Anything, &models.Workspace{ID: models.DefaultWorkspace}).
+		Return(errors.Wrap(models.ErrConflict, "workspace repository error"))
+	mockWorkspaceUsecase.On("CreateWorkspace", mock.Anything, &models.Workspace{ID: "error"}).
+		Return(errors.New("error"))
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: models.Workspace{ID: "team", Name: "Team"}})
+	require.NoError(t, err)
+
+	e := echo.New()
+	workspaceDelivery.New(e, mockWorkspaceUsecase, adminOnly, observability.NopLogger())
+
+	cases := map[string]TestCaseRequest {
+		"success": {
+			Body: `{"id":"team","name":"Team"}`,
+			ExpectedResponse: string(jsonResponse) + "\n",
+			StatusCode: http.StatusCreated,
+		},
+		"missing_id": {
+			Body: `{"name":"Team"}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"too_long_id": {
+			Body: `{"id":"` + strings.Repeat("a", 65) + `"}`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"invalid_body": {
+			Body: `{"id":`,
+			StatusCode: http.StatusBadRequest,
+		},
+		"conflict": {
+			Body: `{"id":"default"}`,
+			StatusCode: http.StatusConflict,
+		},
+		"usecase_error": {
+			Body: `{"id":"error"}`,
+			StatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/workspaces", strings.NewReader(test.Body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("X-Admin", "true")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("forbidden", func(t *testing.T) {
+		req := httptest.NewRequest(echo.POST, "/workspaces", strings.NewReader(`{"id":"team"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestHttpDeliveryGetWorkspaces(t *testing.T) {
+	workspaces := []models.Workspace {
+		{ID: models.DefaultWorkspace, Name: "Default"},
+		{ID: "team", Name: "Team"},
+	}
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("GetWorkspaces", mock.Anything).Return(workspaces, nil).Once()
+	mockWorkspaceUsecase.On("GetWorkspaces", mock.Anything).Return(nil, errors.New("error")).Once()
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: workspaces})
+	require.NoError(t, err)
+
+	e := echo.New()
+	workspaceDelivery.New(e, mockWorkspaceUsecase, adminOnly, observability.NopLogger())
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, "/workspaces", nil)
+		req.Header.Set("X-Admin", "true")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, string(jsonResponse) + "\n", rec.Body.String())
+
+	rec = get()
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+// withPrincipal authorizes every request as principal.
+func withPrincipal(principal *models.Principal) func(scope string) echo.MiddlewareFunc {
+	return func(scope string) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.SetRequest(c.Request().WithContext(pkg.WithPrincipal(c.Request().Context(), principal)))
+				return next(c)
+			}
+		}
+	}
+}
+
+func TestHttpDeliveryGetMembers(t *testing.T) {
+	principal := &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleViewer}
+	members := []models.Member {
+		{WorkspaceID: "team", OwnerID: "owner", Role: models.RoleViewer},
+		{WorkspaceID: "team", OwnerID: "root", Role: models.RoleOwner},
+	}
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("GetMembers", mock.Anything, principal, "team").Return(members, nil)
+	mockWorkspaceUsecase.On("GetMembers", mock.Anything, principal, "other").
+		Return(nil, errors.Wrap(models.ErrForbidden, "workspace is not the workspace of the client"))
+	mockWorkspaceUsecase.On("GetMembers", mock.Anything, principal, "error").Return(nil, errors.New("error"))
+
+	jsonResponse, err := json.Marshal(pkg.Response{Body: members})
+	require.NoError(t, err)
+
+	e := echo.New()
+	workspaceDelivery.New(e, mockWorkspaceUsecase, withPrincipal(principal), observability.NopLogger())
+
+	cases := map[string]TestCaseRequest {
+		"success": {
+			Body: "team",
+			ExpectedResponse: string(jsonResponse) + "\n",
+			StatusCode: http.StatusOK,
+		},
+		"forbidden": {
+			Body: "other",
+			StatusCode: http.StatusForbidden,
+		},
+		"usecase_error": {
+			Body: "error",
+			StatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/workspaces/" + test.Body + "/members", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHttpDeliverySaveMember(t *testing.T) {
+	principal := &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleAdmin}
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor}).Return(nil)
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "team", OwnerID: "new_owner", Role: models.RoleOwner}).
+		Return(errors.Wrap(models.ErrForbidden, "only owners grant the owner role"))
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "team", OwnerID: "root", Role: models.RoleViewer}).
+		Return(errors.Wrap(models.ErrConflict, "workspace repository error"))
+	mockWorkspaceUsecase.On("SaveMember", mock.Anything, principal,
+		&models.Member{WorkspaceID: "team", OwnerID: "unknown_workspace", Role: models.RoleViewer}).
+		Return(errors.Wrap(models.ErrNotFound, "workspace repository error"))
+
+	jsonResponse, err := json.Marshal(pkg.Response {
+		Body: models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor},
+	})
+	require.NoError(t, err)
+
+	e := echo.New()
+	workspaceDelivery.New(e, mockWorkspaceUsecase, withPrincipal(principal), observability.NopLogger())
+
+	type testCaseSave struct {
+		OwnerID string
+		TestCaseRequest
+	}
+
+	cases := map[string]testCaseSave {
+		"success": {
+			OwnerID: "editor",
+			TestCaseRequest: TestCaseRequest {
+				Body: `{"role":"editor","workspace_id":"other"}`,
+				ExpectedResponse: string(jsonResponse) + "\n",
+				StatusCode: http.StatusOK,
+			},
+		},
+		"unknown_role": {
+			OwnerID: "editor",
+			TestCaseRequest: TestCaseRequest {
+				Body: `{"role":"superuser"}`,
+				StatusCode: http.StatusBadRequest,
+			},
+		},
+		"invalid_body": {
+			OwnerID: "editor",
+			TestCaseRequest: TestCaseRequest {
+				Body: `{"role":`,
+				StatusCode: http.StatusBadRequest,
+			},
+		},
+		"forbidden": {
+			OwnerID: "new_owner",
+			TestCaseRequest: TestCaseRequest {
+				Body: `{"role":"owner"}`,
+				StatusCode: http.StatusForbidden,
+			},
+		},
+		"last_owner": {
+			OwnerID: "root",
+			TestCaseRequest: TestCaseRequest {
+				Body: `{"role":"viewer"}`,
+				StatusCode: http.StatusConflict,
+			},
+		},
+		"not_found": {
+			OwnerID: "unknown_workspace",
+			TestCaseRequest: TestCaseRequest {
+				Body: `{"role":"viewer"}`,
+				StatusCode: http.StatusNotFound,
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.PUT, "/workspaces/team/members/" + test.OwnerID, strings.NewReader(test.Body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+			if test.ExpectedResponse != "" {
+				assert.Equal(t, test.ExpectedResponse, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHttpDeliveryDeleteMember(t *testing.T) {
+	principal := &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleOwner}
+
+	mockWorkspaceUsecase := workspaceMocks.NewUseCaseI(t)
+	mockWorkspaceUsecase.On("DeleteMember", mock.Anything, principal, "team", "editor").Return(nil)
+	mockWorkspaceUsecase.On("DeleteMember", mock.Anything, principal, "team", "owner").
+		Return(errors.Wrap(models.ErrConflict, "workspace repository error"))
+	mockWorkspaceUsecase.On("DeleteMember", mock.Anything, principal, "team", "unknown").
+		Return(errors.Wrap(models.ErrNotFound, "workspace repository error"))
+
+	e := echo.New()
+	workspaceDelivery.New(e, mockWorkspaceUsecase, withPrincipal(principal), observability.NopLogger())
+
+	cases := map[string]TestCaseRequest {
+		"success": {
+			Body: "editor",
+			StatusCode: http.StatusNoContent,
+		},
+		"last_owner": {
+			Body: "owner",
+			StatusCode: http.StatusConflict,
+		},
+		"not_found": {
+			Body: "unknown",
+			StatusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/workspaces/team/members/" + test.Body, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			require.Equal(t, test.StatusCode, rec.Code)
+		})
+	}
+}
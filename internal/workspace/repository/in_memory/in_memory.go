@@ -0,0 +1,191 @@
+package in_memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	auditRep "github.com/kuzkuss/url_service/internal/audit/repository"
+	"github.com/kuzkuss/url_service/internal/workspace/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+// memberKey identifies the member by its owner in the workspace.
+type memberKey struct {
+	workspaceID string
+	ownerID     string
+}
+
+type workspaceRepository struct {
+	mx              sync.RWMutex
+	store           map[string]models.Workspace
+	members         map[memberKey]models.Member
+	auditRepository auditRep.RepositoryI
+}
+
+// New returns the repository writing audit records of changes to auditRepository,
+// records are dropped if it is nil.
+func New(auditRepository auditRep.RepositoryI) repository.RepositoryI {
+	createdAt := time.Now()
+	return &workspaceRepository{
+		store:           map[string]models.Workspace{
+			models.DefaultWorkspace: {ID: models.DefaultWorkspace, Name: "Default", CreatedAt: &createdAt},
+		},
+		members:         make(map[memberKey]models.Member),
+		auditRepository: auditRepository,
+	}
+}
+
+func (dbWorkspace *workspaceRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace,
+	audit *models.AuditRecord) error {
+	dbWorkspace.mx.Lock()
+	defer dbWorkspace.mx.Unlock()
+
+	if _, ok := dbWorkspace.store[workspace.ID]; ok {
+		return models.ErrConflict
+	}
+
+	createdAt := time.Now()
+	workspace.CreatedAt = &createdAt
+	if err := dbWorkspace.createAuditRecord(ctx, audit, nil, *workspace); err != nil {
+		return err
+	}
+	dbWorkspace.store[workspace.ID] = *workspace
+	return nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	dbWorkspace.mx.RLock()
+	workspace, ok := dbWorkspace.store[id]
+	dbWorkspace.mx.RUnlock()
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return &workspace, nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspaces(ctx context.Context) ([]models.Workspace, error) {
+	dbWorkspace.mx.RLock()
+	workspaces := make([]models.Workspace, 0, len(dbWorkspace.store))
+	for _, workspace := range dbWorkspace.store {
+		workspaces = append(workspaces, workspace)
+	}
+	dbWorkspace.mx.RUnlock()
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].ID < workspaces[j].ID
+	})
+	return workspaces, nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectMember(ctx context.Context, workspaceID string,
+	ownerID string) (*models.Member, error) {
+	dbWorkspace.mx.RLock()
+	member, ok := dbWorkspace.members[memberKey{workspaceID, ownerID}]
+	dbWorkspace.mx.RUnlock()
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return &member, nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectMembers(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	dbWorkspace.mx.RLock()
+	members := make([]models.Member, 0)
+	for key, member := range dbWorkspace.members {
+		if key.workspaceID == workspaceID {
+			members = append(members, member)
+		}
+	}
+	dbWorkspace.mx.RUnlock()
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].OwnerID < members[j].OwnerID
+	})
+	return members, nil
+}
+
+func (dbWorkspace *workspaceRepository) SaveMember(ctx context.Context, member *models.Member,
+	audit *models.AuditRecord) (*models.Member, error) {
+	dbWorkspace.mx.Lock()
+	defer dbWorkspace.mx.Unlock()
+
+	if _, ok := dbWorkspace.store[member.WorkspaceID]; !ok {
+		return nil, models.ErrNotFound
+	}
+	if member.Role != models.RoleOwner && dbWorkspace.isLastOwner(member.WorkspaceID, member.OwnerID) {
+		return nil, models.ErrConflict
+	}
+
+	key := memberKey{member.WorkspaceID, member.OwnerID}
+	before, ok := dbWorkspace.members[key]
+	var beforeState interface{}
+	if ok {
+		member.CreatedAt = before.CreatedAt
+		beforeState = before
+	} else {
+		createdAt := time.Now()
+		member.CreatedAt = &createdAt
+	}
+	if err := dbWorkspace.createAuditRecord(ctx, audit, beforeState, *member); err != nil {
+		return nil, err
+	}
+	dbWorkspace.members[key] = *member
+
+	if !ok {
+		return nil, nil
+	}
+	return &before, nil
+}
+
+func (dbWorkspace *workspaceRepository) DeleteMember(ctx context.Context, workspaceID string,
+	ownerID string, audit *models.AuditRecord) (*models.Member, error) {
+	dbWorkspace.mx.Lock()
+	defer dbWorkspace.mx.Unlock()
+
+	key := memberKey{workspaceID, ownerID}
+	member, ok := dbWorkspace.members[key]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if dbWorkspace.isLastOwner(workspaceID, ownerID) {
+		return nil, models.ErrConflict
+	}
+
+	if err := dbWorkspace.createAuditRecord(ctx, audit, member, nil); err != nil {
+		return nil, err
+	}
+	delete(dbWorkspace.members, key)
+	return &member, nil
+}
+
+// isLastOwner reports whether ownerID is the only owner of the workspace, the store must be locked.
+func (dbWorkspace *workspaceRepository) isLastOwner(workspaceID string, ownerID string) bool {
+	owners := 0
+	for key, member := range dbWorkspace.members {
+		if key.workspaceID != workspaceID || member.Role != models.RoleOwner {
+			continue
+		}
+		if key.ownerID != ownerID {
+			return false
+		}
+		owners++
+	}
+	return owners == 1
+}
+
+// createAuditRecord writes the record of the change unless it is nil, before the change
+// is made. The store must be locked.
+func (dbWorkspace *workspaceRepository) createAuditRecord(ctx context.Context, audit *models.AuditRecord,
+	before interface{}, after interface{}) error {
+	if audit == nil || dbWorkspace.auditRepository == nil {
+		return nil
+	}
+	if err := audit.SetStates(before, after); err != nil {
+		return err
+	}
+	return dbWorkspace.auditRepository.CreateRecord(ctx, audit)
+}
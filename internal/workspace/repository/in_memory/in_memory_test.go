@@ -0,0 +1,114 @@
+package in_memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	auditMocks "github.com/kuzkuss/url_service/internal/audit/repository/mocks"
+	workspaceRep "github.com/kuzkuss/url_service/internal/workspace/repository/in_memory"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestRepositoryWorkspaces(t *testing.T) {
+	repository := workspaceRep.New(nil)
+
+	workspace, err := repository.SelectWorkspace(context.Background(), models.DefaultWorkspace)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultWorkspace, workspace.ID)
+
+	require.NoError(t, repository.CreateWorkspace(context.Background(), &models.Workspace{ID: "team", Name: "Team"}, nil))
+	assert.Equal(t, models.ErrConflict, repository.CreateWorkspace(context.Background(), &models.Workspace{ID: "team"}, nil))
+
+	_, err = repository.SelectWorkspace(context.Background(), "other")
+	assert.Equal(t, models.ErrNotFound, err)
+
+	workspaces, err := repository.SelectWorkspaces(context.Background())
+	require.NoError(t, err)
+	require.Len(t, workspaces, 2)
+	assert.Equal(t, "team", workspaces[1].ID)
+	assert.Equal(t, "Team", workspaces[1].Name)
+}
+
+func TestRepositoryMembers(t *testing.T) {
+	repository := workspaceRep.New(nil)
+	require.NoError(t, repository.CreateWorkspace(context.Background(), &models.Workspace{ID: "team"}, nil))
+
+	_, err := repository.SaveMember(context.Background(), &models.Member{WorkspaceID: "other", OwnerID: "owner",
+		Role: models.RoleOwner}, nil)
+	assert.Equal(t, models.ErrNotFound, err)
+
+	before, err := repository.SaveMember(context.Background(), &models.Member{WorkspaceID: "team", OwnerID: "owner",
+		Role: models.RoleOwner}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, before)
+
+	editor := &models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleViewer}
+	_, err = repository.SaveMember(context.Background(), editor, nil)
+	require.NoError(t, err)
+	createdAt := editor.CreatedAt
+
+	editor = &models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor}
+	before, err = repository.SaveMember(context.Background(), editor, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, before.Role)
+	assert.Equal(t, createdAt, editor.CreatedAt)
+
+	// the last owner is neither demoted nor removed
+	_, err = repository.SaveMember(context.Background(), &models.Member{WorkspaceID: "team", OwnerID: "owner",
+		Role: models.RoleAdmin}, nil)
+	assert.Equal(t, models.ErrConflict, err)
+	_, err = repository.DeleteMember(context.Background(), "team", "owner", nil)
+	assert.Equal(t, models.ErrConflict, err)
+
+	member, err := repository.SelectMember(context.Background(), "team", "editor")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, member.Role)
+
+	_, err = repository.SelectMember(context.Background(), models.DefaultWorkspace, "editor")
+	assert.Equal(t, models.ErrNotFound, err)
+
+	members, err := repository.SelectMembers(context.Background(), "team")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "editor", members[0].OwnerID)
+	assert.Equal(t, "owner", members[1].OwnerID)
+
+	deleted, err := repository.DeleteMember(context.Background(), "team", "editor", nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, deleted.Role)
+
+	_, err = repository.DeleteMember(context.Background(), "team", "editor", nil)
+	assert.Equal(t, models.ErrNotFound, err)
+}
+
+func TestRepositoryMembersAudit(t *testing.T) {
+	auditErr := errors.New("error")
+
+	mockAuditRepo := auditMocks.NewRepositoryI(t)
+	mockAuditRepo.On("CreateRecord", mock.Anything, mock.MatchedBy(func(record *models.AuditRecord) bool {
+		return record.Before == nil && string(record.After) != ""
+	})).Return(nil).Once()
+	mockAuditRepo.On("CreateRecord", mock.Anything, mock.MatchedBy(func(record *models.AuditRecord) bool {
+		return record.Action == models.AuditMemberDelete
+	})).Return(auditErr).Once()
+
+	repository := workspaceRep.New(mockAuditRepo)
+	require.NoError(t, repository.CreateWorkspace(context.Background(), &models.Workspace{ID: "team"}, nil))
+
+	_, err := repository.SaveMember(context.Background(), &models.Member{WorkspaceID: "team", OwnerID: "editor",
+		Role: models.RoleEditor}, &models.AuditRecord{Action: models.AuditMemberUpdate})
+	require.NoError(t, err)
+
+	// the member is kept if the deletion can not be recorded
+	_, err = repository.DeleteMember(context.Background(), "team", "editor",
+		&models.AuditRecord{Action: models.AuditMemberDelete})
+	assert.Equal(t, auditErr, err)
+
+	_, err = repository.SelectMember(context.Background(), "team", "editor")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/internal/workspace/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "workspace"
+
+type workspaceRepository struct {
+	repository repository.RepositoryI
+	metrics    *observability.RepositoryMetrics
+}
+
+// New wraps workspace repository observing latency of every query.
+func New(repo repository.RepositoryI, metrics *observability.RepositoryMetrics) repository.RepositoryI {
+	return &workspaceRepository{
+		repository: repo,
+		metrics:    metrics,
+	}
+}
+
+func (dbWorkspace *workspaceRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace,
+	audit *models.AuditRecord) error {
+	start := time.Now()
+	err := dbWorkspace.repository.CreateWorkspace(ctx, workspace, audit)
+	dbWorkspace.metrics.Observe(repositoryName, "CreateWorkspace", start, err)
+	return err
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	start := time.Now()
+	workspace, err := dbWorkspace.repository.SelectWorkspace(ctx, id)
+	dbWorkspace.metrics.Observe(repositoryName, "SelectWorkspace", start, err)
+	return workspace, err
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspaces(ctx context.Context) ([]models.Workspace, error) {
+	start := time.Now()
+	workspaces, err := dbWorkspace.repository.SelectWorkspaces(ctx)
+	dbWorkspace.metrics.Observe(repositoryName, "SelectWorkspaces", start, err)
+	return workspaces, err
+}
+
+func (dbWorkspace *workspaceRepository) SelectMember(ctx context.Context, workspaceID string,
+	ownerID string) (*models.Member, error) {
+	start := time.Now()
+	member, err := dbWorkspace.repository.SelectMember(ctx, workspaceID, ownerID)
+	dbWorkspace.metrics.Observe(repositoryName, "SelectMember", start, err)
+	return member, err
+}
+
+func (dbWorkspace *workspaceRepository) SelectMembers(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	start := time.Now()
+	members, err := dbWorkspace.repository.SelectMembers(ctx, workspaceID)
+	dbWorkspace.metrics.Observe(repositoryName, "SelectMembers", start, err)
+	return members, err
+}
+
+func (dbWorkspace *workspaceRepository) SaveMember(ctx context.Context, member *models.Member,
+	audit *models.AuditRecord) (*models.Member, error) {
+	start := time.Now()
+	before, err := dbWorkspace.repository.SaveMember(ctx, member, audit)
+	dbWorkspace.metrics.Observe(repositoryName, "SaveMember", start, err)
+	return before, err
+}
+
+func (dbWorkspace *workspaceRepository) DeleteMember(ctx context.Context, workspaceID string,
+	ownerID string, audit *models.AuditRecord) (*models.Member, error) {
+	start := time.Now()
+	deleted, err := dbWorkspace.repository.DeleteMember(ctx, workspaceID, ownerID, audit)
+	dbWorkspace.metrics.Observe(repositoryName, "DeleteMember", start, err)
+	return deleted, err
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kuzkuss/url_service/internal/workspace/repository"
+	"github.com/kuzkuss/url_service/models"
+	"github.com/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type workspaceRepository struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) repository.RepositoryI {
+	return &workspaceRepository{
+		db: db,
+	}
+}
+
+func (dbWorkspace *workspaceRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace,
+	audit *models.AuditRecord) error {
+	err := dbWorkspace.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Returning{}).Create(workspace).Error; err != nil {
+			return err
+		}
+		return createAuditRecord(tx, audit, nil, *workspace)
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.ErrConflict
+	} else if err != nil {
+		return errors.Wrap(err, "database error (table workspaces)")
+	}
+
+	return nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	workspace := models.Workspace{}
+
+	tx := dbWorkspace.db.WithContext(ctx).Where("id = ?", id).Take(&workspace)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	} else if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table workspaces)")
+	}
+
+	return &workspace, nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspaces(ctx context.Context) ([]models.Workspace, error) {
+	workspaces := make([]models.Workspace, 0)
+
+	tx := dbWorkspace.db.WithContext(ctx).Order("id").Find(&workspaces)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table workspaces)")
+	}
+
+	return workspaces, nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectMember(ctx context.Context, workspaceID string,
+	ownerID string) (*models.Member, error) {
+	member := models.Member{}
+
+	tx := dbWorkspace.db.WithContext(ctx).Where("workspace_id = ? AND owner_id = ?", workspaceID, ownerID).Take(&member)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	} else if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table workspace_members)")
+	}
+
+	return &member, nil
+}
+
+func (dbWorkspace *workspaceRepository) SelectMembers(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	members := make([]models.Member, 0)
+
+	tx := dbWorkspace.db.WithContext(ctx).Where("workspace_id = ?", workspaceID).Order("owner_id").Find(&members)
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "database error (table workspace_members)")
+	}
+
+	return members, nil
+}
+
+func (dbWorkspace *workspaceRepository) SaveMember(ctx context.Context, member *models.Member,
+	audit *models.AuditRecord) (*models.Member, error) {
+	var before *models.Member
+	err := dbWorkspace.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lastOwner, err := isLastOwner(tx, member.WorkspaceID, member.OwnerID)
+		if err != nil {
+			return err
+		}
+		if lastOwner && member.Role != models.RoleOwner {
+			return models.ErrConflict
+		}
+
+		members := make([]models.Member, 0, 1)
+		err = tx.Where("workspace_id = ? AND owner_id = ?", member.WorkspaceID, member.OwnerID).Limit(1).Find(&members).Error
+		if err != nil {
+			return err
+		}
+		var beforeState interface{}
+		if len(members) > 0 {
+			before = &members[0]
+			beforeState = members[0]
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "owner_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}, clause.Returning{}).Create(member).Error
+		if err != nil {
+			return err
+		}
+		return createAuditRecord(tx, audit, beforeState, *member)
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.Is(err, models.ErrConflict) {
+		return nil, models.ErrConflict
+	} else if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return nil, models.ErrNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "database error (table workspace_members)")
+	}
+
+	return before, nil
+}
+
+func (dbWorkspace *workspaceRepository) DeleteMember(ctx context.Context, workspaceID string,
+	ownerID string, audit *models.AuditRecord) (*models.Member, error) {
+	deleted := make([]models.Member, 0, 1)
+	err := dbWorkspace.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lastOwner, err := isLastOwner(tx, workspaceID, ownerID)
+		if err != nil {
+			return err
+		}
+		if lastOwner {
+			return models.ErrConflict
+		}
+
+		err = tx.Clauses(clause.Returning{}).
+			Where("workspace_id = ? AND owner_id = ?", workspaceID, ownerID).
+			Delete(&deleted).Error
+		if err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return models.ErrNotFound
+		}
+		return createAuditRecord(tx, audit, deleted[0], nil)
+	})
+
+	if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrConflict) {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.Wrap(err, "database error (table workspace_members)")
+	}
+
+	return &deleted[0], nil
+}
+
+// isLastOwner reports whether ownerID is the only owner of the workspace. Owners
+// are locked until the end of tx, so concurrent changes can not remove all of them.
+func isLastOwner(tx *gorm.DB, workspaceID string, ownerID string) (bool, error) {
+	var owners []string
+	err := tx.Model(&models.Member{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("workspace_id = ? AND role = ?", workspaceID, models.RoleOwner).
+		Pluck("owner_id", &owners).Error
+	if err != nil {
+		return false, err
+	}
+
+	return len(owners) == 1 && owners[0] == ownerID, nil
+}
+
+// createAuditRecord writes the record of the change in tx unless it is nil.
+func createAuditRecord(tx *gorm.DB, audit *models.AuditRecord, before interface{}, after interface{}) error {
+	if audit == nil {
+		return nil
+	}
+	if err := audit.SetStates(before, after); err != nil {
+		return err
+	}
+	return tx.Create(audit).Error
+}
@@ -0,0 +1,279 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kuzkuss/url_service/models"
+	workspaceRep "github.com/kuzkuss/url_service/internal/workspace/repository/postgres"
+)
+
+func newGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gdb, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gdb.Logger.LogMode(logger.Info)
+
+	return gdb, mock
+}
+
+func TestRepositoryCreateWorkspace(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	createdAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := regexp.QuoteMeta(`INSERT INTO "workspaces" ("name","id") VALUES ($1,$2) RETURNING *`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("Team", "team").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow("team", "Team", createdAt))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs("", "team").WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	repository := workspaceRep.New(gdb)
+
+	workspace := &models.Workspace{ID: "team", Name: "Team"}
+	require.NoError(t, repository.CreateWorkspace(context.Background(), workspace, nil))
+	require.NotNil(t, workspace.CreatedAt)
+	assert.True(t, createdAt.Equal(*workspace.CreatedAt))
+
+	err := repository.CreateWorkspace(context.Background(), &models.Workspace{ID: "team"}, nil)
+	assert.Equal(t, models.ErrConflict, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositorySelectWorkspace(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	query := regexp.QuoteMeta(`SELECT * FROM "workspaces" WHERE id = $1 LIMIT 1`)
+
+	mock.ExpectQuery(query).WithArgs("team").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("team", "Team"))
+	mock.ExpectQuery(query).WithArgs("other").WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(query).WithArgs("error").WillReturnError(errors.New("error"))
+
+	repository := workspaceRep.New(gdb)
+
+	workspace, err := repository.SelectWorkspace(context.Background(), "team")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Workspace{ID: "team", Name: "Team"}, workspace)
+
+	_, err = repository.SelectWorkspace(context.Background(), "other")
+	assert.Equal(t, models.ErrNotFound, err)
+
+	_, err = repository.SelectWorkspace(context.Background(), "error")
+	assert.Error(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositorySelectWorkspaces(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workspaces" ORDER BY id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("default", "Default").AddRow("team", "Team"))
+
+	repository := workspaceRep.New(gdb)
+
+	workspaces, err := repository.SelectWorkspaces(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []models.Workspace{{ID: "default", Name: "Default"}, {ID: "team", Name: "Team"}}, workspaces)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositorySelectMembers(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workspace_members" WHERE workspace_id = $1 AND owner_id = $2 LIMIT 1`)).
+		WithArgs("team", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role"}).AddRow("team", "owner", "owner"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workspace_members" WHERE workspace_id = $1 AND owner_id = $2 LIMIT 1`)).
+		WithArgs("team", "other").WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workspace_members" WHERE workspace_id = $1 ORDER BY owner_id`)).
+		WithArgs("team").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role"}).
+			AddRow("team", "editor", "editor").
+			AddRow("team", "owner", "owner"))
+
+	repository := workspaceRep.New(gdb)
+
+	member, err := repository.SelectMember(context.Background(), "team", "owner")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Member{WorkspaceID: "team", OwnerID: "owner", Role: models.RoleOwner}, member)
+
+	_, err = repository.SelectMember(context.Background(), "team", "other")
+	assert.Equal(t, models.ErrNotFound, err)
+
+	members, err := repository.SelectMembers(context.Background(), "team")
+	require.NoError(t, err)
+	assert.Equal(t, []models.Member {
+		{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor},
+		{WorkspaceID: "team", OwnerID: "owner", Role: models.RoleOwner},
+	}, members)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositorySaveMember(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	createdAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	ownersQuery := regexp.QuoteMeta(`SELECT "owner_id" FROM "workspace_members" WHERE workspace_id = $1 AND role = $2 FOR UPDATE`)
+	selectQuery := regexp.QuoteMeta(`SELECT * FROM "workspace_members" WHERE workspace_id = $1 AND owner_id = $2 LIMIT 1`)
+	query := regexp.QuoteMeta(`INSERT INTO "workspace_members" ("workspace_id","owner_id","role") VALUES ($1,$2,$3) ` +
+		`ON CONFLICT ("workspace_id","owner_id") DO UPDATE SET "role"="excluded"."role" RETURNING *`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow("owner"))
+	mock.ExpectQuery(selectQuery).WithArgs("team", "editor").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role", "created_at"}).
+			AddRow("team", "editor", "viewer", createdAt))
+	mock.ExpectQuery(query).WithArgs("team", "editor", "editor").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role", "created_at"}).
+			AddRow("team", "editor", "editor", createdAt))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow("owner"))
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("other", "owner").WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+	mock.ExpectQuery(selectQuery).WithArgs("other", "owner").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role"}))
+	mock.ExpectQuery(query).WithArgs("other", "owner", "owner").WillReturnError(&pgconn.PgError{Code: "23503"})
+	mock.ExpectRollback()
+
+	repository := workspaceRep.New(gdb)
+
+	member := &models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor}
+	before, err := repository.SaveMember(context.Background(), member, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, before.Role)
+	require.NotNil(t, member.CreatedAt)
+	assert.True(t, createdAt.Equal(*member.CreatedAt))
+
+	_, err = repository.SaveMember(context.Background(), &models.Member{WorkspaceID: "team", OwnerID: "owner",
+		Role: models.RoleAdmin}, nil)
+	assert.Equal(t, models.ErrConflict, err)
+
+	_, err = repository.SaveMember(context.Background(), &models.Member{WorkspaceID: "other", OwnerID: "owner",
+		Role: models.RoleOwner}, nil)
+	assert.Equal(t, models.ErrNotFound, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDeleteMember(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	ownersQuery := regexp.QuoteMeta(`SELECT "owner_id" FROM "workspace_members" WHERE workspace_id = $1 AND role = $2 FOR UPDATE`)
+	query := regexp.QuoteMeta(`DELETE FROM "workspace_members" WHERE workspace_id = $1 AND owner_id = $2 RETURNING *`)
+	owners := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"owner_id"}).AddRow("owner")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", "owner").WillReturnRows(owners())
+	mock.ExpectQuery(query).WithArgs("team", "editor").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role"}).AddRow("team", "editor", "editor"))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", "owner").WillReturnRows(owners())
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", "owner").WillReturnRows(owners())
+	mock.ExpectQuery(query).WithArgs("team", "unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "owner_id", "role"}))
+	mock.ExpectRollback()
+
+	repository := workspaceRep.New(gdb)
+
+	deleted, err := repository.DeleteMember(context.Background(), "team", "editor", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &models.Member{WorkspaceID: "team", OwnerID: "editor", Role: models.RoleEditor}, deleted)
+
+	_, err = repository.DeleteMember(context.Background(), "team", "owner", nil)
+	assert.Equal(t, models.ErrConflict, err)
+
+	_, err = repository.DeleteMember(context.Background(), "team", "unknown", nil)
+	assert.Equal(t, models.ErrNotFound, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryDeleteMemberAudit(t *testing.T) {
+	gdb, mock := newGormMock(t)
+
+	ownersQuery := regexp.QuoteMeta(`SELECT "owner_id" FROM "workspace_members" WHERE workspace_id = $1 AND role = $2 FOR UPDATE`)
+	query := regexp.QuoteMeta(`DELETE FROM "workspace_members" WHERE workspace_id = $1 AND owner_id = $2 RETURNING *`)
+	auditQuery := regexp.QuoteMeta(`INSERT INTO "audit_log" ("created_at","actor","action","resource","before_state",` +
+		`"after_state","transport","client_ip","request_id") VALUES ($1,$2,$3,$4,$5,(NULL),$6,$7,$8) RETURNING "id"`)
+	deletedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"workspace_id", "owner_id", "role"}).AddRow("team", "editor", "editor")
+	}
+	now := time.Now()
+	auditErr := errors.New("error")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", models.RoleOwner).WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+	mock.ExpectQuery(query).WithArgs("team", "editor").WillReturnRows(deletedRows())
+	mock.ExpectQuery(auditQuery).
+		WithArgs(now, "owner", models.AuditMemberDelete, "team/editor",
+			[]byte(`{"workspace_id":"team","owner_id":"editor","role":"editor"}`), "", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	// the member is kept if the deletion can not be recorded
+	mock.ExpectBegin()
+	mock.ExpectQuery(ownersQuery).WithArgs("team", models.RoleOwner).WillReturnRows(sqlmock.NewRows([]string{"owner_id"}))
+	mock.ExpectQuery(query).WithArgs("team", "editor").WillReturnRows(deletedRows())
+	mock.ExpectQuery(auditQuery).WillReturnError(auditErr)
+	mock.ExpectRollback()
+
+	repository := workspaceRep.New(gdb)
+
+	record := &models.AuditRecord{Time: now, Actor: "owner", Action: models.AuditMemberDelete, Resource: "team/editor"}
+	_, err := repository.DeleteMember(context.Background(), "team", "editor", record)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), record.ID)
+
+	_, err = repository.DeleteMember(context.Background(), "team", "editor",
+		&models.AuditRecord{Time: now, Actor: "owner", Action: models.AuditMemberDelete, Resource: "team/editor"})
+	assert.Equal(t, auditErr, errors.Cause(err))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+// RepositoryI stores workspaces and their members. The default workspace always exists.
+// Changes write the audit record with states of the workspace or the member atomically
+// with the change unless it is nil, so the change fails if it can not be recorded.
+type RepositoryI interface {
+	// CreateWorkspace fails with models.ErrConflict if the workspace exists.
+	CreateWorkspace(ctx context.Context, workspace *models.Workspace, audit *models.AuditRecord) (error)
+	SelectWorkspace(ctx context.Context, id string) (*models.Workspace, error)
+	SelectWorkspaces(ctx context.Context) ([]models.Workspace, error)
+	SelectMember(ctx context.Context, workspaceID string, ownerID string) (*models.Member, error)
+	// SelectMembers returns members of the workspace ordered by owner.
+	SelectMembers(ctx context.Context, workspaceID string) ([]models.Member, error)
+	// SaveMember adds the member or changes its role and returns the previous membership,
+	// nil for a new member. SaveMember and DeleteMember fail with models.ErrConflict
+	// if the workspace would lose its last owner and with models.ErrNotFound
+	// if the workspace or the member does not exist.
+	SaveMember(ctx context.Context, member *models.Member, audit *models.AuditRecord) (*models.Member, error)
+	DeleteMember(ctx context.Context, workspaceID string, ownerID string, audit *models.AuditRecord) (*models.Member, error)
+}
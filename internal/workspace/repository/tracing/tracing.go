@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/internal/observability"
+	"github.com/kuzkuss/url_service/internal/workspace/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+const repositoryName = "workspace"
+
+type workspaceRepository struct {
+	repository repository.RepositoryI
+	tracing    *observability.RepositoryTracing
+}
+
+// New wraps workspace repository starting span of every query.
+func New(repo repository.RepositoryI, tracing *observability.RepositoryTracing) repository.RepositoryI {
+	return &workspaceRepository{
+		repository: repo,
+		tracing:    tracing,
+	}
+}
+
+func (dbWorkspace *workspaceRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace,
+	audit *models.AuditRecord) error {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "CreateWorkspace")
+	err := dbWorkspace.repository.CreateWorkspace(ctx, workspace, audit)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "SelectWorkspace")
+	workspace, err := dbWorkspace.repository.SelectWorkspace(ctx, id)
+	observability.EndSpan(span, err)
+	return workspace, err
+}
+
+func (dbWorkspace *workspaceRepository) SelectWorkspaces(ctx context.Context) ([]models.Workspace, error) {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "SelectWorkspaces")
+	workspaces, err := dbWorkspace.repository.SelectWorkspaces(ctx)
+	observability.EndSpan(span, err)
+	return workspaces, err
+}
+
+func (dbWorkspace *workspaceRepository) SelectMember(ctx context.Context, workspaceID string,
+	ownerID string) (*models.Member, error) {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "SelectMember")
+	member, err := dbWorkspace.repository.SelectMember(ctx, workspaceID, ownerID)
+	observability.EndSpan(span, err)
+	return member, err
+}
+
+func (dbWorkspace *workspaceRepository) SelectMembers(ctx context.Context, workspaceID string) ([]models.Member, error) {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "SelectMembers")
+	members, err := dbWorkspace.repository.SelectMembers(ctx, workspaceID)
+	observability.EndSpan(span, err)
+	return members, err
+}
+
+func (dbWorkspace *workspaceRepository) SaveMember(ctx context.Context, member *models.Member,
+	audit *models.AuditRecord) (*models.Member, error) {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "SaveMember")
+	before, err := dbWorkspace.repository.SaveMember(ctx, member, audit)
+	observability.EndSpan(span, err)
+	return before, err
+}
+
+func (dbWorkspace *workspaceRepository) DeleteMember(ctx context.Context, workspaceID string,
+	ownerID string, audit *models.AuditRecord) (*models.Member, error) {
+	ctx, span := dbWorkspace.tracing.Start(ctx, repositoryName, "DeleteMember")
+	deleted, err := dbWorkspace.repository.DeleteMember(ctx, workspaceID, ownerID, audit)
+	observability.EndSpan(span, err)
+	return deleted, err
+}
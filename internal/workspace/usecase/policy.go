@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+// Action is an operation permitted by the role of the principal in its workspace.
+type Action string
+
+const (
+	// listing and watching links
+	ActionLinksRead   Action = "links.read"
+	ActionLinksCreate Action = "links.create"
+	// changing and deleting links
+	ActionLinksWrite   Action = "links.write"
+	ActionMembersRead  Action = "members.read"
+	ActionMembersWrite Action = "members.write"
+)
+
+// minRoles are the lowest roles permitted to perform actions in the workspace.
+var minRoles = map[Action]models.Role{
+	ActionLinksRead:    models.RoleViewer,
+	ActionLinksCreate:  models.RoleEditor,
+	ActionLinksWrite:   models.RoleEditor,
+	ActionMembersRead:  models.RoleViewer,
+	ActionMembersWrite: models.RoleAdmin,
+}
+
+// ownActions are permitted in the default workspace on own links to principals
+// whose role does not permit them, including principals without role, as before
+// roles were introduced. Other workspaces are available to their members only.
+var ownActions = map[Action]bool{
+	ActionLinksRead:   true,
+	ActionLinksCreate: true,
+	ActionLinksWrite:  true,
+}
+
+// Authorize checks whether principal may perform action in its workspace. It reports
+// whether the action applies to links of every member of the workspace; otherwise only
+// links of the principal are available. It fails with models.ErrForbidden if the action
+// is not permitted. Administrators act as owners of every workspace.
+func Authorize(principal *models.Principal, action Action) (allMembers bool, err error) {
+	if principal == nil {
+		return false, models.ErrUnauthorized
+	}
+
+	role := EffectiveRole(principal)
+	if role.Includes(minRoles[action]) {
+		return true, nil
+	}
+
+	if ownActions[action] && isDefault(principal.WorkspaceID) {
+		return false, nil
+	}
+
+	if role == "" {
+		return false, errors.Wrapf(models.ErrForbidden, "%s requires a role in the workspace", action)
+	}
+	return false, errors.Wrapf(models.ErrForbidden, "role %s does not permit %s", role, action)
+}
+
+func isDefault(workspaceID string) bool {
+	return workspaceID == "" || workspaceID == models.DefaultWorkspace
+}
+
+// EffectiveRole returns the role of principal in its workspace, the owner role for administrators.
+func EffectiveRole(principal *models.Principal) models.Role {
+	if principal.HasScope(models.ScopeAdmin) {
+		return models.RoleOwner
+	}
+	return principal.Role
+}
@@ -0,0 +1,92 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestAuthorize(t *testing.T) {
+	actions := []workspaceUsecase.Action{
+		workspaceUsecase.ActionLinksRead,
+		workspaceUsecase.ActionLinksCreate,
+		workspaceUsecase.ActionLinksWrite,
+		workspaceUsecase.ActionMembersRead,
+		workspaceUsecase.ActionMembersWrite,
+	}
+
+	type expected struct {
+		Permitted  []bool
+		AllMembers bool
+	}
+
+	cases := map[string]struct {
+		Principal *models.Principal
+		expected
+	}{
+		"not_member": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: "team"},
+			expected:  expected{Permitted: []bool{false, false, false, false, false}},
+		},
+		"not_member_of_default_workspace": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: models.DefaultWorkspace},
+			expected:  expected{Permitted: []bool{true, true, true, false, false}, AllMembers: false},
+		},
+		"viewer": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleViewer},
+			expected:  expected{Permitted: []bool{true, false, false, true, false}, AllMembers: true},
+		},
+		"editor": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleEditor},
+			expected:  expected{Permitted: []bool{true, true, true, true, false}, AllMembers: true},
+		},
+		"admin": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleAdmin},
+			expected:  expected{Permitted: []bool{true, true, true, true, true}, AllMembers: true},
+		},
+		"owner": {
+			Principal: &models.Principal{OwnerID: "owner", WorkspaceID: "team", Role: models.RoleOwner},
+			expected:  expected{Permitted: []bool{true, true, true, true, true}, AllMembers: true},
+		},
+		"administrator": {
+			Principal: &models.Principal{Scopes: []string{models.ScopeAdmin}},
+			expected:  expected{Permitted: []bool{true, true, true, true, true}, AllMembers: true},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			for idx, action := range actions {
+				allMembers, err := workspaceUsecase.Authorize(test.Principal, action)
+				if !test.Permitted[idx] {
+					require.Equal(t, models.ErrForbidden, errors.Cause(err), action)
+					continue
+				}
+				require.NoError(t, err, action)
+				assert.Equal(t, test.AllMembers, allMembers, action)
+			}
+		})
+	}
+
+	t.Run("viewer_of_default_workspace_changes_own_links", func(t *testing.T) {
+		viewer := &models.Principal{OwnerID: "owner", WorkspaceID: models.DefaultWorkspace, Role: models.RoleViewer}
+
+		allMembers, err := workspaceUsecase.Authorize(viewer, workspaceUsecase.ActionLinksRead)
+		require.NoError(t, err)
+		assert.True(t, allMembers)
+
+		allMembers, err = workspaceUsecase.Authorize(viewer, workspaceUsecase.ActionLinksCreate)
+		require.NoError(t, err)
+		assert.False(t, allMembers)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := workspaceUsecase.Authorize(nil, workspaceUsecase.ActionLinksRead)
+		require.Equal(t, models.ErrUnauthorized, errors.Cause(err))
+	})
+}
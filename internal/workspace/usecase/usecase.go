@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	"github.com/kuzkuss/url_service/internal/observability"
+	workspaceRep "github.com/kuzkuss/url_service/internal/workspace/repository"
+	"github.com/kuzkuss/url_service/models"
+)
+
+type UseCaseI interface {
+	// CreateWorkspace fails with models.ErrConflict if the workspace exists.
+	CreateWorkspace(ctx context.Context, workspace *models.Workspace) (error)
+	GetWorkspace(ctx context.Context, id string) (*models.Workspace, error)
+	GetWorkspaces(ctx context.Context) ([]models.Workspace, error)
+	// GetRole returns the role of the owner in the workspace, empty if the owner is not its member.
+	GetRole(ctx context.Context, workspaceID string, ownerID string) (models.Role, error)
+	// GetMembers, SaveMember and DeleteMember manage members of the workspace of principal,
+	// administrators manage members of every workspace. They fail with models.ErrForbidden
+	// if it is not permitted by the role of principal: only owners grant and revoke the owner role.
+	GetMembers(ctx context.Context, principal *models.Principal, workspaceID string) ([]models.Member, error)
+	SaveMember(ctx context.Context, principal *models.Principal, member *models.Member) (error)
+	DeleteMember(ctx context.Context, principal *models.Principal, workspaceID string, ownerID string) (error)
+}
+
+type useCase struct {
+	workspaceRepository workspaceRep.RepositoryI
+	auditUC             auditUsecase.UseCaseI
+	logger              *slog.Logger
+}
+
+// New creates workspace usecase. Created workspaces and changes of members are recorded
+// in the audit log by auditUC unless it is nil.
+func New(workspaceRepository workspaceRep.RepositoryI, auditUC auditUsecase.UseCaseI, logger *slog.Logger) UseCaseI {
+	return &useCase{
+		workspaceRepository: workspaceRepository,
+		auditUC:             auditUC,
+		logger:              logger,
+	}
+}
+
+func (uc *useCase) CreateWorkspace(ctx context.Context, workspace *models.Workspace) (err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.CreateWorkspace")
+	defer func() { observability.EndSpan(span, err) }()
+
+	err = uc.workspaceRepository.CreateWorkspace(ctx, workspace,
+		uc.auditRecord(ctx, models.AuditWorkspaceCreate, workspace.ID))
+	if err != nil {
+		return errors.Wrap(err, "workspace repository error")
+	}
+
+	uc.logger.InfoContext(ctx, "workspace created", "workspace_id", workspace.ID)
+	return nil
+}
+
+func (uc *useCase) GetWorkspace(ctx context.Context, id string) (_ *models.Workspace, err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.GetWorkspace")
+	defer func() { observability.EndSpan(span, err) }()
+
+	workspace, err := uc.workspaceRepository.SelectWorkspace(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "workspace repository error")
+	}
+
+	return workspace, nil
+}
+
+func (uc *useCase) GetWorkspaces(ctx context.Context) (_ []models.Workspace, err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.GetWorkspaces")
+	defer func() { observability.EndSpan(span, err) }()
+
+	workspaces, err := uc.workspaceRepository.SelectWorkspaces(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "workspace repository error")
+	}
+
+	return workspaces, nil
+}
+
+func (uc *useCase) GetRole(ctx context.Context, workspaceID string, ownerID string) (_ models.Role, err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.GetRole")
+	defer func() { observability.EndSpan(span, err) }()
+
+	member, err := uc.workspaceRepository.SelectMember(ctx, workspaceID, ownerID)
+	if errors.Is(err, models.ErrNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrap(err, "workspace repository error")
+	}
+
+	return member.Role, nil
+}
+
+func (uc *useCase) GetMembers(ctx context.Context, principal *models.Principal,
+	workspaceID string) (_ []models.Member, err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.GetMembers")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if err := authorizeMembers(principal, workspaceID, ActionMembersRead); err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.workspaceRepository.SelectWorkspace(ctx, workspaceID); err != nil {
+		return nil, errors.Wrap(err, "workspace repository error")
+	}
+
+	members, err := uc.workspaceRepository.SelectMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "workspace repository error")
+	}
+
+	return members, nil
+}
+
+func (uc *useCase) SaveMember(ctx context.Context, principal *models.Principal, member *models.Member) (err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.SaveMember")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if err := authorizeMembers(principal, member.WorkspaceID, ActionMembersWrite); err != nil {
+		return err
+	}
+	if !member.Role.Valid() {
+		return errors.Wrapf(models.ErrBadRequest, "role %q is unknown", member.Role)
+	}
+
+	if _, err := uc.workspaceRepository.SelectWorkspace(ctx, member.WorkspaceID); err != nil {
+		return errors.Wrap(err, "workspace repository error")
+	}
+
+	if EffectiveRole(principal) != models.RoleOwner {
+		if member.Role == models.RoleOwner {
+			return errors.Wrap(models.ErrForbidden, "only owners grant the owner role")
+		}
+		if err := uc.checkNotOwner(ctx, member.WorkspaceID, member.OwnerID); err != nil &&
+			!errors.Is(err, models.ErrNotFound) {
+			return err
+		}
+	}
+
+	_, err = uc.workspaceRepository.SaveMember(ctx, member,
+		uc.auditRecord(ctx, models.AuditMemberUpdate, memberResource(member.WorkspaceID, member.OwnerID)))
+	if err != nil {
+		return errors.Wrap(err, "workspace repository error")
+	}
+
+	uc.logger.InfoContext(ctx, "workspace member saved", "workspace_id", member.WorkspaceID,
+		"member_id", member.OwnerID, "role", member.Role)
+	return nil
+}
+
+func (uc *useCase) DeleteMember(ctx context.Context, principal *models.Principal, workspaceID string,
+	ownerID string) (err error) {
+	ctx, span := observability.StartSpan(ctx, "workspace.usecase.DeleteMember")
+	defer func() { observability.EndSpan(span, err) }()
+
+	if err := authorizeMembers(principal, workspaceID, ActionMembersWrite); err != nil {
+		return err
+	}
+
+	if EffectiveRole(principal) != models.RoleOwner {
+		if err := uc.checkNotOwner(ctx, workspaceID, ownerID); err != nil {
+			return err
+		}
+	}
+
+	_, err = uc.workspaceRepository.DeleteMember(ctx, workspaceID, ownerID,
+		uc.auditRecord(ctx, models.AuditMemberDelete, memberResource(workspaceID, ownerID)))
+	if err != nil {
+		return errors.Wrap(err, "workspace repository error")
+	}
+
+	uc.logger.InfoContext(ctx, "workspace member deleted", "workspace_id", workspaceID, "member_id", ownerID)
+	return nil
+}
+
+// checkNotOwner fails with models.ErrForbidden if the member is an owner of the workspace,
+// so it can not be changed by principals other than owners.
+func (uc *useCase) checkNotOwner(ctx context.Context, workspaceID string, ownerID string) error {
+	member, err := uc.workspaceRepository.SelectMember(ctx, workspaceID, ownerID)
+	if err != nil {
+		return errors.Wrap(err, "workspace repository error")
+	}
+	if member.Role == models.RoleOwner {
+		return errors.Wrap(models.ErrForbidden, "only owners revoke the owner role")
+	}
+	return nil
+}
+
+// auditRecord returns the record of the change written by the repository with the change,
+// nil if changes are not recorded.
+func (uc *useCase) auditRecord(ctx context.Context, action string, resource string) *models.AuditRecord {
+	if uc.auditUC == nil {
+		return nil
+	}
+	return uc.auditUC.NewRecord(ctx, action, resource)
+}
+
+// authorizeMembers checks whether principal may perform action on members of the workspace.
+func authorizeMembers(principal *models.Principal, workspaceID string, action Action) error {
+	if _, err := Authorize(principal, action); err != nil {
+		return err
+	}
+
+	if !principal.HasScope(models.ScopeAdmin) && principal.WorkspaceID != workspaceID {
+		return errors.Wrapf(models.ErrForbidden, "workspace %q is not the workspace of the client", workspaceID)
+	}
+	return nil
+}
+
+// memberResource identifies the member in the audit log.
+func memberResource(workspaceID string, ownerID string) string {
+	return workspaceID + "/" + ownerID
+}
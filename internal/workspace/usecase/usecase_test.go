@@ -0,0 +1,163 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	auditInMem "github.com/kuzkuss/url_service/internal/audit/repository/in_memory"
+	auditUsecase "github.com/kuzkuss/url_service/internal/audit/usecase"
+	"github.com/kuzkuss/url_service/internal/observability"
+	workspaceInMem "github.com/kuzkuss/url_service/internal/workspace/repository/in_memory"
+	workspaceMocks "github.com/kuzkuss/url_service/internal/workspace/repository/mocks"
+	workspaceUsecase "github.com/kuzkuss/url_service/internal/workspace/usecase"
+	"github.com/kuzkuss/url_service/models"
+)
+
+func TestUsecaseCreateWorkspace(t *testing.T) {
+	workspace := &models.Workspace{ID: "team", Name: "Team"}
+
+	auditRepository := auditInMem.New()
+	usecase := workspaceUsecase.New(workspaceInMem.New(auditRepository),
+		auditUsecase.New(auditRepository, observability.NopLogger()), observability.NopLogger())
+
+	require.NoError(t, usecase.CreateWorkspace(context.Background(), workspace))
+	assert.NotNil(t, workspace.CreatedAt)
+
+	err := usecase.CreateWorkspace(context.Background(), &models.Workspace{ID: "team"})
+	assert.True(t, errors.Is(errors.Cause(err), models.ErrConflict))
+
+	// the failed creation is not recorded
+	records, err := auditRepository.SelectRecords(context.Background(), models.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, models.AuditWorkspaceCreate, records[0].Action)
+	assert.Equal(t, "team", records[0].Resource)
+	assert.Nil(t, records[0].Before)
+	assert.NotNil(t, records[0].After)
+
+	got, err := usecase.GetWorkspace(context.Background(), "team")
+	require.NoError(t, err)
+	assert.Equal(t, "Team", got.Name)
+
+	_, err = usecase.GetWorkspace(context.Background(), "other")
+	assert.True(t, errors.Is(errors.Cause(err), models.ErrNotFound))
+
+	workspaces, err := usecase.GetWorkspaces(context.Background())
+	require.NoError(t, err)
+	require.Len(t, workspaces, 2)
+	assert.Equal(t, models.DefaultWorkspace, workspaces[0].ID)
+	assert.Equal(t, "team", workspaces[1].ID)
+}
+
+func TestUsecaseGetWorkspacesError(t *testing.T) {
+	repoErr := errors.New("error")
+
+	mockWorkspaceRepo := workspaceMocks.NewRepositoryI(t)
+	mockWorkspaceRepo.On("SelectWorkspaces", mock.Anything).Return(nil, repoErr)
+
+	usecase := workspaceUsecase.New(mockWorkspaceRepo, nil, observability.NopLogger())
+
+	_, err := usecase.GetWorkspaces(context.Background())
+	assert.Equal(t, repoErr, errors.Cause(err))
+}
+
+func TestUsecaseMembers(t *testing.T) {
+	administrator := &models.Principal{Scopes: []string{models.ScopeAdmin}}
+	owner := &models.Principal{OwnerID: "root", WorkspaceID: "team", Role: models.RoleOwner}
+	admin := &models.Principal{OwnerID: "admin", WorkspaceID: "team", Role: models.RoleAdmin}
+	viewer := &models.Principal{OwnerID: "viewer", WorkspaceID: "team", Role: models.RoleViewer}
+	stranger := &models.Principal{OwnerID: "stranger", WorkspaceID: models.DefaultWorkspace, Role: models.RoleOwner}
+
+	auditRepository := auditInMem.New()
+	repository := workspaceInMem.New(auditRepository)
+	require.NoError(t, repository.CreateWorkspace(context.Background(), &models.Workspace{ID: "team"}, nil))
+	usecase := workspaceUsecase.New(repository, auditUsecase.New(auditRepository, observability.NopLogger()),
+		observability.NopLogger())
+
+	save := func(principal *models.Principal, ownerID string, role models.Role) error {
+		return usecase.SaveMember(context.Background(), principal,
+			&models.Member{WorkspaceID: "team", OwnerID: ownerID, Role: role})
+	}
+
+	require.NoError(t, save(administrator, "root", models.RoleOwner))
+	require.NoError(t, save(owner, "admin", models.RoleAdmin))
+	require.NoError(t, save(admin, "viewer", models.RoleViewer))
+
+	t.Run("roles", func(t *testing.T) {
+		role, err := usecase.GetRole(context.Background(), "team", "admin")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleAdmin, role)
+
+		role, err = usecase.GetRole(context.Background(), "team", "stranger")
+		require.NoError(t, err)
+		assert.Empty(t, role)
+	})
+
+	t.Run("members", func(t *testing.T) {
+		members, err := usecase.GetMembers(context.Background(), viewer, "team")
+		require.NoError(t, err)
+		require.Len(t, members, 3)
+		assert.Equal(t, "admin", members[0].OwnerID)
+
+		_, err = usecase.GetMembers(context.Background(), stranger, "team")
+		assert.Equal(t, models.ErrForbidden, errors.Cause(err))
+
+		_, err = usecase.GetMembers(context.Background(), administrator, "other")
+		assert.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		assert.Equal(t, models.ErrForbidden, errors.Cause(save(viewer, "editor", models.RoleEditor)))
+		assert.Equal(t, models.ErrForbidden, errors.Cause(save(admin, "editor", models.RoleOwner)))
+		assert.Equal(t, models.ErrForbidden, errors.Cause(save(admin, "root", models.RoleViewer)))
+		assert.Equal(t, models.ErrForbidden, errors.Cause(save(stranger, "editor", models.RoleEditor)))
+
+		err := usecase.DeleteMember(context.Background(), admin, "team", "root")
+		assert.Equal(t, models.ErrForbidden, errors.Cause(err))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.Equal(t, models.ErrBadRequest, errors.Cause(save(owner, "editor", "superuser")))
+
+		err := usecase.SaveMember(context.Background(), administrator,
+			&models.Member{WorkspaceID: "other", OwnerID: "editor", Role: models.RoleEditor})
+		assert.Equal(t, models.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("last_owner", func(t *testing.T) {
+		assert.Equal(t, models.ErrConflict, errors.Cause(save(owner, "root", models.RoleAdmin)))
+
+		err := usecase.DeleteMember(context.Background(), owner, "team", "root")
+		assert.Equal(t, models.ErrConflict, errors.Cause(err))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, usecase.DeleteMember(context.Background(), admin, "team", "viewer"))
+
+		role, err := usecase.GetRole(context.Background(), "team", "viewer")
+		require.NoError(t, err)
+		assert.Empty(t, role)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		records, err := auditRepository.SelectRecords(context.Background(), models.AuditFilter{Limit: 10})
+		require.NoError(t, err)
+		// rejected changes are not recorded
+		require.Len(t, records, 4)
+
+		assert.Equal(t, models.AuditMemberDelete, records[0].Action)
+		assert.Equal(t, "team/viewer", records[0].Resource)
+		assert.NotNil(t, records[0].Before)
+		assert.Nil(t, records[0].After)
+
+		assert.Equal(t, models.AuditMemberUpdate, records[3].Action)
+		assert.Equal(t, "team/root", records[3].Resource)
+		assert.Nil(t, records[3].Before)
+		assert.Contains(t, string(records[3].After), `"role":"owner"`)
+	})
+}
@@ -0,0 +1,33 @@
+package models
+
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeAdmin      = "admin"
+)
+
+type APIKey struct {
+	Key     string `json:"api_key,omitempty" readonly:"true" gorm:"-"`
+	KeyHash string `json:"-" gorm:"column:key_hash"`
+	OwnerID string `json:"owner_id,omitempty" validate:"required" gorm:"column:owner_id"`
+	// the default workspace if it is empty
+	WorkspaceID string `json:"workspace_id,omitempty" gorm:"column:workspace_id"`
+}
+
+type Principal struct {
+	OwnerID     string
+	WorkspaceID string
+	// role of the owner in the workspace, empty if the owner is not its member
+	Role   Role
+	Scopes []string
+}
+
+// HasScope reports whether principal is granted scope. The admin scope grants every scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
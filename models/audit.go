@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AuditLinkCreate       = "link.create"
+	AuditLinkUpdate       = "link.update"
+	AuditLinkDelete       = "link.delete"
+	AuditAPIKeyCreate     = "api_key.create"
+	AuditWebhookCreate    = "webhook.create"
+	AuditWebhookDelete    = "webhook.delete"
+	AuditWebhookRedeliver = "webhook.redeliver"
+	AuditWorkspaceCreate  = "workspace.create"
+	AuditMemberUpdate     = "member.update"
+	AuditMemberDelete     = "member.delete"
+
+	// actors of operations made without an owner
+	AuditActorAdmin     = "admin"
+	AuditActorAnonymous = "anonymous"
+)
+
+// AuditRecord is an entry of the append-only audit log of mutating operations.
+// Before and After hold the state of the resource around the operation, they are
+// empty for created and deleted resources respectively.
+type AuditRecord struct {
+	ID        int64           `json:"id" gorm:"column:id;<-:false"`
+	Time      time.Time       `json:"time" gorm:"column:created_at"`
+	Actor     string          `json:"actor" gorm:"column:actor"`
+	Action    string          `json:"action" gorm:"column:action"`
+	Resource  string          `json:"resource" gorm:"column:resource"`
+	Before    json.RawMessage `json:"before,omitempty" swaggertype:"object" gorm:"column:before_state"`
+	After     json.RawMessage `json:"after,omitempty" swaggertype:"object" gorm:"column:after_state"`
+	Transport string          `json:"transport,omitempty" gorm:"column:transport"`
+	ClientIP  string          `json:"client_ip,omitempty" gorm:"column:client_ip"`
+	RequestID string          `json:"request_id,omitempty" gorm:"column:request_id"`
+}
+
+func (AuditRecord) TableName() string {
+	return "audit_log"
+}
+
+// SetStates encodes states of the resource before and after the operation to JSON,
+// nil states are left empty.
+func (r *AuditRecord) SetStates(before interface{}, after interface{}) (err error) {
+	if r.Before, err = encodeAuditState(before); err != nil {
+		return err
+	}
+	r.After, err = encodeAuditState(after)
+	return err
+}
+
+func encodeAuditState(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding state error")
+	}
+	return encoded, nil
+}
+
+// AuditFilter selects audit records, empty fields match any record. Records are
+// returned newest first; BeforeID pages through them by the id of the last record.
+type AuditFilter struct {
+	Actor     string
+	Action    string
+	Resource  string
+	Transport string
+	From      time.Time
+	To        time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+// Matches reports whether record is selected by the filter, not taking Limit into account.
+func (f *AuditFilter) Matches(record *AuditRecord) bool {
+	switch {
+	case f.Actor != "" && record.Actor != f.Actor,
+		f.Action != "" && record.Action != f.Action,
+		f.Resource != "" && record.Resource != f.Resource,
+		f.Transport != "" && record.Transport != f.Transport,
+		!f.From.IsZero() && record.Time.Before(f.From),
+		!f.To.IsZero() && !record.Time.Before(f.To),
+		f.BeforeID != 0 && record.ID >= f.BeforeID:
+		return false
+	}
+	return true
+}
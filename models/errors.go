@@ -8,4 +8,13 @@ var (
 	ErrNotFound            = errors.New("item is not found")
 	ErrBadRequest          = errors.New("bad request")
 	ErrInternalServerError = errors.New("internal server error")
+	ErrConflict            = errors.New("item already exists")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrServiceUnavailable  = errors.New("service unavailable")
+	ErrIdempotencyMismatch = errors.New("idempotency key is reused with different request")
+	ErrRequestInProgress   = errors.New("request with the same idempotency key is in progress")
+	ErrCursorExpired       = errors.New("cursor is expired")
+	ErrSlowConsumer        = errors.New("events are received too slowly")
 )
@@ -0,0 +1,16 @@
+package models
+
+const (
+	StatusOK = "ok"
+)
+
+type HealthStatus struct {
+	Status string `json:"status"`
+}
+
+type BuildInfo struct {
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Database  string `json:"database"`
+}
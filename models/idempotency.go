@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+const IdempotencyKeyMaxLength = 255
+
+// IdempotencyRecord stores result of the request made with Idempotency-Key.
+// Keys are separate for every owner in every workspace. Record without response is
+// a reservation of the key by the request in progress.
+type IdempotencyRecord struct {
+	WorkspaceID string    `gorm:"column:workspace_id"`
+	OwnerID     string    `gorm:"column:owner_id"`
+	Key         string    `gorm:"column:idempotency_key"`
+	Fingerprint string    `gorm:"column:fingerprint"`
+	Response    []byte    `gorm:"column:response"`
+	Completed   bool      `gorm:"column:completed"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
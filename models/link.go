@@ -1,6 +1,35 @@
 package models
 
+import (
+	"time"
+)
+
 type Link struct {
 	OriginalLink string `json:"original_link,omitempty" validate:"required" gorm:"column:original_link"`
 	ShortLink    string `json:"short_link,omitempty" readonly:"true" gorm:"column:short_link"`
+	// short links on different domains are independent, empty if domains are not configured
+	Domain       string `json:"domain,omitempty" gorm:"column:domain"`
+	// public URL of the short link on its domain, set by the service
+	ShortURL     string `json:"short_url,omitempty" readonly:"true" gorm:"-"`
+	OwnerID      string `json:"-" gorm:"column:owner_id"`
+	WorkspaceID  string `json:"-" gorm:"column:workspace_id"`
+	// counted by the repository on every redirect to the original link
+	Clicks       int64 `json:"clicks,omitempty" readonly:"true" gorm:"column:clicks;<-:false"`
+	// set by the database, so they are never written by the service
+	CreatedAt    *time.Time `json:"created_at,omitempty" readonly:"true" gorm:"column:created_at;<-:false"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty" readonly:"true" gorm:"column:updated_at;<-:false"`
+}
+
+// LinkScope selects links of the workspace a client may access: links of the owner,
+// or links of every owner of the workspace if AllOwners is set. An empty owner
+// is not a wildcard, it selects links without owner only.
+type LinkScope struct {
+	WorkspaceID string
+	OwnerID     string
+	AllOwners   bool
+}
+
+// Includes reports whether the link is selected by the scope.
+func (s LinkScope) Includes(link Link) bool {
+	return link.WorkspaceID == s.WorkspaceID && (s.AllOwners || link.OwnerID == s.OwnerID)
 }
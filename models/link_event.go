@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+)
+
+type LinkEventType string
+
+const (
+	LinkCreated LinkEventType = "created"
+	LinkUpdated LinkEventType = "updated"
+	LinkDeleted LinkEventType = "deleted"
+	// reserved for links disabled without deletion; links cannot be disabled yet,
+	// so such events are not published
+	LinkDisabled LinkEventType = "disabled"
+)
+
+// LinkEvent is a change of the link. Cursor is the position of the event
+// in the stream of events, watching can be resumed after it. OutboxID is the id
+// of the outbox event for events passed to sinks of the relay, the same event
+// relayed again has the same OutboxID.
+type LinkEvent struct {
+	Cursor   string
+	OutboxID int64
+	Type   LinkEventType
+	Link   Link
+	Time   time.Time
+}
+
+// OutboxEvent is a link event written by the repository together with the change
+// of the link and kept until it is published. Events of a link are ordered by ID.
+type OutboxEvent struct {
+	ID        int64         `gorm:"column:id"`
+	Type      LinkEventType `gorm:"column:type"`
+	Domain    string        `gorm:"column:domain"`
+	ShortLink string        `gorm:"column:short_link"`
+	// stored separately, since owner and workspace are not encoded with the link
+	OwnerID     string    `gorm:"column:owner_id"`
+	WorkspaceID string    `gorm:"column:workspace_id"`
+	Link        Link      `gorm:"column:link;serializer:json"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "link_outbox"
+}
+
+func NewOutboxEvent(eventType LinkEventType, link Link) *OutboxEvent {
+	return &OutboxEvent{
+		Type:        eventType,
+		Domain:      link.Domain,
+		ShortLink:   link.ShortLink,
+		OwnerID:     link.OwnerID,
+		WorkspaceID: link.WorkspaceID,
+		Link:        link,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// LinkEvent returns the event to publish.
+func (e *OutboxEvent) LinkEvent() LinkEvent {
+	link := e.Link
+	link.OwnerID = e.OwnerID
+	link.WorkspaceID = e.WorkspaceID
+	return LinkEvent{
+		OutboxID: e.ID,
+		Type:     e.Type,
+		Link:     link,
+		Time:     e.CreatedAt,
+	}
+}
+
+// LinkFeedEvent is a link event relayed from the outbox to the feed, which every
+// instance of the service reads to publish events to its watchers. Events are
+// appended by one relay at a time, so ids of the feed grow in order of commits.
+type LinkFeedEvent struct {
+	ID          int64         `gorm:"column:id"`
+	Type        LinkEventType `gorm:"column:type"`
+	OwnerID     string        `gorm:"column:owner_id"`
+	WorkspaceID string        `gorm:"column:workspace_id"`
+	Link        Link          `gorm:"column:link;serializer:json"`
+	CreatedAt   time.Time     `gorm:"column:created_at"`
+	// set by the database when the event is appended, old events are pruned by it
+	AppendedAt *time.Time `gorm:"column:appended_at;<-:false"`
+}
+
+func (LinkFeedEvent) TableName() string {
+	return "link_feed"
+}
+
+func NewLinkFeedEvent(event LinkEvent) *LinkFeedEvent {
+	return &LinkFeedEvent{
+		Type:        event.Type,
+		OwnerID:     event.Link.OwnerID,
+		WorkspaceID: event.Link.WorkspaceID,
+		Link:        event.Link,
+		CreatedAt:   event.Time,
+	}
+}
+
+// LinkEvent returns the event to publish.
+func (e *LinkFeedEvent) LinkEvent() LinkEvent {
+	link := e.Link
+	link.OwnerID = e.OwnerID
+	link.WorkspaceID = e.WorkspaceID
+	return LinkEvent{
+		Type: e.Type,
+		Link: link,
+		Time: e.CreatedAt,
+	}
+}
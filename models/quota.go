@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
+)
+
+type QuotaUsage struct {
+	OwnerID     string    `gorm:"column:owner_id"`
+	Period      string    `gorm:"column:period"`
+	PeriodStart time.Time `gorm:"column:period_start"`
+	Count       int64     `gorm:"column:count"`
+}
+
+func (QuotaUsage) TableName() string {
+	return "quota_usage"
+}
+
+type QuotaLimit struct {
+	Period      string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Limit       int64
+}
+
+// RateLimitError is returned when request exceeds rate limit or quota.
+// It matches ErrTooManyRequests with errors.Is.
+type RateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s, retry after %s", ErrTooManyRequests.Error(), e.Reason, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebhookLinkCreated = "link.created"
+	WebhookLinkUpdated = "link.updated"
+	WebhookLinkDeleted = "link.deleted"
+	// sent once for a link, when number of clicks on it reaches click threshold of the subscription
+	WebhookLinkClicks = "link.clicks"
+)
+
+// WebhookSubscription is URL notified about events of links of the owner in the workspace.
+// Empty Events subscribe to all events. Secret signs notifications; it is
+// returned only when the subscription is created.
+type WebhookSubscription struct {
+	ID             string     `json:"id,omitempty" readonly:"true" gorm:"column:id"`
+	OwnerID        string     `json:"-" gorm:"column:owner_id"`
+	WorkspaceID    string     `json:"-" gorm:"column:workspace_id"`
+	URL            string     `json:"url,omitempty" validate:"required,url" gorm:"column:url"`
+	Secret         string     `json:"secret,omitempty" validate:"omitempty,min=16" gorm:"column:secret"`
+	Events         []string   `json:"events,omitempty" validate:"dive,oneof=link.created link.updated link.deleted link.clicks" gorm:"column:events;serializer:json"`
+	ClickThreshold int64      `json:"click_threshold,omitempty" validate:"gte=0" gorm:"column:click_threshold"`
+	CreatedAt      *time.Time `json:"created_at,omitempty" readonly:"true" gorm:"column:created_at;<-:false"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookScope selects subscriptions of the owner in the workspace.
+type WebhookScope struct {
+	WorkspaceID string
+	OwnerID     string
+}
+
+// Includes reports whether the subscription is in the scope.
+func (s WebhookScope) Includes(subscription WebhookSubscription) bool {
+	return subscription.WorkspaceID == s.WorkspaceID && subscription.OwnerID == s.OwnerID
+}
+
+// Accepts reports whether the subscription is notified about event.
+func (s *WebhookSubscription) Accepts(event string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookNotification is the body of the request sent to the subscription URL.
+// ID is the same in every attempt to deliver it.
+type WebhookNotification struct {
+	ID    string    `json:"id"`
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Link  Link      `json:"link"`
+}
+
+// WebhookDelivery is a notification sent to the subscription until the receiver
+// responds with 2xx status. Deliveries which are not accepted in the allowed
+// number of attempts are kept as dead letters and can be redelivered.
+type WebhookDelivery struct {
+	ID             string          `json:"id" gorm:"column:id"`
+	SubscriptionID string          `json:"subscription_id" gorm:"column:subscription_id"`
+	Event          string          `json:"event" gorm:"column:event"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object" gorm:"column:payload"`
+	Attempts       int             `json:"attempts" gorm:"column:attempts"`
+	LastError      string          `json:"last_error,omitempty" gorm:"column:last_error"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"column:created_at"`
+	FailedAt       *time.Time      `json:"failed_at,omitempty" gorm:"column:failed_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_dead_letters"
+}
+
+// QueuedWebhookDelivery is a delivery waiting in the queue of its subscription for
+// the attempt at NextAttemptAt. Deliveries of a subscription are attempted one at a
+// time in order of the queue, so the receiver gets notifications in order of events.
+type QueuedWebhookDelivery struct {
+	WebhookDelivery `gorm:"embedded"`
+	NextAttemptAt   time.Time `gorm:"column:next_attempt_at"`
+}
+
+func (QueuedWebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookFiredThreshold records that the click threshold of the subscription fired
+// for the link, so the subscription is notified about clicks on the link once.
+type WebhookFiredThreshold struct {
+	SubscriptionID string    `gorm:"column:subscription_id"`
+	Domain         string    `gorm:"column:domain"`
+	ShortLink      string    `gorm:"column:short_link"`
+	FiredAt        time.Time `gorm:"column:fired_at"`
+}
+
+func (WebhookFiredThreshold) TableName() string {
+	return "webhook_fired_thresholds"
+}
+
+// WebhookAttempt is a record of the delivery log of the subscription.
+type WebhookAttempt struct {
+	ID             int64     `json:"-" gorm:"column:id;<-:false"`
+	SubscriptionID string    `json:"-" gorm:"column:subscription_id"`
+	DeliveryID     string    `json:"delivery_id" gorm:"column:delivery_id"`
+	Event          string    `json:"event" gorm:"column:event"`
+	Attempt        int       `json:"attempt" gorm:"column:attempt"`
+	Delivered      bool      `json:"delivered" gorm:"column:delivered"`
+	StatusCode     int       `json:"status_code,omitempty" gorm:"column:status_code"`
+	Error          string    `json:"error,omitempty" gorm:"column:error"`
+	DurationMS     int64     `json:"duration_ms" gorm:"column:duration_ms"`
+	Time           time.Time `json:"time" gorm:"column:attempted_at"`
+}
+
+func (WebhookAttempt) TableName() string {
+	return "webhook_attempts"
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultWorkspace holds links of clients not bound to another workspace: administrators,
+// clients authenticated by certificate and api keys or tokens without workspace.
+const DefaultWorkspace = "default"
+
+// Workspace isolates links of a team: links are created, listed and changed within
+// the workspace of the credentials of the client.
+type Workspace struct {
+	ID   string `json:"id" validate:"required,max=64" gorm:"column:id"`
+	Name string `json:"name,omitempty" gorm:"column:name"`
+	// set by the database, so it is never written by the service
+	CreatedAt *time.Time `json:"created_at,omitempty" readonly:"true" gorm:"column:created_at;<-:false"`
+}
+
+// Role grants a member permissions in the workspace, every role includes
+// permissions of the lower ones.
+type Role string
+
+const (
+	// reads links of every member
+	RoleViewer Role = "viewer"
+	// creates links and changes links of every member
+	RoleEditor Role = "editor"
+	// manages members, except owners
+	RoleAdmin Role = "admin"
+	// manages every member, the last owner can not be removed
+	RoleOwner Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// Valid reports whether role is one of the known roles.
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Includes reports whether role grants every permission of other.
+func (r Role) Includes(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
+// Member grants role in the workspace to the owner of api keys and tokens.
+type Member struct {
+	WorkspaceID string `json:"workspace_id" gorm:"column:workspace_id"`
+	OwnerID     string `json:"owner_id" gorm:"column:owner_id"`
+	Role        Role   `json:"role" validate:"required,oneof=viewer editor admin owner" enums:"viewer,editor,admin,owner" gorm:"column:role"`
+	// set by the database, so it is never written by the service
+	CreatedAt *time.Time `json:"created_at,omitempty" readonly:"true" gorm:"column:created_at;<-:false"`
+}
+
+func (Member) TableName() string {
+	return "workspace_members"
+}
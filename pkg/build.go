@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// GitCommit and BuildTime are set at build time:
+//
+//	go build -ldflags "-X github.com/kuzkuss/url_service/pkg.GitCommit=$(git rev-parse HEAD) -X github.com/kuzkuss/url_service/pkg.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// If they are not set, version control information embedded by go build is used.
+var (
+	GitCommit string
+	BuildTime string
+)
+
+// BuildInfo returns commit and time of the build and Go version used.
+func BuildInfo() (commit string, buildTime string, goVersion string) {
+	commit, buildTime = GitCommit, BuildTime
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && buildTime == "":
+				buildTime = setting.Value
+			}
+		}
+	}
+
+	if commit == "" {
+		commit = "unknown"
+	}
+	if buildTime == "" {
+		buildTime = "unknown"
+	}
+
+	return commit, buildTime, runtime.Version()
+}
@@ -0,0 +1,18 @@
+package pkg
+
+import (
+	"context"
+
+	"github.com/kuzkuss/url_service/models"
+)
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*models.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*models.Principal)
+	return principal, ok && principal != nil
+}
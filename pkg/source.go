@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"context"
+)
+
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// Source describes where the request came from: transport it is received by
+// and IP address of the client.
+type Source struct {
+	Transport string
+	ClientIP  string
+}
+
+type sourceKey struct{}
+
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func SourceFromContext(ctx context.Context) Source {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	return source
+}
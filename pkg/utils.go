@@ -1,6 +1,27 @@
 package pkg
 
+import (
+	"strconv"
+	"time"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
 type Response struct {
 	Body interface{} `json:"body"`
 }
 
+// RetryAfterSeconds formats delay as value of Retry-After header: whole seconds, rounded up.
+func RetryAfterSeconds(delay time.Duration) string {
+	seconds := int64((delay + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// Validate checks value by its validate struct tags. Every delivery validates
+// requests with it, so all transports accept the same input.
+func Validate(value interface{}) error {
+	return validator.New().Struct(value)
+}
@@ -24,6 +24,13 @@ const _ = grpc.SupportPackageIsVersion7
 type LinksClient interface {
 	CreateShortLink(ctx context.Context, in *OriginalLink, opts ...grpc.CallOption) (*ShortLink, error)
 	GetOriginalLink(ctx context.Context, in *ShortLink, opts ...grpc.CallOption) (*OriginalLink, error)
+	ListLinks(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*LinkList, error)
+	UpdateLink(ctx context.Context, in *Link, opts ...grpc.CallOption) (*Nothing, error)
+	DeleteLink(ctx context.Context, in *ShortLink, opts ...grpc.CallOption) (*Nothing, error)
+	// WatchLinks streams changes of the links of the caller (of all links for
+	// administrators) until the call is cancelled. OutOfRange means events after
+	// the cursor are not kept anymore, so links must be listed again.
+	WatchLinks(ctx context.Context, in *WatchLinksRequest, opts ...grpc.CallOption) (Links_WatchLinksClient, error)
 }
 
 type linksClient struct {
@@ -52,12 +59,78 @@ func (c *linksClient) GetOriginalLink(ctx context.Context, in *ShortLink, opts .
 	return out, nil
 }
 
+func (c *linksClient) ListLinks(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*LinkList, error) {
+	out := new(LinkList)
+	err := c.cc.Invoke(ctx, "/link.Links/ListLinks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linksClient) UpdateLink(ctx context.Context, in *Link, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/link.Links/UpdateLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linksClient) DeleteLink(ctx context.Context, in *ShortLink, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/link.Links/DeleteLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linksClient) WatchLinks(ctx context.Context, in *WatchLinksRequest, opts ...grpc.CallOption) (Links_WatchLinksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Links_ServiceDesc.Streams[0], "/link.Links/WatchLinks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &linksWatchLinksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Links_WatchLinksClient interface {
+	Recv() (*LinkEvent, error)
+	grpc.ClientStream
+}
+
+type linksWatchLinksClient struct {
+	grpc.ClientStream
+}
+
+func (x *linksWatchLinksClient) Recv() (*LinkEvent, error) {
+	m := new(LinkEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // LinksServer is the server API for Links service.
 // All implementations must embed UnimplementedLinksServer
 // for forward compatibility
 type LinksServer interface {
 	CreateShortLink(context.Context, *OriginalLink) (*ShortLink, error)
 	GetOriginalLink(context.Context, *ShortLink) (*OriginalLink, error)
+	ListLinks(context.Context, *Nothing) (*LinkList, error)
+	UpdateLink(context.Context, *Link) (*Nothing, error)
+	DeleteLink(context.Context, *ShortLink) (*Nothing, error)
+	// WatchLinks streams changes of the links of the caller (of all links for
+	// administrators) until the call is cancelled. OutOfRange means events after
+	// the cursor are not kept anymore, so links must be listed again.
+	WatchLinks(*WatchLinksRequest, Links_WatchLinksServer) error
 	mustEmbedUnimplementedLinksServer()
 }
 
@@ -71,6 +144,18 @@ func (UnimplementedLinksServer) CreateShortLink(context.Context, *OriginalLink)
 func (UnimplementedLinksServer) GetOriginalLink(context.Context, *ShortLink) (*OriginalLink, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOriginalLink not implemented")
 }
+func (UnimplementedLinksServer) ListLinks(context.Context, *Nothing) (*LinkList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLinks not implemented")
+}
+func (UnimplementedLinksServer) UpdateLink(context.Context, *Link) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateLink not implemented")
+}
+func (UnimplementedLinksServer) DeleteLink(context.Context, *ShortLink) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteLink not implemented")
+}
+func (UnimplementedLinksServer) WatchLinks(*WatchLinksRequest, Links_WatchLinksServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLinks not implemented")
+}
 func (UnimplementedLinksServer) mustEmbedUnimplementedLinksServer() {}
 
 // UnsafeLinksServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +205,81 @@ func _Links_GetOriginalLink_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Links_ListLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinksServer).ListLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/link.Links/ListLinks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinksServer).ListLinks(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Links_UpdateLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Link)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinksServer).UpdateLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/link.Links/UpdateLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinksServer).UpdateLink(ctx, req.(*Link))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Links_DeleteLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShortLink)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinksServer).DeleteLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/link.Links/DeleteLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinksServer).DeleteLink(ctx, req.(*ShortLink))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Links_WatchLinks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchLinksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LinksServer).WatchLinks(m, &linksWatchLinksServer{stream})
+}
+
+type Links_WatchLinksServer interface {
+	Send(*LinkEvent) error
+	grpc.ServerStream
+}
+
+type linksWatchLinksServer struct {
+	grpc.ServerStream
+}
+
+func (x *linksWatchLinksServer) Send(m *LinkEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Links_ServiceDesc is the grpc.ServiceDesc for Links service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,6 +295,186 @@ var Links_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOriginalLink",
 			Handler:    _Links_GetOriginalLink_Handler,
 		},
+		{
+			MethodName: "ListLinks",
+			Handler:    _Links_ListLinks_Handler,
+		},
+		{
+			MethodName: "UpdateLink",
+			Handler:    _Links_UpdateLink_Handler,
+		},
+		{
+			MethodName: "DeleteLink",
+			Handler:    _Links_DeleteLink_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchLinks",
+			Handler:       _Links_WatchLinks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "link.proto",
+}
+
+// WorkspacesClient is the client API for Workspaces service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WorkspacesClient interface {
+	ListMembers(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (*MemberList, error)
+	// SaveMember grants the role to the owner, changing the role of the existing member
+	SaveMember(ctx context.Context, in *Member, opts ...grpc.CallOption) (*Nothing, error)
+	// DeleteMember uses workspaceId and ownerId of the member only
+	DeleteMember(ctx context.Context, in *Member, opts ...grpc.CallOption) (*Nothing, error)
+}
+
+type workspacesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWorkspacesClient(cc grpc.ClientConnInterface) WorkspacesClient {
+	return &workspacesClient{cc}
+}
+
+func (c *workspacesClient) ListMembers(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (*MemberList, error) {
+	out := new(MemberList)
+	err := c.cc.Invoke(ctx, "/link.Workspaces/ListMembers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workspacesClient) SaveMember(ctx context.Context, in *Member, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/link.Workspaces/SaveMember", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workspacesClient) DeleteMember(ctx context.Context, in *Member, opts ...grpc.CallOption) (*Nothing, error) {
+	out := new(Nothing)
+	err := c.cc.Invoke(ctx, "/link.Workspaces/DeleteMember", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WorkspacesServer is the server API for Workspaces service.
+// All implementations must embed UnimplementedWorkspacesServer
+// for forward compatibility
+type WorkspacesServer interface {
+	ListMembers(context.Context, *MembersRequest) (*MemberList, error)
+	// SaveMember grants the role to the owner, changing the role of the existing member
+	SaveMember(context.Context, *Member) (*Nothing, error)
+	// DeleteMember uses workspaceId and ownerId of the member only
+	DeleteMember(context.Context, *Member) (*Nothing, error)
+	mustEmbedUnimplementedWorkspacesServer()
+}
+
+// UnimplementedWorkspacesServer must be embedded to have forward compatible implementations.
+type UnimplementedWorkspacesServer struct {
+}
+
+func (UnimplementedWorkspacesServer) ListMembers(context.Context, *MembersRequest) (*MemberList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMembers not implemented")
+}
+func (UnimplementedWorkspacesServer) SaveMember(context.Context, *Member) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SaveMember not implemented")
+}
+func (UnimplementedWorkspacesServer) DeleteMember(context.Context, *Member) (*Nothing, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMember not implemented")
+}
+func (UnimplementedWorkspacesServer) mustEmbedUnimplementedWorkspacesServer() {}
+
+// UnsafeWorkspacesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WorkspacesServer will
+// result in compilation errors.
+type UnsafeWorkspacesServer interface {
+	mustEmbedUnimplementedWorkspacesServer()
+}
+
+func RegisterWorkspacesServer(s grpc.ServiceRegistrar, srv WorkspacesServer) {
+	s.RegisterService(&Workspaces_ServiceDesc, srv)
+}
+
+func _Workspaces_ListMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkspacesServer).ListMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/link.Workspaces/ListMembers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkspacesServer).ListMembers(ctx, req.(*MembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Workspaces_SaveMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Member)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkspacesServer).SaveMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/link.Workspaces/SaveMember",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkspacesServer).SaveMember(ctx, req.(*Member))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Workspaces_DeleteMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Member)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkspacesServer).DeleteMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/link.Workspaces/DeleteMember",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkspacesServer).DeleteMember(ctx, req.(*Member))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Workspaces_ServiceDesc is the grpc.ServiceDesc for Workspaces service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Workspaces_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "link.Workspaces",
+	HandlerType: (*WorkspacesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListMembers",
+			Handler:    _Workspaces_ListMembers_Handler,
+		},
+		{
+			MethodName: "SaveMember",
+			Handler:    _Workspaces_SaveMember_Handler,
+		},
+		{
+			MethodName: "DeleteMember",
+			Handler:    _Workspaces_DeleteMember_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "link.proto",